@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// anchorTextCount is an anchor text and how many links use it
+type anchorTextCount struct {
+	Text  string
+	Count int
+}
+
+// getAnchorTextsFromHTML returns the text of every link in the HTML with whitespace collapsed,
+// lowercased if caseFold is set. Links without text are skipped.
+func getAnchorTextsFromHTML(html string, caseFold bool) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	var texts []string
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			return
+		}
+		if caseFold {
+			text = strings.ToLower(text)
+		}
+		texts = append(texts, text)
+	})
+	return texts
+}
+
+// topAnchorTexts returns the n most frequent anchor texts, most frequent first and ties sorted by text
+func topAnchorTexts(counts map[string]int, n int) []anchorTextCount {
+	top := make([]anchorTextCount, 0, len(counts))
+	for text, count := range counts {
+		top = append(top, anchorTextCount{Text: text, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Text < top[j].Text
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// printTopAnchorTexts prints the n most frequent anchor texts across the crawl
+func printTopAnchorTexts(w io.Writer, counts map[string]int, n int) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  TOP ANCHOR TEXTS")
+	fmt.Fprintln(w, "=============================")
+	for _, anchor := range topAnchorTexts(counts, n) {
+		fmt.Fprintf(w, "%d links: %q\n", anchor.Count, anchor.Text)
+	}
+}
+
+// getAnchorTextsByURL maps each link in the HTML, resolved against rawBaseURL as extractURLsFromHTML
+// resolves it, to the texts of the anchors pointing to it with whitespace collapsed
+func getAnchorTextsByURL(html, rawBaseURL string) map[string][]string {
+	base, err := url.Parse(rawBaseURL)
+	if err != nil {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	texts := make(map[string][]string)
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		parsed, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		link := base.ResolveReference(parsed).String()
+		texts[link] = append(texts[link], strings.Join(strings.Fields(s.Text()), " "))
+	})
+	return texts
+}
+
+// followAnchorText keeps the links with an anchor text matching cfg.anchorTextFilter, along with the
+// non-anchor links in always (such as a canonical or a Link header's next page). The other links are
+// counted as skipped. Anchors resolve against linkBase, like the page's links, and escaped hash-bang
+// links are matched by their original form.
+func (cfg *config) followAnchorText(htmlBody string, linkBase *url.URL, urls []string, always ...string) []string {
+	if cfg.anchorTextFilter == nil {
+		return urls
+	}
+	matching := make(map[string]bool)
+	for link, texts := range getAnchorTextsByURL(htmlBody, linkBase.String()) {
+		for _, text := range texts {
+			if cfg.anchorTextFilter.MatchString(text) {
+				matching[link] = true
+				matching[escapeHashBangURL(link)] = true
+				break
+			}
+		}
+	}
+	for _, link := range always {
+		if link != "" {
+			matching[link] = true
+		}
+	}
+
+	kept := urls[:0:0]
+	skipped := 0
+	for _, link := range urls {
+		if matching[link] {
+			kept = append(kept, link)
+		} else {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		cfg.mu.Lock()
+		cfg.skipReasons["anchor text"] += skipped
+		cfg.mu.Unlock()
+	}
+	return kept
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGetAnchorTextsFromHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		caseFold bool
+		expected []string
+	}{
+		{
+			name: "whitespace collapsed and empty text skipped",
+			html: `<a href="/a">  Read
+			more </a><a href="/b"></a><a href="/c"><img src="x.png"></a><a>No href</a>`,
+			expected: []string{"Read more"},
+		},
+		{
+			name:     "case folded",
+			html:     `<a href="/a">Home</a><a href="/b">HOME</a>`,
+			caseFold: true,
+			expected: []string{"home", "home"},
+		},
+		{
+			name:     "case preserved",
+			html:     `<a href="/a">Home</a><a href="/b">HOME</a>`,
+			expected: []string{"Home", "HOME"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := getAnchorTextsFromHTML(tc.html, tc.caseFold)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTopAnchorTexts(t *testing.T) {
+	counts := map[string]int{"about": 1, "home": 3, "blog": 1, "contact": 2}
+
+	expected := []anchorTextCount{{"home", 3}, {"contact", 2}, {"about", 1}}
+	if actual := topAnchorTexts(counts, 3); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if actual := topAnchorTexts(counts, 10); len(actual) != len(counts) {
+		t.Errorf("expected all %d texts, got %d", len(counts), len(actual))
+	}
+}
+
+func TestCrawlCountsAnchorTexts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/">Home</a><a href="/">home</a><a href="/about">About us</a><a href="/blog">Blog</a></body></html>`)
+		case "/about", "/blog":
+			fmt.Fprint(w, `<html><body><a href="/">HOME</a><a href="/about">About  us</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.anchorTexts = make(map[string]int)
+	cfg.anchorCaseFold = true
+	runTestCrawl(cfg)
+
+	expected := []anchorTextCount{{"home", 4}, {"about us", 3}, {"blog", 1}}
+	if actual := topAnchorTexts(cfg.anchorTexts, 3); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCrawlFollowsOnlyMatchingAnchorText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages := map[string]string{
+			"/":        `<a href="/page/2">Next page</a> <a href="/about">About</a> <a href="/archive">Older posts</a>`,
+			"/page/2":  `<a href="/page/3">Next  page</a> <a href="/contact">Contact</a>`,
+			"/page/3":  `<a href="/">Home</a>`,
+			"/about":   ``,
+			"/archive": ``,
+			"/contact": ``,
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", body)
+	}))
+	defer server.Close()
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.anchorTextFilter = regexp.MustCompile(`^Next`)
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	runTestCrawl(cfg)
+
+	var crawled []string
+	for page := range cfg.pages {
+		crawled = append(crawled, page)
+	}
+	sort.Strings(crawled)
+	var expected []string
+	for _, path := range []string{"/", "/page/2", "/page/3"} {
+		key, _ := cfg.normalizeURL(server.URL + path)
+		expected = append(expected, key)
+	}
+	if !reflect.DeepEqual(crawled, expected) {
+		t.Errorf("expected only the Next links to be followed: %v, got %v", expected, crawled)
+	}
+	if cfg.skipReasons["anchor text"] != 4 {
+		t.Errorf("expected 4 links skipped for their anchor text, got %v", cfg.skipReasons)
+	}
+	// Skipped links are still recorded as discovered
+	if about, _ := cfg.normalizeURL(server.URL + "/about"); cfg.inboundLinks[about] == nil {
+		t.Errorf("expected /about to be recorded as a discovered link")
+	}
+}
+
+func TestFollowAnchorTextOnAllowedHost(t *testing.T) {
+	// Relative links on b.test resolve against b.test, so their anchor text is matched there
+	site := multiHostSite{
+		"a.test/":   {"http://b.test/"},
+		"b.test/":   {"/b1"},
+		"b.test/b1": {},
+	}
+	cfg := newTestConfig(t, "http://a.test/", 10)
+	useTransport(cfg, site)
+	cfg.allowedHosts = map[string]bool{"b.test": true}
+	cfg.anchorTextFilter = regexp.MustCompile(`^link$`)
+	cfg.Run(time.Minute)
+
+	if _, ok := cfg.pages["b.test/b1"]; !ok {
+		t.Errorf("expected b.test/b1 followed for its anchor text, got %v", cfg.pages)
+	}
+	if cfg.skipReasons["anchor text"] != 0 {
+		t.Errorf("expected no link skipped for its anchor text, got %v", cfg.skipReasons)
+	}
+}
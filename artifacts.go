@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// artifactPaths names output files, grouping them under an optional directory with an optional name prefix
+type artifactPaths struct {
+	dir  string
+	name string
+}
+
+// newArtifactPaths returns the paths used for --out-dir and --name. With an output directory but no
+// name, the name defaults to the crawled host.
+func newArtifactPaths(dir, name string, baseURL *url.URL) artifactPaths {
+	if dir != "" && name == "" {
+		name = baseURL.Hostname()
+	}
+	return artifactPaths{dir: dir, name: name}
+}
+
+// file returns the path for an artifact, e.g. file("report.json") is "out/example.com-report.json"
+// with --out-dir out, or just "report.json" without --out-dir and --name
+func (a artifactPaths) file(base string) string {
+	if a.name != "" {
+		base = a.name + "-" + base
+	}
+	return filepath.Join(a.dir, base)
+}
+
+// prepare creates the output directory if one is configured
+func (a artifactPaths) prepare() error {
+	if a.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	return nil
+}
+
+// writeGraph saves the link graph to filename in the given format (png, dot or graphml).
+// layout selects the force-directed PNG layout and is ignored by the other formats.
+func writeGraph(cfg *config, baseURL, format, filename string, layout *forceLayoutOptions) error {
+	switch format {
+	case "dot":
+		return GenerateDOT(cfg.pages, cfg.externalLinks, baseURL, filename)
+	case "graphml":
+		return GenerateGraphML(cfg.pages, cfg.externalLinks, baseURL, filename)
+	default:
+		return GenerateGraphVisualization(cfg.pages, cfg.externalLinks, baseURL, filename, layout)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactPathsFile(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com:8443/docs")
+
+	tests := []struct {
+		name     string
+		dir      string
+		prefix   string
+		expected string
+	}{
+		{"defaults", "", "", "report.json"},
+		{"output directory named after host", "out", "", filepath.Join("out", "example.com-report.json")},
+		{"output directory with name", "out", "nightly", filepath.Join("out", "nightly-report.json")},
+		{"name only", "", "nightly", "nightly-report.json"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := newArtifactPaths(tc.dir, tc.prefix, baseURL).file("report.json"); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestArtifactsLandInOutputDirectory(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/about"},
+		"/about": {"/"},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	outDir := filepath.Join(t.TempDir(), "artifacts")
+	paths := newArtifactPaths(outDir, "", cfg.baseURL)
+	if err := paths.prepare(); err != nil {
+		t.Fatalf("failed to prepare output directory: %v", err)
+	}
+
+	report, err := cfg.buildReport(server.URL)
+	if err != nil {
+		t.Fatalf("failed to build report: %v", err)
+	}
+	if err := writeJSONReport(report, paths.file("report.json")); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	for _, format := range []string{"png", "dot", "graphml"} {
+		if err := writeGraph(cfg, server.URL, format, paths.file("graph."+format), nil); err != nil {
+			t.Fatalf("failed to write %s graph: %v", format, err)
+		}
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatalf("failed to read output directory: %v", err)
+	}
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		found[entry.Name()] = true
+	}
+	for _, name := range []string{"127.0.0.1-report.json", "127.0.0.1-graph.png", "127.0.0.1-graph.dot", "127.0.0.1-graph.graphml"} {
+		if !found[name] {
+			t.Errorf("expected %s in the output directory, got %v", name, found)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Most rel=canonical links followed when resolving a page's final canonical
+const maxCanonicalHops = 10
+
+// getCanonicalFromHTML returns the page's <link rel="canonical"> URL resolved against baseURL, or ""
+func getCanonicalFromHTML(html string, baseURL *url.URL) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+	canonical := ""
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		rel, _ := s.Attr("rel")
+		for _, value := range strings.Fields(strings.ToLower(rel)) {
+			if value != "canonical" {
+				continue
+			}
+			href, _ := s.Attr("href")
+			if parsed, err := url.Parse(strings.TrimSpace(href)); err == nil && strings.TrimSpace(href) != "" {
+				canonical = baseURL.ResolveReference(parsed).String()
+				return false
+			}
+		}
+		return true
+	})
+	return canonical
+}
+
+// resolveCanonical follows declared canonicals from start for at most maxHops links. It returns the pages
+// visited, starting with start, and whether the last one closes a loop back to an earlier page.
+func resolveCanonical(canonicals map[string]string, start string, maxHops int) (path []string, loop bool) {
+	path = []string{start}
+	seen := map[string]bool{start: true}
+	for current := start; len(path)-1 < maxHops; {
+		next, ok := canonicals[current]
+		if !ok {
+			return path, false
+		}
+		path = append(path, next)
+		if seen[next] {
+			return path, true
+		}
+		seen[next] = true
+		current = next
+	}
+	return path, false
+}
+
+// canonicalIssue is a canonical chain longer than one hop, or a canonical loop
+type canonicalIssue struct {
+	Path []string // pages in order, as full URLs; a loop ends with the page it returns to
+	Loop bool
+}
+
+// canonicalIssues resolves the canonical of every page that declared one. Chains of more than one hop
+// are reported from the page starting them, and each loop is reported once.
+func (cfg *config) canonicalIssues() []canonicalIssue {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	// A page that is itself the canonical of another page is in the middle of a chain
+	isTarget := make(map[string]bool)
+	for _, target := range cfg.declaredCanonicals {
+		isTarget[target] = true
+	}
+
+	var issues []canonicalIssue
+	reportedLoops := make(map[string]bool)
+	for page := range cfg.declaredCanonicals {
+		path, loop := resolveCanonical(cfg.declaredCanonicals, page, maxCanonicalHops)
+		if loop {
+			// Identify the cycle by its members so it's reported once, starting from its smallest page
+			start := indexOf(path, path[len(path)-1])
+			cycle := path[start : len(path)-1]
+			members := append([]string(nil), cycle...)
+			sort.Strings(members)
+			key := strings.Join(members, " ")
+			if reportedLoops[key] {
+				continue
+			}
+			reportedLoops[key] = true
+			first := indexOf(cycle, members[0])
+			path = append(append(append([]string(nil), cycle[first:]...), cycle[:first]...), members[0])
+		} else if len(path) <= 2 || isTarget[page] {
+			continue
+		}
+
+		full := make([]string, len(path))
+		for i, p := range path {
+			full[i] = fullPageURL(p, cfg.baseURL, cfg.canonicalURLs)
+		}
+		issues = append(issues, canonicalIssue{Path: full, Loop: loop})
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Path[0] < issues[j].Path[0] })
+	return issues
+}
+
+// indexOf returns the position of value in values, or -1
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// printCanonicalReport lists canonical chains and loops
+func printCanonicalReport(w io.Writer, issues []canonicalIssue) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  CANONICAL ISSUES")
+	fmt.Fprintln(w, "=============================")
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No canonical chains or loops found")
+	}
+	for _, issue := range issues {
+		kind := fmt.Sprintf("chain of %d hops", len(issue.Path)-1)
+		if issue.Loop {
+			kind = "loop"
+		}
+		fmt.Fprintf(w, "%s: %s\n", kind, strings.Join(issue.Path, " -> "))
+	}
+}
+
+// canonicalBuckets splits crawled pages by how their canonical relates to their own URL
+type canonicalBuckets struct {
+	SelfCanonical []string
+	Elsewhere     map[string]string // page -> the other page it names as canonical
+	NoCanonical   []string
+}
+
+// canonicalBuckets classifies every page with a recorded canonical, as full URLs with sorted lists
+func (cfg *config) canonicalBuckets() canonicalBuckets {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	buckets := canonicalBuckets{Elsewhere: make(map[string]string)}
+	for page, canonical := range cfg.pageCanonicals {
+		full := fullPageURL(page, cfg.baseURL, cfg.canonicalURLs)
+		switch canonical {
+		case "":
+			buckets.NoCanonical = append(buckets.NoCanonical, full)
+		case page:
+			buckets.SelfCanonical = append(buckets.SelfCanonical, full)
+		default:
+			buckets.Elsewhere[full] = fullPageURL(canonical, cfg.baseURL, cfg.canonicalURLs)
+		}
+	}
+	sort.Strings(buckets.SelfCanonical)
+	sort.Strings(buckets.NoCanonical)
+	return buckets
+}
+
+// printCanonicalBucketReport counts the pages in each canonical bucket, listing those that aren't self-canonical
+func printCanonicalBucketReport(w io.Writer, buckets canonicalBuckets) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  CANONICAL STATUS")
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "Self-canonical: %d\n", len(buckets.SelfCanonical))
+
+	fmt.Fprintf(w, "Canonicalized elsewhere: %d\n", len(buckets.Elsewhere))
+	pages := make([]string, 0, len(buckets.Elsewhere))
+	for page := range buckets.Elsewhere {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	for _, page := range pages {
+		fmt.Fprintf(w, "  %s -> %s\n", page, buckets.Elsewhere[page])
+	}
+
+	fmt.Fprintf(w, "No canonical: %d\n", len(buckets.NoCanonical))
+	for _, page := range buckets.NoCanonical {
+		fmt.Fprintf(w, "  %s\n", page)
+	}
+}
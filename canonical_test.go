@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetCanonicalFromHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post?page=2")
+
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{name: "absolute", html: `<link rel="canonical" href="https://example.com/blog/post">`, expected: "https://example.com/blog/post"},
+		{name: "relative", html: `<link rel="Canonical" href="post">`, expected: "https://example.com/blog/post"},
+		{name: "first wins", html: `<link rel="canonical" href="/a"><link rel="canonical" href="/b">`, expected: "https://example.com/a"},
+		{name: "none", html: `<link rel="alternate" href="/fr">`},
+		{name: "empty href", html: `<link rel="canonical" href="">`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := getCanonicalFromHTML(tc.html, base); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveCanonical(t *testing.T) {
+	canonicals := map[string]string{"a": "b", "b": "c", "x": "y", "y": "x"}
+
+	tests := []struct {
+		start        string
+		maxHops      int
+		expectedPath []string
+		expectedLoop bool
+	}{
+		{start: "a", maxHops: 10, expectedPath: []string{"a", "b", "c"}},
+		{start: "c", maxHops: 10, expectedPath: []string{"c"}},
+		{start: "x", maxHops: 10, expectedPath: []string{"x", "y", "x"}, expectedLoop: true},
+		{start: "a", maxHops: 1, expectedPath: []string{"a", "b"}},
+	}
+	for _, tc := range tests {
+		path, loop := resolveCanonical(canonicals, tc.start, tc.maxHops)
+		if !reflect.DeepEqual(path, tc.expectedPath) || loop != tc.expectedLoop {
+			t.Errorf("from %s: expected %v (loop %v), got %v (loop %v)", tc.start, tc.expectedPath, tc.expectedLoop, path, loop)
+		}
+	}
+}
+
+func TestCanonicalLoopReported(t *testing.T) {
+	canonicals := map[string]string{
+		"/":      "",
+		"/a":     "/b",
+		"/b":     "/a",
+		"/old":   "/older",
+		"/older": "/new",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canonical, ok := canonicals[r.URL.Path]
+		if !ok && r.URL.Path != "/new" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><head>")
+		if canonical != "" {
+			fmt.Fprintf(w, `<link rel="canonical" href="%s">`, canonical)
+		}
+		fmt.Fprint(w, "</head><body>")
+		if r.URL.Path == "/" {
+			// Only /a and /old are linked; their canonicals are found through the tags
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/old">old</a>`)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.declaredCanonicals = make(map[string]string)
+	runTestCrawl(cfg)
+
+	issues := cfg.canonicalIssues()
+	if len(issues) != 2 {
+		t.Fatalf("expected a loop and a chain, got %+v", issues)
+	}
+
+	loop, chain := issues[0], issues[1]
+	if !loop.Loop || len(loop.Path) != 3 || !strings.HasSuffix(loop.Path[0], "/a") || !strings.HasSuffix(loop.Path[1], "/b") || !strings.HasSuffix(loop.Path[2], "/a") {
+		t.Errorf("expected the loop /a -> /b -> /a, got %+v", loop)
+	}
+	if chain.Loop || len(chain.Path) != 3 || !strings.HasSuffix(chain.Path[0], "/old") || !strings.HasSuffix(chain.Path[2], "/new") {
+		t.Errorf("expected the chain /old -> /older -> /new, got %+v", chain)
+	}
+
+	var out strings.Builder
+	printCanonicalReport(&out, issues)
+	if !strings.Contains(out.String(), "loop: ") || !strings.Contains(out.String(), "chain of 2 hops: ") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestCanonicalBuckets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/"></head><body><a href="/print">print</a><a href="/plain">plain</a></body></html>`)
+		case "/print":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/"></head><body></body></html>`)
+		case "/plain":
+			fmt.Fprint(w, `<html><head></head><body></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.pageCanonicals = make(map[string]string)
+	runTestCrawl(cfg)
+
+	buckets := cfg.canonicalBuckets()
+	if len(buckets.SelfCanonical) != 1 || strings.Contains(buckets.SelfCanonical[0], "/p") {
+		t.Errorf("expected the home page to be self-canonical, got %v", buckets.SelfCanonical)
+	}
+	if len(buckets.Elsewhere) != 1 {
+		t.Fatalf("expected one page canonicalized elsewhere, got %v", buckets.Elsewhere)
+	}
+	for page, canonical := range buckets.Elsewhere {
+		if !strings.HasSuffix(page, "/print") || strings.HasSuffix(canonical, "/print") {
+			t.Errorf("expected /print canonicalized to the home page, got %s -> %s", page, canonical)
+		}
+	}
+	if len(buckets.NoCanonical) != 1 || !strings.HasSuffix(buckets.NoCanonical[0], "/plain") {
+		t.Errorf("expected /plain to declare no canonical, got %v", buckets.NoCanonical)
+	}
+
+	var out strings.Builder
+	printCanonicalBucketReport(&out, buckets)
+	for _, expected := range []string{"Self-canonical: 1", "Canonicalized elsewhere: 1", "/print -> ", "No canonical: 1"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in report:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// capturedResponse is the metadata saved next to each recorded response body
+type capturedResponse struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+}
+
+// captureKey names the files holding a request's recorded response, derived from its normalized URL
+func captureKey(req *http.Request) string {
+	key, err := normalizeURL(req.URL.String())
+	if err != nil {
+		key = req.URL.String()
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordingTransport is an http.RoundTripper that saves every response it receives from next to dir
+// as <key>.json (status and headers) and <key>.body, so the crawl can be replayed later
+type recordingTransport struct {
+	next http.RoundTripper
+	dir  string
+}
+
+// newRecordingTransport creates dir if needed and returns a transport recording into it
+func newRecordingTransport(next http.RoundTripper, dir string) (*recordingTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create record directory: %v", err)
+	}
+	return &recordingTransport{next: next, dir: dir}, nil
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response for recording: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	meta, err := json.MarshalIndent(capturedResponse{URL: req.URL.String(), StatusCode: resp.StatusCode, Header: resp.Header}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recorded response: %v", err)
+	}
+	key := filepath.Join(t.dir, captureKey(req))
+	if err := os.WriteFile(key+".body", body, 0644); err != nil {
+		return nil, fmt.Errorf("failed to record response: %v", err)
+	}
+	if err := os.WriteFile(key+".json", meta, 0644); err != nil {
+		return nil, fmt.Errorf("failed to record response: %v", err)
+	}
+	return resp, nil
+}
+
+// replayTransport is an http.RoundTripper serving responses saved by recordingTransport instead of
+// using the network. Requests with no recorded response fail.
+type replayTransport struct {
+	dir string
+}
+
+// newReplayTransport returns a transport replaying the recording in dir
+func newReplayTransport(dir string) (*replayTransport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("replay path %s is not a directory", dir)
+	}
+	return &replayTransport{dir: dir}, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := filepath.Join(t.dir, captureKey(req))
+
+	data, err := os.ReadFile(key + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no recorded response for %s", req.URL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recorded response for %s: %v", req.URL, err)
+	}
+	var meta capturedResponse
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse recorded response for %s: %v", req.URL, err)
+	}
+	body, err := os.ReadFile(key + ".body")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recorded body for %s: %v", req.URL, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", meta.StatusCode, http.StatusText(meta.StatusCode)),
+		StatusCode:    meta.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        meta.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
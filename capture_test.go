@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useTestTransport restores the shared client's transport once the test ends
+func useTestTransport(t *testing.T, transport http.RoundTripper) {
+	t.Helper()
+	previous := httpClient.Transport
+	httpClient.Transport = transport
+	t.Cleanup(func() { httpClient.Transport = previous })
+}
+
+func TestRecordThenReplayProducesIdenticalReport(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":          {"/about", "/blog", "https://external.example/"},
+		"/about":     {"/", "/missing"},
+		"/blog":      {"/blog/post"},
+		"/blog/post": {"/about"},
+	})
+	dir := t.TempDir()
+
+	recorder, err := newRecordingTransport(httpClient.Transport, dir)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	useTestTransport(t, recorder)
+	recorded := newTestConfig(t, server.URL, 10)
+	runTestCrawl(recorded)
+	recordedReport, err := recorded.buildReport(server.URL)
+	if err != nil {
+		t.Fatalf("failed to build recorded report: %v", err)
+	}
+
+	// Replay with the server gone, so any request not served from the recording fails
+	server.Close()
+	replayer, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("failed to create replayer: %v", err)
+	}
+	httpClient.Transport = replayer
+	replayed := newTestConfig(t, server.URL, 10)
+	runTestCrawl(replayed)
+	replayedReport, err := replayed.buildReport(server.URL)
+	if err != nil {
+		t.Fatalf("failed to build replayed report: %v", err)
+	}
+
+	if !reflect.DeepEqual(recordedReport, replayedReport) {
+		t.Errorf("replayed report differs from recorded one\nrecorded: %+v\nreplayed: %+v", recordedReport, replayedReport)
+	}
+	if len(replayedReport.Pages) != 5 {
+		t.Errorf("expected 5 pages in the replayed report, got %v", replayedReport.Pages)
+	}
+}
+
+func TestReplayMissFails(t *testing.T) {
+	replayer, err := newReplayTransport(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create replayer: %v", err)
+	}
+	req, err := http.NewRequest("GET", "https://example.com/never-recorded", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if _, err := replayer.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("expected a missing recording error, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// normalizeURLCaseInsensitive normalizes a URL like normalizeURL, lowercasing its path so that
+// /About and /about are the same page, as they are on case-insensitive servers
+func normalizeURLCaseInsensitive(rawURL string) (string, error) {
+	return urlNormalizer{caseInsensitive: true}.normalize(rawURL)
+}
+
+// caseCollision is a page reached through paths differing only by letter case
+type caseCollision struct {
+	Page     string   // normalized page URL
+	Variants []string // distinct paths linking to it, sorted
+}
+
+// recordPathVariant notes the path rawURL used to reach normalizedURL, if variants are being tracked
+func (cfg *config) recordPathVariant(normalizedURL string, u *url.URL) {
+	if cfg.pathVariants == nil {
+		return
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	if path == "" {
+		path = "/"
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	if cfg.pathVariants[normalizedURL] == nil {
+		cfg.pathVariants[normalizedURL] = make(map[string]bool)
+	}
+	cfg.pathVariants[normalizedURL][path] = true
+}
+
+// caseCollisions returns the pages reached through more than one spelling of their path, sorted by page
+func (cfg *config) caseCollisions() []caseCollision {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	var collisions []caseCollision
+	for page, paths := range cfg.pathVariants {
+		if len(paths) < 2 {
+			continue
+		}
+		variants := make([]string, 0, len(paths))
+		for path := range paths {
+			variants = append(variants, path)
+		}
+		sort.Strings(variants)
+		collisions = append(collisions, caseCollision{Page: page, Variants: variants})
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Page < collisions[j].Page
+	})
+	return collisions
+}
+
+// printCaseCollisionReport prints the pages that collapsed because their paths differ only by case
+func printCaseCollisionReport(w io.Writer, collisions []caseCollision) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  CASE-INSENSITIVE DUPLICATES")
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "Pages linked with inconsistent path case: %d\n", len(collisions))
+	for _, collision := range collisions {
+		fmt.Fprintf(w, "%s: %s\n", collision.Page, strings.Join(collision.Variants, ", "))
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeURLCaseInsensitive(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputURL string
+		expected string
+	}{
+		{
+			name:     "path lowercased",
+			inputURL: "https://example.com/About/Team",
+			expected: "example.com/about/team",
+		},
+		{
+			name:     "escaped path lowercased",
+			inputURL: "https://example.com/Caf%C3%A9%20Menu",
+			expected: "example.com/café menu",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := normalizeURLCaseInsensitive(tc.inputURL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+
+	// With --crawl-fragments, a hash-bang route keeps its case
+	normalizer := urlNormalizer{caseInsensitive: true, hashBangRoutes: true}
+	if actual, _ := normalizer.normalize("https://example.com/Shop?_escaped_fragment_=/Products"); actual != "example.com/shop#!/Products" {
+		t.Errorf("expected the route to keep its case, got %q", actual)
+	}
+}
+
+func TestCrawlPageCaseInsensitivePaths(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/About", "/about"},
+		"/About": {"/"},
+		"/about": {"/"},
+	})
+
+	for _, caseInsensitive := range []bool{false, true} {
+		cfg := newTestConfig(t, server.URL, 10)
+		if caseInsensitive {
+			cfg.normalize = normalizeURLCaseInsensitive
+			cfg.pathVariants = make(map[string]map[string]bool)
+		}
+		runTestCrawl(cfg)
+
+		expectedPages := 3
+		if caseInsensitive {
+			expectedPages = 2
+		}
+		if len(cfg.pages) != expectedPages {
+			t.Errorf("caseInsensitive=%v: expected %d pages, got %v", caseInsensitive, expectedPages, cfg.pages)
+		}
+
+		collisions := cfg.caseCollisions()
+		if !caseInsensitive {
+			if len(collisions) != 0 {
+				t.Errorf("expected no collisions without the flag, got %+v", collisions)
+			}
+			continue
+		}
+		about, _ := cfg.normalizeURL(server.URL + "/about")
+		expected := []caseCollision{{Page: about, Variants: []string{"/About", "/about"}}}
+		if !reflect.DeepEqual(collisions, expected) {
+			t.Errorf("expected collisions %+v, got %+v", expected, collisions)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkpointStats are the crawl counters saved with each checkpoint of the JSON report
+type checkpointStats struct {
+	Partial         bool      `json:"partial"` // false once the crawl is done
+	WrittenAt       time.Time `json:"written_at"`
+	ElapsedSeconds  float64   `json:"elapsed_seconds"`
+	PagesAttempted  int64     `json:"pages_attempted"`
+	FailedPages     int64     `json:"failed_pages"`
+	BytesDownloaded int64     `json:"bytes_downloaded"`
+}
+
+// writeCheckpoint saves a snapshot of the report and counters to filename. The file is replaced in one
+// step, so tools polling it never read a half-written report.
+func (cfg *config) writeCheckpoint(filename, baseURL string, start time.Time, partial bool) error {
+	report, err := cfg.buildReport(baseURL)
+	if err != nil {
+		return err
+	}
+	now := cfg.clock.Now()
+	report.Checkpoint = &checkpointStats{
+		Partial:         partial,
+		WrittenAt:       now,
+		ElapsedSeconds:  now.Sub(start).Seconds(),
+		PagesAttempted:  atomic.LoadInt64(cfg.totalRequests),
+		FailedPages:     atomic.LoadInt64(cfg.failedRequests),
+		BytesDownloaded: atomic.LoadInt64(cfg.bytesDownloaded),
+	}
+	tmp := filename + ".tmp"
+	if err := writeJSONReport(report, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %v", err)
+	}
+	return nil
+}
+
+// startCheckpoints writes the report so far to filename every interval until the returned function is
+// called, which writes the final report
+func (cfg *config) startCheckpoints(filename, baseURL string, interval time.Duration) (stop func()) {
+	start := cfg.clock.Now()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-cfg.clock.After(interval):
+				if err := cfg.writeCheckpoint(filename, baseURL, start, true); err != nil {
+					fmt.Printf("Error writing checkpoint: %v\n", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+			if err := cfg.writeCheckpoint(filename, baseURL, start, false); err != nil {
+				fmt.Printf("Error writing checkpoint: %v\n", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointsWritePartialReportsMidCrawl(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/slow">slow</a></body></html>`)
+		case "/slow":
+			// Hold the crawl open until the test has seen a checkpoint
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>done</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "report.json")
+	readCheckpoint := func() (*Report, error) {
+		report, err := loadJSONReport(filename)
+		if err == nil && report.Checkpoint == nil {
+			err = fmt.Errorf("report has no checkpoint")
+		}
+		return report, err
+	}
+
+	cfg := newTestConfig(t, server.URL, 10)
+	stop := cfg.startCheckpoints(filename, server.URL, 20*time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		runTestCrawl(cfg)
+		close(done)
+	}()
+
+	// Wait for a checkpoint taken while /slow is still being fetched
+	var partial *Report
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if report, err := readCheckpoint(); err == nil && report.Checkpoint.PagesAttempted >= 1 {
+			partial = report
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+	<-done
+	stop()
+
+	if partial == nil {
+		t.Fatal("expected a checkpoint to be written mid-crawl")
+	}
+	if !partial.Checkpoint.Partial || partial.Checkpoint.PagesAttempted != 1 {
+		t.Errorf("expected a partial checkpoint with the root page done, got %+v", partial.Checkpoint)
+	}
+	if len(partial.Pages) != 2 {
+		t.Errorf("expected the root and the queued /slow page in the checkpoint, got %v", partial.Pages)
+	}
+
+	final, err := readCheckpoint()
+	if err != nil {
+		t.Fatalf("failed to read the final report: %v", err)
+	}
+	if final.Checkpoint.Partial || final.Checkpoint.PagesAttempted != 2 {
+		t.Errorf("expected a complete final report with both pages, got %+v", final.Checkpoint)
+	}
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected no temporary file left behind, got %v", err)
+	}
+
+	// The checkpoint is the regular JSON report with the progress added
+	data, _ := os.ReadFile(filename)
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil || raw["pages"] == nil || raw["checkpoint"] == nil {
+		t.Errorf("expected pages and checkpoint in the JSON, got %s", data)
+	}
+}
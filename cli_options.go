@@ -0,0 +1,731 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cliOptions holds the optional --flags accepted on the command line
+type cliOptions struct {
+	generateGraph bool
+	graphFormat   string // "png", "dot" or "graphml"
+	graphLayout   string // "circle" or "force"
+	graphGrid     bool   // approximate the force layout with a spatial grid
+	output        string // "text" or "json"
+	// Directory and name prefix for output files, and explicit paths overriding them
+	outDir     string
+	name       string
+	reportFile string
+	graphFile  string
+	baseline   string // previous JSON report to diff against
+	pretty     bool
+	noColor    bool
+	topN       int  // report entries per section, 0 means all
+	liveReport bool // redraw the top pages in place while crawling
+	sortBy     reportSort
+	maxDepth   int // 0 means unlimited
+	// Print the statistics and the number of pages and external links, without listing them
+	summaryOnly bool
+	// How often the JSON report so far is saved while crawling (0 only writes it at the end)
+	checkpointInterval time.Duration
+	// When false, redirects from internal pages to other hosts are recorded rather than followed
+	followExternalRedirects bool
+	// Only follow redirects within the requested URL's registered domain, recording the others as external
+	redirectsOnlyToBase bool
+	// File of URLs and URL prefixes never to crawl, and its entries once loaded
+	excludeFile string
+	denylist    []string
+	// Fraction of discovered links to enqueue (1 disables sampling) and the seed for reproducible sampling
+	sampleRate float64
+	sampleSeed uint64
+	// Bypass robots.txt and robots meta directives (for authorized crawls of one's own site)
+	ignoreRobots bool
+	// Cap on the robots.txt Crawl-delay honored between requests to a host
+	maxCrawlDelay time.Duration
+	// Report structural warnings for malformed HTML
+	htmlWarnings bool
+	// URL schemes to follow; links with any other scheme are skipped
+	allowedSchemes map[string]bool
+	// Header and <meta> name carrying a page's crawl priority hint, and the priority of pages without one
+	priorityHeader  string
+	priorityMeta    string
+	defaultPriority int
+	// After softDeadline (0 disables) no new page starts; at hardDeadline the crawl is cancelled
+	softDeadline time.Duration
+	hardDeadline time.Duration
+	// Intern kept URLs and drop per-page details that only feed rarely used report lines
+	compactMemory bool
+	// Bound on queued links (0 means unbounded) and what to do when the queue is full
+	maxQueue    int
+	queuePolicy string
+	// Maximum distinct external URLs to record (0 means unlimited)
+	maxExternal int
+	// Fetch robots.txt (and optionally the sitemap) before crawling, failing fast on an unreachable seed
+	warmup        bool
+	warmupSitemap bool
+	// State file holding the crawl ledger, and how recently crawled pages are reused instead of refetched
+	stateFile string
+	maxAge    time.Duration
+	// Keep per-host health in the state file and start hosts that were flaky last run with one request slot
+	rememberHostHealth bool
+	// Directory to save every response to, or to serve recorded responses from instead of the network
+	recordDir string
+	replayDir string
+	// Crawl hash-bang routes (#!/path) as pages via the _escaped_fragment_ scheme
+	crawlFragments bool
+	// Log DNS, connect, TLS and first-byte timings of each request and average them in the statistics
+	traceRequests bool
+	// Report redirect loops and chains of several redirects
+	redirectChains bool
+	// Fetch http links over https on hosts already seen redirecting http to https
+	upgradeToHTTPS bool
+	// Retry pages over http after a TLS error over https, unless a link explicitly asked for https
+	schemeFallback bool
+	// Treat paths differing only by case as the same page and report where that happened
+	caseInsensitivePaths bool
+	// Whether normalization strips, adds or preserves a path's trailing slash
+	trailingSlash string
+	// Treat URLs with different queries as different pages, optionally ignoring the case of parameter names
+	keepQuery       bool
+	ignoreQueryCase bool
+	// Record URLs differing only by #fragment as separate pages, fetching each URL once
+	fragmentsAsPages bool
+	// File of seed URLs crawled as independent sites with one report each, and how many to crawl at once
+	seedsFile     string
+	parallelSeeds int
+	// Warn about TLS certificates expiring within this window
+	certExpiryWindow time.Duration
+	// Print the site as a tree of shortest-path parents
+	tree bool
+	// Cap on referrers kept per page in the JSON report's link graph
+	maxReferrers int
+	// How long resolved host addresses are cached (0 disables the DNS cache)
+	dnsCacheTTL time.Duration
+	// File mapping hosts to the proxy, credentials and TLS settings their requests need ("" uses one client)
+	hostProfilesFile string
+	// Maximum simultaneous requests to a single host (0 means only the global limit applies)
+	concurrencyPerHost int
+	// Number of most frequent anchor texts to report (0 disables) and whether to ignore their case
+	topAnchors     int
+	anchorCaseFold bool
+	// Only follow links whose anchor text matches (nil follows every link)
+	anchorTextFilter *regexp.Regexp
+	// Maximum internal links followed per page (0 follows every link)
+	followPerPage int
+	// File to stream crawl events to as NDJSON ("" disables the event log)
+	eventsFile string
+	// Response headers to capture per page (nil disables capturing)
+	captureHeaders []string
+	// File to dump every page's captured headers to, as JSON or CSV by extension ("" disables the dump)
+	headersOut string
+	// Accept-Language header to request a localized version of the site ("" keeps the default)
+	acceptLanguage string
+	// Record the HTTP protocol version each page was served over and report it per host
+	recordProtocol bool
+	// Report external links rolled up by registered domain
+	onlyNewHosts bool
+	// Report external links per domain category (social, CDN, analytics, other), with optional extra patterns
+	categorizeExternal     bool
+	externalCategoriesFile string
+	// Read rel=canonical links, crawl their targets and report canonical chains and loops
+	respectCanonical bool
+	// Extra hosts to crawl besides the seed's (nil crawls only the seed's host)
+	allowedHosts map[string]bool
+	// Which hosts count as the seed's own: exact, registered-domain or suffix
+	hostMatch string
+	// How redirected pages are listed in the report: target, source or both
+	redirectReport string
+	// How deep each host is explored from its entry point (nil sets no per-host limit)
+	hostDepthLimits *hostDepthLimits
+	// How long each host may be crawled from its first request (0 is unlimited)
+	maxHostDuration time.Duration
+	// Report which pages are self-canonical, canonicalized elsewhere or declare no canonical
+	canonicalReport bool
+	// Report self-linking pages and pages whose outbound links are mostly external
+	linkProfile bool
+	// Report hreflang alternates that are broken or lack a return link
+	hreflang bool
+	// Abort the crawl with a non-zero exit status at the first broken internal page
+	failFast bool
+	// Check each distinct external link once after the internal crawl, allowing externalTimeout per link
+	validateExternal bool
+	externalTimeout  time.Duration
+	// Exit non-zero when a validated external link is dead, or only when it answers with one of
+	// brokenExternalStatuses (nil counts every dead link)
+	failOnBrokenExternal   bool
+	brokenExternalStatuses map[int]bool
+	// Check each distinct image once after the crawl and report broken ones by referring page
+	validateImages bool
+	// Catalogue each distinct image once after the crawl: content type, size and dimensions
+	crawlImagesAsPages bool
+	// Inventory links to downloadable files by type and declared size instead of crawling them, and the
+	// file extensions counted as downloads (nil uses defaultDownloadExtensions)
+	inventoryDownloads bool
+	downloadExtensions map[string]bool
+	// Simultaneous checks of external links and images (0 uses the crawl concurrency)
+	externalConcurrency int
+	// On-page SEO checks to report (nil disables the SEO report)
+	seoChecks map[string]bool
+	// Check each page's JSON-LD against the required fields of its @type, with extra rules from
+	// structuredDataRulesFile on top of the built-in ones (structuredDataRules is loaded from it by main)
+	validateStructuredData  bool
+	structuredDataRulesFile string
+	structuredDataRules     map[string][]string
+	// Fetch every page again with mobileUserAgent and report pages whose main text differs
+	compareMobile   bool
+	mobileUserAgent string
+	// Report clusters of pages whose main text SimHashes differ by at most nearDuplicateDistance bits
+	nearDuplicates        bool
+	nearDuplicateDistance int
+	// How visited pages are tracked ("map" or "bloom") and the bloom filter's false-positive rate
+	visitedStore string
+	bloomFPRate  float64
+	// Method and body (given inline or read from a file) for the seed request
+	method   string
+	body     string
+	bodyFile string
+	// Response size caps by content type prefix (nil applies the built-in cap to everything)
+	sizePolicy sizePolicy
+	// User-Agent override and a contact for site operators, sent in the User-Agent and From headers
+	userAgent string
+	contact   string
+	// Render pages in headless Chrome before extracting links (requires a build with the chromedp tag)
+	renderJS bool
+	// File of cookies to preload per host, e.g. to get past consent walls ("" preloads none)
+	cookiesFile string
+	// Login form submitted before crawling (disabled when login.url is empty)
+	login loginOptions
+}
+
+// parseCLIFlags separates --flags from positional arguments.
+// Flags may be given as "--name", "--name=value" or "--name value" (for flags that take a value).
+func parseCLIFlags(args []string) (cliOptions, []string, error) {
+	opts := cliOptions{
+		graphFormat:             "png",
+		graphLayout:             "circle",
+		output:                  "text",
+		followExternalRedirects: true,
+		sampleRate:              1,
+		sampleSeed:              1,
+		allowedSchemes:          defaultAllowedSchemes,
+		queuePolicy:             queuePolicyBlock,
+		maxCrawlDelay:           defaultMaxCrawlDelay,
+		maxReferrers:            defaultMaxReferrers,
+		certExpiryWindow:        defaultCertExpiryWindow,
+		trailingSlash:           trailingSlashStrip,
+		hostMatch:               hostMatchExact,
+		redirectReport:          redirectReportTarget,
+		hardDeadline:            defaultHardDeadline,
+		nearDuplicateDistance:   defaultNearDuplicateDistance,
+		mobileUserAgent:         defaultMobileUserAgent,
+		externalTimeout:         defaultExternalCheckTimeout,
+		visitedStore:            "map",
+		bloomFPRate:             defaultBloomFalsePositiveRate,
+		login: loginOptions{
+			userField:     "username",
+			passwordField: "password",
+		},
+	}
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+
+		// boolValue returns true for a bare flag, or the parsed value of "--name=value"
+		boolValue := func() (bool, error) {
+			if !hasValue {
+				return true, nil
+			}
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return false, fmt.Errorf("invalid value for --%s: %q", name, value)
+			}
+			return parsed, nil
+		}
+
+		// stringValue returns the value of "--name=value" or consumes the following argument
+		stringValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("flag --%s requires a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+
+		// nonNegativeIntValue parses the flag's value as an integer >= 0
+		nonNegativeIntValue := func() (int, error) {
+			raw, err := stringValue()
+			if err != nil {
+				return 0, err
+			}
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 0 {
+				return 0, fmt.Errorf("--%s must be a non-negative integer, got %q", name, raw)
+			}
+			return parsed, nil
+		}
+
+		// sampleRateValue parses the flag's value as a fraction in (0, 1]
+		sampleRateValue := func() (float64, error) {
+			raw, err := stringValue()
+			if err != nil {
+				return 0, err
+			}
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil || parsed <= 0 || parsed > 1 {
+				return 0, fmt.Errorf("--%s must be a number greater than 0 and at most 1, got %q", name, raw)
+			}
+			return parsed, nil
+		}
+
+		var err error
+		switch name {
+		case "graph":
+			opts.generateGraph, err = boolValue()
+		case "graph-format":
+			if opts.graphFormat, err = stringValue(); err == nil && opts.graphFormat != "png" && opts.graphFormat != "dot" && opts.graphFormat != "graphml" {
+				err = fmt.Errorf("--%s must be png, dot or graphml, got %q", name, opts.graphFormat)
+			}
+		case "graph-layout":
+			if opts.graphLayout, err = stringValue(); err == nil && opts.graphLayout != "circle" && opts.graphLayout != "force" {
+				err = fmt.Errorf("--%s must be circle or force, got %q", name, opts.graphLayout)
+			}
+		case "graph-layout-grid":
+			opts.graphGrid, err = boolValue()
+		case "output":
+			if opts.output, err = stringValue(); err == nil && opts.output != "text" && opts.output != "json" {
+				err = fmt.Errorf("--%s must be text or json, got %q", name, opts.output)
+			}
+		case "out-dir":
+			opts.outDir, err = stringValue()
+		case "name":
+			opts.name, err = stringValue()
+		case "report-file":
+			opts.reportFile, err = stringValue()
+		case "graph-file":
+			opts.graphFile, err = stringValue()
+		case "baseline":
+			opts.baseline, err = stringValue()
+		case "pretty":
+			opts.pretty, err = boolValue()
+		case "no-color":
+			opts.noColor, err = boolValue()
+		case "sort-by":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.sortBy, err = parseReportSort(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "live-report":
+			opts.liveReport, err = boolValue()
+		case "checkpoint-interval":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.checkpointInterval, err = time.ParseDuration(raw); err != nil || opts.checkpointInterval <= 0 {
+					err = fmt.Errorf("--%s must be a positive duration such as 30s, got %q", name, raw)
+				}
+			}
+		case "summary-only":
+			opts.summaryOnly, err = boolValue()
+		case "top-n":
+			opts.topN, err = nonNegativeIntValue()
+		case "max-depth":
+			opts.maxDepth, err = nonNegativeIntValue()
+		case "allow-hosts":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.allowedHosts, err = parseAllowedHosts(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "host-match":
+			if opts.hostMatch, err = stringValue(); err == nil && opts.hostMatch != hostMatchExact && opts.hostMatch != hostMatchRegisteredDomain && opts.hostMatch != hostMatchSuffix {
+				err = fmt.Errorf("--%s must be exact, registered-domain or suffix, got %q", name, opts.hostMatch)
+			}
+		case "redirect-report":
+			if opts.redirectReport, err = stringValue(); err == nil && opts.redirectReport != redirectReportTarget && opts.redirectReport != redirectReportSource && opts.redirectReport != redirectReportBoth {
+				err = fmt.Errorf("--%s must be source, target or both, got %q", name, opts.redirectReport)
+			}
+		case "max-host-duration":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.maxHostDuration, err = time.ParseDuration(raw); err != nil || opts.maxHostDuration <= 0 {
+					err = fmt.Errorf("--%s must be a positive duration such as 30s, got %q", name, raw)
+				}
+			}
+		case "max-depth-per-host":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.hostDepthLimits, err = parseHostDepthLimits(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "follow-external-redirects":
+			opts.followExternalRedirects, err = boolValue()
+		case "allow-insecure-redirects-only-to-base":
+			opts.redirectsOnlyToBase, err = boolValue()
+		case "allowed-schemes":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				opts.allowedSchemes = make(map[string]bool)
+				for _, scheme := range strings.Split(raw, ",") {
+					if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+						opts.allowedSchemes[scheme] = true
+					}
+				}
+				if len(opts.allowedSchemes) == 0 {
+					err = fmt.Errorf("--%s must list at least one scheme", name)
+				}
+			}
+		case "max-external":
+			opts.maxExternal, err = nonNegativeIntValue()
+		case "max-queue":
+			opts.maxQueue, err = nonNegativeIntValue()
+		case "queue-policy":
+			if opts.queuePolicy, err = stringValue(); err == nil && opts.queuePolicy != queuePolicyBlock && opts.queuePolicy != queuePolicyDrop {
+				err = fmt.Errorf("--%s must be %s or %s, got %q", name, queuePolicyBlock, queuePolicyDrop, opts.queuePolicy)
+			}
+		case "warmup":
+			opts.warmup, err = boolValue()
+		case "warmup-sitemap":
+			opts.warmupSitemap, err = boolValue()
+		case "state":
+			opts.stateFile, err = stringValue()
+		case "max-age":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.maxAge, err = time.ParseDuration(raw); err != nil || opts.maxAge <= 0 {
+					err = fmt.Errorf("--%s must be a positive duration such as 24h, got %q", name, raw)
+				}
+			}
+		case "cert-expiry-window":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.certExpiryWindow, err = time.ParseDuration(raw); err != nil || opts.certExpiryWindow < 0 {
+					err = fmt.Errorf("--%s must be a non-negative duration such as 720h, got %q", name, raw)
+				}
+			}
+		case "tree":
+			opts.tree, err = boolValue()
+		case "max-referrers":
+			opts.maxReferrers, err = nonNegativeIntValue()
+		case "host-profiles":
+			opts.hostProfilesFile, err = stringValue()
+		case "dns-cache-ttl":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.dnsCacheTTL, err = time.ParseDuration(raw); err != nil || opts.dnsCacheTTL < 0 {
+					err = fmt.Errorf("--%s must be a non-negative duration such as 5m, got %q", name, raw)
+				}
+			}
+		case "remember-host-health":
+			opts.rememberHostHealth, err = boolValue()
+		case "concurrency-per-host":
+			opts.concurrencyPerHost, err = nonNegativeIntValue()
+		case "top-anchors":
+			opts.topAnchors, err = nonNegativeIntValue()
+		case "anchor-text-filter":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.anchorTextFilter, err = regexp.Compile(raw); err != nil {
+					err = fmt.Errorf("invalid --%s pattern: %v", name, err)
+				}
+			}
+		case "follow-per-page":
+			opts.followPerPage, err = nonNegativeIntValue()
+		case "anchor-case-fold":
+			opts.anchorCaseFold, err = boolValue()
+		case "method":
+			opts.method, err = stringValue()
+		case "body":
+			opts.body, err = stringValue()
+		case "body-file":
+			opts.bodyFile, err = stringValue()
+		case "max-filesize-per-type":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.sizePolicy, err = parseSizePolicy(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "user-agent":
+			opts.userAgent, err = stringValue()
+		case "contact":
+			opts.contact, err = stringValue()
+		case "events":
+			opts.eventsFile, err = stringValue()
+		case "record-protocol":
+			opts.recordProtocol, err = boolValue()
+		case "capture-headers":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.captureHeaders, err = parseHeaderList(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "headers-out":
+			opts.headersOut, err = stringValue()
+		case "accept-language":
+			if opts.acceptLanguage, err = stringValue(); err == nil && strings.TrimSpace(opts.acceptLanguage) == "" {
+				err = fmt.Errorf("--%s requires a language such as fr-FR", name)
+			}
+		case "categorize-external":
+			opts.categorizeExternal, err = boolValue()
+		case "external-categories":
+			opts.externalCategoriesFile, err = stringValue()
+			opts.categorizeExternal = true
+		case "only-new-hosts":
+			opts.onlyNewHosts, err = boolValue()
+		case "respect-canonical":
+			opts.respectCanonical, err = boolValue()
+		case "canonical-report":
+			opts.canonicalReport, err = boolValue()
+		case "link-profile":
+			opts.linkProfile, err = boolValue()
+		case "hreflang":
+			opts.hreflang, err = boolValue()
+		case "fail-fast":
+			opts.failFast, err = boolValue()
+		case "follow-only-internal-then-validate-external":
+			opts.validateExternal, err = boolValue()
+		case "fail-on-broken-external":
+			opts.failOnBrokenExternal, err = boolValue()
+		case "broken-external-statuses":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.brokenExternalStatuses, err = parseStatusList(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "validate-images":
+			opts.validateImages, err = boolValue()
+		case "crawl-images-as-pages":
+			opts.crawlImagesAsPages, err = boolValue()
+		case "inventory-downloads":
+			opts.inventoryDownloads, err = boolValue()
+		case "download-extensions":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.downloadExtensions, err = parseDownloadExtensions(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "external-concurrency":
+			if opts.externalConcurrency, err = nonNegativeIntValue(); err == nil && opts.externalConcurrency == 0 {
+				err = fmt.Errorf("--%s must be at least 1", name)
+			}
+		case "external-timeout":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.externalTimeout, err = time.ParseDuration(raw); err != nil || opts.externalTimeout <= 0 {
+					err = fmt.Errorf("--%s must be a positive duration such as 10s, got %q", name, raw)
+				}
+			}
+		case "compare-mobile":
+			opts.compareMobile, err = boolValue()
+		case "mobile-user-agent":
+			if opts.mobileUserAgent, err = stringValue(); err == nil && strings.TrimSpace(opts.mobileUserAgent) == "" {
+				err = fmt.Errorf("--%s requires a User-Agent", name)
+			}
+		case "dedupe-near-duplicate-content":
+			opts.nearDuplicates, err = boolValue()
+		case "near-duplicate-distance":
+			if opts.nearDuplicateDistance, err = nonNegativeIntValue(); err == nil && opts.nearDuplicateDistance > 63 {
+				err = fmt.Errorf("--%s must be at most 63", name)
+			}
+		case "seo-checks":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.seoChecks, err = parseSEOChecks(raw); err != nil {
+					err = fmt.Errorf("--%s: %v", name, err)
+				}
+			}
+		case "json-schema-validate":
+			opts.validateStructuredData, err = boolValue()
+		case "structured-data-rules":
+			opts.structuredDataRulesFile, err = stringValue()
+		case "render-js":
+			opts.renderJS, err = boolValue()
+		case "crawl-fragments":
+			opts.crawlFragments, err = boolValue()
+		case "trace-requests":
+			opts.traceRequests, err = boolValue()
+		case "seeds":
+			opts.seedsFile, err = stringValue()
+		case "parallel-seeds":
+			if opts.parallelSeeds, err = nonNegativeIntValue(); err == nil && opts.parallelSeeds == 0 {
+				err = fmt.Errorf("--%s must be at least 1", name)
+			}
+		case "redirect-chains":
+			opts.redirectChains, err = boolValue()
+		case "max-redirect-to-https":
+			opts.upgradeToHTTPS, err = boolValue()
+		case "scheme-fallback":
+			opts.schemeFallback, err = boolValue()
+		case "trailing-slash":
+			if opts.trailingSlash, err = stringValue(); err == nil && opts.trailingSlash != trailingSlashStrip && opts.trailingSlash != trailingSlashAdd && opts.trailingSlash != trailingSlashPreserve {
+				err = fmt.Errorf("--%s must be strip, add or preserve, got %q", name, opts.trailingSlash)
+			}
+		case "case-insensitive-paths":
+			opts.caseInsensitivePaths, err = boolValue()
+		case "keep-query":
+			opts.keepQuery, err = boolValue()
+		case "ignore-query-case":
+			opts.ignoreQueryCase, err = boolValue()
+		case "fragments-as-pages":
+			opts.fragmentsAsPages, err = boolValue()
+		case "visited-store":
+			if opts.visitedStore, err = stringValue(); err == nil && opts.visitedStore != "map" && opts.visitedStore != "bloom" {
+				err = fmt.Errorf("--%s must be map or bloom, got %q", name, opts.visitedStore)
+			}
+		case "bloom-fp-rate":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.bloomFPRate, err = strconv.ParseFloat(raw, 64); err != nil || opts.bloomFPRate <= 0 || opts.bloomFPRate >= 1 {
+					err = fmt.Errorf("--%s must be a number between 0 and 1, got %q", name, raw)
+				}
+			}
+		case "record":
+			opts.recordDir, err = stringValue()
+		case "replay":
+			opts.replayDir, err = stringValue()
+		case "cookies-file":
+			opts.cookiesFile, err = stringValue()
+		case "login":
+			opts.login.url, err = stringValue()
+		case "login-user":
+			opts.login.user, err = stringValue()
+		case "login-password":
+			opts.login.password, err = stringValue()
+		case "login-user-field":
+			opts.login.userField, err = stringValue()
+		case "login-password-field":
+			opts.login.passwordField, err = stringValue()
+		case "login-csrf-field":
+			opts.login.csrfField, err = stringValue()
+		case "html-warnings":
+			opts.htmlWarnings, err = boolValue()
+		case "ignore-robots":
+			opts.ignoreRobots, err = boolValue()
+		case "max-crawl-delay":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.maxCrawlDelay, err = time.ParseDuration(raw); err != nil || opts.maxCrawlDelay < 0 {
+					err = fmt.Errorf("--%s must be a non-negative duration such as 10s, got %q", name, raw)
+				}
+			}
+		case "soft-deadline", "hard-deadline":
+			var raw string
+			var deadline time.Duration
+			if raw, err = stringValue(); err == nil {
+				if deadline, err = time.ParseDuration(raw); err != nil || deadline <= 0 {
+					err = fmt.Errorf("--%s must be a positive duration such as 5m, got %q", name, raw)
+				}
+			}
+			if name == "soft-deadline" {
+				opts.softDeadline = deadline
+			} else {
+				opts.hardDeadline = deadline
+			}
+		case "compact-memory":
+			opts.compactMemory, err = boolValue()
+		case "priority-header":
+			opts.priorityHeader, err = stringValue()
+		case "priority-meta":
+			opts.priorityMeta, err = stringValue()
+		case "default-priority":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.defaultPriority, err = strconv.Atoi(raw); err != nil {
+					err = fmt.Errorf("--%s must be an integer, got %q", name, raw)
+				}
+			}
+		case "exclude-file":
+			opts.excludeFile, err = stringValue()
+		case "sample-rate":
+			opts.sampleRate, err = sampleRateValue()
+		case "sample-seed":
+			var raw string
+			if raw, err = stringValue(); err == nil {
+				if opts.sampleSeed, err = strconv.ParseUint(raw, 10, 64); err != nil {
+					err = fmt.Errorf("--%s must be a non-negative integer, got %q", name, raw)
+				}
+			}
+		default:
+			err = fmt.Errorf("unknown flag: --%s", name)
+		}
+		if err != nil {
+			return opts, nil, err
+		}
+	}
+
+	if opts.maxAge > 0 && opts.stateFile == "" {
+		return opts, nil, fmt.Errorf("--max-age requires --state")
+	}
+	if opts.ignoreQueryCase && !opts.keepQuery {
+		return opts, nil, fmt.Errorf("--ignore-query-case requires --keep-query")
+	}
+	if opts.structuredDataRulesFile != "" && !opts.validateStructuredData {
+		return opts, nil, fmt.Errorf("--structured-data-rules requires --json-schema-validate")
+	}
+	if opts.failOnBrokenExternal && !opts.validateExternal {
+		return opts, nil, fmt.Errorf("--fail-on-broken-external requires --follow-only-internal-then-validate-external")
+	}
+	if opts.brokenExternalStatuses != nil && !opts.failOnBrokenExternal {
+		return opts, nil, fmt.Errorf("--broken-external-statuses requires --fail-on-broken-external")
+	}
+	if opts.downloadExtensions != nil && !opts.inventoryDownloads {
+		return opts, nil, fmt.Errorf("--download-extensions requires --inventory-downloads")
+	}
+	if opts.rememberHostHealth && opts.stateFile == "" {
+		return opts, nil, fmt.Errorf("--remember-host-health requires --state")
+	}
+
+	if opts.recordDir != "" && opts.replayDir != "" {
+		return opts, nil, fmt.Errorf("--record and --replay cannot be used together")
+	}
+
+	if opts.body != "" && opts.bodyFile != "" {
+		return opts, nil, fmt.Errorf("--body and --body-file cannot be used together")
+	}
+
+	if opts.softDeadline > 0 && opts.softDeadline >= opts.hardDeadline {
+		return opts, nil, fmt.Errorf("--soft-deadline must be shorter than --hard-deadline (%v)", opts.hardDeadline)
+	}
+
+	if opts.mobileUserAgent != defaultMobileUserAgent && !opts.compareMobile {
+		return opts, nil, fmt.Errorf("--mobile-user-agent requires --compare-mobile")
+	}
+	if opts.nearDuplicateDistance != defaultNearDuplicateDistance && !opts.nearDuplicates {
+		return opts, nil, fmt.Errorf("--near-duplicate-distance requires --dedupe-near-duplicate-content")
+	}
+	if opts.headersOut != "" && opts.captureHeaders == nil {
+		return opts, nil, fmt.Errorf("--headers-out requires --capture-headers")
+	}
+
+	if opts.defaultPriority != 0 && opts.priorityHeader == "" && opts.priorityMeta == "" {
+		return opts, nil, fmt.Errorf("--default-priority requires --priority-header or --priority-meta")
+	}
+
+	if opts.parallelSeeds > 0 && opts.seedsFile == "" {
+		return opts, nil, fmt.Errorf("--parallel-seeds requires --seeds")
+	}
+	if opts.seedsFile != "" && opts.parallelSeeds == 0 {
+		opts.parallelSeeds = 1
+	}
+
+	return opts, positional, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// clientProfile is the proxy, credentials and TLS settings requests to a host need. Hosts with equal
+// profiles share one client, and so its connections.
+type clientProfile struct {
+	Proxy              string // proxy URL ("" connects directly)
+	Username, Password string // HTTP basic auth credentials ("" sends none)
+	InsecureSkipVerify bool   // accept any TLS certificate, e.g. for staging hosts with self-signed ones
+}
+
+// loadHostProfiles reads the client profile of each host from filename. Each line holds a host (with an
+// optional port) followed by key=value settings, e.g.
+// "staging.example.com proxy=http://proxy:3128 user=alice password=secret insecure=true";
+// blank lines and lines starting with # are ignored.
+func loadHostProfiles(filename string) (map[string]clientProfile, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host profiles: %v", err)
+	}
+	defer file.Close()
+
+	profiles := make(map[string]clientProfile)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var profile clientProfile
+		for _, setting := range fields[1:] {
+			key, value, ok := strings.Cut(setting, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value, got %q", line, setting)
+			}
+			switch strings.ToLower(key) {
+			case "proxy":
+				if parsed, err := url.Parse(value); err != nil || parsed.Host == "" {
+					return nil, fmt.Errorf("line %d: invalid proxy URL %q", line, value)
+				}
+				profile.Proxy = value
+			case "user":
+				profile.Username = value
+			case "password":
+				profile.Password = value
+			case "insecure":
+				profile.InsecureSkipVerify = value == "true"
+			default:
+				return nil, fmt.Errorf("line %d: unknown setting %q", line, key)
+			}
+		}
+		profiles[strings.ToLower(fields[0])] = profile
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read host profiles: %v", err)
+	}
+	return profiles, nil
+}
+
+// clientPool holds the HTTP clients of one crawl: base, and one client per client profile, created lazily
+// from base and reused for every host mapped to that profile. Hosts without a profile use base itself.
+type clientPool struct {
+	base  *http.Client
+	hosts map[string]clientProfile // host or host:port -> profile (nil when no host has one)
+
+	mu      sync.Mutex
+	clients map[clientProfile]*http.Client
+}
+
+// newClientPool creates a pool sending requests with base, or with a client of their host's profile
+func newClientPool(base *http.Client, hosts map[string]clientProfile) *clientPool {
+	return &clientPool{base: base, hosts: hosts, clients: make(map[clientProfile]*http.Client)}
+}
+
+// clientPoolKey is the context key carrying the clients of the crawl a request belongs to
+type clientPoolKey struct{}
+
+// withClients returns a context whose requests are sent with the clients of pool (nil leaves ctx unchanged)
+func withClients(ctx context.Context, pool *clientPool) context.Context {
+	if pool == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clientPoolKey{}, pool)
+}
+
+// requestContext returns ctx carrying what the requests of cfg's crawl are sent with: its clients, identity,
+// size policy and Accept-Language
+func (cfg *config) requestContext(ctx context.Context) context.Context {
+	ctx = withIdentity(withClients(ctx, cfg.clients), cfg.identity)
+	return withAcceptLanguage(withSizePolicy(ctx, cfg.sizePolicy), cfg.acceptLanguage)
+}
+
+// clientFor returns the client to send req with: that of its crawl for the host, or httpClient for
+// requests outside a crawl
+func clientFor(req *http.Request) *http.Client {
+	return clientForURL(req.Context(), req.URL)
+}
+
+// clientForURL returns the client a request for u made with ctx is sent with
+func clientForURL(ctx context.Context, u *url.URL) *http.Client {
+	if pool, ok := ctx.Value(clientPoolKey{}).(*clientPool); ok {
+		return pool.client(u)
+	}
+	return httpClient
+}
+
+// profile returns the profile of u's host, preferring an entry for host:port over one for the host
+func (p *clientPool) profile(u *url.URL) (clientProfile, bool) {
+	if profile, ok := p.hosts[strings.ToLower(u.Host)]; ok {
+		return profile, true
+	}
+	profile, ok := p.hosts[strings.ToLower(u.Hostname())]
+	return profile, ok
+}
+
+// client returns the client of u's host profile, or base for hosts without one
+func (p *clientPool) client(u *url.URL) *http.Client {
+	profile, ok := p.profile(u)
+	if !ok {
+		return p.base
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, ok := p.clients[profile]; ok {
+		return client
+	}
+	// Credentials only go to hosts of this profile, not to hosts a redirect leads to
+	client := newProfileClient(p.base, profile, func(u *url.URL) bool {
+		target, ok := p.profile(u)
+		return ok && target == profile
+	})
+	p.clients[profile] = client
+	return client
+}
+
+// newProfileClient copies base, keeping its timeout, redirect policy and cookies, with a transport applying
+// profile. Proxy and TLS settings need base to use an *http.Transport, possibly wrapped by --record, whose
+// recording then wraps the profile's transport; credentials are added either way, to the requests whose
+// URL matches authHost.
+func newProfileClient(base *http.Client, profile clientProfile, authHost func(u *url.URL) bool) *http.Client {
+	client := *base
+	transport := base.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	recorder, recording := transport.(*recordingTransport)
+	if recording {
+		transport = recorder.next
+	}
+	if t, ok := transport.(*http.Transport); ok {
+		t = t.Clone()
+		if profile.Proxy != "" {
+			proxyURL, _ := url.Parse(profile.Proxy)
+			t.Proxy = http.ProxyURL(proxyURL)
+		}
+		if profile.InsecureSkipVerify {
+			if t.TLSClientConfig == nil {
+				t.TLSClientConfig = &tls.Config{}
+			}
+			t.TLSClientConfig.InsecureSkipVerify = true
+		}
+		transport = t
+	}
+	if profile.Username != "" || profile.Password != "" {
+		transport = &basicAuthTransport{next: transport, username: profile.Username, password: profile.Password, match: authHost}
+	}
+	if recording {
+		transport = &recordingTransport{next: transport, dir: recorder.dir}
+	}
+	client.Transport = transport
+	return &client
+}
+
+// basicAuthTransport adds HTTP basic auth credentials to requests for matching URLs that don't carry an
+// Authorization header
+type basicAuthTransport struct {
+	next               http.RoundTripper
+	username, password string
+	match              func(u *url.URL) bool
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") == "" && t.match(req.URL) {
+		req = req.Clone(req.Context())
+		req.SetBasicAuth(t.username, t.password)
+	}
+	return t.next.RoundTrip(req)
+}
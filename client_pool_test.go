@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadHostProfiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expected  map[string]clientProfile
+		expectErr bool
+	}{
+		{
+			name:    "profiles with comments",
+			content: "# staging\nStaging.example.com proxy=http://proxy:3128 insecure=true\n\nadmin.example.com:8443 user=alice password=secret\n",
+			expected: map[string]clientProfile{
+				"staging.example.com":    {Proxy: "http://proxy:3128", InsecureSkipVerify: true},
+				"admin.example.com:8443": {Username: "alice", Password: "secret"},
+			},
+		},
+		{
+			name:      "setting without a value",
+			content:   "example.com insecure\n",
+			expectErr: true,
+		},
+		{
+			name:      "unknown setting",
+			content:   "example.com token=abc\n",
+			expectErr: true,
+		},
+		{
+			name:      "invalid proxy",
+			content:   "example.com proxy=proxy\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "profiles.txt")
+			if err := os.WriteFile(filename, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("failed to write profiles: %v", err)
+			}
+			profiles, err := loadHostProfiles(filename)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", profiles)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(profiles, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, profiles)
+			}
+		})
+	}
+}
+
+func TestClientPoolPerProfile(t *testing.T) {
+	// Requests through the proxy arrive with the absolute URL they were meant for
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	protected := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "alice" || password != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+	}))
+	defer protected.Close()
+	protectedURL, _ := url.Parse(protected.URL)
+
+	base := newHTTPClient()
+	pool := newClientPool(base, map[string]clientProfile{
+		"proxied.test":    {Proxy: proxy.URL},
+		"mirror.test":     {Proxy: proxy.URL},
+		protectedURL.Host: {Username: "alice", Password: "secret"},
+	})
+
+	proxiedURL, _ := url.Parse("http://proxied.test/page")
+	mirrorURL, _ := url.Parse("http://mirror.test/page")
+	otherURL, _ := url.Parse("http://other.test/page")
+	proxiedClient := pool.client(proxiedURL)
+	authClient := pool.client(protectedURL)
+
+	if proxiedClient == base || authClient == base || proxiedClient == authClient {
+		t.Fatalf("expected a distinct client per profile")
+	}
+	if pool.client(proxiedURL) != proxiedClient || pool.client(mirrorURL) != proxiedClient {
+		t.Errorf("expected hosts with the same profile to reuse one client")
+	}
+	if pool.client(otherURL) != base {
+		t.Errorf("expected hosts without a profile to use the base client")
+	}
+
+	resp, err := proxiedClient.Get(proxiedURL.String())
+	if err != nil {
+		t.Fatalf("proxied request failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(proxied) != 1 || !strings.HasPrefix(proxied[0], "http://proxied.test/page") {
+		t.Errorf("expected the request to go through the proxy, got %v", proxied)
+	}
+
+	resp, err = authClient.Get(protected.URL)
+	if err != nil {
+		t.Fatalf("authenticated request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected credentials to be sent, got HTTP %d", resp.StatusCode)
+	}
+	if resp, err = base.Get(protected.URL); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected the base client to send no credentials, got HTTP %d", resp.StatusCode)
+		}
+	}
+}
+
+func TestClientPoolProfileUnderRecording(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	dir := t.TempDir()
+	base := newHTTPClient()
+	recorder, err := newRecordingTransport(base.Transport, dir)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	base.Transport = recorder
+	pool := newClientPool(base, map[string]clientProfile{"proxied.test": {Proxy: proxy.URL}})
+
+	resp, err := pool.client(&url.URL{Scheme: "http", Host: "proxied.test"}).Get("http://proxied.test/page")
+	if err != nil {
+		t.Fatalf("proxied request failed: %v", err)
+	}
+	resp.Body.Close()
+	if len(proxied) != 1 {
+		t.Errorf("expected the recorded request to still go through the proxy, got %v", proxied)
+	}
+	if recorded, _ := os.ReadDir(dir); len(recorded) == 0 {
+		t.Error("expected the proxied response to be recorded")
+	}
+}
+
+func TestClientPoolProfileClientsShareCookieJar(t *testing.T) {
+	var cookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			cookie = c.Value
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	base := newHTTPClient()
+	if err := ensureCookieJar(base); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pool := newClientPool(base, map[string]clientProfile{serverURL.Host: {Username: "alice", Password: "secret"}})
+
+	// The profile client is created (as warmup would) before the session cookie is set on base's jar
+	client := pool.client(serverURL)
+	base.Jar.SetCookies(serverURL, []*http.Cookie{{Name: "session", Value: "abc"}})
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if cookie != "abc" {
+		t.Errorf("expected the profile client to send the session cookie set later on base, got %q", cookie)
+	}
+}
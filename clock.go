@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time for backoff, rate limiting and timeouts, so tests can control it
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+// clockKey is the context key carrying the Clock used by page requests
+type clockKey struct{}
+
+// withClock returns a context whose page requests wait for rate limits and retries on clock
+func withClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// clockFrom returns the Clock carried by ctx, or the real clock
+func clockFrom(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+	return realClock{}
+}
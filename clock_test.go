@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when advanced, so time-based logic runs without real waiting
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Advance moves the clock forward by d, firing every timer that falls due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// waitForTimers blocks until n timers are pending, so a test advances the clock only once code is waiting on it
+func (c *fakeClock) waitForTimers(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		pending := len(c.waiters)
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d pending timers", n)
+}
+
+func TestRetryWithBackoffFakeClock(t *testing.T) {
+	clock := newFakeClock()
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.clock = clock
+	start := clock.Now()
+	realStart := time.Now()
+
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- cfg.retryWithBackoff(func() error {
+			attempts++
+			if attempts < 3 {
+				return &FetchError{Kind: FetchHTTPStatus, StatusCode: 503, Status: "503 Service Unavailable", URL: "https://example.com"}
+			}
+			return nil
+		})
+	}()
+
+	// Two failures back off for the base delay and then twice that
+	for _, delay := range []time.Duration{baseRetryDelay, 2 * baseRetryDelay} {
+		clock.waitForTimers(t, 1)
+		clock.Advance(delay)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 3*baseRetryDelay {
+		t.Errorf("expected %v of backoff on the fake clock, got %v", 3*baseRetryDelay, elapsed)
+	}
+	if elapsed := time.Since(realStart); elapsed >= baseRetryDelay {
+		t.Errorf("expected no real sleeping, took %v", elapsed)
+	}
+}
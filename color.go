@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// ANSI escape sequences used for the pretty report
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// reportStyle controls how printReport formats its output
+type reportStyle struct {
+	color bool // wrap counts and headers in ANSI color codes
+	align bool // right-align counts into a single column
+	topN  int  // print only the first topN entries of each section (0 prints them all)
+	sort  reportSort
+	// Print only how many entries each section has, not the entries
+	summaryOnly bool
+}
+
+// newReportStyle picks the report style for w. Pretty output is only used when w is a terminal,
+// so files and pipes always receive plain text; noColor keeps alignment but drops ANSI codes.
+func newReportStyle(w io.Writer, pretty, noColor bool) reportStyle {
+	if !pretty || !isTerminal(w) {
+		return reportStyle{}
+	}
+	return reportStyle{color: !noColor, align: true}
+}
+
+// isTerminal reports whether w is a character device such as a TTY
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the given ANSI code when color is enabled
+func (s reportStyle) paint(code, text string) string {
+	if !s.color {
+		return text
+	}
+	return code + text + ansiReset
+}
+
+// truncate returns the first topN entries of the sorted list and how many were left out
+func (s reportStyle) truncate(list []Page) (shown []Page, more int) {
+	if s.topN <= 0 || len(list) <= s.topN {
+		return list, 0
+	}
+	return list[:s.topN], len(list) - s.topN
+}
+
+// countWidth returns the column width needed to right-align the counts in list,
+// or 0 when alignment is disabled
+func (s reportStyle) countWidth(list []Page) int {
+	if !s.align {
+		return 0
+	}
+	width := 0
+	for _, page := range list {
+		if n := len(strconv.Itoa(page.Count)); n > width {
+			width = n
+		}
+	}
+	return width
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintReportNoColorWhenNotTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	style := newReportStyle(&buf, true, false)
+	if style.color || style.align {
+		t.Fatalf("expected plain style for non-terminal writer, got %+v", style)
+	}
+
+	pages := map[string]int{"example.com": 3, "example.com/about": 12}
+	externalLinks := map[string]int{"https://other.com": 1}
+	if err := printReport(&buf, pages, externalLinks, nil, "https://example.com", style); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	if strings.Contains(output, "\x1b[") {
+		t.Errorf("expected no ANSI color codes in output, got %q", output)
+	}
+	if !strings.Contains(output, "Found 3 internal links to https://example.com\n") {
+		t.Errorf("expected plain unaligned report line, got %q", output)
+	}
+}
+
+func TestPrintReportColorWhenEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	style := reportStyle{color: true, align: true}
+
+	pages := map[string]int{"example.com": 3, "example.com/about": 12}
+	if err := printReport(&buf, pages, map[string]int{}, nil, "https://example.com", style); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, ansiCyan) {
+		t.Errorf("expected colored counts in output, got %q", output)
+	}
+	if !strings.Contains(output, " 3"+ansiReset+" internal links to https://example.com\n") {
+		t.Errorf("expected right-aligned count, got %q", output)
+	}
+}
+
+func TestPrintReportTopN(t *testing.T) {
+	var buf bytes.Buffer
+	style := reportStyle{topN: 2}
+
+	pages := map[string]int{"example.com": 3, "example.com/about": 12, "example.com/blog": 7, "example.com/faq": 1}
+	externalLinks := map[string]int{"https://a.com": 1, "https://b.com": 5}
+	if err := printReport(&buf, pages, externalLinks, nil, "https://example.com", style); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	if rows := strings.Count(output, "internal links to"); rows != 2 {
+		t.Errorf("expected 2 internal rows, got %d in %q", rows, output)
+	}
+	for _, expected := range []string{
+		"Found 12 internal links to https://example.com/about\n",
+		"Found 7 internal links to https://example.com/blog\n",
+		"(… and 2 more)\n",
+		"Found 5 external links to https://b.com\nFound 1 external links to https://a.com\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output, got %q", expected, output)
+		}
+	}
+	if strings.Count(output, "more)") != 1 {
+		t.Errorf("expected no \"more\" line for the external section within the limit, got %q", output)
+	}
+}
+
+func TestPrintReportSummaryOnly(t *testing.T) {
+	var buf bytes.Buffer
+	pages := map[string]int{"example.com": 3, "example.com/about": 12}
+	externalLinks := map[string]int{"https://other.com": 1}
+	if err := printReport(&buf, pages, externalLinks, nil, "https://example.com", reportStyle{summaryOnly: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	if strings.Contains(output, "Found ") || strings.Contains(output, "EXTERNAL LINKS REPORT") {
+		t.Errorf("expected no page or external link listing, got %q", output)
+	}
+	for _, expected := range []string{"REPORT for https://example.com", "Internal pages: 2\n", "External links: 1\n"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in the summary, got %q", expected, output)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "unique"
+
+// compactMemory trims what a crawl keeps until its report is written. URLs recorded in the crawl's maps
+// and in the state file's link lists are interned, so a URL linked from thousands of pages is stored once
+// rather than once per page and never pins the page buffer it was parsed from. Per-page details that only
+// feed rarely used report lines, the pages behind external redirects and the rel=next links checked for
+// pagination gaps, are no longer recorded.
+func (cfg *config) compactMemory() {
+	cfg.compact = true
+	cfg.externalRedirects = nil
+	cfg.nextLinks = nil
+}
+
+// intern returns the canonical copy of s when memory is compacted, and s itself otherwise
+func (cfg *config) intern(s string) string {
+	if !cfg.compact {
+		return s
+	}
+	return unique.Make(s).Value()
+}
+
+// internAll returns urls interned into a slice of their own, so the extraction buffer isn't retained
+// either (urls itself when memory isn't compacted)
+func (cfg *config) internAll(urls []string) []string {
+	if !cfg.compact {
+		return urls
+	}
+	interned := make([]string, len(urls))
+	for i, u := range urls {
+		interned[i] = unique.Make(u).Value()
+	}
+	return interned
+}
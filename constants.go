@@ -1,6 +1,10 @@
 package main
 
+import "time"
+
 const (
 	// Maximum number of URLs to extract from a single page
 	maxURLsPerPage = 1000
+	// How long a crawl may run unless --hard-deadline says otherwise
+	defaultHardDeadline = 10 * time.Minute
 )
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultAcceptLanguage is sent when --accept-language isn't given
+const defaultAcceptLanguage = "en-US,en;q=0.5"
+
+// acceptLanguageKey is the context key carrying the Accept-Language of the crawl a page is fetched for
+type acceptLanguageKey struct{}
+
+// withAcceptLanguage returns a context whose page requests are sent with the Accept-Language value
+func withAcceptLanguage(ctx context.Context, value string) context.Context {
+	return context.WithValue(ctx, acceptLanguageKey{}, value)
+}
+
+// acceptLanguageFor returns the Accept-Language header to send with page requests made with ctx
+func acceptLanguageFor(ctx context.Context) string {
+	if value, ok := ctx.Value(acceptLanguageKey{}).(string); ok && value != "" {
+		return value
+	}
+	return defaultAcceptLanguage
+}
+
+// primaryLanguage returns the lowercased primary subtag of the first language in an Accept-Language or
+// Content-Language value ("fr-FR,fr;q=0.9" -> "fr")
+func primaryLanguage(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	first, _, _ = strings.Cut(first, ";")
+	primary, _, _ := strings.Cut(strings.TrimSpace(first), "-")
+	return strings.ToLower(primary)
+}
+
+// printContentLanguageReport counts pages per Content-Language and lists the pages that don't declare the
+// requested language, so an i18n audit can confirm it got the intended locale
+func printContentLanguageReport(w io.Writer, languages map[string]string, requested string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  CONTENT LANGUAGE")
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "Requested: %s\n", requested)
+
+	counts := make(map[string]int)
+	var mismatched []string
+	for page, language := range languages {
+		if language == "" {
+			language = "(none)"
+		}
+		counts[language]++
+		if primaryLanguage(languages[page]) != primaryLanguage(requested) {
+			mismatched = append(mismatched, page)
+		}
+	}
+
+	names := make([]string, 0, len(counts))
+	for language := range counts {
+		names = append(names, language)
+	}
+	sort.Strings(names)
+	for _, language := range names {
+		fmt.Fprintf(w, "%s: %d pages\n", language, counts[language])
+	}
+
+	sort.Strings(mismatched)
+	fmt.Fprintf(w, "Pages not in the requested language: %d\n", len(mismatched))
+	for _, page := range mismatched {
+		language := languages[page]
+		if language == "" {
+			language = "no Content-Language"
+		}
+		fmt.Fprintf(w, "  %s (%s)\n", page, language)
+	}
+}
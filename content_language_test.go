@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrimaryLanguage(t *testing.T) {
+	tests := map[string]string{
+		"fr-FR":            "fr",
+		"fr-FR,fr;q=0.9":   "fr",
+		"EN":               "en",
+		" de-CH ; q=0.8":   "de",
+		"":                 "",
+		"zh-Hant-TW, en":   "zh",
+		"en-US,en;q=0.5":   "en",
+		"pt-BR;q=1, pt-PT": "pt",
+	}
+	for value, expected := range tests {
+		if actual := primaryLanguage(value); actual != expected {
+			t.Errorf("primaryLanguage(%q): expected %q, got %q", value, expected, actual)
+		}
+	}
+}
+
+func TestAcceptLanguageSentAndContentLanguageRecorded(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			mu.Lock()
+			received = append(received, r.Header.Get("Accept-Language"))
+			mu.Unlock()
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Language", "fr-FR")
+			fmt.Fprint(w, `<html><body><a href="/english">en</a><a href="/plain">plain</a></body></html>`)
+		case "/english":
+			w.Header().Set("Content-Language", "en")
+			fmt.Fprint(w, `<html><body></body></html>`)
+		case "/plain":
+			fmt.Fprint(w, `<html><body></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.acceptLanguage = "fr-FR"
+	cfg.contentLanguages = make(map[string]string)
+	cfg.Run(time.Minute)
+
+	if len(received) != 3 {
+		t.Errorf("expected 3 page requests, got %d", len(received))
+	}
+	for _, header := range received {
+		if header != "fr-FR" {
+			t.Errorf("expected Accept-Language fr-FR on every page request, got %q", header)
+		}
+	}
+	expected := map[string]string{
+		server.URL:              "fr-FR",
+		server.URL + "/english": "en",
+		server.URL + "/plain":   "",
+	}
+	for page, language := range expected {
+		if actual, ok := cfg.contentLanguages[page]; !ok || actual != language {
+			t.Errorf("expected Content-Language %q recorded for %s, got %q", language, page, actual)
+		}
+	}
+
+	var out strings.Builder
+	printContentLanguageReport(&out, cfg.contentLanguages, cfg.acceptLanguage)
+	for _, line := range []string{
+		"fr-FR: 1 pages",
+		"Pages not in the requested language: 2",
+		server.URL + "/english (en)",
+		server.URL + "/plain (no Content-Language)",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected report to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ensureCookieJar attaches a cookie jar to client if it doesn't have one
+func ensureCookieJar(client *http.Client) error {
+	if client.Jar != nil {
+		return nil
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return fmt.Errorf("failed to create cookie jar: %v", err)
+	}
+	client.Jar = jar
+	return nil
+}
+
+// loadHostCookies reads the cookies to send to each host from filename. Each line holds a host followed by
+// one or more name=value cookies, e.g. "www.example.com consent=accepted region=eu"; blank lines and lines
+// starting with # are ignored. A host listed on several lines gets the cookies of all of them.
+func loadHostCookies(filename string) (map[string][]*http.Cookie, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %v", err)
+	}
+	defer file.Close()
+
+	cookies := make(map[string][]*http.Cookie)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		host := strings.ToLower(fields[0])
+		if strings.ContainsAny(host, "/:") {
+			return nil, fmt.Errorf("line %d: expected a host name without scheme, port or path, got %q", line, fields[0])
+		}
+		if len(fields) == 1 {
+			return nil, fmt.Errorf("line %d: no cookies given for %s", line, host)
+		}
+		for _, pair := range fields[1:] {
+			name, value, ok := strings.Cut(pair, "=")
+			cookie := &http.Cookie{Name: name, Value: value}
+			if !ok || cookie.Valid() != nil {
+				return nil, fmt.Errorf("line %d: invalid cookie %q, expected name=value", line, pair)
+			}
+			cookies[host] = append(cookies[host], cookie)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %v", err)
+	}
+	return cookies, nil
+}
+
+// preloadCookies adds each host's cookies to client's cookie jar, creating the jar if needed. The cookies are
+// host-only: they are sent to that exact host, over http and https, but not to its subdomains.
+func preloadCookies(client *http.Client, cookies map[string][]*http.Cookie) error {
+	if err := ensureCookieJar(client); err != nil {
+		return err
+	}
+	for host, hostCookies := range cookies {
+		client.Jar.SetCookies(&url.URL{Scheme: "http", Host: host, Path: "/"}, hostCookies)
+	}
+	return nil
+}
+
+// cookiesFor returns the cookies a request for u made with ctx would carry: those in the jar of its client
+func cookiesFor(ctx context.Context, u *url.URL) []*http.Cookie {
+	client := clientForURL(ctx, u)
+	if client.Jar == nil {
+		return nil
+	}
+	return client.Jar.Cookies(u)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadHostCookies(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expected  map[string][]string
+		expectErr bool
+	}{
+		{
+			name:    "cookies per host",
+			content: "# consent walls\nWWW.example.com consent=accepted region=eu\n\nshop.example.com consent=accepted\nwww.example.com currency=EUR\n",
+			expected: map[string][]string{
+				"www.example.com":  {"consent=accepted", "region=eu", "currency=EUR"},
+				"shop.example.com": {"consent=accepted"},
+			},
+		},
+		{
+			name:      "host with scheme",
+			content:   "https://example.com consent=accepted\n",
+			expectErr: true,
+		},
+		{
+			name:      "host without cookies",
+			content:   "example.com\n",
+			expectErr: true,
+		},
+		{
+			name:      "cookie without value",
+			content:   "example.com consent\n",
+			expectErr: true,
+		},
+		{
+			name:      "invalid cookie name",
+			content:   "example.com con;sent=yes\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "cookies.txt")
+			if err := os.WriteFile(filename, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("failed to write cookies: %v", err)
+			}
+			cookies, err := loadHostCookies(filename)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", cookies)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make(map[string][]string)
+			for host, hostCookies := range cookies {
+				for _, cookie := range hostCookies {
+					got[host] = append(got[host], cookie.String())
+				}
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestPreloadedCookieUnlocksContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		// Without the consent cookie only the consent wall is served
+		if cookie, err := r.Cookie("consent"); err != nil || cookie.Value != "accepted" {
+			fmt.Fprint(w, `<html><body><p>Please accept cookies</p></body></html>`)
+			return
+		}
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/about">about</a></body></html>`)
+		case "/about":
+			fmt.Fprint(w, `<html><body>about</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	original := httpClient
+	httpClient = &http.Client{Transport: http.DefaultTransport, CheckRedirect: checkRedirect}
+	t.Cleanup(func() { httpClient = original })
+
+	cookies := map[string][]*http.Cookie{
+		serverURL.Hostname(): {{Name: "consent", Value: "accepted"}},
+		"other.example.com":  {{Name: "region", Value: "eu"}},
+	}
+	if err := preloadCookies(httpClient, cookies); err != nil {
+		t.Fatalf("failed to preload cookies: %v", err)
+	}
+	if sent := httpClient.Jar.Cookies(&url.URL{Scheme: "http", Host: "sub.other.example.com"}); len(sent) != 0 {
+		t.Errorf("expected cookies to be scoped to their exact host, got %v for a subdomain", sent)
+	}
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	if len(cfg.pages) != 2 {
+		t.Errorf("expected the consent cookie to unlock both pages, got %v", cfg.pages)
+	}
+}
+
+func TestCookiesFor(t *testing.T) {
+	site := &url.URL{Scheme: "https", Host: "www.example.com"}
+	base := &http.Client{}
+	if err := preloadCookies(base, map[string][]*http.Cookie{"www.example.com": {{Name: "consent", Value: "accepted"}}}); err != nil {
+		t.Fatalf("failed to preload cookies: %v", err)
+	}
+	// A host with a profile has a client of its own, sharing the crawl's jar
+	pool := newClientPool(base, map[string]clientProfile{"www.example.com": {Username: "alice"}})
+	ctx := withClients(context.Background(), pool)
+
+	if cookies := cookiesFor(ctx, site); len(cookies) != 1 || cookies[0].Name != "consent" {
+		t.Errorf("expected the crawl's consent cookie, got %v", cookies)
+	}
+	if cookies := cookiesFor(ctx, &url.URL{Scheme: "https", Host: "other.example.com"}); len(cookies) != 0 {
+		t.Errorf("expected no cookies for another host, got %v", cookies)
+	}
+	if cookies := cookiesFor(withClients(context.Background(), newClientPool(&http.Client{}, nil)), site); cookies != nil {
+		t.Errorf("expected no cookies without a jar, got %v", cookies)
+	}
+}
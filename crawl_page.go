@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,28 +27,263 @@ type config struct {
 	externalLinks      map[string]int
 	baseURL            *url.URL
 	maxPages           int
+	maxDepth           int // 0 means no depth limit
 	batchSize          int
 	mu                 *sync.Mutex
 	concurrencyControl chan struct{}
 	wg                 *sync.WaitGroup
 	ctx                context.Context
+	// Page URLs listed by the seed host's sitemap during warmup, queued one hop from the seed when the crawl starts
+	sitemapSeeds []string
+	// Per-page log lines are dropped while --live-report redraws the terminal
+	quietPages bool
+	// Source of time for backoff, rate limits, timeouts and the ledger (realClock outside tests)
+	clock Clock
 	// Error tracking for circuit breaker pattern
 	hostErrors   map[string]*int64
 	hostErrorsMu *sync.RWMutex
 	// Statistics
-	totalRequests  *int64
-	failedRequests *int64
+	totalRequests   *int64
+	totalAttempts   *int64 // HTTP attempts for pages, retries included
+	failedRequests  *int64
+	bytesDownloaded *int64
+	// Failed pages per kind of fetch error (guarded by mu)
+	failureKinds map[FetchErrorKind]int
+	// Number of pages first recorded at each link depth from the base URL (guarded by mu)
+	depthCounts map[int]int
+	// Redirect policy: when false, redirects to another host are recorded instead of followed
+	followExternalRedirects bool
+	externalRedirects       map[string]string // external target URL -> redirecting source URL (nil disables; guarded by mu)
+	// Only follow redirects within the registered domain of the requested URL; others are recorded as external
+	redirectsOnlyToBase bool
+	// Filters every discovered link must pass to be enqueued, in order, and how many links each skip
+	// reason accounted for (guarded by mu)
+	filters     []FilterFunc
+	skipReasons map[string]int
+	// robots.txt rules per host; ignoreRobots bypasses them and robots meta directives
+	robots       *robotsCache
+	ignoreRobots bool
+	// Optional collection of malformed-HTML warnings (off by default)
+	collectHTMLWarnings bool
+	htmlWarnings        *int64
+	// URL schemes that are followed, and how many links with other schemes were skipped (guarded by mu)
+	allowedSchemes map[string]bool
+	skippedSchemes map[string]int
+	// Optional bound on links waiting for a concurrency slot (nil frontier means unbounded)
+	frontier      chan struct{}
+	queuePolicy   string // queuePolicyBlock or queuePolicyDrop
+	droppedLinks  *int64
+	peakQueueSize *int64
+	// Canonical URL confirmed by a trailing-slash redirect, keyed by normalized URL (guarded by mu)
+	canonicalURLs map[string]string
+	// Normalized URL that redirected -> normalized URL of the page it redirected to, and the links counted
+	// toward that page through each redirecting URL (guarded by mu)
+	redirectedPages map[string]string
+	redirectLinks   map[string]int
+	// Normalized URL served with a same-host Content-Location -> normalized URL of that location, the page
+	// it was recorded under (guarded by mu)
+	contentLocations map[string]string
+	// How redirected pages are listed in the report: redirectReportTarget ("" too), redirectReportSource
+	// or redirectReportBoth
+	redirectReport string
+	// Pages that could not be fetched, with the last error (guarded by mu)
+	brokenLinks map[string]string
+	// Optional ledger of past crawls; pages crawled within maxAge reuse its links instead of being fetched
+	ledger      *crawlLedger
+	maxAge      time.Duration
+	reusedPages *int64
+	// Crawl hash-bang (#!) routes of single-page apps as distinct pages
+	crawlFragments bool
+	// Optional fetches shared by URLs differing only by fragment, keyed by the URL without it; each
+	// #fragment is then recorded as its own page (nil treats fragments as the same page; guarded by mu)
+	sharedFetches map[string]*sharedFetch
+	// HTTP clients of this crawl, with its own transport, cookies and host profiles (nil sends requests
+	// with the shared httpClient)
+	clients *clientPool
+	// How the crawler introduces itself: User-Agent, From header and robots.txt product token
+	identity crawlerIdentity
+	// Caps on page sizes per content type (nil applies maxResponseSize to everything)
+	sizePolicy sizePolicy
+	// Accept-Language header sent with every page request
+	acceptLanguage string
+	// Optional replacement for normalizeURL deciding which URLs are the same page (nil uses normalizeURL)
+	normalize func(rawURL string) (string, error)
+	// Trailing-slash policy of normalize. Only when the slash is stripped ("" or strip) does a redirect
+	// between /page and /page/ decide the form the report shows.
+	trailingSlash string
+	// Optional paths each page was reached through, for reporting pages whose links differ only by
+	// path case (nil disables tracking; guarded by mu)
+	pathVariants map[string]map[string]bool
+	// Enabled on-page SEO checks (nil disables them) and the pages failing each one (guarded by mu)
+	seoChecks map[string]bool
+	seoIssues map[string][]string
+	// Optional required JSON-LD fields per schema.org @type, and the pages whose structured data misses
+	// any, keyed by page URL (nil disables --json-schema-validate; guarded by mu)
+	structuredDataRules  map[string][]string
+	structuredDataIssues map[string][]structuredDataIssue
+	// Optional pattern a link's anchor text must match for the link to be followed (nil follows every link)
+	anchorTextFilter *regexp.Regexp
+	// Maximum internal links followed per page, after all of them are recorded (0 follows every link)
+	followPerPage int
+	// Optional ordering of pages waiting for concurrencyControl by declared priority (nil keeps arrival order)
+	priorities *crawlPriorities
+	// Optional bound on simultaneous requests to one host, on top of concurrencyControl (nil means unbounded)
+	hostLimiter *hostLimiter
+	// Record each host's health in the ledger and warm up throttled hosts as their pages succeed
+	rememberHostHealth bool
+	// Optional link graph: pages linking to each internal target, keeping at most maxReferrers per target
+	// (nil disables recording; guarded by mu)
+	inboundLinks map[string]*inboundLinks
+	maxReferrers int
+	// Optional depth at which each page was first visited (nil disables tracking; guarded by mu)
+	pageDepths map[string]int
+	// Optional HTTP status each page was last fetched with, keyed by normalized URL (nil disables tracking;
+	// guarded by mu)
+	pageStatuses map[string]int
+	// TLS metadata per host, from the most recent HTTPS response (guarded by mu)
+	tlsInfo          map[string]hostTLSInfo
+	certExpiryWindow time.Duration
+	// Optional site-wide anchor text frequencies (nil disables counting; guarded by mu)
+	anchorTexts    map[string]int
+	anchorCaseFold bool
+	// Results of validating external links after the crawl, keyed by URL (nil disables validation; guarded by mu)
+	externalChecks map[string]externalCheck
+	// Optional pages referencing each image (nil disables image validation) and the result of checking
+	// each image after the crawl (guarded by mu)
+	imageReferrers map[string][]string
+	imageChecks    map[string]externalCheck
+	// Optional type, size and dimensions of each image, fetched after the crawl (nil disables; guarded by mu)
+	imageInventory map[string]imageInfo
+	// Optional downloadable files linked from crawled pages, with their declared size once fetched after the
+	// crawl, and the file extensions counted as downloads (nil disables the inventory; guarded by mu)
+	downloads          map[string]downloadInfo
+	downloadExtensions map[string]bool
+	// Optional concurrency limit for checking external links and images after the crawl
+	// (nil shares concurrencyControl)
+	validationSlots chan struct{}
+	// Set while the crawl is paused by a signal; workers wait before starting new pages. The crawls of
+	// --seeds share it, so one signal pauses them all.
+	paused *atomic.Bool
+	// Optional time after which no new page starts while pages in flight finish (0 disables), and
+	// whether it has passed
+	softDeadline time.Duration
+	draining     atomic.Bool
+	// Optional outbound link profile of each crawled page, keyed by page URL (nil disables it; guarded by mu)
+	linkProfiles map[string]linkProfile
+	// Optional content extracted from each crawled page, keyed by page URL, for the JSON report (nil disables
+	// it; guarded by mu)
+	pageData map[string]*PageData
+	// Optional method and body for fetching the seed page (nil fetches it with GET)
+	seedRequest *seedRequest
+	// rel=next targets -> the page pointing at them, for reporting pagination gaps (nil disables; guarded by mu)
+	nextLinks map[string]string
+	// Optional NDJSON log of crawl events (nil discards them)
+	events *eventLog
+	// Optional captured response headers (name -> value) of each crawled page, keyed by page URL
+	// (nil disables capturing; guarded by mu)
+	pageHeaders     map[string]map[string]string
+	capturedHeaders []string
+	// Optional HTTP protocol version each crawled page was served over, keyed by page URL (nil disables
+	// recording; guarded by mu)
+	pageProtocols map[string]string
+	// Optional Content-Language of each crawled page, keyed by page URL (nil disables it; guarded by mu)
+	contentLanguages map[string]string
+	// Optional rel=canonical declared by each page, when it names another page: normalized page ->
+	// normalized canonical (nil disables --respect-canonical; guarded by mu)
+	declaredCanonicals map[string]string
+	// Optional normalized rel=canonical of every crawled page, "" for pages declaring none
+	// (nil disables --canonical-report; guarded by mu)
+	pageCanonicals map[string]string
+	// Optional SimHash of each page's main text, keyed by page URL, and the Hamming distance up to which
+	// pages are near-duplicates (nil disables detection; guarded by mu)
+	simHashes             map[string]uint64
+	nearDuplicateDistance int
+	// Optional pages whose main text differs when fetched again with mobileUserAgent, keyed by page URL,
+	// and how many pages were compared (nil disables the mobile fetch; guarded by mu)
+	mobileDifferences map[string]mobileDifference
+	mobileCompared    int
+	mobileUserAgent   string
+	// Optional pages fetched over http after a TLS error over https, with the error (nil disables
+	// --scheme-fallback), and whether each discovered page may be downgraded, keyed by normalized URL
+	// (guarded by mu)
+	schemeFallbacks    map[string]string
+	schemeDowngradable map[string]bool
+	// Optional hosts crawled besides the seed's (nil crawls only the seed's host)
+	allowedHosts map[string]bool
+	// Which hosts count as the seed's: hostMatchExact ("" too), hostMatchRegisteredDomain or hostMatchSuffix
+	hostMatch string
+	// Optional depth limits per host, counted from the shallowest depth each host was reached at
+	// (nil disables them; hostEntryDepths is guarded by mu)
+	hostDepthLimits *hostDepthLimits
+	hostEntryDepths map[string]int
+	// Optional time each host may be crawled for from its first request (0 is unlimited), when each
+	// host was first requested, and how many links were skipped on hosts past the limit (guarded by mu)
+	maxHostDuration  time.Duration
+	hostStartTimes   map[string]time.Time
+	timeLimitedHosts map[string]int
+	// Optional hreflang alternates (language -> URL) of each page that has any, keyed by normalized URL
+	// (nil disables extraction; guarded by mu)
+	alternates map[string]map[string]string
+	// Stop the whole crawl at the first internal page returning an HTTP error; cancel is set by Run
+	// and failure holds the page that stopped it (guarded by mu)
+	failFast bool
+	cancel   context.CancelFunc
+	failure  *crawlFailure
+	// Optional compact visited set replacing pages for deduplication (nil uses pages)
+	visited VisitStore
+	// Optional cap on distinct external URLs recorded (0 is unlimited), and how many new ones were
+	// left out once it was reached (guarded by mu)
+	maxExternal            int
+	truncatedExternalLinks int
+	// Optional hosts seen redirecting http to https, whose http links are then fetched over https directly
+	// (nil disables upgrading; guarded by mu)
+	httpsHosts map[string]bool
+	// Optional per-request phase timing (nil disables tracing)
+	tracer *requestTracer
+	// Optional redirect loops and long redirect chains, keyed by the URL they start from (nil disables
+	// tracking; guarded by mu)
+	redirectIssues map[string]redirectIssue
+	// Optional headless browser rendering each fetched page before links are extracted (nil uses the raw HTML)
+	renderer jsRenderer
+	// Shuts down the renderer's browser once the crawl is done (nil when there's no renderer)
+	stopRenderer func()
+	// Intern the URLs kept for the whole crawl (see compactMemory)
+	compact bool
 }
 
 // addPageVisit safely adds a page visit to the map and returns whether this is the first visit
-// and whether adding this page would exceed the maxPages limit
-func (cfg *config) addPageVisit(normalizedURL string) (isFirst bool, exceedsLimit bool) {
+// and whether adding this page would exceed the maxPages limit. The depth of a first visit is
+// recorded in depthCounts.
+func (cfg *config) addPageVisit(normalizedURL string, depth int) (isFirst bool, exceedsLimit bool) {
 	cfg.mu.Lock()
 	defer cfg.mu.Unlock()
 
+	// A visit store only remembers that a page was seen, not its URL or link count
+	if cfg.visited != nil {
+		if cfg.visited.Contains(normalizedURL) {
+			return false, false
+		}
+		if cfg.visited.Len() >= cfg.maxPages {
+			return false, true
+		}
+		cfg.visited.Add(normalizedURL)
+		cfg.depthCounts[depth]++
+		return true, false
+	}
+
+	// Links to a URL that redirected count toward the page it redirected to
+	if target, ok := cfg.redirectedPages[normalizedURL]; ok {
+		cfg.redirectLinks[normalizedURL]++
+		normalizedURL = target
+	}
+	// and links to a URL served with a Content-Location toward the page at that location
+	if location, ok := cfg.contentLocations[normalizedURL]; ok {
+		normalizedURL = location
+	}
 	count, exists := cfg.pages[normalizedURL]
 	if exists {
 		cfg.pages[normalizedURL] = count + 1
+		cfg.recordPageDepth(normalizedURL, depth)
 		return false, false
 	}
 
@@ -57,11 +294,95 @@ func (cfg *config) addPageVisit(normalizedURL string) (isFirst bool, exceedsLimi
 
 	// This is a new page, add it
 	cfg.pages[normalizedURL] = 1
+	cfg.depthCounts[depth]++
+	cfg.recordPageDepth(normalizedURL, depth)
 	return true, false
 }
 
-// incrementHostError tracks errors per host for circuit breaker pattern
-func (cfg *config) incrementHostError(host string) {
+// recordPageDepth notes that a link reached the page at depth, keeping the smallest depth seen, as the
+// order links arrive in depends on timing. The caller must hold cfg.mu.
+func (cfg *config) recordPageDepth(normalizedURL string, depth int) {
+	if cfg.pageDepths == nil {
+		return
+	}
+	if known, exists := cfg.pageDepths[normalizedURL]; !exists || depth < known {
+		cfg.pageDepths[normalizedURL] = depth
+	}
+}
+
+// recordExternalLink counts a link to an external URL. Once maxExternal distinct URLs are tracked,
+// links to new ones are only counted as truncated, while tracked URLs keep counting up.
+func (cfg *config) recordExternalLink(rawURL string) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	if _, tracked := cfg.externalLinks[rawURL]; !tracked && cfg.maxExternal > 0 && len(cfg.externalLinks) >= cfg.maxExternal {
+		cfg.truncatedExternalLinks++
+		return
+	}
+	cfg.externalLinks[rawURL]++
+}
+
+// normalizeURL normalizes a URL with the configured normalize function, or the built-in normalizeURL
+// keeping the #fragment when fragments are distinct pages
+func (cfg *config) normalizeURL(rawURL string) (string, error) {
+	normalize := normalizeURL
+	if cfg.normalize != nil {
+		normalize = cfg.normalize
+	}
+	normalized, err := normalize(rawURL)
+	if err != nil {
+		return normalized, err
+	}
+	if cfg.sharedFetches != nil {
+		normalized = withFragment(normalized, rawURL)
+	}
+	return cfg.intern(normalized), nil
+}
+
+// foldPageVisit moves the visit recorded for a first-visited page at depth to canonicalURL, returning
+// true if canonicalURL had already been visited (so the page needs no further processing). Later links
+// to normalizedURL count toward canonicalURL too: for a page that redirected, keeping the links counted
+// so far in redirectLinks, otherwise for one folded by its Content-Location. It's all one update, so no
+// link is counted in between.
+func (cfg *config) foldPageVisit(normalizedURL, canonicalURL string, depth int, redirected bool) (alreadyVisited bool) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	if redirected {
+		cfg.redirectedPages[normalizedURL] = canonicalURL
+		cfg.redirectLinks[normalizedURL] += cfg.pages[normalizedURL]
+	} else {
+		cfg.contentLocations[normalizedURL] = canonicalURL
+	}
+
+	if cfg.visited != nil {
+		if cfg.visited.Contains(canonicalURL) {
+			cfg.depthCounts[depth]--
+			return true
+		}
+		cfg.visited.Add(canonicalURL)
+		return false
+	}
+
+	count := cfg.pages[normalizedURL]
+	delete(cfg.pages, normalizedURL)
+	if known, exists := cfg.pageDepths[normalizedURL]; exists {
+		delete(cfg.pageDepths, normalizedURL)
+		cfg.recordPageDepth(canonicalURL, known)
+	}
+	if existing, exists := cfg.pages[canonicalURL]; exists {
+		cfg.pages[canonicalURL] = existing + count
+		cfg.depthCounts[depth]--
+		return true
+	}
+	cfg.pages[canonicalURL] = count
+	return false
+}
+
+// incrementHostError tracks errors per host for circuit breaker pattern. It reports whether this error
+// tripped the breaker, so later pages on the host are skipped.
+func (cfg *config) incrementHostError(host string) (tripped bool) {
 	cfg.hostErrorsMu.Lock()
 	defer cfg.hostErrorsMu.Unlock()
 
@@ -69,7 +390,14 @@ func (cfg *config) incrementHostError(host string) {
 		var zero int64 = 0
 		cfg.hostErrors[host] = &zero
 	}
-	atomic.AddInt64(cfg.hostErrors[host], 1)
+	return atomic.AddInt64(cfg.hostErrors[host], 1) == maxErrorsPerHost
+}
+
+// recordHostError counts an error for the circuit breaker, logging an event if it trips for the host
+func (cfg *config) recordHostError(host, rawURL string) {
+	if cfg.incrementHostError(host) {
+		cfg.events.emit(crawlEvent{Type: eventCircuitBreakerTrip, URL: rawURL, Error: fmt.Sprintf("%d errors on %s", maxErrorsPerHost, host)})
+	}
 }
 
 // shouldSkipHost determines if we should skip a host due to too many errors
@@ -105,11 +433,20 @@ func (cfg *config) retryWithBackoff(operation func() error) error {
 			select {
 			case <-cfg.ctx.Done():
 				return cfg.ctx.Err()
-			case <-time.After(delay):
+			case <-cfg.clock.After(delay):
 			}
 		}
 
 		if err := operation(); err != nil {
+			// A refused external redirect is a policy decision, retrying won't change it
+			var redirectErr *externalRedirectError
+			if errors.As(err, &redirectErr) {
+				return err
+			}
+			// Neither will a permanent failure such as a missing host or a 404
+			if !isRetryableError(err) {
+				return err
+			}
 			lastErr = err
 			continue
 		}
@@ -119,102 +456,554 @@ func (cfg *config) retryWithBackoff(operation func() error) error {
 	return fmt.Errorf("operation failed after %d retries, last error: %w", maxRetries, lastErr)
 }
 
-// crawlPage recursively crawls pages starting from rawCurrentURL, staying within the same domain as baseURL
-func (cfg *config) crawlPage(rawCurrentURL string) {
+// crawlPage recursively crawls pages starting from rawCurrentURL, staying within the same domain as baseURL.
+// depth is the number of links followed from the base URL to reach rawCurrentURL.
+func (cfg *config) crawlPage(rawCurrentURL string, depth int) {
 	// Check if context is cancelled
 	select {
 	case <-cfg.ctx.Done():
+		cfg.leaveFrontier(depth)
 		cfg.wg.Done() // Decrement WaitGroup since we're not doing any work
 		return
 	default:
 	}
 
-	// Acquire concurrency control
-	cfg.concurrencyControl <- struct{}{}
+	// Past the soft deadline only pages already being fetched may finish
+	if cfg.draining.Load() {
+		cfg.leaveFrontier(depth)
+		cfg.wg.Done()
+		return
+	}
+
+	// Hold new pages back while the crawl is paused
+	if !cfg.waitWhilePaused() {
+		cfg.leaveFrontier(depth)
+		cfg.wg.Done()
+		return
+	}
+
+	// Acquire concurrency control; the page is no longer waiting in the frontier
+	if !cfg.acquireSlot(rawCurrentURL) {
+		cfg.leaveFrontier(depth)
+		cfg.wg.Done()
+		return
+	}
+	cfg.leaveFrontier(depth)
+	slotReleased := false
+	releaseSlot := func() {
+		if !slotReleased {
+			slotReleased = true
+			<-cfg.concurrencyControl
+		}
+	}
 	defer func() {
-		<-cfg.concurrencyControl
+		releaseSlot()
 		cfg.wg.Done() // Decrement WaitGroup after releasing concurrency control
 	}()
+	// The soft deadline may have passed while waiting for the slot
+	if cfg.draining.Load() {
+		return
+	}
 
 	// Parse the current URL
 	currentURL, err := url.Parse(rawCurrentURL)
 	if err != nil {
 		cfg.incrementStats(true)
-		fmt.Printf("Error parsing current URL %s: %v\n", rawCurrentURL, err)
+		cfg.logf("Error parsing current URL %s: %v\n", rawCurrentURL, err)
 		return
 	}
 
 	// Check circuit breaker - skip hosts with too many errors
 	if cfg.shouldSkipHost(currentURL.Hostname()) {
 		cfg.incrementStats(true)
-		fmt.Printf("Skipping %s due to too many previous errors\n", currentURL.Hostname())
+		cfg.logf("Skipping %s due to too many previous errors\n", currentURL.Hostname())
 		return
 	}
 
 	// Check if current URL is on the same domain as base URL
-	if currentURL.Hostname() != cfg.baseURL.Hostname() {
-		// Track external link
-		cfg.mu.Lock()
-		cfg.externalLinks[rawCurrentURL]++
-		cfg.mu.Unlock()
+	if !cfg.isCrawledHost(currentURL.Hostname()) {
+		cfg.recordExternalLink(rawCurrentURL)
+		return
+	}
+
+	// Respect robots.txt unless explicitly overridden
+	if !cfg.ignoreRobots && !cfg.robots.allowed(cfg.ctx, currentURL) {
+		cfg.logf("Skipping %s: disallowed by robots.txt\n", rawCurrentURL)
 		return
 	}
 
 	// Get normalized version of the current URL
-	normalizedURL, err := normalizeURL(rawCurrentURL)
+	normalizedURL, err := cfg.normalizeURL(rawCurrentURL)
 	if err != nil {
 		cfg.incrementStats(true)
-		cfg.incrementHostError(currentURL.Hostname())
-		fmt.Printf("Error normalizing URL %s: %v\n", rawCurrentURL, err)
+		cfg.recordHostError(currentURL.Hostname(), rawCurrentURL)
+		cfg.logf("Error normalizing URL %s: %v\n", rawCurrentURL, err)
 		return
 	}
 
+	cfg.recordPathVariant(normalizedURL, currentURL)
+
 	// Atomically check if this is the first visit and if we've reached the page limit
-	isFirst, exceedsLimit := cfg.addPageVisit(normalizedURL)
+	isFirst, exceedsLimit := cfg.addPageVisit(normalizedURL, depth)
 	if exceedsLimit {
 		return
 	}
 	if !isFirst {
 		return
 	}
+	// Leave the rest of a host crawled for longer than --max-host-duration; the page keeps its links
+	// but isn't fetched
+	if cfg.hostTimeLimited(currentURL.Hostname()) {
+		return
+	}
+	hostDepthReached, pastHostDepth := cfg.hostDepthReached(currentURL.Hostname(), depth)
+	if pastHostDepth {
+		return
+	}
+
+	// Reuse the links from a recent crawl instead of refetching the page
+	if cfg.ledger != nil && cfg.maxAge > 0 {
+		if links, fresh := cfg.ledger.fresh(normalizedURL, cfg.maxAge, cfg.clock.Now()); fresh {
+			atomic.AddInt64(cfg.reusedPages, 1)
+			cfg.logf("Reusing: %s (crawled within the last %v)\n", rawCurrentURL, cfg.maxAge)
+			if (cfg.maxDepth > 0 && depth >= cfg.maxDepth) || hostDepthReached {
+				return
+			}
+			releaseSlot()
+			cfg.enqueueLinks(links, depth, cfg.defaultPriority())
+			return
+		}
+	}
 
 	// Print what we're crawling
-	fmt.Printf("Crawling: %s\n", rawCurrentURL)
+	cfg.logf("Crawling: %s\n", rawCurrentURL)
+
+	// Space out requests to the host as its robots.txt Crawl-delay asks
+	if !cfg.ignoreRobots {
+		if err := cfg.robots.waitCrawlDelay(cfg.ctx, cfg.clock, currentURL); err != nil {
+			return
+		}
+	}
 
 	// Create a context with timeout for this specific request
 	requestCtx, cancel := context.WithTimeout(cfg.ctx, 30*time.Second)
 	defer cancel()
+	if !cfg.followExternalRedirects {
+		requestCtx = withoutExternalRedirects(requestCtx, cfg.isCrawledHost)
+	}
+	if cfg.redirectsOnlyToBase {
+		requestCtx = withSameDomainRedirects(requestCtx)
+	}
+	requestCtx = withAttemptCounter(requestCtx, cfg.totalAttempts)
+	requestCtx = withClock(requestCtx, cfg.clock)
+	if cfg.tracer != nil {
+		requestCtx = withRequestTracer(requestCtx, cfg.tracer)
+	}
+	// Only the seed page uses the --method/--body request; discovered links are fetched with GET
+	if depth == 0 && cfg.seedRequest != nil {
+		requestCtx = withSeedRequest(requestCtx, cfg.seedRequest)
+	}
+
+	// Use retry mechanism for getting HTML, from fetchURL which only differs after a scheme fallback
+	fetchURL := rawCurrentURL
+	attempt := 0
+	fetchWithRetries := func() (*pageResponse, error) {
+		var fetched *pageResponse
+		err := cfg.retryWithBackoff(func() error {
+			if attempt > 0 {
+				cfg.events.emit(crawlEvent{Type: eventRetry, URL: fetchURL, Attempt: attempt})
+			}
+			attempt++
+			if cfg.hostLimiter != nil {
+				release, err := cfg.hostLimiter.acquire(requestCtx, currentURL.Hostname())
+				if err != nil {
+					return err
+				}
+				defer release()
+			}
+			cfg.events.emit(crawlEvent{Type: eventRequestStarted, URL: fetchURL, Depth: depth})
+			start := cfg.clock.Now()
+			var htmlErr error
+			fetched, htmlErr = cfg.fetchRenderedPage(requestCtx, fetchURL)
+			completed := crawlEvent{Type: eventRequestCompleted, URL: fetchURL, LatencyMS: cfg.clock.Now().Sub(start).Milliseconds()}
+			if htmlErr == nil {
+				completed.Status = fetched.StatusCode
+			} else {
+				completed.Status = httpErrorStatus(htmlErr)
+				completed.Error = htmlErr.Error()
+				completed.ErrorKind = string(fetchErrorKind(htmlErr))
+			}
+			cfg.events.emit(completed)
+			return htmlErr
+		})
+		return fetched, err
+	}
+	var page *pageResponse
+	sharedPage := false
+	if cfg.sharedFetches != nil {
+		page, sharedPage, err = cfg.fetchShared(withoutFragment(currentURL), fetchWithRetries)
+	} else {
+		page, err = fetchWithRetries()
+	}
+	// Sites with broken TLS on some endpoints may still serve the page over http
+	if fallbackURL := cfg.schemeFallbackURL(currentURL, normalizedURL, err); fallbackURL != "" {
+		cfg.logf("TLS error on %s, retrying over http\n", rawCurrentURL)
+		reason := err.Error()
+		fetchURL, attempt = fallbackURL, 0
+		if page, err = fetchWithRetries(); err == nil {
+			cfg.recordSchemeFallback(rawCurrentURL, reason)
+		}
+	}
+	cfg.recordPageStatus(normalizedURL, page, err)
+	var loopErr *redirectLoopError
+	if err == nil {
+		cfg.recordRedirectIssue(page.RedirectChain, false)
+	} else if errors.As(err, &loopErr) {
+		cfg.recordRedirectIssue(loopErr.Chain, true)
+	}
 
-	// Use retry mechanism for getting HTML
-	var htmlBody string
-	err = cfg.retryWithBackoff(func() error {
-		var htmlErr error
-		htmlBody, htmlErr = getHTMLWithContext(requestCtx, rawCurrentURL)
-		return htmlErr
-	})
+	// Redirects off-site are tracked as external links rather than crawled
+	var redirectErr *externalRedirectError
+	if errors.As(err, &redirectErr) {
+		cfg.incrementStats(false)
+		cfg.recordHostHealth(currentURL.Hostname(), true)
+		cfg.recordExternalLink(redirectErr.Target)
+		if cfg.externalRedirects != nil {
+			cfg.mu.Lock()
+			cfg.externalRedirects[redirectErr.Target] = redirectErr.Source
+			cfg.mu.Unlock()
+		}
+		cfg.logf("Not following redirect from %s to external %s\n", redirectErr.Source, redirectErr.Target)
+		return
+	}
 
 	if err != nil {
 		cfg.incrementStats(true)
-		cfg.incrementHostError(currentURL.Hostname())
-		fmt.Printf("Error getting HTML from %s after retries: %v\n", rawCurrentURL, err)
+		cfg.recordHostError(currentURL.Hostname(), rawCurrentURL)
+		cfg.recordHostHealth(currentURL.Hostname(), false)
+		cfg.mu.Lock()
+		cfg.brokenLinks[rawCurrentURL] = err.Error()
+		cfg.mu.Unlock()
+		cfg.recordFailureKind(err)
+		cfg.events.emit(crawlEvent{Type: eventError, URL: rawCurrentURL, Error: err.Error(), ErrorKind: string(fetchErrorKind(err))})
+		cfg.logf("Error getting HTML from %s after retries: %v\n", rawCurrentURL, err)
+		cfg.failFastOn(rawCurrentURL, normalizedURL, err)
 		return
 	}
 
 	cfg.incrementStats(false) // Successful request
+	cfg.recordHostHealth(currentURL.Hostname(), true)
+	cfg.events.emit(crawlEvent{Type: eventPageRecorded, URL: rawCurrentURL, Depth: depth})
+	htmlBody := page.Body
+	if !sharedPage {
+		atomic.AddInt64(cfg.bytesDownloaded, int64(len(htmlBody)))
+	}
+
+	cfg.noteHTTPSUpgrade(currentURL, page.FinalURL)
+
+	if page.TLS != nil {
+		if finalURL, err := url.Parse(page.FinalURL); err == nil {
+			cfg.mu.Lock()
+			cfg.tlsInfo[finalURL.Hostname()] = newHostTLSInfo(page.TLS)
+			cfg.mu.Unlock()
+		}
+	}
+
+	if cfg.structuredDataRules != nil {
+		if issues := checkStructuredData(htmlBody, cfg.structuredDataRules); len(issues) > 0 {
+			cfg.mu.Lock()
+			cfg.structuredDataIssues[rawCurrentURL] = issues
+			cfg.mu.Unlock()
+		}
+	}
+
+	// A page reached through a redirect lives at the final URL, so record the visit there rather than
+	// under a URL that only redirects. As in browsers, the fragment carries over the redirect.
+	if finalURL, err := url.Parse(page.FinalURL); err == nil && len(page.RedirectChain) > 1 && cfg.isCrawledHost(finalURL.Hostname()) {
+		if finalURL.Fragment == "" {
+			finalURL.Fragment = currentURL.Fragment
+		}
+		if finalNormalized, err := cfg.normalizeURL(finalURL.String()); err == nil && finalNormalized != normalizedURL {
+			cfg.logf("Recording %s under %s (redirected)\n", rawCurrentURL, finalURL)
+			if cfg.foldPageVisit(normalizedURL, finalNormalized, depth, true) {
+				return
+			}
+			normalizedURL = finalNormalized
+		}
+	}
+
+	// A same-host Content-Location names the page's canonical URL, so record the visit under it
+	if page.ContentLocation != "" {
+		if location, err := url.Parse(page.ContentLocation); err == nil && location.Hostname() == currentURL.Hostname() {
+			if canonicalURL, err := cfg.normalizeURL(page.ContentLocation); err == nil && canonicalURL != normalizedURL {
+				cfg.logf("Folding %s into %s (Content-Location)\n", rawCurrentURL, page.ContentLocation)
+				if cfg.foldPageVisit(normalizedURL, canonicalURL, depth, false) {
+					return
+				}
+				normalizedURL = canonicalURL
+			}
+		}
+	}
+
+	// A redirect between /page and /page/ confirms both are the same page, so report the form the server prefers
+	if (cfg.trailingSlash == "" || cfg.trailingSlash == trailingSlashStrip) && page.FinalURL != rawCurrentURL && isTrailingSlashVariant(rawCurrentURL, page.FinalURL) {
+		cfg.mu.Lock()
+		cfg.canonicalURLs[normalizedURL] = page.FinalURL
+		cfg.mu.Unlock()
+	}
+
+	if cfg.collectHTMLWarnings {
+		for _, warning := range checkHTMLStructure(htmlBody) {
+			atomic.AddInt64(cfg.htmlWarnings, 1)
+			cfg.logf("Warning: malformed HTML on %s: %s\n", rawCurrentURL, warning)
+		}
+	}
+
+	if cfg.seoChecks != nil {
+		if issues := checkSEO(htmlBody, cfg.seoChecks); len(issues) > 0 {
+			cfg.mu.Lock()
+			for _, check := range issues {
+				cfg.seoIssues[check] = append(cfg.seoIssues[check], rawCurrentURL)
+			}
+			cfg.mu.Unlock()
+		}
+	}
+
+	cfg.recordSimHash(rawCurrentURL, htmlBody)
+	cfg.compareMobile(withClock(cfg.ctx, cfg.clock), rawCurrentURL, htmlBody)
+
+	if cfg.pageHeaders != nil {
+		headers := captureHeaders(page.Header, cfg.capturedHeaders)
+		cfg.mu.Lock()
+		cfg.pageHeaders[rawCurrentURL] = headers
+		cfg.mu.Unlock()
+	}
+
+	if cfg.pageProtocols != nil {
+		cfg.mu.Lock()
+		cfg.pageProtocols[rawCurrentURL] = page.Proto
+		cfg.mu.Unlock()
+	}
+
+	if cfg.contentLanguages != nil {
+		cfg.mu.Lock()
+		cfg.contentLanguages[rawCurrentURL] = page.ContentLanguage
+		cfg.mu.Unlock()
+	}
+
+	canonicalTarget := ""
+	if cfg.declaredCanonicals != nil || cfg.pageCanonicals != nil {
+		// A canonical in the HTML takes precedence over one in the Link header
+		canonical := getCanonicalFromHTML(htmlBody, currentURL)
+		if canonical == "" {
+			canonical = headerLinkTarget(page.HeaderLinks, "canonical")
+		}
+		target := ""
+		if canonical != "" {
+			if normalized, err := cfg.normalizeURL(canonical); err == nil {
+				target = normalized
+			}
+		}
+		cfg.mu.Lock()
+		if cfg.pageCanonicals != nil {
+			cfg.pageCanonicals[normalizedURL] = target
+		}
+		if cfg.declaredCanonicals != nil && target != "" && target != normalizedURL {
+			canonicalTarget = canonical
+			cfg.declaredCanonicals[normalizedURL] = target
+		}
+		cfg.mu.Unlock()
+	}
+
+	// The page's content is extracted once for the JSON report, and its alternates and pagination links
+	// reused from there
+	var data *PageData
+	if cfg.pageData != nil {
+		data = extractPageData(htmlBody, currentURL)
+		data.URL = rawCurrentURL
+		cfg.mu.Lock()
+		cfg.pageData[rawCurrentURL] = data
+		cfg.mu.Unlock()
+	}
+
+	if cfg.alternates != nil {
+		var alternates map[string]string
+		if data != nil {
+			alternates = data.Alternates
+		} else {
+			alternates = getAlternatesFromHTML(htmlBody, currentURL)
+		}
+		if len(alternates) > 0 {
+			cfg.mu.Lock()
+			cfg.alternates[normalizedURL] = alternates
+			cfg.mu.Unlock()
+		}
+	}
+
+	if cfg.anchorTexts != nil {
+		texts := getAnchorTextsFromHTML(htmlBody, cfg.anchorCaseFold)
+		cfg.mu.Lock()
+		for _, text := range texts {
+			cfg.anchorTexts[text]++
+		}
+		cfg.mu.Unlock()
+	}
+
+	cfg.recordImages(rawCurrentURL, htmlBody, currentURL)
+
+	// Don't follow links any deeper once the depth limit, or the host's own limit, is reached
+	if (cfg.maxDepth > 0 && depth >= cfg.maxDepth) || hostDepthReached {
+		return
+	}
+
+	// Honor <meta name="robots" content="nofollow"> unless explicitly overridden
+	if !cfg.ignoreRobots && hasRobotsMetaDirective(htmlBody, "nofollow", cfg.identity.robotsToken) {
+		cfg.logf("Not following links on %s: robots meta nofollow\n", rawCurrentURL)
+		cfg.recordCrawl(normalizedURL, nil)
+		return
+	}
 
 	// Get all URLs from the HTML with error handling
-	urls, err := getURLsFromHTML(htmlBody, cfg.baseURL.String())
+	// Links resolve against the seed URL, except on other allowed hosts where they're relative to that host
+	linkBase := cfg.baseURL
+	if currentURL.Hostname() != cfg.baseURL.Hostname() {
+		linkBase = currentURL
+	}
+	urls, skippedSchemes, err := extractURLsFromHTML(htmlBody, linkBase.String(), cfg.allowedSchemes)
+	if errors.Is(err, errBinaryContent) {
+		cfg.logf("Warning: skipping links on %s: %v\n", rawCurrentURL, err)
+		return
+	}
 	if err != nil {
-		fmt.Printf("Error getting URLs from HTML of %s: %v\n", rawCurrentURL, err)
+		cfg.logf("Error getting URLs from HTML of %s: %v\n", rawCurrentURL, err)
 		return
 	}
+	if len(skippedSchemes) > 0 {
+		cfg.mu.Lock()
+		for scheme, count := range skippedSchemes {
+			cfg.skippedSchemes[scheme] += count
+		}
+		cfg.mu.Unlock()
+	}
+
+	if cfg.crawlFragments {
+		for i, foundURL := range urls {
+			urls[i] = escapeHashBangURL(foundURL)
+		}
+	}
+	// Follow a paginated series before the page's other links, whether the HTML or the Link header declares it
+	var next string
+	if data != nil {
+		next = data.Next
+	} else {
+		next, _ = getPaginationFromHTML(htmlBody, currentURL)
+	}
+	if next == "" {
+		next = headerLinkTarget(page.HeaderLinks, "next")
+	}
+	if next != "" {
+		next = cfg.followableNext(next, depth+1)
+	}
+	if next != "" {
+		urls = prioritizeNext(urls, next)
+		if cfg.nextLinks != nil {
+			cfg.mu.Lock()
+			cfg.nextLinks[next] = rawCurrentURL
+			cfg.mu.Unlock()
+		}
+	}
+	cfg.recordLinks(normalizedURL, depth, urls)
+	cfg.recordDownloads(urls)
+	cfg.noteLinkSchemes(htmlBody, linkBase)
+	if cfg.linkProfiles != nil || data != nil {
+		profile := newLinkProfile(rawCurrentURL, urls, cfg.normalizeURL)
+		cfg.mu.Lock()
+		if cfg.linkProfiles != nil {
+			cfg.linkProfiles[rawCurrentURL] = profile
+		}
+		if data != nil {
+			data.OutgoingLinks, data.Links = cfg.internAll(urls), profile
+		}
+		cfg.mu.Unlock()
+	}
+	// Crawl an internal canonical even when no link points to it, so canonical chains can be resolved
+	if canonicalTarget != "" {
+		if parsed, err := url.Parse(canonicalTarget); err == nil && cfg.isCrawledHost(parsed.Hostname()) {
+			urls = append(urls, canonicalTarget)
+		}
+	}
 
 	// Limit the number of URLs to process to avoid memory explosion
 	if len(urls) > maxURLsPerPage {
 		urls = urls[:maxURLsPerPage]
-		fmt.Printf("Limiting URLs from %s to %d (originally %d)\n", rawCurrentURL, maxURLsPerPage, len(urls))
+		cfg.logf("Limiting URLs from %s to %d (originally %d)\n", rawCurrentURL, maxURLsPerPage, len(urls))
+	}
+	cfg.recordCrawl(normalizedURL, urls)
+	for _, foundURL := range urls {
+		cfg.events.emit(crawlEvent{Type: eventLinkDiscovered, URL: foundURL, Source: rawCurrentURL, Depth: depth + 1})
+	}
+	urls = cfg.followAnchorText(htmlBody, linkBase, urls, canonicalTarget, next)
+	urls = cfg.limitFollowed(urls, canonicalTarget, next)
+
+	// Enqueueing may block on a full frontier, so give up the concurrency slot first
+	// to let queued pages make progress
+	releaseSlot()
+
+	priority := cfg.defaultPriority()
+	if cfg.priorities != nil {
+		priority = cfg.priorities.pagePriority(page.Header, htmlBody)
+	}
+
+	// Enqueueing can wait a long time for frontier room, so let the body be collected meanwhile
+	htmlBody, page = "", nil
+	cfg.enqueueLinks(urls, depth, priority)
+}
+
+// acquireSlot waits for a concurrency slot for rawURL's page, in priority order when priority hints are
+// enabled. It returns false if the crawl was cancelled before a slot was free.
+func (cfg *config) acquireSlot(rawURL string) bool {
+	if cfg.priorities != nil {
+		return cfg.priorities.acquire(cfg.ctx, rawURL)
+	}
+	cfg.concurrencyControl <- struct{}{}
+	return true
+}
+
+// defaultPriority is the crawl priority of links on pages without a priority hint
+func (cfg *config) defaultPriority() int {
+	if cfg.priorities == nil {
+		return 0
 	}
+	return cfg.priorities.defaultPriority
+}
 
+// recordPageStatus notes the HTTP status a page was fetched with, if statuses are being tracked
+func (cfg *config) recordPageStatus(normalizedURL string, page *pageResponse, err error) {
+	if cfg.pageStatuses == nil {
+		return
+	}
+	status := httpErrorStatus(err)
+	if err == nil {
+		status = page.StatusCode
+	}
+	if status == 0 {
+		return
+	}
+	cfg.mu.Lock()
+	cfg.pageStatuses[normalizedURL] = status
+	cfg.mu.Unlock()
+}
+
+// recordCrawl notes a fetched page and its links in the ledger, if one is in use
+func (cfg *config) recordCrawl(normalizedURL string, links []string) {
+	if cfg.ledger != nil {
+		cfg.ledger.record(normalizedURL, cfg.internAll(links), cfg.clock.Now())
+	}
+}
+
+// enqueueLinks starts crawling the links found on a page at depth, queueing them at priority when priority
+// hints are enabled. The caller must not hold a concurrency slot.
+func (cfg *config) enqueueLinks(urls []string, depth, priority int) {
 	// Process URLs in batches to avoid creating too many goroutines at once
 	batchSize := cfg.batchSize
 	for i := 0; i < len(urls); i += batchSize {
@@ -225,7 +1014,15 @@ func (cfg *config) crawlPage(rawCurrentURL string) {
 
 		// Process this batch of URLs
 		for j := i; j < end; j++ {
-			foundURL := urls[j]
+			// Past the soft deadline no new page is queued
+			if cfg.draining.Load() {
+				return
+			}
+			foundURL := cfg.upgradeToHTTPS(urls[j])
+
+			if !cfg.keepLink(foundURL, depth+1) {
+				continue
+			}
 
 			// Add to WaitGroup first to avoid race condition
 			cfg.wg.Add(1)
@@ -237,8 +1034,17 @@ func (cfg *config) crawlPage(rawCurrentURL string) {
 				cfg.wg.Done()
 				return
 			default:
-				go cfg.crawlPage(foundURL)
 			}
+
+			// Wait for (or give up on) room in a bounded frontier
+			if !cfg.enterFrontier() {
+				cfg.wg.Done()
+				continue
+			}
+			if cfg.priorities != nil {
+				cfg.priorities.assign(foundURL, priority)
+			}
+			go cfg.crawlPage(foundURL, depth+1)
 		}
 	}
 }
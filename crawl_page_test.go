@@ -0,0 +1,771 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// newTestServer serves each path in site as an HTML page linking to the given paths
+func newTestServer(t *testing.T, site map[string][]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links, ok := site[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>")
+		for _, link := range links {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestConfig builds a config equivalent to the one main creates, crawling rawBaseURL
+func newTestConfig(t testing.TB, rawBaseURL string, maxPages int) *config {
+	t.Helper()
+	baseURL, err := url.Parse(rawBaseURL)
+	if err != nil {
+		t.Fatalf("failed to parse base URL: %v", err)
+	}
+	var totalRequests, failedRequests, bytesDownloaded, htmlWarnings, droppedLinks, peakQueueSize, reusedPages, totalAttempts int64
+	return &config{
+		pages:              make(map[string]int),
+		externalLinks:      make(map[string]int),
+		baseURL:            baseURL,
+		maxPages:           maxPages,
+		batchSize:          5,
+		mu:                 &sync.Mutex{},
+		clock:              realClock{},
+		concurrencyControl: make(chan struct{}, 5),
+		wg:                 &sync.WaitGroup{},
+		ctx:                context.Background(),
+		hostErrors:         make(map[string]*int64),
+		hostErrorsMu:       &sync.RWMutex{},
+		totalRequests:      &totalRequests,
+		failedRequests:     &failedRequests,
+		bytesDownloaded:    &bytesDownloaded,
+		depthCounts:        make(map[int]int),
+
+		followExternalRedirects: true,
+		externalRedirects:       make(map[string]string),
+		robots:                  newRobotsCache(),
+		htmlWarnings:            &htmlWarnings,
+		allowedSchemes:          defaultAllowedSchemes,
+		skippedSchemes:          make(map[string]int),
+		skipReasons:             make(map[string]int),
+		failureKinds:            make(map[FetchErrorKind]int),
+		queuePolicy:             queuePolicyBlock,
+		droppedLinks:            &droppedLinks,
+		peakQueueSize:           &peakQueueSize,
+		canonicalURLs:           make(map[string]string),
+		redirectedPages:         make(map[string]string),
+		redirectLinks:           make(map[string]int),
+		contentLocations:        make(map[string]string),
+		brokenLinks:             make(map[string]string),
+		reusedPages:             &reusedPages,
+		seoIssues:               make(map[string][]string),
+		tlsInfo:                 make(map[string]hostTLSInfo),
+		nextLinks:               make(map[string]string),
+		totalAttempts:           &totalAttempts,
+		paused:                  new(atomic.Bool),
+		identity:                defaultIdentity,
+		acceptLanguage:          defaultAcceptLanguage,
+	}
+}
+
+// useTransport sends the requests of cfg's crawl through transport, keeping the shared client's settings
+func useTransport(cfg *config, transport http.RoundTripper) {
+	client := newHTTPClient()
+	client.Transport = transport
+	cfg.clients = newClientPool(client, nil)
+}
+
+// runTestCrawl crawls from the config's base URL and waits for all goroutines to finish
+func runTestCrawl(cfg *config) {
+	cfg.wg.Add(1)
+	go cfg.crawlPage(cfg.baseURL.String(), 0)
+	cfg.wg.Wait()
+}
+
+func TestCrawlPageDepthCounts(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/c", "/"},
+		"/b": {"/a"},
+		"/c": {},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	expected := map[int]int{0: 1, 1: 2, 2: 1}
+	if len(cfg.depthCounts) != len(expected) {
+		t.Fatalf("expected depth counts %v, got %v", expected, cfg.depthCounts)
+	}
+	for depth, count := range expected {
+		if cfg.depthCounts[depth] != count {
+			t.Errorf("depth %d: expected %d pages, got %d", depth, count, cfg.depthCounts[depth])
+		}
+	}
+}
+
+func TestCrawlPageMaxDepth(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":  {"/a"},
+		"/a": {"/c"},
+		"/c": {},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.maxDepth = 1
+	runTestCrawl(cfg)
+
+	if cfg.depthCounts[2] != 0 {
+		t.Errorf("expected no pages beyond max depth, got %v", cfg.depthCounts)
+	}
+	if len(cfg.pages) != 2 {
+		t.Errorf("expected 2 pages crawled, got %d: %v", len(cfg.pages), cfg.pages)
+	}
+}
+
+func TestCrawlPageExternalRedirectNotFollowed(t *testing.T) {
+	var externalHits int64
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&externalHits, 1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>external</body></html>")
+	}))
+	defer external.Close()
+	// Use a different hostname for the same loopback server so it counts as another host
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1) + "/landing"
+
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/moved">moved</a></body></html>`)
+		case "/moved":
+			http.Redirect(w, r, externalURL, http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer internal.Close()
+
+	cfg := newTestConfig(t, internal.URL, 10)
+	cfg.followExternalRedirects = false
+	runTestCrawl(cfg)
+
+	if hits := atomic.LoadInt64(&externalHits); hits != 0 {
+		t.Errorf("expected external host not to be requested, got %d hits", hits)
+	}
+	if cfg.externalLinks[externalURL] != 1 {
+		t.Errorf("expected %s recorded as external link, got %v", externalURL, cfg.externalLinks)
+	}
+	if source := cfg.externalRedirects[externalURL]; source != internal.URL+"/moved" {
+		t.Errorf("expected redirect source %s/moved, got %q", internal.URL, source)
+	}
+}
+
+func TestCrawlPageSkipsDisallowedSchemes(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":   {"ftp://files.example.com/file.zip", "ws://example.com/socket", "/ok"},
+		"/ok": {},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	if len(cfg.pages) != 2 {
+		t.Errorf("expected 2 pages crawled, got %v", cfg.pages)
+	}
+	if len(cfg.externalLinks) != 0 {
+		t.Errorf("expected ftp and ws links not to be enqueued, got external links %v", cfg.externalLinks)
+	}
+	if cfg.skippedSchemes["ftp"] != 1 || cfg.skippedSchemes["ws"] != 1 {
+		t.Errorf("expected ftp and ws to be counted as skipped, got %v", cfg.skippedSchemes)
+	}
+}
+
+func TestCrawlPageFoldsTrailingSlashRedirect(t *testing.T) {
+	var aRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+		case "/a":
+			http.Redirect(w, r, "/a/", http.StatusMovedPermanently)
+		case "/a/":
+			atomic.AddInt64(&aRequests, 1)
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/a/">a slash</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	if len(cfg.pages) != 2 {
+		t.Fatalf("expected / and /a to be 2 pages, got %v", cfg.pages)
+	}
+	normalizedA, _ := normalizeURL(server.URL + "/a")
+	if cfg.pages[normalizedA] != 2 {
+		t.Errorf("expected /a and /a/ to count as one page with 2 links, got %v", cfg.pages)
+	}
+	if hits := atomic.LoadInt64(&aRequests); hits != 1 {
+		t.Errorf("expected /a/ to be fetched once, got %d", hits)
+	}
+	if canonical := cfg.canonicalURLs[normalizedA]; canonical != server.URL+"/a/" {
+		t.Errorf("expected canonical form %s/a/, got %q", server.URL, canonical)
+	}
+}
+
+func TestCrawlPageFoldsContentLocation(t *testing.T) {
+	var itemRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/item-view?id=1">view</a><a href="/items/1">item</a></body></html>`)
+		case "/item-view", "/items/1":
+			atomic.AddInt64(&itemRequests, 1)
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Location", "/item/1")
+			// Linking to itself again once it has been folded
+			fmt.Fprint(w, `<html><body>item 1 <a href="/items/1">permalink</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	normalizedItem, _ := normalizeURL(server.URL + "/item/1")
+	if len(cfg.pages) != 2 {
+		t.Fatalf("expected / and /item/1 to be 2 pages, got %v", cfg.pages)
+	}
+	// Both links from /, and the permalink on whichever request URL was folded first
+	if cfg.pages[normalizedItem] != 3 {
+		t.Errorf("expected every link to fold into %s with 3 links, got %v", normalizedItem, cfg.pages)
+	}
+	if requests := atomic.LoadInt64(&itemRequests); requests != 2 {
+		t.Errorf("expected /item-view and /items/1 fetched once each, got %d requests", requests)
+	}
+	if cfg.depthCounts[1] != 1 {
+		t.Errorf("expected one page at depth 1 after folding, got %v", cfg.depthCounts)
+	}
+}
+
+func TestCrawlPageContentLocationAliasNotRefetched(t *testing.T) {
+	var itemRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/items/1">item</a></body></html>`)
+		case "/items/1":
+			atomic.AddInt64(&itemRequests, 1)
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Location", "/item/1")
+			fmt.Fprint(w, `<html><body><a href="/items/1">permalink</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	normalizedItem, _ := normalizeURL(server.URL + "/item/1")
+	if requests := atomic.LoadInt64(&itemRequests); requests != 1 {
+		t.Errorf("expected /items/1 fetched once, got %d requests", requests)
+	}
+	if len(cfg.pages) != 2 || cfg.pages[normalizedItem] != 2 {
+		t.Errorf("expected the permalink counted toward %s, got %v", normalizedItem, cfg.pages)
+	}
+}
+
+func TestCrawlPageRecordsRedirectsUnderFinalURL(t *testing.T) {
+	var oldRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/old">old</a><a href="/new">new</a></body></html>`)
+		case "/old":
+			atomic.AddInt64(&oldRequests, 1)
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/old">moved here</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	normalizedOld, _ := normalizeURL(server.URL + "/old")
+	normalizedNew, _ := normalizeURL(server.URL + "/new")
+	if _, ok := cfg.pages[normalizedOld]; ok {
+		t.Errorf("expected no page recorded under the redirecting URL, got %v", cfg.pages)
+	}
+	if cfg.pages[normalizedNew] != 3 {
+		t.Errorf("expected all 3 links counted toward %s, got %v", normalizedNew, cfg.pages)
+	}
+	if cfg.redirectedPages[normalizedOld] != normalizedNew {
+		t.Errorf("expected %s noted as redirecting to %s, got %v", normalizedOld, normalizedNew, cfg.redirectedPages)
+	}
+	if requests := atomic.LoadInt64(&oldRequests); requests != 1 {
+		t.Errorf("expected the redirecting URL to be fetched once, got %d", requests)
+	}
+
+	report, err := cfg.buildReport(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	oldURL, newURL := fullPageURL(normalizedOld, cfg.baseURL, nil), fullPageURL(normalizedNew, cfg.baseURL, nil)
+	if target := report.Redirects[oldURL]; target != newURL {
+		t.Errorf("expected the report to map %s to %s, got %v", oldURL, newURL, report.Redirects)
+	}
+}
+
+func TestCrawlPageCrawlFragments(t *testing.T) {
+	var productRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Query().Get("_escaped_fragment_") == "/products" {
+			atomic.AddInt64(&productRequests, 1)
+			fmt.Fprint(w, `<html><body>products</body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><a href="/#!/products">products</a><a href="#section">section</a></body></html>`)
+	}))
+	defer server.Close()
+
+	for _, enabled := range []bool{false, true} {
+		atomic.StoreInt64(&productRequests, 0)
+		cfg := newTestConfig(t, server.URL, 10)
+		cfg.crawlFragments = enabled
+		cfg.normalize = urlNormalizer{hashBangRoutes: enabled}.normalize
+		runTestCrawl(cfg)
+
+		normalizedProducts, _ := urlNormalizer{hashBangRoutes: true}.normalize(server.URL + "/?_escaped_fragment_=/products")
+		_, crawled := cfg.pages[normalizedProducts]
+		if crawled != enabled || (atomic.LoadInt64(&productRequests) == 1) != enabled {
+			t.Errorf("crawlFragments=%v: expected #!/products crawled=%v, got pages %v", enabled, enabled, cfg.pages)
+		}
+		expectedPages := 1
+		if enabled {
+			expectedPages = 2
+		}
+		if len(cfg.pages) != expectedPages {
+			t.Errorf("crawlFragments=%v: expected %d pages (#section skipped), got %v", enabled, expectedPages, cfg.pages)
+		}
+	}
+}
+
+func TestCrawlPageFragmentsAsPages(t *testing.T) {
+	var docsRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/docs#install">install</a><a href="/docs#config">config</a></body></html>`)
+		case "/docs":
+			atomic.AddInt64(&docsRequests, 1)
+			fmt.Fprint(w, `<html><body>docs</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.sharedFetches = make(map[string]*sharedFetch)
+	runTestCrawl(cfg)
+
+	for _, fragment := range []string{"#install", "#config"} {
+		normalized, _ := cfg.normalizeURL(server.URL + "/docs" + fragment)
+		if _, ok := cfg.pages[normalized]; !ok {
+			t.Errorf("expected /docs%s recorded as its own page, got %v", fragment, cfg.pages)
+		}
+	}
+	if len(cfg.pages) != 3 {
+		t.Errorf("expected 3 pages, got %v", cfg.pages)
+	}
+	if requests := atomic.LoadInt64(&docsRequests); requests != 1 {
+		t.Errorf("expected /docs to be fetched once, got %d requests", requests)
+	}
+}
+
+func TestFetchSharedKeepsResult(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.sharedFetches = make(map[string]*sharedFetch)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var fetches int64
+	fetch := func() (*pageResponse, error) {
+		atomic.AddInt64(&fetches, 1)
+		close(started)
+		<-release
+		return &pageResponse{Body: "docs"}, nil
+	}
+
+	// A variant arriving during the fetch waits for its result
+	results := make(chan *pageResponse, 2)
+	go func() {
+		page, _, _ := cfg.fetchShared("https://example.com/docs", fetch)
+		results <- page
+	}()
+	<-started
+	go func() {
+		page, shared, _ := cfg.fetchShared("https://example.com/docs", fetch)
+		if !shared {
+			t.Error("expected the second variant to share the fetch")
+		}
+		results <- page
+	}()
+	close(release)
+	for i := 0; i < 2; i++ {
+		if page := <-results; page == nil || page.Body != "docs" {
+			t.Errorf("expected both variants to get the page, got %+v", page)
+		}
+	}
+
+	// A variant arriving after the fetch finished gets the same page without refetching
+	page, shared, err := cfg.fetchShared("https://example.com/docs", fetch)
+	if page == nil || page.Body != "docs" || !shared || err != nil {
+		t.Errorf("expected a late variant to get the page, got %+v, %v, %v", page, shared, err)
+	}
+	if fetches := atomic.LoadInt64(&fetches); fetches != 1 {
+		t.Errorf("expected one fetch, got %d", fetches)
+	}
+
+	// Errors are kept the same way
+	fetchErr := errors.New("connection refused")
+	cfg.fetchShared("https://example.com/broken", func() (*pageResponse, error) { return nil, fetchErr })
+	if page, shared, err := cfg.fetchShared("https://example.com/broken", fetch); page != nil || !shared || err != fetchErr {
+		t.Errorf("expected a late variant to get the fetch error, got %+v, %v, %v", page, shared, err)
+	}
+}
+
+func TestCrawlPageLateFragmentVariant(t *testing.T) {
+	var docsRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/docs":
+			atomic.AddInt64(&docsRequests, 1)
+			fmt.Fprint(w, `<html><body><a href="/guide">guide</a></body></html>`)
+		case "/guide":
+			fmt.Fprint(w, `<html><body>guide</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.sharedFetches = make(map[string]*sharedFetch)
+	// Each variant is crawled only after the previous one finished, including the links it followed
+	for _, path := range []string{"/docs#install", "/docs#config", "/missing#a", "/missing#b"} {
+		cfg.wg.Add(1)
+		cfg.crawlPage(server.URL+path, 0)
+		cfg.wg.Wait()
+	}
+
+	for _, fragment := range []string{"#install", "#config"} {
+		normalized, _ := cfg.normalizeURL(server.URL + "/docs" + fragment)
+		if _, ok := cfg.pages[normalized]; !ok {
+			t.Errorf("expected /docs%s recorded as its own page, got %v", fragment, cfg.pages)
+		}
+	}
+	if requests := atomic.LoadInt64(&docsRequests); requests != 1 {
+		t.Errorf("expected /docs to be fetched once, got %d requests", requests)
+	}
+	for _, fragment := range []string{"#a", "#b"} {
+		if _, ok := cfg.brokenLinks[server.URL+"/missing"+fragment]; !ok {
+			t.Errorf("expected /missing%s recorded as broken, got %v", fragment, cfg.brokenLinks)
+		}
+	}
+}
+
+func TestCrawlPageCustomNormalize(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/About", "/about", "/ABOUT"},
+		"/About": {},
+		"/about": {},
+		"/ABOUT": {},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.normalize = func(rawURL string) (string, error) {
+		normalized, err := normalizeURL(rawURL)
+		return strings.ToLower(normalized), err
+	}
+	runTestCrawl(cfg)
+
+	if len(cfg.pages) != 2 {
+		t.Fatalf("expected case variants of /about to be 1 page, got %v", cfg.pages)
+	}
+	normalizedAbout, _ := normalizeURL(server.URL + "/about")
+	if cfg.pages[normalizedAbout] != 3 {
+		t.Errorf("expected 3 links to %s, got %v", normalizedAbout, cfg.pages)
+	}
+	if *cfg.totalRequests != 2 {
+		t.Errorf("expected / and one /about variant to be fetched, got %d requests", *cfg.totalRequests)
+	}
+}
+
+func TestRecordExternalLinkCap(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.maxExternal = 2
+
+	for _, link := range []string{
+		"https://a.example.org/",
+		"https://b.example.org/",
+		"https://c.example.org/", // over the cap
+		"https://a.example.org/",
+		"https://d.example.org/", // over the cap
+		"https://b.example.org/",
+		"https://a.example.org/",
+	} {
+		cfg.recordExternalLink(link)
+	}
+
+	expected := map[string]int{
+		"https://a.example.org/": 3,
+		"https://b.example.org/": 2,
+	}
+	if !reflect.DeepEqual(cfg.externalLinks, expected) {
+		t.Errorf("expected %v, got %v", expected, cfg.externalLinks)
+	}
+	if cfg.truncatedExternalLinks != 2 {
+		t.Errorf("expected 2 truncated external links, got %d", cfg.truncatedExternalLinks)
+	}
+}
+
+// heavySite serves a binary tree of large pages: /p/N links to /p/2N+1 and /p/2N+2 and is padded to size bytes
+type heavySite struct {
+	size int
+}
+
+func (s heavySite) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Request: req}
+	var n int
+	if _, err := fmt.Sscanf(req.URL.Path, "/p/%d", &n); err != nil {
+		resp.StatusCode = http.StatusNotFound
+		resp.Body = io.NopCloser(strings.NewReader(""))
+		return resp, nil
+	}
+	resp.Header.Set("Content-Type", "text/html")
+	page := fmt.Sprintf(`<html><body><a href="/p/%d">left</a><a href="/p/%d">right</a><p>`, 2*n+1, 2*n+2)
+	page += strings.Repeat("x", s.size) + "</p></body></html>"
+	resp.Body = io.NopCloser(strings.NewReader(page))
+	return resp, nil
+}
+
+// crawlHeavySite crawls 60 pages of heavySite with a tiny frontier, so that pages wait in enqueueLinks
+// while their children are crawled, and returns the peak heap in use during the crawl
+func crawlHeavySite(tb testing.TB) uint64 {
+	cfg := newTestConfig(tb, "http://heavy.test/p/0", 60)
+	useTransport(cfg, heavySite{size: 512 * 1024})
+	cfg.concurrencyControl = make(chan struct{}, 4)
+	cfg.frontier = make(chan struct{}, 1)
+	cfg.queuePolicy = queuePolicyBlock
+
+	runtime.GC()
+	var peak uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > peak {
+				peak = stats.HeapInuse
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	cfg.Run(time.Minute)
+	close(done)
+	<-sampled
+
+	if len(cfg.pages) != 60 {
+		tb.Fatalf("expected 60 pages, got %d", len(cfg.pages))
+	}
+	return peak
+}
+
+func BenchmarkCrawlPeakMemory(b *testing.B) {
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		if p := crawlHeavySite(b); p > peak {
+			peak = p
+		}
+	}
+	b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+}
+
+// denseSite serves pages pages with long URLs, each linking to the links pages after it
+type denseSite struct {
+	pages, links int
+}
+
+func (s denseSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Request: req}
+	var n int
+	if _, err := fmt.Sscanf(req.URL.Path, "/docs/reference/articles/page-%d", &n); err != nil {
+		resp.StatusCode = http.StatusNotFound
+		resp.Body = io.NopCloser(strings.NewReader(""))
+		return resp, nil
+	}
+	resp.Header.Set("Content-Type", "text/html")
+	var page strings.Builder
+	page.WriteString("<html><body>")
+	for i := 1; i <= s.links; i++ {
+		fmt.Fprintf(&page, `<a href="/docs/reference/articles/page-%d">link</a>`, (n+i)%s.pages)
+	}
+	page.WriteString("</body></html>")
+	resp.Body = io.NopCloser(strings.NewReader(page.String()))
+	return resp, nil
+}
+
+// crawlDenseSite crawls 300 pages of denseSite with a state file ledger and the inbound links report, and
+// returns the heap still in use once the crawl is done, while its results are kept for the report
+func crawlDenseSite(tb testing.TB, compact bool) uint64 {
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	cfg := newTestConfig(tb, "http://dense.test/docs/reference/articles/page-0", 300)
+	useTransport(cfg, denseSite{pages: 300, links: 150})
+	cfg.quietPages = true
+	cfg.ledger = newCrawlLedger()
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	cfg.maxReferrers = defaultMaxReferrers
+	if compact {
+		cfg.compactMemory()
+	}
+	cfg.Run(time.Minute)
+
+	runtime.GC()
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	if len(cfg.pages) != 300 {
+		tb.Fatalf("expected 300 pages, got %d", len(cfg.pages))
+	}
+	runtime.KeepAlive(cfg)
+	return after.HeapInuse - before.HeapInuse
+}
+
+func BenchmarkCrawlRetainedMemory(b *testing.B) {
+	for _, compact := range []bool{false, true} {
+		b.Run(fmt.Sprintf("compact=%v", compact), func(b *testing.B) {
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				if r := crawlDenseSite(b, compact); r > retained {
+					retained = r
+				}
+			}
+			b.ReportMetric(float64(retained)/(1<<20), "retained-MB")
+		})
+	}
+}
+
+func TestCompactMemory(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":       {"/a", "/b", "/shared"},
+		"/a":      {"/shared"},
+		"/b":      {"/shared"},
+		"/shared": {},
+	})
+
+	for _, compact := range []bool{false, true} {
+		cfg := newTestConfig(t, server.URL, 10)
+		cfg.ledger = newCrawlLedger()
+		cfg.nextLinks = make(map[string]string)
+		cfg.externalRedirects = make(map[string]string)
+		if compact {
+			cfg.compactMemory()
+		}
+		runTestCrawl(cfg)
+
+		if len(cfg.pages) != 4 {
+			t.Errorf("compact=%v: expected the crawl to be unaffected, got pages %v", compact, cfg.pages)
+		}
+		if skipped := cfg.nextLinks == nil && cfg.externalRedirects == nil; skipped != compact {
+			t.Errorf("compact=%v: expected per-page details skipped=%v", compact, compact)
+		}
+
+		// Pages linking to the same URL share one copy of it in the state file's link lists
+		a, _ := cfg.normalizeURL(server.URL + "/a")
+		b, _ := cfg.normalizeURL(server.URL + "/b")
+		linksA, linksB := cfg.ledger.entries[a].Links, cfg.ledger.entries[b].Links
+		if len(linksA) != 1 || len(linksB) != 1 || linksA[0] != linksB[0] {
+			t.Fatalf("compact=%v: expected both pages to link to /shared, got %v and %v", compact, linksA, linksB)
+		}
+		if shared := unsafe.StringData(linksA[0]) == unsafe.StringData(linksB[0]); shared != compact {
+			t.Errorf("compact=%v: expected the /shared URL stored once=%v", compact, compact)
+		}
+	}
+}
+
+func TestCrawlPageRedirectWithinMatchedHostsFollowed(t *testing.T) {
+	// www.example.test belongs to the site under the suffix policy, so only the redirect to other.test is external
+	site := redirectSite{
+		"http://example.test/moved": "http://www.example.test/page",
+		"http://example.test/old":   "http://other.test/",
+	}
+	cfg := newTestConfig(t, "http://example.test/", 10)
+	useTransport(cfg, site)
+	cfg.hostMatch = hostMatchSuffix
+	cfg.followExternalRedirects = false
+	cfg.Run(time.Minute)
+
+	expected := map[string]string{"http://other.test/": "http://example.test/old"}
+	if !reflect.DeepEqual(cfg.externalRedirects, expected) {
+		t.Errorf("expected external redirects %v, got %v", expected, cfg.externalRedirects)
+	}
+	if target := cfg.redirectedPages["example.test/moved"]; target != "example.test/page" {
+		t.Errorf("expected /moved to be followed to www.example.test/page, got %v", cfg.redirectedPages)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// crawlPriorities orders pages waiting for a concurrency slot by a priority hint that pages declare in a
+// response header or <meta> tag, so important parts of a site are crawled first within maxPages.
+// A page is only known to be important once fetched, so its hint becomes the priority of the links found on it.
+type crawlPriorities struct {
+	header          string // response header holding the hint ("" to ignore headers)
+	meta            string // <meta name> holding the hint ("" to ignore meta tags)
+	defaultPriority int    // priority of pages without a hint, and of the seed
+
+	mu      sync.Mutex
+	links   map[string]int // priority of each discovered link that hasn't started yet
+	waiting priorityQueue
+	seq     int64
+	wake    chan struct{} // signals the dispatcher that a page started waiting
+}
+
+// newCrawlPriorities reads priority hints from header and meta; higher values are crawled first
+func newCrawlPriorities(header, meta string, defaultPriority int) *crawlPriorities {
+	return &crawlPriorities{
+		header:          header,
+		meta:            meta,
+		defaultPriority: defaultPriority,
+		links:           make(map[string]int),
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// pagePriority returns the priority a fetched page declares, preferring the header over the meta tag.
+// Missing or non-integer hints give the default priority.
+func (p *crawlPriorities) pagePriority(header http.Header, htmlBody string) int {
+	if p.header != "" {
+		if priority, err := strconv.Atoi(strings.TrimSpace(header.Get(p.header))); err == nil {
+			return priority
+		}
+	}
+	if p.meta != "" {
+		if content, ok := getMetaContentFromHTML(htmlBody, p.meta); ok {
+			if priority, err := strconv.Atoi(strings.TrimSpace(content)); err == nil {
+				return priority
+			}
+		}
+	}
+	return p.defaultPriority
+}
+
+// assign sets the priority of a discovered link. A link found on several pages keeps the highest priority.
+func (p *crawlPriorities) assign(rawURL string, priority int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if current, ok := p.links[rawURL]; !ok || priority > current {
+		p.links[rawURL] = priority
+	}
+}
+
+// priorityWaiter is a page waiting for a concurrency slot
+type priorityWaiter struct {
+	priority int
+	seq      int64         // arrival order, breaking ties first come first served
+	ready    chan struct{} // closed once the page holds a slot
+	index    int
+}
+
+// priorityQueue is a max-heap of waiting pages by priority, then arrival
+type priorityQueue []*priorityWaiter
+
+func (q priorityQueue) Len() int { return len(q) }
+func (q priorityQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q priorityQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+func (q *priorityQueue) Push(x interface{}) {
+	w := x.(*priorityWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	*q = old[:len(old)-1]
+	w.index = -1
+	return w
+}
+
+// acquire waits until the dispatcher hands rawURL's page a concurrency slot. It returns false if ctx
+// is cancelled first, in which case no slot is held.
+func (p *crawlPriorities) acquire(ctx context.Context, rawURL string) bool {
+	p.mu.Lock()
+	priority, ok := p.links[rawURL]
+	if !ok {
+		priority = p.defaultPriority
+	}
+	delete(p.links, rawURL)
+	p.seq++
+	w := &priorityWaiter{priority: priority, seq: p.seq, ready: make(chan struct{})}
+	heap.Push(&p.waiting, w)
+	p.mu.Unlock()
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-w.ready:
+		return true
+	case <-ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if w.index < 0 {
+			// The slot was handed over just as the crawl was cancelled
+			return true
+		}
+		heap.Remove(&p.waiting, w.index)
+		return false
+	}
+}
+
+// dispatch takes each concurrency slot from slots as it frees up and hands it to the most important
+// waiting page, until ctx is cancelled
+func (p *crawlPriorities) dispatch(ctx context.Context, slots chan struct{}) {
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		if !p.handOver(ctx) {
+			<-slots
+			return
+		}
+	}
+}
+
+// handOver gives the slot the dispatcher holds to the first waiting page, waiting for one if need be.
+// It returns false if ctx is cancelled first.
+func (p *crawlPriorities) handOver(ctx context.Context) bool {
+	for {
+		p.mu.Lock()
+		if p.waiting.Len() > 0 {
+			w := heap.Pop(&p.waiting).(*priorityWaiter)
+			p.mu.Unlock()
+			close(w.ready)
+			return true
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-p.wake:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPagePriority(t *testing.T) {
+	priorities := newCrawlPriorities("X-Crawl-Priority", "crawl-priority", 5)
+	tests := []struct {
+		name     string
+		header   string
+		html     string
+		expected int
+	}{
+		{"header", "10", "", 10},
+		{"meta", "", `<meta name="Crawl-Priority" content="-2">`, -2},
+		{"header wins over meta", "7", `<meta name="crawl-priority" content="1">`, 7},
+		{"invalid header falls back to meta", "high", `<meta name="crawl-priority" content="3">`, 3},
+		{"no hint", "", `<meta name="description" content="9">`, 5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.header != "" {
+				header.Set("X-Crawl-Priority", tc.header)
+			}
+			if actual := priorities.pagePriority(header, tc.html); actual != tc.expected {
+				t.Errorf("expected priority %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlPrioritiesHandOutSlotsByPriority(t *testing.T) {
+	priorities := newCrawlPriorities("", "crawl-priority", 0)
+	priorities.assign("https://example.com/low", 1)
+	priorities.assign("https://example.com/high", 9)
+	priorities.assign("https://example.com/high", 2) // a lower priority from another page doesn't demote it
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for _, path := range []string{"/low", "/none", "/high"} {
+		wg.Add(1)
+		go func(rawURL string) {
+			defer wg.Done()
+			if priorities.acquire(ctx, rawURL) {
+				mu.Lock()
+				order = append(order, rawURL)
+				mu.Unlock()
+			}
+		}("https://example.com" + path)
+	}
+	// Let every page queue up before the first slot is handed out
+	for {
+		priorities.mu.Lock()
+		waiting := priorities.waiting.Len()
+		priorities.mu.Unlock()
+		if waiting == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	slots := make(chan struct{}, 1)
+	go priorities.dispatch(ctx, slots)
+	for i := 0; i < 3; i++ {
+		// Wait for the page holding the slot, then free it for the next one
+		for {
+			mu.Lock()
+			done := len(order) > i
+			mu.Unlock()
+			if done {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		<-slots
+	}
+	wg.Wait()
+
+	expected := []string{"https://example.com/high", "https://example.com/low", "https://example.com/none"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("expected slots in order %v, got %v", expected, order)
+	}
+}
+
+func TestCrawlPrioritiesAcquireGivesUpOnCancel(t *testing.T) {
+	priorities := newCrawlPriorities("X-Crawl-Priority", "", 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if priorities.acquire(ctx, "https://example.com/") {
+		t.Error("expected acquire to fail on a cancelled context")
+	}
+	if priorities.waiting.Len() != 0 {
+		t.Errorf("expected the cancelled page to leave the queue, %d still waiting", priorities.waiting.Len())
+	}
+}
+
+func TestCrawlVisitsHighPriorityLinksFirst(t *testing.T) {
+	// /urgent declares a high priority and /later a low one, so /urgent's links jump ahead of /later's
+	pages := map[string]struct {
+		priority string
+		links    []string
+	}{
+		"/":         {"", []string{"/later", "/urgent"}},
+		"/later":    {"1", []string{"/later/a", "/later/b"}},
+		"/urgent":   {"9", []string{"/urgent/a"}},
+		"/later/a":  {"", nil},
+		"/later/b":  {"", nil},
+		"/urgent/a": {"", nil},
+	}
+	var mu sync.Mutex
+	var visited []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		visited = append(visited, r.URL.Path)
+		mu.Unlock()
+		if page.priority != "" {
+			w.Header().Set("X-Crawl-Priority", page.priority)
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>")
+		for _, link := range page.links {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.concurrencyControl = make(chan struct{}, 1)
+	cfg.priorities = newCrawlPriorities("X-Crawl-Priority", "", 5)
+	cfg.Run(time.Minute)
+
+	if len(visited) != len(pages) {
+		t.Fatalf("expected all %d pages to be crawled, got %v", len(pages), visited)
+	}
+	position := make(map[string]int)
+	for i, path := range visited {
+		position[path] = i
+	}
+	// Both /later and /urgent wait at the default priority, so /urgent/a (9) is crawled before the
+	// children of /later (1) even when /later was crawled first
+	for _, low := range []string{"/later/a", "/later/b"} {
+		if position["/urgent/a"] > position[low] {
+			t.Errorf("expected /urgent/a before %s, got order %v", low, visited)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsResolver looks up a host's IP addresses; *net.Resolver satisfies it
+type dnsResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// dnsCacheEntry is a cached lookup result and the round-robin position within it
+type dnsCacheEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+	next    int
+}
+
+// dnsCache resolves hosts through resolver and keeps the result for ttl, so concurrent requests
+// across many pages of the same hosts don't each pay for a DNS lookup
+type dnsCache struct {
+	resolver dnsResolver
+	ttl      time.Duration
+	dialer   *net.Dialer
+	mu       sync.Mutex
+	entries  map[string]*dnsCacheEntry
+}
+
+// newDNSCache creates a cache of lookups made with resolver, each kept for ttl
+func newDNSCache(resolver dnsResolver, ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		resolver: resolver,
+		ttl:      ttl,
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		entries:  make(map[string]*dnsCacheEntry),
+	}
+}
+
+// lookup returns the addresses for host, rotated so successive calls start with successive A records
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	if ok && time.Now().Before(entry.expires) {
+		addrs := entry.rotate()
+		c.mu.Unlock()
+		return addrs, nil
+	}
+	c.mu.Unlock()
+
+	// Resolve without holding the lock so lookups of other hosts aren't blocked
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry = &dnsCacheEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+	c.entries[host] = entry
+	return entry.rotate(), nil
+}
+
+// rotate returns the addresses starting at the round-robin position and advances it
+func (e *dnsCacheEntry) rotate() []net.IPAddr {
+	start := e.next % len(e.addrs)
+	e.next++
+	rotated := make([]net.IPAddr, 0, len(e.addrs))
+	rotated = append(rotated, e.addrs[start:]...)
+	return append(rotated, e.addrs[:start]...)
+}
+
+// DialContext is an http.Transport DialContext hook dialing cached addresses. Each address is tried
+// in round-robin order until one connects.
+func (c *dnsCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("dial %s: %w", address, lastErr)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubResolver resolves every host to addrs and counts lookups
+type stubResolver struct {
+	addrs   []net.IPAddr
+	lookups int64
+}
+
+func (r *stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	atomic.AddInt64(&r.lookups, 1)
+	return r.addrs, nil
+}
+
+func TestDNSCacheReusesLookupWithinTTL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	const ttl = 200 * time.Millisecond
+	resolver := &stubResolver{addrs: []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}}
+	cache := newDNSCache(resolver, ttl)
+	client := &http.Client{Transport: &http.Transport{DialContext: cache.DialContext, DisableKeepAlives: true}}
+
+	get := func() {
+		t.Helper()
+		resp, err := client.Get("http://cached.test:" + serverURL.Port() + "/")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	get()
+	get()
+	if lookups := atomic.LoadInt64(&resolver.lookups); lookups != 1 {
+		t.Errorf("expected the second request to reuse the cached lookup, got %d lookups", lookups)
+	}
+
+	time.Sleep(ttl + 50*time.Millisecond)
+	get()
+	if lookups := atomic.LoadInt64(&resolver.lookups); lookups != 2 {
+		t.Errorf("expected a new lookup after the TTL expired, got %d lookups", lookups)
+	}
+}
+
+func TestDNSCacheRoundRobin(t *testing.T) {
+	resolver := &stubResolver{addrs: []net.IPAddr{
+		{IP: net.ParseIP("10.0.0.1")},
+		{IP: net.ParseIP("10.0.0.2")},
+		{IP: net.ParseIP("10.0.0.3")},
+	}}
+	cache := newDNSCache(resolver, time.Minute)
+
+	for i, expected := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.1"} {
+		addrs, err := cache.lookup(context.Background(), "example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(addrs) != 3 || addrs[0].String() != expected {
+			t.Errorf("lookup %d: expected %s first, got %v", i, expected, addrs)
+		}
+	}
+}
+
+func TestDNSCacheRespectsCancellation(t *testing.T) {
+	cache := newDNSCache(net.DefaultResolver, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.DialContext(ctx, "tcp", "example.invalid:80"); err == nil {
+		t.Errorf("expected a cancelled context to fail the dial")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// dotQuote quotes s as a DOT string literal
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, using page URLs as node IDs
+func (gv *GraphVisualizer) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph crawl {")
+	fmt.Fprintln(bw, "  node [shape=ellipse, style=filled];")
+
+	// Sort node URLs so output is stable between runs
+	urls := make([]string, 0, len(gv.nodes))
+	for nodeURL := range gv.nodes {
+		urls = append(urls, nodeURL)
+	}
+	sort.Strings(urls)
+
+	for _, nodeURL := range urls {
+		node := gv.nodes[nodeURL]
+		color := "lightblue"
+		if node.IsExternal {
+			color = "orange"
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, count=%d, external=%t, fillcolor=%s];\n",
+			dotQuote(node.URL), dotQuote(gv.createShortLabel(node.URL)), node.Count, node.IsExternal, color)
+	}
+
+	for _, edge := range gv.edges {
+		// Skip edges whose endpoints aren't in the graph, as DrawGraph does
+		if gv.nodes[edge.From] == nil || gv.nodes[edge.To] == nil {
+			continue
+		}
+		fmt.Fprintf(bw, "  %s -> %s [weight=%d];\n", dotQuote(edge.From), dotQuote(edge.To), edge.Weight)
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// GenerateDOT writes the crawl results as a Graphviz DOT file
+func GenerateDOT(pages map[string]int, externalLinks map[string]int, baseURL, filename string) error {
+	gv, err := buildGraph(pages, externalLinks, baseURL)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create DOT file: %v", err)
+	}
+	if err := gv.WriteDOT(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write DOT file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close DOT file: %v", err)
+	}
+
+	fmt.Printf("DOT graph saved to: %s\n", filename)
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGraphVisualizerAPIRendersDOT(t *testing.T) {
+	gv := NewGraphVisualizer(800, 600)
+	gv.AddNode("https://example.com", 3, false)
+	gv.AddNode("https://example.com/about", 1, false)
+	gv.AddNode("https://other.com/page", 2, true)
+	gv.AddEdge("https://example.com", "https://example.com/about", 1)
+	gv.AddEdge("https://example.com", "https://other.com/page", 2)
+	gv.AddEdge("https://example.com", "https://missing.com", 1) // endpoint isn't a node
+
+	var buf bytes.Buffer
+	if err := gv.WriteDOT(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "digraph crawl {") || !strings.HasSuffix(output, "}\n") {
+		t.Errorf("expected a digraph block, got %s", output)
+	}
+	for _, expected := range []string{
+		`"https://example.com/about" [label="example.com/about", count=1, external=false, fillcolor=lightblue];`,
+		`"https://other.com/page" [label="other.com/page", count=2, external=true, fillcolor=orange];`,
+		`"https://example.com" -> "https://example.com/about" [weight=1];`,
+		`"https://example.com" -> "https://other.com/page" [weight=2];`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %s, got %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "missing.com") {
+		t.Errorf("expected the edge to a missing node to be skipped, got %s", output)
+	}
+}
+
+func TestDotQuote(t *testing.T) {
+	if actual := dotQuote(`a "quoted" \\ path`); actual != `"a \"quoted\" \\\\ path"` {
+		t.Errorf("unexpected quoting: %s", actual)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File extensions --inventory-downloads looks for in links when --download-extensions isn't given
+var defaultDownloadExtensions = []string{
+	"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "odt", "ods", "csv",
+	"zip", "gz", "tar", "7z", "dmg", "exe",
+	"mp3", "wav", "mp4", "mov", "avi", "webm",
+}
+
+// downloadInfo is a downloadable file linked from the site, catalogued by --inventory-downloads
+type downloadInfo struct {
+	Type       string `json:"type"` // lowercased file extension, e.g. "pdf"
+	StatusCode int    `json:"status,omitempty"`
+	Size       int64  `json:"size"` // declared bytes, or -1 when the server didn't say
+	Error      string `json:"error,omitempty"`
+}
+
+// downloadTypeSummary is the number and declared total size of the downloads of one type
+type downloadTypeSummary struct {
+	Type     string
+	Count    int
+	Size     int64
+	Unsized  int // downloads counted without a size, as the server didn't declare one or failed
+	Failures int
+}
+
+// parseDownloadExtensions reads a comma-separated list of file extensions, with or without the leading dot
+func parseDownloadExtensions(raw string) (map[string]bool, error) {
+	extensions := make(map[string]bool)
+	for _, extension := range strings.Split(raw, ",") {
+		if extension = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(extension), ".")); extension != "" {
+			extensions[extension] = true
+		}
+	}
+	if len(extensions) == 0 {
+		return nil, fmt.Errorf("no file extensions given")
+	}
+	return extensions, nil
+}
+
+// downloadType returns the lowercased extension of u's path when it is one of extensions, or ""
+func downloadType(u *url.URL, extensions map[string]bool) string {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	extension := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+	if !extensions[extension] {
+		return ""
+	}
+	return extension
+}
+
+// recordDownloads notes the links of a page pointing at downloadable files, if downloads are inventoried
+func (cfg *config) recordDownloads(links []string) {
+	if cfg.downloads == nil {
+		return
+	}
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for _, link := range links {
+		u, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		if fileType := downloadType(u, cfg.downloadExtensions); fileType != "" {
+			if _, recorded := cfg.downloads[link]; !recorded {
+				cfg.downloads[link] = downloadInfo{Type: fileType, Size: -1}
+			}
+		}
+	}
+}
+
+// downloadFilter skips the downloadable files on crawled hosts: they are inventoried instead of fetched
+// as pages. Downloads elsewhere are kept, to be recorded as external links.
+func downloadFilter(extensions map[string]bool, isCrawledHost func(host string) bool) FilterFunc {
+	return func(u *url.URL, depth int) (bool, string) {
+		if downloadType(u, extensions) != "" && isCrawledHost(u.Hostname()) {
+			return false, "download"
+		}
+		return true, ""
+	}
+}
+
+// fetchDownloadSize asks for a download's declared size with HEAD. Servers not supporting HEAD are asked
+// for its first byte with GET, which still tells the full size.
+func fetchDownloadSize(ctx context.Context, rawURL string, timeout time.Duration) (status int, size int64, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return 0, -1, fmt.Errorf("failed to create request: %v", err)
+		}
+		setIdentityHeaders(req)
+		if method == http.MethodGet {
+			req.Header.Set("Range", "bytes=0-0")
+		}
+		resp, err := clientFor(req).Do(req)
+		if err != nil {
+			return 0, -1, err
+		}
+		// Only the headers matter, don't download the file
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		status, size = resp.StatusCode, -1
+		if status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented {
+			continue
+		}
+		if status < http.StatusBadRequest {
+			size = responseTotalSize(resp)
+		}
+		break
+	}
+	return status, size, nil
+}
+
+// sizeDownloads is the second phase of --inventory-downloads: once the crawl is done, it asks for the
+// declared size of every distinct download once, pacing requests like page fetches
+func (cfg *config) sizeDownloads(ctx context.Context, timeout time.Duration) {
+	cfg.mu.Lock()
+	links := make([]string, 0, len(cfg.downloads))
+	for link, info := range cfg.downloads {
+		if info.StatusCode == 0 && info.Error == "" {
+			links = append(links, link)
+		}
+	}
+	cfg.mu.Unlock()
+	sort.Strings(links)
+
+	if len(links) > 0 {
+		fmt.Printf("\nSizing %d downloads...\n", len(links))
+	}
+
+	cfg.checkLinks(ctx, links, func(ctx context.Context, link string) externalCheck {
+		if u, err := url.Parse(link); err == nil && !cfg.ignoreRobots {
+			if err := cfg.robots.waitCrawlDelay(ctx, cfg.clock, u); err != nil {
+				return externalCheck{Error: err.Error()}
+			}
+		}
+		cfg.clock.Sleep(requestDelay)
+		status, size, err := fetchDownloadSize(ctx, link, timeout)
+		if err != nil {
+			return externalCheck{Error: err.Error()}
+		}
+		cfg.mu.Lock()
+		info := cfg.downloads[link]
+		info.StatusCode, info.Size = status, size
+		cfg.downloads[link] = info
+		cfg.mu.Unlock()
+		return externalCheck{StatusCode: status}
+	}, func(link string, check externalCheck) {
+		if check.Error != "" {
+			info := cfg.downloads[link]
+			info.Error = check.Error
+			cfg.downloads[link] = info
+		}
+	})
+}
+
+// summarizeDownloads groups the downloads by type, sorted by type
+func summarizeDownloads(downloads map[string]downloadInfo) []downloadTypeSummary {
+	byType := make(map[string]*downloadTypeSummary)
+	for _, info := range downloads {
+		summary := byType[info.Type]
+		if summary == nil {
+			summary = &downloadTypeSummary{Type: info.Type}
+			byType[info.Type] = summary
+		}
+		summary.Count++
+		if info.Error != "" || info.StatusCode >= http.StatusBadRequest {
+			summary.Failures++
+		}
+		if info.Size >= 0 {
+			summary.Size += info.Size
+		} else {
+			summary.Unsized++
+		}
+	}
+
+	summaries := make([]downloadTypeSummary, 0, len(byType))
+	for _, summary := range byType {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Type < summaries[j].Type })
+	return summaries
+}
+
+// printDownloadInventory prints the number and declared total size of the downloads of each type
+func printDownloadInventory(w io.Writer, downloads map[string]downloadInfo) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  DOWNLOAD INVENTORY")
+	fmt.Fprintln(w, "=============================")
+	if len(downloads) == 0 {
+		fmt.Fprintln(w, "No downloads found")
+		return
+	}
+	for _, summary := range summarizeDownloads(downloads) {
+		line := fmt.Sprintf("%s: %d files, %d bytes", summary.Type, summary.Count, summary.Size)
+		if summary.Unsized > 0 {
+			line += fmt.Sprintf(" (%d without a declared size)", summary.Unsized)
+		}
+		if summary.Failures > 0 {
+			line += fmt.Sprintf(", %d broken", summary.Failures)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
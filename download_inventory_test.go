@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInventoryDownloadsByType(t *testing.T) {
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body>
+				<a href="/files/report.pdf">report</a>
+				<a href="/files/Guide.PDF">guide</a>
+				<a href="/files/archive.zip">archive</a>
+				<a href="/about">about</a>
+			</body></html>`)
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/files/report.pdf">report again</a></body></html>`)
+		case "/files/report.pdf", "/files/Guide.PDF", "/files/archive.zip":
+			fetched = append(fetched, r.Method+" "+r.URL.Path)
+			sizes := map[string]string{"/files/report.pdf": "1000", "/files/Guide.PDF": "500", "/files/archive.zip": "2048"}
+			w.Header().Set("Content-Length", sizes[r.URL.Path])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.downloads = make(map[string]downloadInfo)
+	cfg.downloadExtensions = map[string]bool{"pdf": true, "zip": true}
+	cfg.filters = append(cfg.filters, downloadFilter(cfg.downloadExtensions, cfg.isCrawledHost))
+	runTestCrawl(cfg)
+
+	// The downloads are never crawled as pages
+	if len(fetched) != 0 {
+		t.Errorf("expected no requests for downloads during the crawl, got %v", fetched)
+	}
+	if len(cfg.pages) != 2 || cfg.skipReasons["download"] != 4 {
+		t.Errorf("expected 2 pages and 4 download links skipped, got %v and %v", cfg.pages, cfg.skipReasons)
+	}
+
+	cfg.sizeDownloads(context.Background(), time.Second)
+	expected := map[string]downloadInfo{
+		server.URL + "/files/report.pdf":  {Type: "pdf", StatusCode: http.StatusOK, Size: 1000},
+		server.URL + "/files/Guide.PDF":   {Type: "pdf", StatusCode: http.StatusOK, Size: 500},
+		server.URL + "/files/archive.zip": {Type: "zip", StatusCode: http.StatusOK, Size: 2048},
+	}
+	if !reflect.DeepEqual(cfg.downloads, expected) {
+		t.Errorf("expected downloads %+v, got %+v", expected, cfg.downloads)
+	}
+	if len(fetched) != 3 || !strings.HasPrefix(fetched[0], http.MethodHead) {
+		t.Errorf("expected one HEAD request per distinct download, got %v", fetched)
+	}
+
+	var out bytes.Buffer
+	printDownloadInventory(&out, cfg.downloads)
+	for _, line := range []string{"pdf: 2 files, 1500 bytes\n", "zip: 1 files, 2048 bytes\n"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in the inventory, got:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestSummarizeDownloads(t *testing.T) {
+	downloads := map[string]downloadInfo{
+		"https://example.com/a.mp4": {Type: "mp4", StatusCode: http.StatusOK, Size: 10},
+		"https://example.com/b.mp4": {Type: "mp4", StatusCode: http.StatusOK, Size: -1},
+		"https://example.com/c.mp4": {Type: "mp4", StatusCode: http.StatusNotFound, Size: -1},
+		"https://example.com/d.csv": {Type: "csv", Size: -1, Error: "timeout"},
+	}
+	expected := []downloadTypeSummary{
+		{Type: "csv", Count: 1, Unsized: 1, Failures: 1},
+		{Type: "mp4", Count: 3, Size: 10, Unsized: 2, Failures: 1},
+	}
+	if got := summarizeDownloads(downloads); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import "net/url"
+
+// Default cap on the referrers stored per link target
+const defaultMaxReferrers = 100
+
+// inboundLinks records the pages linking to one target. Only the first maxReferrers distinct referrers
+// are kept, so hub pages linked from thousands of pages stay cheap, while Total counts every link.
+type inboundLinks struct {
+	Referrers []string `json:"referrers"`
+	Total     int      `json:"total"`
+
+	// The referrer crawled at the smallest depth, kept even when it's past the cap, for buildTree
+	shallowest      string
+	shallowestDepth int
+}
+
+// recordLinks notes the internal links found on the page at normalizedSource, crawled at depth. Each
+// page's links are recorded once, since the page is only processed on its first visit.
+func (cfg *config) recordLinks(normalizedSource string, depth int, urls []string) {
+	if cfg.inboundLinks == nil {
+		return
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for _, rawURL := range urls {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || !cfg.isCrawledHost(parsed.Hostname()) {
+			continue
+		}
+		target, err := cfg.normalizeURL(rawURL)
+		if err != nil {
+			continue
+		}
+
+		inbound := cfg.inboundLinks[target]
+		if inbound == nil {
+			inbound = &inboundLinks{}
+			cfg.inboundLinks[target] = inbound
+		}
+		inbound.Total++
+		// Different URLs on one page can normalize to the same target; list the page once
+		alreadyListed := len(inbound.Referrers) > 0 && inbound.Referrers[len(inbound.Referrers)-1] == normalizedSource
+		if !alreadyListed && len(inbound.Referrers) < cfg.maxReferrers {
+			inbound.Referrers = append(inbound.Referrers, normalizedSource)
+		}
+		if inbound.shallowest == "" || depth < inbound.shallowestDepth {
+			inbound.shallowest, inbound.shallowestDepth = normalizedSource, depth
+		}
+	}
+}
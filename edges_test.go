@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRecordLinksCapsReferrers(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	cfg.maxReferrers = 3
+
+	for i := 0; i < 5; i++ {
+		cfg.recordLinks(fmt.Sprintf("example.com/page%d", i), 1, []string{"https://example.com/hub", "https://other.com/"})
+
+		hub := cfg.inboundLinks["example.com/hub"]
+		if hub.Total != i+1 {
+			t.Errorf("after %d pages: expected total %d, got %d", i+1, i+1, hub.Total)
+		}
+		if expected := min(i+1, 3); len(hub.Referrers) != expected {
+			t.Errorf("after %d pages: expected %d referrers, got %v", i+1, expected, hub.Referrers)
+		}
+	}
+
+	expected := []string{"example.com/page0", "example.com/page1", "example.com/page2"}
+	if referrers := cfg.inboundLinks["example.com/hub"].Referrers; !reflect.DeepEqual(referrers, expected) {
+		t.Errorf("expected the first referrers to be kept, got %v", referrers)
+	}
+	if len(cfg.inboundLinks) != 1 {
+		t.Errorf("expected external links not to be recorded, got %v", cfg.inboundLinks)
+	}
+}
+
+func TestCrawlRecordsInboundLinks(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/about", "/blog"},
+		"/about": {"/blog", "/blog/"},
+		"/blog":  {"/"},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	cfg.maxReferrers = defaultMaxReferrers
+	runTestCrawl(cfg)
+
+	blog, _ := normalizeURL(server.URL + "/blog")
+	inbound := cfg.inboundLinks[blog]
+	if inbound == nil || inbound.Total != 3 || len(inbound.Referrers) != 2 {
+		t.Errorf("expected /blog linked 3 times from 2 pages, got %+v", inbound)
+	}
+}
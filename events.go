@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types written to the --events log
+const (
+	eventRequestStarted     = "request_started"
+	eventRequestCompleted   = "request_completed"
+	eventRetry              = "retry"
+	eventPageRecorded       = "page_recorded"
+	eventLinkDiscovered     = "link_discovered"
+	eventError              = "error"
+	eventCircuitBreakerTrip = "circuit_breaker_trip"
+)
+
+// crawlEvent is one line of the --events log
+type crawlEvent struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	URL       string    `json:"url"`
+	Status    int       `json:"status,omitempty"`     // HTTP status of a completed request
+	LatencyMS int64     `json:"latency_ms,omitempty"` // duration of a completed request
+	Attempt   int       `json:"attempt,omitempty"`    // retry number, starting at 1
+	Depth     int       `json:"depth,omitempty"`
+	Source    string    `json:"source,omitempty"` // page a discovered link was found on
+	Error     string    `json:"error,omitempty"`
+	ErrorKind string    `json:"error_kind,omitempty"` // FetchErrorKind of a failed request
+}
+
+// eventLog streams crawl events as newline-delimited JSON. A nil eventLog discards events.
+type eventLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now func() time.Time
+}
+
+// newEventLog returns an event log writing to w
+func newEventLog(w io.Writer) *eventLog {
+	return &eventLog{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// emit timestamps and writes one event. Write errors are ignored so a full disk doesn't stop the crawl.
+func (l *eventLog) emit(event crawlEvent) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	event.Time = l.now()
+	_ = l.enc.Encode(event)
+}
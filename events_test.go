@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEventLogWritesNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	log := newEventLog(&buf)
+	log.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	log.emit(crawlEvent{Type: eventRequestCompleted, URL: "https://example.com/", Status: 200, LatencyMS: 12})
+	log.emit(crawlEvent{Type: eventError, URL: "https://example.com/missing", Error: "HTTP error 404"})
+
+	expected := `{"time":"2024-01-02T03:04:05Z","type":"request_completed","url":"https://example.com/","status":200,"latency_ms":12}
+{"time":"2024-01-02T03:04:05Z","type":"error","url":"https://example.com/missing","error":"HTTP error 404"}
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// A nil log discards events
+	var disabled *eventLog
+	disabled.emit(crawlEvent{Type: eventError})
+}
+
+func TestCrawlEmitsEvents(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/about", "/missing"},
+		"/about": {},
+	})
+
+	var buf bytes.Buffer
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.events = newEventLog(&buf)
+	runTestCrawl(cfg)
+
+	counts := make(map[string]int)
+	var seedCompleted *crawlEvent
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event crawlEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+		if event.Time.IsZero() || event.URL == "" {
+			t.Errorf("expected every event to have a time and URL, got %+v", event)
+		}
+		counts[event.Type]++
+		if event.Type == eventRequestCompleted && event.URL == server.URL {
+			seedCompleted = &event
+		}
+	}
+
+	if seedCompleted == nil || seedCompleted.Status != 200 {
+		t.Errorf("expected a request_completed event with status 200 for the seed page, got %+v", seedCompleted)
+	}
+	expected := map[string]int{
+		eventRequestStarted:   3,
+		eventRequestCompleted: 3,
+		eventPageRecorded:     2,
+		eventLinkDiscovered:   2,
+		eventError:            1,
+	}
+	for eventType, count := range expected {
+		if counts[eventType] != count {
+			t.Errorf("expected %d %s events, got %d", count, eventType, counts[eventType])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// otherCategory is the bucket for external domains matching no known pattern
+const otherCategory = "other"
+
+// domainCategory assigns the category to a domain and all of its subdomains
+type domainCategory struct {
+	Domain   string
+	Category string
+}
+
+// defaultDomainCategories are the built-in known domains, by category
+var defaultDomainCategories = []domainCategory{
+	{"facebook.com", "social"},
+	{"fb.com", "social"},
+	{"instagram.com", "social"},
+	{"twitter.com", "social"},
+	{"x.com", "social"},
+	{"linkedin.com", "social"},
+	{"youtube.com", "social"},
+	{"tiktok.com", "social"},
+	{"pinterest.com", "social"},
+	{"reddit.com", "social"},
+	{"mastodon.social", "social"},
+	{"jsdelivr.net", "cdn"},
+	{"cloudflare.com", "cdn"},
+	{"cdnjs.com", "cdn"},
+	{"unpkg.com", "cdn"},
+	{"cloudfront.net", "cdn"},
+	{"akamaihd.net", "cdn"},
+	{"fastly.net", "cdn"},
+	{"googleapis.com", "cdn"},
+	{"gstatic.com", "cdn"},
+	{"bootstrapcdn.com", "cdn"},
+	{"google-analytics.com", "analytics"},
+	{"googletagmanager.com", "analytics"},
+	{"doubleclick.net", "analytics"},
+	{"hotjar.com", "analytics"},
+	{"segment.com", "analytics"},
+	{"mixpanel.com", "analytics"},
+	{"plausible.io", "analytics"},
+	{"matomo.cloud", "analytics"},
+}
+
+// loadDomainCategories returns the built-in domain categories, preceded by those in filename when one is
+// given so that they take precedence. Each line of the file holds a domain and its category, e.g.
+// "cdn.example.net cdn"; blank lines and lines starting with # are ignored.
+func loadDomainCategories(filename string) ([]domainCategory, error) {
+	if filename == "" {
+		return defaultDomainCategories, nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain categories: %v", err)
+	}
+	defer file.Close()
+
+	var categories []domainCategory
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a domain and a category, got %q", line, text)
+		}
+		categories = append(categories, domainCategory{Domain: strings.ToLower(fields[0]), Category: strings.ToLower(fields[1])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain categories: %v", err)
+	}
+	return append(categories, defaultDomainCategories...), nil
+}
+
+// categorizeHost returns the category of the first entry matching host or one of its parent domains,
+// or otherCategory
+func categorizeHost(host string, categories []domainCategory) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, entry := range categories {
+		if host == entry.Domain || strings.HasSuffix(host, "."+entry.Domain) {
+			return entry.Category
+		}
+	}
+	return otherCategory
+}
+
+// externalCategory is a category of external domains with the links and distinct domains it covers
+type externalCategory struct {
+	Category string
+	Links    int
+	Domains  int
+}
+
+// categorizeExternalLinks totals the external link counts per category, most linked first
+func categorizeExternalLinks(externalLinks map[string]int, categories []domainCategory) []externalCategory {
+	links := make(map[string]int)
+	domains := make(map[string]map[string]bool)
+	for link, count := range externalLinks {
+		parsed, err := url.Parse(link)
+		if err != nil || parsed.Hostname() == "" {
+			continue
+		}
+		category := categorizeHost(parsed.Hostname(), categories)
+		links[category] += count
+		if domains[category] == nil {
+			domains[category] = make(map[string]bool)
+		}
+		domains[category][strings.ToLower(parsed.Hostname())] = true
+	}
+
+	result := make([]externalCategory, 0, len(links))
+	for category, count := range links {
+		result = append(result, externalCategory{Category: category, Links: count, Domains: len(domains[category])})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Links != result[j].Links {
+			return result[i].Links > result[j].Links
+		}
+		return result[i].Category < result[j].Category
+	})
+	return result
+}
+
+// printExternalCategoryReport lists the external link counts per domain category
+func printExternalCategoryReport(w io.Writer, categories []externalCategory) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  EXTERNAL LINK CATEGORIES")
+	fmt.Fprintln(w, "=============================")
+	if len(categories) == 0 {
+		fmt.Fprintln(w, "No external links found")
+		return
+	}
+	for _, category := range categories {
+		fmt.Fprintf(w, "%s: %d links to %d hosts\n", category.Category, category.Links, category.Domains)
+	}
+}
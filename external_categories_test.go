@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCategorizeHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"facebook.com", "social"},
+		{"www.facebook.com", "social"},
+		{"cdn.jsdelivr.net", "cdn"},
+		{"www.google-analytics.com", "analytics"},
+		{"notfacebook.com", "other"},
+		{"example.org", "other"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.host, func(t *testing.T) {
+			if actual := categorizeHost(tc.host, defaultDomainCategories); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLoadDomainCategoriesOverridesBuiltIns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "categories.txt")
+	content := "# our own infrastructure\nstatic.example.net cdn\nfacebook.com partner\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write categories: %v", err)
+	}
+	categories, err := loadDomainCategories(file)
+	if err != nil {
+		t.Fatalf("loadDomainCategories() error: %v", err)
+	}
+
+	for host, expected := range map[string]string{
+		"img.static.example.net": "cdn",
+		"facebook.com":           "partner",
+		"twitter.com":            "social",
+	} {
+		if actual := categorizeHost(host, categories); actual != expected {
+			t.Errorf("%s: expected %q, got %q", host, expected, actual)
+		}
+	}
+
+	if err := os.WriteFile(file, []byte("missing-category.com\n"), 0644); err != nil {
+		t.Fatalf("failed to write categories: %v", err)
+	}
+	if _, err := loadDomainCategories(file); err == nil {
+		t.Error("expected an error for a line without a category")
+	}
+}
+
+func TestCategorizeExternalLinks(t *testing.T) {
+	links := map[string]int{
+		"https://www.facebook.com/example":              2,
+		"https://twitter.com/example":                   1,
+		"https://cdn.jsdelivr.net/npm/lib.js":           1,
+		"https://example.org/":                          1,
+		"https://www.googletagmanager.com/gtm.js?id=1":  1,
+		"https://www.googletagmanager.com/gtag/js?id=2": 1,
+	}
+
+	expected := []externalCategory{
+		{Category: "social", Links: 3, Domains: 2},
+		{Category: "analytics", Links: 2, Domains: 1},
+		{Category: "cdn", Links: 1, Domains: 1},
+		{Category: "other", Links: 1, Domains: 1},
+	}
+	if actual := categorizeExternalLinks(links, defaultDomainCategories); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default time allowed for checking one external link
+const defaultExternalCheckTimeout = 10 * time.Second
+
+// externalCheck is the outcome of validating one external link
+type externalCheck struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// alive reports whether the link answered with a non-error status
+func (c externalCheck) alive() bool {
+	return c.Error == "" && c.StatusCode < 400
+}
+
+// checkExternalLink requests rawURL with HEAD, falling back to GET for servers that don't support HEAD
+func checkExternalLink(ctx context.Context, rawURL string, timeout time.Duration) externalCheck {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var check externalCheck
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return externalCheck{Error: fmt.Sprintf("failed to create request: %v", err)}
+		}
+		setIdentityHeaders(req)
+
+		resp, err := clientFor(req).Do(req)
+		if err != nil {
+			return externalCheck{Error: err.Error()}
+		}
+		// Only the status matters, don't download the body
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		check = externalCheck{StatusCode: resp.StatusCode}
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+	return check
+}
+
+// validateExternalLinks is the second phase of --follow-only-internal-then-validate-external: once the
+// internal crawl is done, it checks every distinct external link exactly once, within the crawl's
+// concurrency limits and pacing requests like page fetches
+func (cfg *config) validateExternalLinks(ctx context.Context, timeout time.Duration) {
+	cfg.mu.Lock()
+	links := make([]string, 0, len(cfg.externalLinks))
+	for link := range cfg.externalLinks {
+		if _, checked := cfg.externalChecks[link]; !checked {
+			links = append(links, link)
+		}
+	}
+	cfg.mu.Unlock()
+	sort.Strings(links)
+
+	if len(links) > 0 {
+		fmt.Printf("\nValidating %d external links...\n", len(links))
+	}
+
+	cfg.checkLinks(ctx, links, func(ctx context.Context, link string) externalCheck {
+		// Several links often share an external host, so respect its Crawl-delay and the request delay
+		if u, err := url.Parse(link); err == nil && !cfg.ignoreRobots {
+			if err := cfg.robots.waitCrawlDelay(ctx, cfg.clock, u); err != nil {
+				return externalCheck{Error: err.Error()}
+			}
+		}
+		cfg.clock.Sleep(requestDelay)
+		return checkExternalLink(ctx, link, timeout)
+	}, func(link string, check externalCheck) {
+		cfg.externalChecks[link] = check
+	})
+}
+
+// checkLinks runs check on each link within the validation concurrency (the crawl's, unless
+// --external-concurrency sets its own) and per-host limits, passing the results to record with cfg.mu
+// held. It returns once every started check is done or ctx is cancelled.
+func (cfg *config) checkLinks(ctx context.Context, links []string, check func(ctx context.Context, link string) externalCheck, record func(link string, result externalCheck)) {
+	slots := cfg.concurrencyControl
+	if cfg.validationSlots != nil {
+		slots = cfg.validationSlots
+	}
+
+	var wg sync.WaitGroup
+	for _, link := range links {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(link string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			var result externalCheck
+			release := func() {}
+			if parsed, err := url.Parse(link); err != nil {
+				result = externalCheck{Error: fmt.Sprintf("invalid URL: %v", err)}
+			} else if cfg.hostLimiter != nil {
+				if release, err = cfg.hostLimiter.acquire(ctx, parsed.Hostname()); err != nil {
+					return
+				}
+			}
+			if result.Error == "" {
+				result = check(ctx, link)
+			}
+			release()
+			atomic.AddInt64(cfg.totalRequests, 1)
+
+			cfg.mu.Lock()
+			record(link, result)
+			cfg.mu.Unlock()
+		}(link)
+	}
+	wg.Wait()
+}
+
+// printExternalCheckReport summarizes the external link validation, listing dead links first
+func printExternalCheckReport(w io.Writer, checks map[string]externalCheck) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  EXTERNAL LINK VALIDATION")
+	fmt.Fprintln(w, "=============================")
+
+	var dead []string
+	for link, check := range checks {
+		if !check.alive() {
+			dead = append(dead, link)
+		}
+	}
+	sort.Strings(dead)
+	fmt.Fprintf(w, "Checked %d external links, %d dead\n", len(checks), len(dead))
+	for _, link := range dead {
+		check := checks[link]
+		if check.Error != "" {
+			fmt.Fprintf(w, "  %s: %s\n", link, check.Error)
+		} else {
+			fmt.Fprintf(w, "  %s: HTTP %d\n", link, check.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateExternalLinksChecksEachLinkOnce(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/alive":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	// Use a different hostname for the same loopback server so it counts as another host
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/about", externalURL + "/alive", externalURL + "/dead"},
+		"/about": {"/", externalURL + "/alive", externalURL + "/dead", externalURL + "/no-head"},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.externalChecks = make(map[string]externalCheck)
+	runTestCrawl(cfg)
+
+	mu.Lock()
+	crawlHits := len(hits)
+	mu.Unlock()
+	if crawlHits != 0 {
+		t.Fatalf("expected no external requests during the internal crawl, got %v", hits)
+	}
+
+	cfg.validateExternalLinks(context.Background(), time.Second)
+
+	expectedHits := map[string]int{"/alive": 1, "/dead": 1, "/no-head": 2}
+	for path, expected := range expectedHits {
+		if hits[path] != expected {
+			t.Errorf("expected %d requests to %s, got %d", expected, path, hits[path])
+		}
+	}
+
+	expectedStatus := map[string]int{"/alive": http.StatusOK, "/dead": http.StatusNotFound, "/no-head": http.StatusOK}
+	for path, status := range expectedStatus {
+		check, ok := cfg.externalChecks[externalURL+path]
+		if !ok || check.StatusCode != status {
+			t.Errorf("expected %s%s checked with status %d, got %+v", externalURL, path, status, check)
+		}
+	}
+
+	// Validating again doesn't re-check links that already have a result
+	cfg.validateExternalLinks(context.Background(), time.Second)
+	if hits["/alive"] != 1 {
+		t.Errorf("expected /alive not to be re-checked, got %d requests", hits["/alive"])
+	}
+}
+
+func TestValidateExternalLinksRespectsExternalConcurrency(t *testing.T) {
+	var inFlight, peak int64
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			old := atomic.LoadInt64(&peak)
+			if current <= old || atomic.CompareAndSwapInt64(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer external.Close()
+
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.concurrencyControl = make(chan struct{}, 8)
+	cfg.validationSlots = make(chan struct{}, 2)
+	cfg.externalChecks = make(map[string]externalCheck)
+	for i := 0; i < 10; i++ {
+		cfg.externalLinks[fmt.Sprintf("%s/%d", external.URL, i)] = 1
+	}
+	// Hold every crawl slot: the external phase must not depend on them
+	for i := 0; i < cap(cfg.concurrencyControl); i++ {
+		cfg.concurrencyControl <- struct{}{}
+	}
+	cfg.validateExternalLinks(context.Background(), time.Second)
+
+	if len(cfg.externalChecks) != 10 {
+		t.Fatalf("expected 10 checked links, got %d", len(cfg.externalChecks))
+	}
+	if peak := atomic.LoadInt64(&peak); peak > 2 {
+		t.Errorf("expected at most 2 simultaneous external checks, got %d", peak)
+	}
+}
+
+func TestValidateExternalLinksPacesRequests(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nCrawl-delay: 10\n")
+		}
+	}))
+	defer external.Close()
+
+	cfg := newTestConfig(t, "https://example.com", 10)
+	clock := newFakeClock()
+	cfg.clock = clock
+	cfg.externalChecks = make(map[string]externalCheck)
+	cfg.externalLinks[external.URL+"/a"] = 1
+	cfg.externalLinks[external.URL+"/b"] = 1
+
+	done := make(chan struct{})
+	go func() {
+		cfg.validateExternalLinks(context.Background(), time.Second)
+		close(done)
+	}()
+
+	// One link waits out the request delay, the other the host's Crawl-delay, before either is requested
+	clock.waitForTimers(t, 2)
+	mu.Lock()
+	early := hits["/a"] + hits["/b"]
+	mu.Unlock()
+	if early != 0 {
+		t.Fatalf("expected no link requested before the delays, got %v", hits)
+	}
+	clock.Advance(10 * time.Second)
+	clock.waitForTimers(t, 1)
+	clock.Advance(requestDelay)
+	<-done
+
+	if len(cfg.externalChecks) != 2 || hits["/robots.txt"] != 1 {
+		t.Errorf("expected both links checked after one robots.txt fetch, got checks %v and requests %v", cfg.externalChecks, hits)
+	}
+}
+
+func TestPrintExternalCheckReport(t *testing.T) {
+	checks := map[string]externalCheck{
+		"https://a.example.com/":    {StatusCode: http.StatusOK},
+		"https://b.example.com/404": {StatusCode: http.StatusNotFound},
+		"https://c.example.com/":    {Error: "connection refused"},
+	}
+
+	var out strings.Builder
+	printExternalCheckReport(&out, checks)
+
+	for _, expected := range []string{
+		"Checked 3 external links, 2 dead",
+		"https://b.example.com/404: HTTP 404",
+		"https://c.example.com/: connection refused",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "a.example.com") {
+		t.Errorf("expected live links to be left out of the report, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// externalHost is a registered domain and the number of external links to it
+type externalHost struct {
+	Domain string
+	Links  int
+}
+
+// registeredDomain returns the registrable part of a URL's host (blog.example.co.uk -> example.co.uk).
+// Hosts without one, such as IP addresses and localhost, are returned whole.
+func registeredDomain(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("no host in URL %s", rawURL)
+	}
+	return hostRegisteredDomain(host), nil
+}
+
+// hostRegisteredDomain returns the registrable part of host, or host itself if it has none
+func hostRegisteredDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// externalHosts rolls the per-URL external link counts up by registered domain, most linked first
+func externalHosts(externalLinks map[string]int) []externalHost {
+	counts := make(map[string]int)
+	for link, count := range externalLinks {
+		domain, err := registeredDomain(link)
+		if err != nil {
+			continue
+		}
+		counts[domain] += count
+	}
+
+	hosts := make([]externalHost, 0, len(counts))
+	for domain, links := range counts {
+		hosts = append(hosts, externalHost{Domain: domain, Links: links})
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Links != hosts[j].Links {
+			return hosts[i].Links > hosts[j].Links
+		}
+		return hosts[i].Domain < hosts[j].Domain
+	})
+	return hosts
+}
+
+// printExternalHostsReport lists every external domain linked from the site with its link count
+func printExternalHostsReport(w io.Writer, hosts []externalHost) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  EXTERNAL HOSTS")
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "Distinct external domains: %d\n", len(hosts))
+	for _, host := range hosts {
+		fmt.Fprintf(w, "%d links to %s\n", host.Links, host.Domain)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisteredDomain(t *testing.T) {
+	tests := []struct {
+		rawURL   string
+		expected string
+		wantErr  bool
+	}{
+		{rawURL: "https://blog.example.com/post", expected: "example.com"},
+		{rawURL: "https://www.example.co.uk/", expected: "example.co.uk"},
+		{rawURL: "https://example.github.io/docs", expected: "example.github.io"},
+		{rawURL: "http://127.0.0.1:8080/", expected: "127.0.0.1"},
+		{rawURL: "http://localhost/", expected: "localhost"},
+		{rawURL: "/relative", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rawURL, func(t *testing.T) {
+			actual, err := registeredDomain(tc.rawURL)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", actual)
+				}
+				return
+			}
+			if err != nil || actual != tc.expected {
+				t.Errorf("expected %q, got %q (err %v)", tc.expected, actual, err)
+			}
+		})
+	}
+}
+
+func TestExternalHostsRollUpByDomain(t *testing.T) {
+	links := map[string]int{
+		"https://docs.example.com/start":  2,
+		"https://www.example.com/pricing": 1,
+		"https://other.org/":              1,
+		"https://a.other.org/page":        1,
+		"https://single.net/":             1,
+	}
+
+	expected := []externalHost{
+		{Domain: "example.com", Links: 3},
+		{Domain: "other.org", Links: 2},
+		{Domain: "single.net", Links: 1},
+	}
+	actual := externalHosts(links)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+
+	var out strings.Builder
+	printExternalHostsReport(&out, actual)
+	if !strings.Contains(out.String(), "Distinct external domains: 3\n3 links to example.com") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
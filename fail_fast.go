@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// crawlFailure is the broken internal page that stopped a --fail-fast crawl
+type crawlFailure struct {
+	URL      string
+	Referrer string // first page found linking to URL, empty for the seed page
+	Err      string
+}
+
+// String describes the failure for the console
+func (f *crawlFailure) String() string {
+	referrer := f.Referrer
+	if referrer == "" {
+		referrer = "(seed page)"
+	}
+	return fmt.Sprintf("%s (linked from %s): %s", f.URL, referrer, f.Err)
+}
+
+// failFastOn stops the crawl if fail-fast is enabled and err is an HTTP error status for the internal page
+// at normalizedURL. Only the first failure is kept; pages cancelled by the abort don't replace it.
+func (cfg *config) failFastOn(rawURL, normalizedURL string, err error) {
+	if !cfg.failFast || httpErrorStatus(err) < http.StatusBadRequest {
+		return
+	}
+
+	cfg.mu.Lock()
+	if cfg.failure != nil {
+		cfg.mu.Unlock()
+		return
+	}
+	failure := &crawlFailure{URL: rawURL, Err: err.Error()}
+	if inbound := cfg.inboundLinks[normalizedURL]; inbound != nil && len(inbound.Referrers) > 0 {
+		failure.Referrer = fullPageURL(inbound.Referrers[0], cfg.baseURL, cfg.canonicalURLs)
+	}
+	cfg.failure = failure
+	cfg.mu.Unlock()
+
+	fmt.Printf("Stopping crawl: broken page %s\n", failure)
+	if cfg.cancel != nil {
+		cfg.cancel()
+	}
+}
+
+// parseStatusList reads a comma-separated list of HTTP error statuses such as "404,410"
+func parseStatusList(raw string) (map[int]bool, error) {
+	statuses := make(map[int]bool)
+	for _, field := range strings.Split(raw, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		status, err := strconv.Atoi(field)
+		if err != nil || status < http.StatusBadRequest || status > 599 {
+			return nil, fmt.Errorf("invalid HTTP error status %q", field)
+		}
+		statuses[status] = true
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("no HTTP statuses given")
+	}
+	return statuses, nil
+}
+
+// brokenExternalLinks returns the checked external links failing --fail-on-broken-external, sorted. With no
+// statuses every dead link fails, unreachable ones included; otherwise only links answering with one of them.
+func brokenExternalLinks(checks map[string]externalCheck, statuses map[int]bool) []string {
+	var broken []string
+	for link, check := range checks {
+		if check.alive() {
+			continue
+		}
+		if statuses == nil || statuses[check.StatusCode] {
+			broken = append(broken, link)
+		}
+	}
+	sort.Strings(broken)
+	return broken
+}
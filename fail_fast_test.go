@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFailFastStopsAtBrokenInternalPage(t *testing.T) {
+	// /docs links to a broken page and to a long chain of slow pages the crawl should never finish
+	const chainLength = 50
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprint(w, `<html><body><a href="/docs">docs</a></body></html>`)
+		case r.URL.Path == "/docs":
+			fmt.Fprint(w, `<html><body><a href="/missing">missing</a><a href="/chain/1">chain</a></body></html>`)
+		case strings.HasPrefix(r.URL.Path, "/chain/"):
+			time.Sleep(20 * time.Millisecond)
+			var n int
+			fmt.Sscanf(r.URL.Path, "/chain/%d", &n)
+			fmt.Fprintf(w, `<html><body><a href="/chain/%d">next</a></body></html>`, n+1)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, chainLength+10)
+	cfg.failFast = true
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	cfg.maxReferrers = defaultMaxReferrers
+	summary := cfg.Run(time.Minute)
+
+	if summary.exitCode() == 0 {
+		t.Fatal("expected a non-zero exit code after a broken internal page")
+	}
+	if summary.Failure.URL != server.URL+"/missing" {
+		t.Errorf("expected failure at %s/missing, got %s", server.URL, summary.Failure.URL)
+	}
+	if !strings.HasSuffix(summary.Failure.Referrer, "/docs") {
+		t.Errorf("expected /docs as the referrer, got %s", summary.Failure.Referrer)
+	}
+	if !strings.Contains(summary.Failure.Err, "404") {
+		t.Errorf("expected the failure to mention the 404 status, got %q", summary.Failure.Err)
+	}
+	if len(summary.Pages) >= chainLength {
+		t.Errorf("expected the crawl to stop early, got %d pages", len(summary.Pages))
+	}
+}
+
+func TestFailFastIgnoredWhenDisabled(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/missing", "/about"},
+		"/about": {},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	summary := cfg.Run(time.Minute)
+
+	if summary.exitCode() != 0 || summary.Failure != nil {
+		t.Errorf("expected the crawl to finish normally, got failure %v", summary.Failure)
+	}
+	if len(summary.Pages) != 3 {
+		t.Errorf("expected every page to be visited, got %v", summary.Pages)
+	}
+}
+
+func TestFailOnBrokenExternalLinks(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alive":
+			w.WriteHeader(http.StatusOK)
+		case "/blocked":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer external.Close()
+	// Use a different hostname for the same loopback server so it counts as another host
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+
+	server := newTestServer(t, map[string][]string{
+		"/": {externalURL + "/alive", externalURL + "/blocked", externalURL + "/dead"},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.externalChecks = make(map[string]externalCheck)
+	summary := cfg.Run(time.Minute)
+	cfg.validateExternalLinks(context.Background(), time.Second)
+
+	tests := []struct {
+		name     string
+		statuses map[int]bool
+		expected []string
+		exitCode int
+	}{
+		{"every dead link", nil, []string{externalURL + "/blocked", externalURL + "/dead"}, 1},
+		{"only not found", map[int]bool{http.StatusNotFound: true, http.StatusGone: true}, []string{externalURL + "/dead"}, 1},
+		{"no matching status", map[int]bool{http.StatusGone: true}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary.BrokenExternal = brokenExternalLinks(cfg.externalChecks, tt.statuses)
+			if !reflect.DeepEqual(summary.BrokenExternal, tt.expected) {
+				t.Errorf("expected broken links %v, got %v", tt.expected, summary.BrokenExternal)
+			}
+			if summary.exitCode() != tt.exitCode {
+				t.Errorf("expected exit code %d, got %d", tt.exitCode, summary.exitCode())
+			}
+		})
+	}
+}
+
+func TestParseStatusList(t *testing.T) {
+	statuses, err := parseStatusList("404, 410,")
+	if err != nil || !reflect.DeepEqual(statuses, map[int]bool{404: true, 410: true}) {
+		t.Errorf("expected 404 and 410, got %v (%v)", statuses, err)
+	}
+	for _, raw := range []string{"", "200", "4xx", "600"} {
+		if _, err := parseStatusList(raw); err == nil {
+			t.Errorf("expected an error for %q", raw)
+		}
+	}
+}
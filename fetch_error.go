@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"syscall"
+)
+
+// FetchErrorKind classifies why fetching a page failed. The values are stable so they can be matched by
+// scripts reading the event log or the crawl statistics.
+type FetchErrorKind string
+
+const (
+	FetchDNS         FetchErrorKind = "dns"          // the host name didn't resolve
+	FetchTimeout     FetchErrorKind = "timeout"      // the request or connection timed out
+	FetchTLS         FetchErrorKind = "tls"          // certificate or TLS protocol problem
+	FetchConnection  FetchErrorKind = "connection"   // refused, reset or dropped connection
+	FetchRedirect    FetchErrorKind = "redirect"     // redirect loop, limit or external target not followed
+	FetchHTTPStatus  FetchErrorKind = "http_status"  // response with an HTTP error status code
+	FetchContentType FetchErrorKind = "content_type" // response that isn't HTML
+	FetchTooLarge    FetchErrorKind = "too_large"    // response over the size limit
+	FetchOther       FetchErrorKind = "other"
+)
+
+// FetchError reports a failed page fetch. Kind says what went wrong; the other fields are set when they
+// apply to that kind.
+type FetchError struct {
+	Kind        FetchErrorKind
+	URL         string
+	StatusCode  int    // FetchHTTPStatus
+	Status      string // FetchHTTPStatus
+	ContentType string // FetchContentType
+	Size        int64  // FetchTooLarge: declared Content-Length, or 0 when the body itself hit the limit
+	Limit       int64  // FetchTooLarge
+	Err         error  // underlying transport error for the network kinds
+}
+
+func (e *FetchError) Error() string {
+	switch e.Kind {
+	case FetchHTTPStatus:
+		return fmt.Sprintf("HTTP error %d (%s) for URL %s", e.StatusCode, e.Status, e.URL)
+	case FetchContentType:
+		return fmt.Sprintf("content-type is not HTML (got: %s) for URL %s", e.ContentType, e.URL)
+	case FetchTooLarge:
+		if e.Size > 0 {
+			return fmt.Sprintf("content too large (%d bytes, max %d) for URL %s", e.Size, e.Limit, e.URL)
+		}
+		return fmt.Sprintf("response body too large (>= %d bytes) for URL %s", e.Limit, e.URL)
+	}
+	return fmt.Sprintf("HTTP request failed: %v", e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// newRequestError wraps an error returned by the HTTP client in a FetchError of the matching kind
+func newRequestError(rawURL string, err error) *FetchError {
+	return &FetchError{Kind: requestErrorKind(err), URL: rawURL, Err: err}
+}
+
+// requestErrorKind classifies an error returned by the HTTP client
+func requestErrorKind(err error) FetchErrorKind {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var loopErr *redirectLoopError
+	var redirectErr *externalRedirectError
+	switch {
+	case errors.As(err, &loopErr), errors.As(err, &redirectErr), errors.Is(err, errTooManyRedirects):
+		return FetchRedirect
+	case errors.As(err, &dnsErr):
+		// Checked before timeouts: a DNS lookup that timed out is still a DNS failure
+		return FetchDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return FetchTimeout
+	case isTLSError(err):
+		return FetchTLS
+	case errors.As(err, &opErr), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return FetchConnection
+	}
+	return FetchOther
+}
+
+// fetchErrorKind returns the kind of the FetchError in err's chain, or FetchOther for any other error
+func fetchErrorKind(err error) FetchErrorKind {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Kind
+	}
+	return FetchOther
+}
+
+// httpErrorStatus returns the status code of an HTTP error status in err's chain, or 0 if there is none
+func httpErrorStatus(err error) int {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) && fetchErr.Kind == FetchHTTPStatus {
+		return fetchErr.StatusCode
+	}
+	return 0
+}
+
+// recordFailureKind counts a failed page under the kind of its fetch error
+func (cfg *config) recordFailureKind(err error) {
+	cfg.mu.Lock()
+	cfg.failureKinds[fetchErrorKind(err)]++
+	cfg.mu.Unlock()
+}
+
+// printFailureKinds prints how many pages failed with each kind of fetch error, sorted by kind
+func printFailureKinds(failureKinds map[FetchErrorKind]int) {
+	kinds := make([]FetchErrorKind, 0, len(failureKinds))
+	for kind := range failureKinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	fmt.Println("Failed pages by kind:")
+	for _, kind := range kinds {
+		fmt.Printf("  %s: %d\n", kind, failureKinds[kind])
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// failingSite fails each request the way its path names
+type failingSite struct{}
+
+func (failingSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.URL.Path {
+	case "/dns":
+		return nil, &net.DNSError{Err: "no such host", Name: req.URL.Hostname(), IsNotFound: true}
+	case "/timeout":
+		return nil, context.DeadlineExceeded
+	case "/tls":
+		return nil, &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}
+	case "/refused":
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+	resp := &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: make(http.Header), Request: req,
+		Body: io.NopCloser(strings.NewReader("<html><body>" + strings.Repeat("a", 64) + "</body></html>"))}
+	resp.Header.Set("Content-Type", "text/html")
+	switch req.URL.Path {
+	case "/missing":
+		resp.StatusCode, resp.Status = http.StatusNotFound, "404 Not Found"
+	case "/json":
+		resp.Header.Set("Content-Type", "application/json")
+	case "/declared-large":
+		resp.ContentLength = 1 << 20
+		resp.Header.Set("Content-Length", "1048576")
+	}
+	return resp, nil
+}
+
+func TestPerformHTTPRequestErrorKinds(t *testing.T) {
+	original := httpClient.Transport
+	httpClient.Transport = failingSite{}
+	t.Cleanup(func() { httpClient.Transport = original })
+	ctx := withSizePolicy(context.Background(), sizePolicy{"text/html": 32})
+
+	tests := []struct {
+		path       string
+		kind       FetchErrorKind
+		statusCode int
+		message    string
+		retryable  bool
+	}{
+		{path: "/dns", kind: FetchDNS, message: "HTTP request failed: "},
+		{path: "/timeout", kind: FetchTimeout, message: "HTTP request failed: ", retryable: true},
+		{path: "/tls", kind: FetchTLS, message: "HTTP request failed: "},
+		{path: "/refused", kind: FetchConnection, message: "HTTP request failed: ", retryable: true},
+		{path: "/missing", kind: FetchHTTPStatus, statusCode: 404, message: "HTTP error 404 (404 Not Found) for URL https://example.test/missing"},
+		{path: "/json", kind: FetchContentType, message: "content-type is not HTML (got: application/json) for URL https://example.test/json"},
+		{path: "/declared-large", kind: FetchTooLarge, message: "content too large (1048576 bytes, max 32) for URL https://example.test/declared-large"},
+		{path: "/body-large", kind: FetchTooLarge, message: "response body too large (>= 32 bytes) for URL https://example.test/body-large"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			_, err := performHTTPRequest(ctx, "https://example.test"+tc.path)
+			var fetchErr *FetchError
+			if !errors.As(err, &fetchErr) {
+				t.Fatalf("expected a FetchError, got %v", err)
+			}
+			if fetchErr.Kind != tc.kind || fetchErr.StatusCode != tc.statusCode {
+				t.Errorf("expected kind %s with status %d, got %s with status %d", tc.kind, tc.statusCode, fetchErr.Kind, fetchErr.StatusCode)
+			}
+			if fetchErr.URL != "https://example.test"+tc.path {
+				t.Errorf("expected the URL to be recorded, got %q", fetchErr.URL)
+			}
+			if !strings.HasPrefix(err.Error(), tc.message) {
+				t.Errorf("expected message starting with %q, got %q", tc.message, err.Error())
+			}
+			if actual := isRetryableError(err); actual != tc.retryable {
+				t.Errorf("expected retryable %v, got %v", tc.retryable, actual)
+			}
+		})
+	}
+}
+
+func TestRequestErrorKindRedirects(t *testing.T) {
+	tests := []error{
+		errTooManyRedirects,
+		&redirectLoopError{Chain: []string{"https://example.test/a", "https://example.test/a"}},
+		&externalRedirectError{Source: "https://example.test/", Target: "https://other.test/"},
+	}
+	for _, err := range tests {
+		if kind := requestErrorKind(err); kind != FetchRedirect {
+			t.Errorf("expected %v to be a redirect error, got %s", err, kind)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FilterFunc decides whether a discovered link at depth is crawled. A link that isn't kept is skipped
+// and counted under reason.
+type FilterFunc func(u *url.URL, depth int) (keep bool, reason string)
+
+// keepLink runs the filters in order on a discovered link and reports whether every one keeps it.
+// The first filter to reject the link has its reason counted in skipReasons.
+func (cfg *config) keepLink(rawURL string, depth int) bool {
+	reason := cfg.filterReason(rawURL, depth)
+	if reason == "" {
+		return true
+	}
+	cfg.mu.Lock()
+	cfg.skipReasons[reason]++
+	cfg.mu.Unlock()
+	return false
+}
+
+// filterReason returns the reason of the first filter rejecting a discovered link at depth, or "" if every
+// filter keeps it. Unlike keepLink, it doesn't count the link as skipped.
+func (cfg *config) filterReason(rawURL string, depth int) string {
+	if len(cfg.filters) == 0 {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// crawlPage reports unparsable URLs
+		return ""
+	}
+	for _, filter := range cfg.filters {
+		if keep, reason := filter(u, depth); !keep {
+			return reason
+		}
+	}
+	return ""
+}
+
+// limitFollowed keeps the first followPerPage internal links of a page, plus the always links (its canonical
+// and next page) wherever they are. External links are all kept, as following them only records them.
+// Dropped links are counted under "follow limit".
+func (cfg *config) limitFollowed(urls []string, always ...string) []string {
+	if cfg.followPerPage <= 0 {
+		return urls
+	}
+	kept := urls[:0:0]
+	internal, skipped := 0, 0
+	for _, link := range urls {
+		parsed, err := url.Parse(link)
+		switch {
+		case err != nil || !cfg.isCrawledHost(parsed.Hostname()) || indexOf(always, link) >= 0:
+			kept = append(kept, link)
+		case internal < cfg.followPerPage:
+			kept = append(kept, link)
+			internal++
+		default:
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		cfg.mu.Lock()
+		cfg.skipReasons["follow limit"] += skipped
+		cfg.mu.Unlock()
+	}
+	return kept
+}
+
+// samplingFilter keeps the links the sampler chooses among those found below the seed page.
+// Links on the seed page itself (depth 1) are always kept.
+func samplingFilter(sampler *linkSampler) FilterFunc {
+	return func(u *url.URL, depth int) (bool, string) {
+		if depth <= 1 || sampler.keep(u.String()) {
+			return true, ""
+		}
+		return false, "sampled out"
+	}
+}
+
+// loadDenylist reads the URLs never to crawl from filename, one per line. An entry ending in * is a prefix,
+// any other entry an exact URL. Blank lines and lines starting with # are ignored.
+func loadDenylist(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read denylist: %v", err)
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read denylist: %v", err)
+	}
+	return entries, nil
+}
+
+// denylistFilter skips links whose normalized form is an exact denylist entry or starts with a prefix
+// entry. Entries are normalized with normalize, so they may be written as full URLs or normalized ones.
+func denylistFilter(entries []string, normalize func(rawURL string) (string, error)) FilterFunc {
+	exact := make(map[string]bool)
+	var prefixes []string
+	for _, entry := range entries {
+		prefix := strings.HasSuffix(entry, "*")
+		entry = strings.TrimSuffix(entry, "*")
+		if !strings.Contains(entry, "://") {
+			entry = "http://" + entry
+		}
+		normalized, err := normalize(entry)
+		if err != nil {
+			continue
+		}
+		if !prefix {
+			exact[normalized] = true
+			continue
+		}
+		// Normalizing may drop the slash that stops /api/ from matching /apiary
+		if strings.HasSuffix(entry, "/") && !strings.HasSuffix(normalized, "/") {
+			normalized += "/"
+		}
+		prefixes = append(prefixes, normalized)
+	}
+
+	return func(u *url.URL, depth int) (bool, string) {
+		normalized, err := normalize(u.String())
+		if err != nil {
+			return true, ""
+		}
+		if exact[normalized] {
+			return false, "denylist"
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(normalized, prefix) {
+				return false, "denylist"
+			}
+		}
+		return true, ""
+	}
+}
+
+// printSkipReasons prints how many links each filter skipped, sorted by reason
+func printSkipReasons(skipReasons map[string]int) {
+	reasons := make([]string, 0, len(skipReasons))
+	for reason := range skipReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	fmt.Println("Links skipped by filters:")
+	for _, reason := range reasons {
+		fmt.Printf("  %s: %d\n", reason, skipReasons[reason])
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrawlPageFilterChain(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":            {"/docs", "/admin/users", "/report.pdf", "/admin/report.pdf"},
+		"/docs":        {"/docs/deep"},
+		"/docs/deep":   {},
+		"/admin/users": {},
+		"/report.pdf":  {},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.filters = []FilterFunc{
+		func(u *url.URL, depth int) (bool, string) {
+			return !strings.HasPrefix(u.Path, "/admin/"), "excluded path prefix"
+		},
+		func(u *url.URL, depth int) (bool, string) {
+			return !strings.HasSuffix(u.Path, ".pdf"), "excluded extension"
+		},
+	}
+	runTestCrawl(cfg)
+
+	if len(cfg.pages) != 3 {
+		t.Errorf("expected only /, /docs and /docs/deep to be crawled, got %v", cfg.pages)
+	}
+	// /admin/report.pdf is rejected by the first filter, so the extension filter never counts it
+	expected := map[string]int{"excluded path prefix": 2, "excluded extension": 1}
+	if !reflect.DeepEqual(cfg.skipReasons, expected) {
+		t.Errorf("expected skip reasons %v, got %v", expected, cfg.skipReasons)
+	}
+}
+
+func TestSamplingFilterKeepsSeedLinks(t *testing.T) {
+	filter := samplingFilter(newLinkSampler(0.0001, 1))
+	u, _ := url.Parse("https://example.com/page")
+	if keep, _ := filter(u, 1); !keep {
+		t.Errorf("expected links on the seed page to be kept")
+	}
+	if keep, reason := filter(u, 2); keep || reason != "sampled out" {
+		t.Errorf("expected a deeper link to be sampled out, got keep=%v reason=%q", keep, reason)
+	}
+}
+
+func TestCrawlPageSkipsDenylistedURLs(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":           {"/docs", "/search", "/api/users", "/api/orders", "/apiary"},
+		"/docs":       {"/search"},
+		"/search":     {},
+		"/api/users":  {},
+		"/api/orders": {},
+		"/apiary":     {},
+	})
+
+	file := filepath.Join(t.TempDir(), "denylist.txt")
+	content := "# heavy endpoints\n" + server.URL + "/search\n\nhttps://www." + strings.TrimPrefix(server.URL, "http://") + "/api/*\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write denylist: %v", err)
+	}
+	entries, err := loadDenylist(file)
+	if err != nil {
+		t.Fatalf("loadDenylist() error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %v", entries)
+	}
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.filters = []FilterFunc{denylistFilter(entries, cfg.normalizeURL)}
+	runTestCrawl(cfg)
+
+	for _, path := range []string{"/", "/docs", "/apiary"} {
+		if key, _ := cfg.normalizeURL(server.URL + path); cfg.pages[key] == 0 {
+			t.Errorf("expected %s to be crawled, got %v", path, cfg.pages)
+		}
+	}
+	if len(cfg.pages) != 3 {
+		t.Errorf("expected denylisted URLs to be skipped, got %v", cfg.pages)
+	}
+	if cfg.skipReasons["denylist"] != 4 {
+		t.Errorf("expected 4 links skipped by the denylist, got %v", cfg.skipReasons)
+	}
+}
+
+func TestFollowPerPageRecordsAllLinks(t *testing.T) {
+	site := map[string][]string{"/": {"https://other.com/page"}}
+	for i := 1; i <= 20; i++ {
+		page := fmt.Sprintf("/item-%d", i)
+		site["/"] = append(site["/"], page)
+		site[page] = nil
+	}
+	server := newTestServer(t, site)
+
+	cfg := newTestConfig(t, server.URL, 100)
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	cfg.maxReferrers = 1
+	cfg.followPerPage = 5
+	runTestCrawl(cfg)
+
+	if len(cfg.inboundLinks) != 20 {
+		t.Errorf("expected all 20 internal links recorded, got %d", len(cfg.inboundLinks))
+	}
+	if len(cfg.pages) != 6 {
+		t.Errorf("expected the seed and the first 5 links crawled, got %v", cfg.pages)
+	}
+	for i := 1; i <= 5; i++ {
+		key, _ := cfg.normalizeURL(fmt.Sprintf("%s/item-%d", server.URL, i))
+		if _, ok := cfg.pages[key]; !ok {
+			t.Errorf("expected /item-%d to be followed, got %v", i, cfg.pages)
+		}
+	}
+	if cfg.externalLinks["https://other.com/page"] != 1 {
+		t.Errorf("expected the external link not to count towards the limit, got %v", cfg.externalLinks)
+	}
+	if cfg.skipReasons["follow limit"] != 15 {
+		t.Errorf("expected 15 links over the limit, got %v", cfg.skipReasons)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import "net/url"
+
+// sharedFetch is a single fetch of a URL, shared by the fragment variants of the page
+type sharedFetch struct {
+	done chan struct{} // closed once page and err are set
+	page *pageResponse
+	err  error
+}
+
+// withFragment appends rawURL's #fragment, if any, to its normalized form
+func withFragment(normalized, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Fragment == "" {
+		return normalized
+	}
+	return normalized + "#" + u.Fragment
+}
+
+// withoutFragment returns u's URL without its #fragment, which is never sent to the server
+func withoutFragment(u *url.URL) string {
+	stripped := *u
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+	return stripped.String()
+}
+
+// fetchShared calls fetch at most once per fetchURL, so fragment variants of a page (/docs#install,
+// /docs#config) are recorded separately but fetched with one request. shared reports whether the
+// result came from another variant's fetch. The result is kept for the rest of the crawl, so a variant
+// arriving after the fetch finished gets the same page or error as the ones that waited for it.
+func (cfg *config) fetchShared(fetchURL string, fetch func() (*pageResponse, error)) (page *pageResponse, shared bool, err error) {
+	cfg.mu.Lock()
+	existing, ok := cfg.sharedFetches[fetchURL]
+	if !ok {
+		existing = &sharedFetch{done: make(chan struct{})}
+		cfg.sharedFetches[fetchURL] = existing
+	}
+	cfg.mu.Unlock()
+
+	if ok {
+		<-existing.done
+		return existing.page, true, existing.err
+	}
+
+	existing.page, existing.err = fetch()
+	close(existing.done)
+	return existing.page, false, existing.err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync/atomic"
+)
+
+// Policies for a full frontier when --max-queue is set
+const (
+	queuePolicyBlock = "block" // wait for room, applying backpressure to discovery
+	queuePolicyDrop  = "drop"  // discard links past the limit
+)
+
+// enterFrontier reserves room in the bounded frontier for a newly discovered link.
+// The frontier holds links that have been enqueued but are still waiting for a concurrency slot.
+// It returns false if the link was dropped or the crawl was cancelled while waiting.
+func (cfg *config) enterFrontier() bool {
+	if cfg.frontier == nil {
+		return true
+	}
+
+	if cfg.queuePolicy == queuePolicyDrop {
+		select {
+		case cfg.frontier <- struct{}{}:
+		default:
+			atomic.AddInt64(cfg.droppedLinks, 1)
+			return false
+		}
+	} else {
+		select {
+		case cfg.frontier <- struct{}{}:
+		case <-cfg.ctx.Done():
+			return false
+		}
+	}
+
+	// Track the peak frontier size
+	size := int64(len(cfg.frontier))
+	for {
+		peak := atomic.LoadInt64(cfg.peakQueueSize)
+		if size <= peak || atomic.CompareAndSwapInt64(cfg.peakQueueSize, peak, size) {
+			break
+		}
+	}
+	return true
+}
+
+// leaveFrontier frees the frontier room held by a page once it starts (or abandons) crawling.
+// Every page below the seed was enqueued through enterFrontier.
+func (cfg *config) leaveFrontier(depth int) {
+	if cfg.frontier != nil && depth > 0 {
+		<-cfg.frontier
+	}
+}
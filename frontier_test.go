@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// newLinkHeavyConfig crawls a home page linking to n leaf pages with a bounded frontier
+func newLinkHeavyConfig(t *testing.T, n, maxQueue int, policy string) *config {
+	t.Helper()
+	site := map[string][]string{"/": {}}
+	for i := 0; i < n; i++ {
+		path := fmt.Sprintf("/page/%d", i)
+		site["/"] = append(site["/"], path)
+		site[path] = []string{}
+	}
+	server := newTestServer(t, site)
+
+	cfg := newTestConfig(t, server.URL, n+1)
+	cfg.concurrencyControl = make(chan struct{}, 2)
+	cfg.frontier = make(chan struct{}, maxQueue)
+	cfg.queuePolicy = policy
+	return cfg
+}
+
+func TestFrontierBlockPolicyHoldsBound(t *testing.T) {
+	cfg := newLinkHeavyConfig(t, 20, 3, queuePolicyBlock)
+	runTestCrawl(cfg)
+
+	if peak := atomic.LoadInt64(cfg.peakQueueSize); peak > 3 {
+		t.Errorf("expected at most 3 queued links, peak was %d", peak)
+	}
+	if dropped := atomic.LoadInt64(cfg.droppedLinks); dropped != 0 {
+		t.Errorf("expected no dropped links with the block policy, got %d", dropped)
+	}
+	if len(cfg.pages) != 21 {
+		t.Errorf("expected every page to be crawled with the block policy, got %d", len(cfg.pages))
+	}
+}
+
+func TestFrontierDropPolicyHoldsBound(t *testing.T) {
+	cfg := newLinkHeavyConfig(t, 20, 3, queuePolicyDrop)
+	runTestCrawl(cfg)
+
+	if peak := atomic.LoadInt64(cfg.peakQueueSize); peak > 3 {
+		t.Errorf("expected at most 3 queued links, peak was %d", peak)
+	}
+	dropped := atomic.LoadInt64(cfg.droppedLinks)
+	if dropped == 0 {
+		t.Errorf("expected links to be dropped with the drop policy")
+	}
+	if int64(len(cfg.pages))+dropped != 21 {
+		t.Errorf("expected crawled pages (%d) plus dropped links (%d) to cover all 21 links", len(cfg.pages), dropped)
+	}
+	if len(cfg.frontier) != 0 {
+		t.Errorf("expected the frontier to be empty after the crawl, got %d", len(cfg.frontier))
+	}
+}
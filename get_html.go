@@ -1,36 +1,32 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
-// Network-level errors that are typically retryable
-var retryableErrors = []string{
-	"timeout",
-	"connection refused",
-	"connection reset",
-	"no such host",
-	"network unreachable",
-	"temporary failure",
-	"i/o timeout",
-}
-
 // HTTP status codes that are retryable
-var retryableHTTPCodes = []string{
-	"HTTP error 429", // Too Many Requests
-	"HTTP error 502", // Bad Gateway
-	"HTTP error 503", // Service Unavailable
-	"HTTP error 504", // Gateway Timeout
-	"HTTP error 520", // Cloudflare unknown error
-	"HTTP error 521", // Cloudflare web server down
-	"HTTP error 522", // Cloudflare connection timeout
-	"HTTP error 523", // Cloudflare origin unreachable
-	"HTTP error 524", // Cloudflare timeout
+var retryableHTTPCodes = map[int]bool{
+	429: true, // Too Many Requests
+	502: true, // Bad Gateway
+	503: true, // Service Unavailable
+	504: true, // Gateway Timeout
+	520: true, // Cloudflare unknown error
+	521: true, // Cloudflare web server down
+	522: true, // Cloudflare connection timeout
+	523: true, // Cloudflare origin unreachable
+	524: true, // Cloudflare timeout
 }
 
 const (
@@ -46,23 +42,140 @@ const (
 	httpRetryDelay = 500 * time.Millisecond
 	// Maximum delay for exponential backoff (cap at 30 seconds)
 	maxBackoffDelay = 30 * time.Second
+	// Maximum number of redirects to follow for a single request
+	maxRedirects = 10
 )
 
-// Global HTTP client with optimized settings for concurrent requests
-var httpClient = &http.Client{
-	Timeout: defaultRequestTimeout,
-	Transport: &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     30 * time.Second,
-		DisableKeepAlives:   false,
-		MaxConnsPerHost:     20, // Limit connections per host
-	},
+// Shared HTTP client for requests made outside a crawl, whose context carries no clients of its own
+var httpClient = newHTTPClient()
+
+// newHTTPClient creates a client with optimized settings for concurrent requests and its own transport,
+// so one crawl's transport changes don't affect another's
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: defaultRequestTimeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     30 * time.Second,
+			DisableKeepAlives:   false,
+			MaxConnsPerHost:     20, // Limit connections per host
+			// Keep negotiating HTTP/2 when a custom DialContext (--dns-cache) or TLS config is set
+			ForceAttemptHTTP2: true,
+		},
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// noExternalRedirectsKey is the context key holding the check for hosts that requests may be redirected to
+type noExternalRedirectsKey struct{}
+
+// withoutExternalRedirects returns a context whose requests stop at redirects to hosts isCrawledHost rejects
+func withoutExternalRedirects(ctx context.Context, isCrawledHost func(host string) bool) context.Context {
+	return context.WithValue(ctx, noExternalRedirectsKey{}, isCrawledHost)
+}
+
+// sameDomainRedirectsKey is the context key marking requests that must not follow redirects off their
+// registered domain
+type sameDomainRedirectsKey struct{}
+
+// withSameDomainRedirects returns a context whose requests stop at redirects to another registered domain
+func withSameDomainRedirects(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sameDomainRedirectsKey{}, true)
+}
+
+// attemptCounterKey is the context key carrying a counter of HTTP attempts, retries included
+type attemptCounterKey struct{}
+
+// withAttemptCounter returns a context whose page requests increment counter once per HTTP attempt
+func withAttemptCounter(ctx context.Context, counter *int64) context.Context {
+	return context.WithValue(ctx, attemptCounterKey{}, counter)
+}
+
+// errTooManyRedirects stops a request that keeps redirecting past maxRedirects
+var errTooManyRedirects = fmt.Errorf("stopped after %d redirects", maxRedirects)
+
+// externalRedirectError reports a redirect to another host that was deliberately not followed
+type externalRedirectError struct {
+	Source string // URL that issued the redirect
+	Target string // external URL it redirected to
+}
+
+func (e *externalRedirectError) Error() string {
+	return fmt.Sprintf("redirect from %s to external URL %s not followed", e.Source, e.Target)
+}
+
+// checkRedirect is the http.Client CheckRedirect hook. It keeps the default redirect limit, stops at
+// redirect loops and, for requests made with withoutExternalRedirects, refuses to leave the crawled hosts
+// (or, with withSameDomainRedirects, the registered domain of the original request).
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+
+	// A site may bounce through a URL once, e.g. to set a cookie and come back; going back to it a second
+	// time would only repeat the same redirects
+	visits := 0
+	for _, prior := range via {
+		if prior.URL.String() == req.URL.String() {
+			visits++
+		}
+	}
+	if visits > 1 {
+		chain := make([]string, 0, len(via)+1)
+		for _, visited := range via {
+			chain = append(chain, visited.URL.String())
+		}
+		return &redirectLoopError{Chain: append(chain, req.URL.String())}
+	}
+
+	if isCrawledHost, _ := req.Context().Value(noExternalRedirectsKey{}).(func(string) bool); isCrawledHost != nil {
+		if !isCrawledHost(req.URL.Hostname()) {
+			return &externalRedirectError{
+				Source: via[len(via)-1].URL.String(),
+				Target: req.URL.String(),
+			}
+		}
+	}
+	// An open redirect on the site must not lead the crawl to another site
+	if sameDomain, _ := req.Context().Value(sameDomainRedirectsKey{}).(bool); sameDomain {
+		if !strings.EqualFold(hostRegisteredDomain(req.URL.Hostname()), hostRegisteredDomain(via[0].URL.Hostname())) {
+			return &externalRedirectError{
+				Source: via[len(via)-1].URL.String(),
+				Target: req.URL.String(),
+			}
+		}
+	}
+	return nil
+}
+
+// pageResponse is a successfully fetched HTML page
+type pageResponse struct {
+	Body            string
+	FinalURL        string               // URL the page was served from after following redirects
+	ContentLocation string               // absolute Content-Location URL declared by the server, if any
+	TLS             *tls.ConnectionState // connection state of the final response, nil over plain HTTP
+	ContentLanguage string               // Content-Language response header, if any
+	StatusCode      int
+	HeaderLinks     []headerLink // links from the Link response header, resolved against the final URL
+	Header          http.Header  // headers of the final response
+	RedirectChain   []string     // URLs from the requested one to FinalURL, one entry when not redirected
+	Proto           string       // protocol of the final response, e.g. "HTTP/1.1" or "HTTP/2.0"
 }
 
 // getHTMLWithContext fetches HTML with context support for cancellation and robust error handling
 func getHTMLWithContext(ctx context.Context, rawURL string) (string, error) {
+	page, err := fetchPage(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	return page.Body, nil
+}
+
+// fetchPage fetches an HTML page with retries, returning the body along with response metadata
+func fetchPage(ctx context.Context, rawURL string) (*pageResponse, error) {
 	var lastErr error
+	clock := clockFrom(ctx)
 
 	// Retry logic with exponential backoff
 	for attempt := 0; attempt <= maxHTTPRetries; attempt++ {
@@ -72,52 +185,74 @@ func getHTMLWithContext(ctx context.Context, rawURL string) (string, error) {
 
 			select {
 			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(delay):
+				return nil, ctx.Err()
+			case <-clock.After(delay):
 			}
 		}
 
 		// Add rate limiting delay (only on first attempt to avoid double delay)
 		if attempt == 0 {
-			time.Sleep(requestDelay)
+			clock.Sleep(requestDelay)
 		}
 
-		body, err := performHTTPRequest(ctx, rawURL)
+		page, err := performHTTPRequest(ctx, rawURL)
 		if err != nil {
 			lastErr = err
 			// Check if this is a retryable error
 			if !isRetryableError(err) {
-				return "", fmt.Errorf("non-retryable error: %w", err)
+				return nil, fmt.Errorf("non-retryable error: %w", err)
 			}
 			continue
 		}
 
-		return body, nil
+		return page, nil
 	}
 
-	return "", fmt.Errorf("HTTP request failed after %d retries for URL %s: %w", maxHTTPRetries, rawURL, lastErr)
+	return nil, fmt.Errorf("HTTP request failed after %d retries for URL %s: %w", maxHTTPRetries, rawURL, lastErr)
 }
 
 // performHTTPRequest performs a single HTTP request
-func performHTTPRequest(ctx context.Context, rawURL string) (string, error) {
-	// Create a new HTTP request with context
-	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+func performHTTPRequest(ctx context.Context, rawURL string) (*pageResponse, error) {
+	if counter, ok := ctx.Value(attemptCounterKey{}).(*int64); ok {
+		atomic.AddInt64(counter, 1)
+	}
+
+	// Time the request's phases when tracing is on
+	tracer, _ := ctx.Value(requestTracerKey{}).(*requestTracer)
+	var trace *requestTrace
+	if tracer != nil {
+		trace, ctx = tracer.begin(ctx)
+	}
+
+	// Create a new HTTP request with context, using the seed request's method and body when there is one
+	method, reqBody := "GET", io.Reader(nil)
+	seed := seedRequestFrom(ctx)
+	if seed != nil {
+		method, reqBody = seed.method, bytes.NewReader(seed.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if seed != nil && seed.contentType != "" {
+		req.Header.Set("Content-Type", seed.contentType)
 	}
 
 	// Add comprehensive headers to avoid being blocked
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Crawler/1.0)")
+	setIdentityHeaders(req)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	req.Header.Set("Accept-Language", acceptLanguageFor(ctx))
 	req.Header.Set("Accept-Encoding", "gzip, deflate")
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
 
-	// Make HTTP request using the global client
-	resp, err := httpClient.Do(req)
+	// Make HTTP request using the crawl's client for the host
+	resp, err := clientFor(req).Do(req)
+	if trace != nil {
+		tracer.finish(trace, rawURL)
+	}
 	if err != nil {
-		return "", fmt.Errorf("HTTP request failed: %w", err)
+		return nil, newRequestError(rawURL, err)
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
@@ -127,58 +262,130 @@ func performHTTPRequest(ctx context.Context, rawURL string) (string, error) {
 
 	// Check for HTTP error status codes
 	if resp.StatusCode >= 400 {
-		return "", fmt.Errorf("HTTP error %d (%s) for URL %s", resp.StatusCode, resp.Status, rawURL)
+		return nil, &FetchError{Kind: FetchHTTPStatus, URL: rawURL, StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	// Check content-type header
 	contentType := resp.Header.Get("Content-Type")
 	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "text/html") {
-		return "", fmt.Errorf("content-type is not HTML (got: %s) for URL %s", contentType, rawURL)
+		return nil, &FetchError{Kind: FetchContentType, URL: rawURL, ContentType: contentType}
+	}
+
+	// Pages without a Content-Type are treated as HTML
+	policy := sizePolicyFor(ctx)
+	maxSize := policy.limit(contentType)
+	if contentType == "" {
+		maxSize = policy.limit("text/html")
 	}
 
 	// Check content-length if provided to avoid reading massive files
 	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
-		if resp.ContentLength > maxResponseSize {
-			return "", fmt.Errorf("content too large (%d bytes, max %d) for URL %s", resp.ContentLength, maxResponseSize, rawURL)
+		if resp.ContentLength > maxSize {
+			return nil, &FetchError{Kind: FetchTooLarge, URL: rawURL, Size: resp.ContentLength, Limit: maxSize}
 		}
 	}
 
 	// Create a limited reader to prevent reading massive responses
-	limitedReader := io.LimitReader(resp.Body, maxResponseSize)
+	limitedReader := io.LimitReader(resp.Body, maxSize)
 
 	// Read the response body with size limit
 	body, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Check if we hit the size limit
-	if len(body) >= maxResponseSize {
-		return "", fmt.Errorf("response body too large (>= %d bytes) for URL %s", maxResponseSize, rawURL)
+	if int64(len(body)) >= maxSize {
+		return nil, &FetchError{Kind: FetchTooLarge, URL: rawURL, Limit: maxSize}
 	}
 
-	return string(body), nil
+	page := &pageResponse{
+		Body:            string(body),
+		FinalURL:        resp.Request.URL.String(),
+		TLS:             resp.TLS,
+		ContentLanguage: resp.Header.Get("Content-Language"),
+		StatusCode:      resp.StatusCode,
+		Header:          resp.Header,
+		RedirectChain:   redirectChain(resp),
+		HeaderLinks:     parseLinkHeader(resp.Header.Values("Link"), resp.Request.URL),
+		Proto:           resp.Proto,
+	}
+	// Content-Location is relative to the URL that was actually requested
+	if contentLocation := resp.Header.Get("Content-Location"); contentLocation != "" {
+		if location, err := resp.Request.URL.Parse(contentLocation); err == nil {
+			page.ContentLocation = location.String()
+		}
+	}
+	return page, nil
 }
 
-// isRetryableError determines if an error is worth retrying
+// isRetryableError determines if an error is worth retrying.
+// Decisions are made on typed errors rather than message text so they hold across Go versions and locales.
 func isRetryableError(err error) bool {
 	if err == nil {
 		return false
 	}
 
-	errStr := err.Error()
+	// Our own cancellation is final, whatever kind of failure it surfaced as
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
 
-	for _, retryable := range retryableErrors {
-		if strings.Contains(strings.ToLower(errStr), retryable) {
+	// Failed fetches are decided by kind; network kinds fall through to inspect the transport error
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		switch fetchErr.Kind {
+		case FetchHTTPStatus:
+			// Server errors that usually clear up on their own
+			return retryableHTTPCodes[fetchErr.StatusCode]
+		case FetchContentType, FetchTooLarge, FetchTLS, FetchRedirect:
+			return false
+		case FetchConnection:
 			return true
 		}
 	}
 
-	for _, retryableCode := range retryableHTTPCodes {
-		if strings.Contains(errStr, retryableCode) {
-			return true
-		}
+	// A timed-out attempt may succeed next time
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	// A host that doesn't exist won't appear on retry, but a DNS timeout or SERVFAIL might resolve
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound && (dnsErr.IsTimeout || dnsErr.IsTemporary)
+	}
+
+	// Certificate and TLS protocol problems are configuration issues, not transient failures
+	if isTLSError(err) {
+		return false
+	}
+
+	// Timeouts anywhere in the stack (including http.Client.Timeout)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// Connection-level failures such as refused or reset connections
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
 	}
 
 	return false
 }
+
+// isTLSError reports whether err comes from a certificate or TLS protocol problem
+func isTLSError(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	return errors.As(err, &certErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCertErr) || errors.As(err, &recordHeaderErr)
+}
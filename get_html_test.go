@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsRetryableError(t *testing.T) {
+	// wrap mimics how the HTTP client and fetchPage wrap the underlying error
+	wrap := func(err error) error {
+		return fmt.Errorf("HTTP request failed: %w", &url.Error{Op: "Get", URL: "https://example.com", Err: err})
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: false,
+		},
+		{
+			name:     "DNS host not found",
+			err:      wrap(&net.DNSError{Err: "no such host", Name: "missing.example", IsNotFound: true}),
+			expected: false,
+		},
+		{
+			name:     "DNS timeout",
+			err:      wrap(&net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}),
+			expected: true,
+		},
+		{
+			name:     "DNS temporary failure",
+			err:      wrap(&net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}),
+			expected: true,
+		},
+		{
+			name:     "connection refused",
+			err:      wrap(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}),
+			expected: true,
+		},
+		{
+			name:     "connection reset",
+			err:      wrap(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}),
+			expected: true,
+		},
+		{
+			name:     "context deadline exceeded",
+			err:      wrap(context.DeadlineExceeded),
+			expected: true,
+		},
+		{
+			name:     "context canceled",
+			err:      wrap(context.Canceled),
+			expected: false,
+		},
+		{
+			name:     "connection failure caused by our own cancellation",
+			err:      newRequestError("https://example.com", wrap(&net.OpError{Op: "dial", Net: "tcp", Err: context.Canceled})),
+			expected: false,
+		},
+		{
+			name:     "unexpected EOF",
+			err:      wrap(io.ErrUnexpectedEOF),
+			expected: true,
+		},
+		{
+			name:     "TLS certificate verification failure",
+			err:      wrap(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}),
+			expected: false,
+		},
+		{
+			name:     "TLS hostname mismatch",
+			err:      wrap(x509.HostnameError{Host: "example.com", Certificate: &x509.Certificate{}}),
+			expected: false,
+		},
+		{
+			name:     "TLS record header error",
+			err:      wrap(tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}),
+			expected: false,
+		},
+		{
+			name:     "HTTP 503",
+			err:      fmt.Errorf("non-retryable error: %w", &FetchError{Kind: FetchHTTPStatus, StatusCode: 503, Status: "503 Service Unavailable", URL: "https://example.com"}),
+			expected: true,
+		},
+		{
+			name:     "HTTP 429",
+			err:      &FetchError{Kind: FetchHTTPStatus, StatusCode: 429, Status: "429 Too Many Requests", URL: "https://example.com"},
+			expected: true,
+		},
+		{
+			name:     "HTTP 404",
+			err:      &FetchError{Kind: FetchHTTPStatus, StatusCode: 404, Status: "404 Not Found", URL: "https://example.com"},
+			expected: false,
+		},
+		{
+			name:     "message mentioning timeout is not enough",
+			err:      errors.New("i/o timeout"),
+			expected: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := isRetryableError(tc.err); actual != tc.expected {
+				t.Errorf("isRetryableError(%v) = %v, expected %v", tc.err, actual, tc.expected)
+			}
+		})
+	}
+}
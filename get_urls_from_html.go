@@ -1,102 +1,171 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 )
 
 const (
-	// Maximum depth to traverse in the HTML tree
-	maxTraversalDepth = 50
+	// Number of leading bytes inspected to tell text from binary content
+	textSniffLength = 1024
+	// Largest fraction of non-text bytes tolerated in the sniffed prefix
+	maxBinaryFraction = 0.1
 )
 
+// errBinaryContent is returned for a body that isn't text, such as a response that is still gzip-compressed
+var errBinaryContent = errors.New("content looks binary (possibly still compressed), not parsing it as HTML")
+
+// defaultAllowedSchemes are the URL schemes followed when no other set is configured
+var defaultAllowedSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+}
+
 // getURLsFromHTML extracts all URLs from anchor tags in the HTML and converts relative URLs to absolute using rawBaseURL.
+// Only http and https links are returned.
 func getURLsFromHTML(htmlBody, rawBaseURL string) ([]string, error) {
+	urls, _, err := extractURLsFromHTML(htmlBody, rawBaseURL, defaultAllowedSchemes)
+	return urls, err
+}
+
+// extractURLsFromHTML is getURLsFromHTML with a configurable set of allowed schemes.
+// Links with any other scheme (mailto:, ftp:, ws:, ...) are skipped and counted per scheme in skippedSchemes.
+func extractURLsFromHTML(htmlBody, rawBaseURL string, allowedSchemes map[string]bool) (urls []string, skippedSchemes map[string]int, err error) {
+	skippedSchemes = make(map[string]int)
+
 	// Early validation
 	if len(htmlBody) == 0 {
-		return []string{}, nil
+		return []string{}, skippedSchemes, nil
 	}
 
+	// The limit applies to the decoded HTML handed to the parser, not the bytes on the wire
 	if len(htmlBody) > 10*1024*1024 { // 10MB limit
-		return nil, fmt.Errorf("HTML body too large (%d bytes, max 10MB)", len(htmlBody))
+		return nil, nil, fmt.Errorf("HTML body too large (%d bytes, max 10MB)", len(htmlBody))
 	}
 
-	var urls []string
-	base, err := url.Parse(rawBaseURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse base URL: %w", err)
+	if !looksLikeText(htmlBody) {
+		return nil, nil, errBinaryContent
 	}
 
-	doc, err := html.Parse(strings.NewReader(htmlBody))
+	base, err := url.Parse(rawBaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse base URL: %w", err)
 	}
 
 	urlSet := make(map[string]bool) // Use map to deduplicate URLs
 
-	var traverse func(*html.Node, int)
-	traverse = func(n *html.Node, depth int) {
-		// Prevent infinite recursion and excessive depth
-		if depth > maxTraversalDepth {
+	// addLink resolves an anchor's href against the base URL and records it once
+	addLink := func(href string) {
+		href = strings.TrimSpace(href)
+		if href == "#" {
+			// Skip bare fragments
 			return
 		}
-
-		// Stop if we've found enough URLs
-		if len(urlSet) >= maxURLsPerPage {
+		// An empty href resolves to the current page URL
+		parsed, parseErr := url.Parse(href)
+		if parseErr != nil {
 			return
 		}
+		resolved := base.ResolveReference(parsed)
+		scheme := strings.ToLower(resolved.Scheme)
+		normalizedURL := resolved.String()
+		if !allowedSchemes[scheme] {
+			// Skip non-page links such as mailto:, tel:, javascript:, ftp: and ws:
+			skippedSchemes[scheme]++
+		} else if !urlSet[normalizedURL] {
+			urlSet[normalizedURL] = true
+			urls = append(urls, normalizedURL)
+		}
+	}
+
+	if err := scanAnchors(htmlBody, func(href string) bool {
+		addLink(href)
+		// Stop once we've found enough URLs
+		return len(urlSet) < maxURLsPerPage
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
 
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, attr := range n.Attr {
-				if attr.Key == "href" {
-					href := strings.TrimSpace(attr.Val)
-					if href == "" {
-						// Empty href: resolve to current page URL
-						resolved := base.ResolveReference(&url.URL{})
-						if resolved != nil {
-							normalizedURL := resolved.String()
-							if !urlSet[normalizedURL] {
-								urlSet[normalizedURL] = true
-								urls = append(urls, normalizedURL)
-							}
-						}
-					} else if href == "#" ||
-						strings.HasPrefix(href, "mailto:") ||
-						strings.HasPrefix(href, "tel:") ||
-						strings.HasPrefix(href, "javascript:") ||
-						strings.HasPrefix(href, "data:") {
-						// Skip fragments and non-page links
-						// Do nothing
-					} else {
-						// Parse and resolve the URL
-						parsed, parseErr := url.Parse(href)
-						if parseErr == nil {
-							resolved := base.ResolveReference(parsed)
-							if resolved != nil {
-								normalizedURL := resolved.String()
-								if !urlSet[normalizedURL] {
-									urlSet[normalizedURL] = true
-									urls = append(urls, normalizedURL)
-								}
-							}
-						}
+	return urls, skippedSchemes, nil
+}
+
+// scanAnchors streams through htmlBody with a tokenizer, calling visit with the first href of each <a> tag
+// until visit returns false. Unlike html.Parse it never builds a tree, so memory use doesn't grow with the page.
+// The tokenizer returns <noscript> content as raw text, which is scanned in turn for its fallback links.
+func scanAnchors(htmlBody string, visit func(href string) bool) error {
+	z := html.NewTokenizer(strings.NewReader(htmlBody))
+	inNoscript := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			return nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			inNoscript = string(name) == "noscript"
+			if string(name) != "a" {
+				continue
+			}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = z.TagAttr()
+				if string(key) == "href" {
+					if !visit(string(value)) {
+						return nil
 					}
 					break // Only process first href attribute
 				}
 			}
-		}
-
-		// Recursively traverse child nodes
-		for c := n.FirstChild; c != nil && len(urlSet) < maxURLsPerPage; c = c.NextSibling {
-			traverse(c, depth+1)
+		case html.TextToken:
+			if inNoscript {
+				inNoscript = false
+				stopped := false
+				if err := scanAnchors(string(z.Text()), func(href string) bool {
+					stopped = !visit(href)
+					return !stopped
+				}); err != nil {
+					return err
+				}
+				if stopped {
+					return nil
+				}
+			}
+		default:
+			inNoscript = false
 		}
 	}
+}
 
-	// Start traversal from the root
-	traverse(doc, 0)
+// looksLikeText reports whether body appears to be text rather than binary data. It rejects the gzip magic
+// number outright and otherwise requires the first textSniffLength bytes to be mostly printable UTF-8.
+func looksLikeText(body string) bool {
+	if strings.HasPrefix(body, "\x1f\x8b") {
+		return false
+	}
 
-	return urls, nil
+	sample := body
+	if len(sample) > textSniffLength {
+		sample = sample[:textSniffLength]
+	}
+	binary, total := 0, 0
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRuneInString(sample[i:])
+		// A rune cut off by the end of the sample isn't evidence of binary data
+		if r == utf8.RuneError && size == 1 && !(len(sample) < len(body) && len(sample)-i < utf8.UTFMax) {
+			binary++
+		} else if r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' {
+			binary++
+		}
+		total++
+		i += size
+	}
+	return total == 0 || float64(binary)/float64(total) <= maxBinaryFraction
 }
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 func TestGetURLsFromHTML(t *testing.T) {
@@ -90,6 +99,40 @@ func TestGetURLsFromHTML(t *testing.T) {
 `,
 			expected: []string{},
 		},
+		{
+			name:     "non-http schemes skipped",
+			inputURL: "https://schemes.com",
+			inputBody: `
+<html>
+	<body>
+		<a href="ftp://files.schemes.com/file.zip">ftp</a>
+		<a href="ws://schemes.com/socket">ws</a>
+		<a href="mailto:someone@schemes.com">mail</a>
+		<a href="/page">page</a>
+	</body>
+</html>
+`,
+			expected: []string{"https://schemes.com/page"},
+		},
+		{
+			name:     "links inside noscript fallbacks",
+			inputURL: "https://spa.com",
+			inputBody: `
+<html>
+	<head>
+		<noscript><a href="/head-fallback">head</a></noscript>
+	</head>
+	<body>
+		<div id="app"></div>
+		<noscript>
+			<nav><a href="/products">Products</a> <a href="/about">About</a></nav>
+		</noscript>
+		<a href="/about">About</a>
+	</body>
+</html>
+`,
+			expected: []string{"https://spa.com/head-fallback", "https://spa.com/products", "https://spa.com/about"},
+		},
 	}
 
 	for i, tc := range tests {
@@ -109,3 +152,173 @@ func TestGetURLsFromHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractURLsFromHTMLSkippedSchemes(t *testing.T) {
+	inputBody := `
+<html>
+	<body>
+		<a href="ftp://files.example.com/file.zip">ftp</a>
+		<a href="ws://example.com/socket">ws</a>
+		<a href="WSS://example.com/socket">wss</a>
+		<a href="ftp://files.example.com/other.zip">ftp</a>
+		<a href="/page">page</a>
+	</body>
+</html>
+`
+	urls, skipped, err := extractURLsFromHTML(inputBody, "https://example.com", defaultAllowedSchemes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://example.com/page"}) {
+		t.Errorf("expected only the http page, got %v", urls)
+	}
+	expectedSkipped := map[string]int{"ftp": 2, "ws": 1, "wss": 1}
+	if !reflect.DeepEqual(skipped, expectedSkipped) {
+		t.Errorf("expected skipped schemes %v, got %v", expectedSkipped, skipped)
+	}
+
+	// A custom allowed set lets ftp links through
+	urls, _, err = extractURLsFromHTML(inputBody, "https://example.com", map[string]bool{"ftp": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(urls) != 2 {
+		t.Errorf("expected the 2 ftp links with ftp allowed, got %v", urls)
+	}
+}
+
+func TestExtractURLsFromHTMLSkipsBinaryContent(t *testing.T) {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write([]byte(`<html><body><a href="/page">page</a></body></html>`))
+	writer.Close()
+
+	random := make([]byte, 2048)
+	for i := range random {
+		random[i] = byte(i * 131 % 256)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantBinary bool
+	}{
+		{"gzip-compressed HTML", gzipped.String(), true},
+		{"binary data", string(random), true},
+		{"HTML", `<html><body><a href="/page">page</a></body></html>`, false},
+		{"non-ASCII HTML", `<html><body><p>Grüße, 世界</p><a href="/page">page</a></body></html>`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := extractURLsFromHTML(tc.body, "https://example.com", defaultAllowedSchemes)
+			if isBinary := errors.Is(err, errBinaryContent); isBinary != tc.wantBinary {
+				t.Errorf("expected binary=%v, got error %v", tc.wantBinary, err)
+			}
+		})
+	}
+}
+
+// treeURLsFromHTML is the former html.Parse based link extraction, kept as a reference for the tokenizer
+func treeURLsFromHTML(htmlBody, rawBaseURL string) ([]string, error) {
+	base, err := url.Parse(rawBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(strings.NewReader(htmlBody))
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	urlSet := make(map[string]bool)
+	var traverse func(*html.Node)
+	traverse = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" {
+					href := strings.TrimSpace(attr.Val)
+					if parsed, err := url.Parse(href); err == nil && href != "#" {
+						resolved := base.ResolveReference(parsed)
+						if defaultAllowedSchemes[strings.ToLower(resolved.Scheme)] && !urlSet[resolved.String()] {
+							urlSet[resolved.String()] = true
+							urls = append(urls, resolved.String())
+						}
+					}
+					break
+				}
+			}
+		}
+		if n.Type == html.ElementNode && n.Data == "noscript" {
+			var content strings.Builder
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				content.WriteString(c.Data)
+			}
+			body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+			nodes, _ := html.ParseFragment(strings.NewReader(content.String()), body)
+			for _, node := range nodes {
+				traverse(node)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			traverse(c)
+		}
+	}
+	traverse(doc)
+	return urls, nil
+}
+
+// linkHeavyPage builds a page of n paragraphs, each with a few links, some of them repeated
+func linkHeavyPage(n int) string {
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html><html><head><title>Links</title><script>var a = '<a href=\"/script\">';</script></head><body>")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&page, `<div class="post"><p>Post %d with <a href="/posts/%d">a link</a>, <a href='/tags/%d?page=2#top'>a tag</a> and <a href="https://other.com/%d">an external link</a>.</p></div>`, i, i, i%10, i%25)
+	}
+	page.WriteString(`<noscript><a href="/no-js">plain version</a></noscript></body></html>`)
+	return page.String()
+}
+
+func TestExtractURLsFromHTMLMatchesTreeParser(t *testing.T) {
+	bodies := map[string]string{
+		"link-heavy page":    linkHeavyPage(200),
+		"unclosed tags":      `<p><a href="/a">a<p><a href=/b>b<ul><li><a href="/c">c</ul>`,
+		"uppercase and mail": `<A HREF="/upper">x</A><a href="mailto:me@example.com">mail</a><a href="">self</a><a href="#">top</a>`,
+		"duplicate href":     `<a href="/first" href="/second">dup</a>`,
+		"entities":           `<a href="/search?q=a&amp;b=c">search</a><a href="/caf&eacute;">café</a>`,
+		"anchors in text":    `<title><a href="/title"></title><textarea><a href="/textarea"></textarea><!-- <a href="/comment"> --><a href="/real">real</a>`,
+		"noscript":           `<head><noscript><a href="/head-fallback">x</a></noscript></head><body><noscript><p><a href="/fallback">x</a></p></noscript></body>`,
+	}
+
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			got, err := getURLsFromHTML(body, "https://example.com/dir/")
+			if err != nil {
+				t.Fatalf("getURLsFromHTML() error: %v", err)
+			}
+			want, err := treeURLsFromHTML(body, "https://example.com/dir/")
+			if err != nil {
+				t.Fatalf("treeURLsFromHTML() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("tokenizer found %v, tree parser found %v", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkGetURLsFromHTMLTokenizer(b *testing.B) {
+	page := linkHeavyPage(2000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		getURLsFromHTML(page, "https://example.com")
+	}
+}
+
+func BenchmarkGetURLsFromHTMLTree(b *testing.B) {
+	page := linkHeavyPage(2000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		treeURLsFromHTML(page, "https://example.com")
+	}
+}
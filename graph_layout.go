@@ -0,0 +1,226 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const (
+	// Default number of force-directed layout iterations
+	defaultLayoutIterations = 100
+	// Margin kept free around the edge of the image
+	layoutMargin = 40.0
+)
+
+// forceLayoutOptions configures the force-directed graph layout
+type forceLayoutOptions struct {
+	iterations int
+	workers    int  // goroutines used for the repulsion step (<= 0 uses GOMAXPROCS)
+	useGrid    bool // approximate repulsion from distant nodes by grid cell (Barnes-Hut-lite)
+}
+
+// layoutVec is a 2D position or displacement
+type layoutVec struct {
+	x, y float64
+}
+
+// forceLayout positions nodes with a Fruchterman-Reingold force-directed layout: every pair of nodes
+// repels and every edge attracts its endpoints. Nodes start from the classic circle/column layout so
+// the result is deterministic.
+func (gv *GraphVisualizer) forceLayout(opts forceLayoutOptions) {
+	gv.layout()
+
+	nodes := gv.sortedNodes(false)
+	nodes = append(nodes, gv.sortedNodes(true)...)
+	n := len(nodes)
+	if n < 2 {
+		return
+	}
+
+	index := make(map[string]int, n)
+	pos := make([]layoutVec, n)
+	for i, node := range nodes {
+		index[node.URL] = i
+		pos[i] = layoutVec{node.X, node.Y}
+	}
+
+	iterations := opts.iterations
+	if iterations <= 0 {
+		iterations = defaultLayoutIterations
+	}
+	workers := opts.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	width := float64(gv.width) - 2*layoutMargin
+	height := float64(gv.height) - 2*layoutMargin
+	k := math.Sqrt(width * height / float64(n)) // ideal edge length
+	temperature := width / 10
+
+	disp := make([]layoutVec, n)
+	for iter := 0; iter < iterations; iter++ {
+		if opts.useGrid {
+			gridRepulsion(pos, disp, k, workers)
+		} else {
+			naiveRepulsion(pos, disp, k, workers)
+		}
+
+		// Attraction along edges
+		for _, edge := range gv.edges {
+			from, okFrom := index[edge.From]
+			to, okTo := index[edge.To]
+			if !okFrom || !okTo || from == to {
+				continue
+			}
+			dx := pos[from].x - pos[to].x
+			dy := pos[from].y - pos[to].y
+			dist := math.Max(math.Hypot(dx, dy), 0.01)
+			force := dist * dist / k
+			disp[from].x -= dx / dist * force
+			disp[from].y -= dy / dist * force
+			disp[to].x += dx / dist * force
+			disp[to].y += dy / dist * force
+		}
+
+		// Move each node at most temperature pixels and keep it inside the image
+		for i := range pos {
+			length := math.Max(math.Hypot(disp[i].x, disp[i].y), 0.01)
+			step := math.Min(length, temperature)
+			pos[i].x = math.Min(math.Max(pos[i].x+disp[i].x/length*step, layoutMargin), layoutMargin+width)
+			pos[i].y = math.Min(math.Max(pos[i].y+disp[i].y/length*step, layoutMargin), layoutMargin+height)
+		}
+
+		// Cool down
+		temperature *= 1 - 1/float64(iterations)
+	}
+
+	for i, node := range nodes {
+		node.X = pos[i].x
+		node.Y = pos[i].y
+	}
+}
+
+// parallelRange splits [0, n) into one contiguous chunk per worker and runs fn on each concurrently
+func parallelRange(n, workers int, fn func(start, end int)) {
+	if workers > n {
+		workers = n
+	}
+	chunk := (n + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunk {
+		end := start + chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+// repel adds the repulsion of a mass of weight nodes at other onto disp
+func repel(disp *layoutVec, p, other layoutVec, k float64, weight float64) {
+	dx := p.x - other.x
+	dy := p.y - other.y
+	dist := math.Hypot(dx, dy)
+	if dist < 0.01 {
+		// Coincident nodes: push apart in an arbitrary but fixed direction
+		dx, dy, dist = 0.01, 0, 0.01
+	}
+	force := weight * k * k / dist
+	disp.x += dx / dist * force
+	disp.y += dy / dist * force
+}
+
+// naiveRepulsion computes the exact O(n²) repulsion between every pair of nodes.
+// Each worker owns a range of nodes and only writes their displacements.
+func naiveRepulsion(pos, disp []layoutVec, k float64, workers int) {
+	parallelRange(len(pos), workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			disp[i] = layoutVec{}
+			for j := range pos {
+				if i != j {
+					repel(&disp[i], pos[i], pos[j], k, 1)
+				}
+			}
+		}
+	})
+}
+
+// layoutCell is a grid cell holding nodes for the approximate repulsion
+type layoutCell struct {
+	members  []int
+	centroid layoutVec
+}
+
+// gridRepulsion approximates repulsion by bucketing nodes into cells of size 2k: nodes in
+// neighboring cells repel exactly, while farther cells act as a single mass at their centroid.
+func gridRepulsion(pos, disp []layoutVec, k float64, workers int) {
+	cellSize := 2 * k
+	type cellKey struct{ x, y int }
+	keyOf := func(p layoutVec) cellKey {
+		return cellKey{int(math.Floor(p.x / cellSize)), int(math.Floor(p.y / cellSize))}
+	}
+
+	cells := make(map[cellKey]*layoutCell)
+	for i, p := range pos {
+		key := keyOf(p)
+		cell := cells[key]
+		if cell == nil {
+			cell = &layoutCell{}
+			cells[key] = cell
+		}
+		cell.members = append(cell.members, i)
+		cell.centroid.x += p.x
+		cell.centroid.y += p.y
+	}
+
+	// Fixed cell order keeps the floating point sums deterministic
+	keys := make([]cellKey, 0, len(cells))
+	for key, cell := range cells {
+		cell.centroid.x /= float64(len(cell.members))
+		cell.centroid.y /= float64(len(cell.members))
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].x != keys[j].x {
+			return keys[i].x < keys[j].x
+		}
+		return keys[i].y < keys[j].y
+	})
+
+	parallelRange(len(pos), workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			disp[i] = layoutVec{}
+			own := keyOf(pos[i])
+			for _, key := range keys {
+				cell := cells[key]
+				near := abs(key.x-own.x) <= 1 && abs(key.y-own.y) <= 1
+				if !near {
+					repel(&disp[i], pos[i], cell.centroid, k, float64(len(cell.members)))
+					continue
+				}
+				for _, j := range cell.members {
+					if i != j {
+						repel(&disp[i], pos[i], pos[j], k, 1)
+					}
+				}
+			}
+		}
+	})
+}
+
+// abs returns the absolute value of an int
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
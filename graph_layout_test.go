@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// newSyntheticGraph builds a graph of n internal pages where each page links to the next two
+func newSyntheticGraph(n int) *GraphVisualizer {
+	gv := NewGraphVisualizer(1200, 800)
+	for i := 0; i < n; i++ {
+		gv.AddNode(fmt.Sprintf("https://example.com/page/%d", i), i%7+1, false)
+	}
+	for i := 0; i < n; i++ {
+		from := fmt.Sprintf("https://example.com/page/%d", i)
+		gv.AddEdge(from, fmt.Sprintf("https://example.com/page/%d", (i+1)%n), 1)
+		gv.AddEdge(from, fmt.Sprintf("https://example.com/page/%d", (i+2)%n), 1)
+	}
+	return gv
+}
+
+func TestForceLayoutKeepsNodesInBounds(t *testing.T) {
+	for _, useGrid := range []bool{false, true} {
+		gv := newSyntheticGraph(50)
+		gv.forceLayout(forceLayoutOptions{iterations: 20, workers: 4, useGrid: useGrid})
+
+		for _, node := range gv.nodes {
+			if math.IsNaN(node.X) || math.IsNaN(node.Y) {
+				t.Fatalf("grid=%v: node %s has NaN position", useGrid, node.URL)
+			}
+			if node.X < layoutMargin || node.X > float64(gv.width)-layoutMargin ||
+				node.Y < layoutMargin || node.Y > float64(gv.height)-layoutMargin {
+				t.Errorf("grid=%v: node %s at (%.1f, %.1f) is outside the image", useGrid, node.URL, node.X, node.Y)
+			}
+		}
+	}
+}
+
+func TestForceLayoutIsDeterministic(t *testing.T) {
+	first := newSyntheticGraph(30)
+	first.forceLayout(forceLayoutOptions{iterations: 10, workers: 1})
+	second := newSyntheticGraph(30)
+	second.forceLayout(forceLayoutOptions{iterations: 10, workers: 8})
+
+	for url, node := range first.nodes {
+		other := second.nodes[url]
+		if node.X != other.X || node.Y != other.Y {
+			t.Errorf("node %s: positions differ between worker counts: (%f, %f) vs (%f, %f)", url, node.X, node.Y, other.X, other.Y)
+		}
+	}
+}
+
+func benchmarkForceLayout(b *testing.B, useGrid bool) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		gv := newSyntheticGraph(1000)
+		b.StartTimer()
+		gv.forceLayout(forceLayoutOptions{iterations: 10, useGrid: useGrid})
+	}
+}
+
+func BenchmarkForceLayoutNaive(b *testing.B) {
+	benchmarkForceLayout(b, false)
+}
+
+func BenchmarkForceLayoutGrid(b *testing.B) {
+	benchmarkForceLayout(b, true)
+}
@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/fogleman/gg"
 	"github.com/golang/freetype/truetype"
@@ -22,6 +23,7 @@ type Node struct {
 	Radius     float64
 	Color      [3]float64 // RGB values
 	IsExternal bool
+	Count      int // Number of links found to this page
 }
 
 // Edge represents a link between pages
@@ -37,6 +39,11 @@ type GraphVisualizer struct {
 	edges  []Edge
 	width  int
 	height int
+	// Force-directed layout settings; nil uses the circle/column layout
+	layoutOptions *forceLayoutOptions
+	// Crawled site and when, shown in the title ("" keeps a generic title)
+	baseURL   string
+	crawlDate time.Time
 }
 
 // getFontPaths returns system font paths based on the operating system
@@ -121,6 +128,36 @@ func NewGraphVisualizer(width, height int) *GraphVisualizer {
 	}
 }
 
+// AddNode adds a page to the graph, or updates it if the URL is already present.
+// count is the number of links found to the page and sets its drawn size.
+func (gv *GraphVisualizer) AddNode(url string, count int, external bool) {
+	node := &Node{
+		URL:        url,
+		Color:      [3]float64{0.2, 0.6, 0.9}, // Blue for internal
+		IsExternal: external,
+		Count:      count,
+	}
+
+	// Node size based on link count
+	if external {
+		node.Color = [3]float64{0.9, 0.4, 0.2} // Orange for external
+		node.Radius = math.Min(3+float64(count)*1.5, 15)
+	} else {
+		node.Radius = math.Min(5+float64(count)*2, 20)
+	}
+
+	gv.nodes[url] = node
+}
+
+// AddEdge adds a link between two pages. Edges whose endpoints aren't nodes are ignored when rendering.
+func (gv *GraphVisualizer) AddEdge(from, to string, weight int) {
+	gv.edges = append(gv.edges, Edge{
+		From:   from,
+		To:     to,
+		Weight: weight,
+	})
+}
+
 // AddInternalPages adds internal pages to the graph
 func (gv *GraphVisualizer) AddInternalPages(pages map[string]int, baseURL string) error {
 	// Parse base URL to get domain
@@ -129,49 +166,11 @@ func (gv *GraphVisualizer) AddInternalPages(pages map[string]int, baseURL string
 		return fmt.Errorf("failed to parse base URL '%s': %v", baseURL, err)
 	}
 
-	// Convert pages to sorted slice for consistent positioning
-	type PageInfo struct {
-		URL   string
-		Count int
-	}
-	var pageList []PageInfo
 	for normalizedURL, count := range pages {
 		// Reconstruct full URL
 		fullURL := parsedBase.Scheme + "://" + normalizedURL
 		// Sanitize the URL before adding to visualization
-		sanitizedURL := gv.sanitizeURLForVisualization(fullURL)
-		pageList = append(pageList, PageInfo{URL: sanitizedURL, Count: count})
-	}
-
-	// Sort by count (descending) for better visualization
-	sort.Slice(pageList, func(i, j int) bool {
-		return pageList[i].Count > pageList[j].Count
-	})
-
-	// Position nodes in a circle for internal pages
-	centerX := float64(gv.width) * 0.3
-	centerY := float64(gv.height) * 0.5
-	radius := math.Min(float64(gv.width), float64(gv.height)) * 0.2
-
-	for i, page := range pageList {
-		angle := 2 * math.Pi * float64(i) / float64(len(pageList))
-		x := centerX + radius*math.Cos(angle)
-		y := centerY + radius*math.Sin(angle)
-
-		// Node size based on link count
-		nodeRadius := 5 + float64(page.Count)*2
-		if nodeRadius > 20 {
-			nodeRadius = 20
-		}
-
-		gv.nodes[page.URL] = &Node{
-			URL:        page.URL,
-			X:          x,
-			Y:          y,
-			Radius:     nodeRadius,
-			Color:      [3]float64{0.2, 0.6, 0.9}, // Blue for internal
-			IsExternal: false,
-		}
+		gv.AddNode(gv.sanitizeURLForVisualization(fullURL), count, false)
 	}
 
 	return nil
@@ -179,45 +178,9 @@ func (gv *GraphVisualizer) AddInternalPages(pages map[string]int, baseURL string
 
 // AddExternalLinks adds external links to the graph
 func (gv *GraphVisualizer) AddExternalLinks(externalLinks map[string]int) {
-	// Convert to sorted slice
-	type ExternalInfo struct {
-		URL   string
-		Count int
-	}
-	var extList []ExternalInfo
 	for url, count := range externalLinks {
 		// Sanitize URL before adding to visualization
-		sanitizedURL := gv.sanitizeURLForVisualization(url)
-		extList = append(extList, ExternalInfo{URL: sanitizedURL, Count: count})
-	}
-
-	// Sort by count (descending)
-	sort.Slice(extList, func(i, j int) bool {
-		return extList[i].Count > extList[j].Count
-	})
-
-	// Position external nodes on the right side
-	startX := float64(gv.width) * 0.7
-	startY := float64(gv.height) * 0.1
-	spacing := float64(gv.height) * 0.8 / float64(len(extList)+1)
-
-	for i, ext := range extList {
-		y := startY + float64(i+1)*spacing
-
-		// Node size based on link count
-		nodeRadius := 3 + float64(ext.Count)*1.5
-		if nodeRadius > 15 {
-			nodeRadius = 15
-		}
-
-		gv.nodes[ext.URL] = &Node{
-			URL:        ext.URL,
-			X:          startX,
-			Y:          y,
-			Radius:     nodeRadius,
-			Color:      [3]float64{0.9, 0.4, 0.2}, // Orange for external
-			IsExternal: true,
-		}
+		gv.AddNode(gv.sanitizeURLForVisualization(url), count, true)
 	}
 }
 
@@ -235,29 +198,64 @@ func (gv *GraphVisualizer) AddEdges(pages map[string]int, externalLinks map[stri
 		fullURL := parsedBase.Scheme + "://" + normalizedURL
 		sanitizedURL := gv.sanitizeURLForVisualization(fullURL)
 		if sanitizedURL != mainURL {
-			gv.edges = append(gv.edges, Edge{
-				From:   mainURL,
-				To:     sanitizedURL,
-				Weight: pages[normalizedURL],
-			})
+			gv.AddEdge(mainURL, sanitizedURL, pages[normalizedURL])
 		}
 	}
 
 	// Create edges to external links (from main page)
 	for extURL, count := range externalLinks {
-		sanitizedExtURL := gv.sanitizeURLForVisualization(extURL)
-		gv.edges = append(gv.edges, Edge{
-			From:   mainURL,
-			To:     sanitizedExtURL,
-			Weight: count,
-		})
+		gv.AddEdge(mainURL, gv.sanitizeURLForVisualization(extURL), count)
 	}
 
 	return nil
 }
 
+// sortedNodes returns the internal or external nodes ordered by count (descending), then URL
+func (gv *GraphVisualizer) sortedNodes(external bool) []*Node {
+	var nodes []*Node
+	for _, node := range gv.nodes {
+		if node.IsExternal == external {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Count != nodes[j].Count {
+			return nodes[i].Count > nodes[j].Count
+		}
+		return nodes[i].URL < nodes[j].URL
+	})
+	return nodes
+}
+
+// layout positions internal pages in a circle on the left and external links in a column on the right
+func (gv *GraphVisualizer) layout() {
+	internal := gv.sortedNodes(false)
+	centerX := float64(gv.width) * 0.3
+	centerY := float64(gv.height) * 0.5
+	radius := math.Min(float64(gv.width), float64(gv.height)) * 0.2
+	for i, node := range internal {
+		angle := 2 * math.Pi * float64(i) / float64(len(internal))
+		node.X = centerX + radius*math.Cos(angle)
+		node.Y = centerY + radius*math.Sin(angle)
+	}
+
+	external := gv.sortedNodes(true)
+	startX := float64(gv.width) * 0.7
+	startY := float64(gv.height) * 0.1
+	spacing := float64(gv.height) * 0.8 / float64(len(external)+1)
+	for i, node := range external {
+		node.X = startX
+		node.Y = startY + float64(i+1)*spacing
+	}
+}
+
 // DrawGraph creates the visualization and saves it to a file
 func (gv *GraphVisualizer) DrawGraph(filename string) error {
+	if gv.layoutOptions != nil {
+		gv.forceLayout(*gv.layoutOptions)
+	} else {
+		gv.layout()
+	}
 	dc := gg.NewContext(gv.width, gv.height)
 
 	// Set background
@@ -328,13 +326,15 @@ func (gv *GraphVisualizer) DrawGraph(filename string) error {
 		dc.DrawString(label, labelX, labelY)
 	}
 
+	title, legend := gv.labels()
+
 	// Add title
 	dc.SetRGB(0, 0, 0)
 	titleSize := 16.0
 	if err := loadSystemFont(dc, titleSize); err != nil {
 		fmt.Printf("Warning: Could not load system font for title: %v\n", err)
 	}
-	dc.DrawString("Web Crawler Link Graph", 20, 30)
+	dc.DrawString(title, 20, 30)
 
 	// Add legend
 	dc.SetRGB(0, 0, 0)
@@ -350,19 +350,39 @@ func (gv *GraphVisualizer) DrawGraph(filename string) error {
 	dc.DrawCircle(20, legendY, 8)
 	dc.Fill()
 	dc.SetRGB(0, 0, 0)
-	dc.DrawString("Internal Pages", 35, legendY+4)
+	dc.DrawString(legend[0], 35, legendY+4)
 
 	// External links legend
 	dc.SetRGB(0.9, 0.4, 0.2)
 	dc.DrawCircle(20, legendY+20, 8)
 	dc.Fill()
 	dc.SetRGB(0, 0, 0)
-	dc.DrawString("External Links", 35, legendY+24)
+	dc.DrawString(legend[1], 35, legendY+24)
 
 	// Save the image
 	return dc.SavePNG(filename)
 }
 
+// labels returns the graph's title, naming the crawled site and date when known, and its legend entries
+// for internal and external nodes with the number of each drawn
+func (gv *GraphVisualizer) labels() (title string, legend [2]string) {
+	title = "Web Crawler Link Graph"
+	if gv.baseURL != "" {
+		title = fmt.Sprintf("Link Graph: %s (crawled %s)", gv.baseURL, gv.crawlDate.Format("2006-01-02"))
+	}
+	internal, external := 0, 0
+	for _, node := range gv.nodes {
+		if node.IsExternal {
+			external++
+		} else {
+			internal++
+		}
+	}
+	legend[0] = fmt.Sprintf("Internal Pages (%d)", internal)
+	legend[1] = fmt.Sprintf("External Links (%d)", external)
+	return title, legend
+}
+
 // createShortLabel creates a short, readable label from a URL
 func (gv *GraphVisualizer) createShortLabel(urlStr string) string {
 	parsed, err := url.Parse(urlStr)
@@ -413,24 +433,37 @@ func (gv *GraphVisualizer) sanitizeURLForVisualization(urlStr string) string {
 	return urlStr
 }
 
-// GenerateGraphVisualization creates a complete graph visualization
-func GenerateGraphVisualization(pages map[string]int, externalLinks map[string]int, baseURL, filename string) error {
+// buildGraph creates a visualizer populated with the crawl results
+func buildGraph(pages map[string]int, externalLinks map[string]int, baseURL string) (*GraphVisualizer, error) {
 	// Validate base URL early
 	if _, err := url.Parse(baseURL); err != nil {
-		return fmt.Errorf("invalid base URL '%s': %v", baseURL, err)
+		return nil, fmt.Errorf("invalid base URL '%s': %v", baseURL, err)
 	}
 
-	// Create visualizer
+	// Create visualizer, titled after the crawled site
 	gv := NewGraphVisualizer(1200, 800)
+	gv.baseURL, gv.crawlDate = baseURL, time.Now()
 
 	// Add data to graph
 	if err := gv.AddInternalPages(pages, baseURL); err != nil {
-		return fmt.Errorf("failed to add internal pages: %v", err)
+		return nil, fmt.Errorf("failed to add internal pages: %v", err)
 	}
 	gv.AddExternalLinks(externalLinks)
 	if err := gv.AddEdges(pages, externalLinks, baseURL); err != nil {
-		return fmt.Errorf("failed to add edges: %v", err)
+		return nil, fmt.Errorf("failed to add edges: %v", err)
+	}
+
+	return gv, nil
+}
+
+// GenerateGraphVisualization creates a complete graph visualization.
+// layout selects a force-directed layout; nil keeps the circle/column layout.
+func GenerateGraphVisualization(pages map[string]int, externalLinks map[string]int, baseURL, filename string, layout *forceLayoutOptions) error {
+	gv, err := buildGraph(pages, externalLinks, baseURL)
+	if err != nil {
+		return err
 	}
+	gv.layoutOptions = layout
 
 	// Generate the image
 	if err := gv.DrawGraph(filename); err != nil {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGraphLabels(t *testing.T) {
+	pages := map[string]int{"example.com": 3, "example.com/about": 1, "example.com/blog": 2}
+	externalLinks := map[string]int{"https://github.com/example": 1, "https://x.com/example": 2}
+	gv, err := buildGraph(pages, externalLinks, "https://example.com")
+	if err != nil {
+		t.Fatalf("failed to build graph: %v", err)
+	}
+	gv.crawlDate = time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC)
+
+	title, legend := gv.labels()
+	if expected := "Link Graph: https://example.com (crawled 2024-05-17)"; title != expected {
+		t.Errorf("expected title %q, got %q", expected, title)
+	}
+	if expected := [2]string{"Internal Pages (3)", "External Links (2)"}; legend != expected {
+		t.Errorf("expected legend %v, got %v", expected, legend)
+	}
+	if err := gv.DrawGraph(filepath.Join(t.TempDir(), "graph.png")); err != nil {
+		t.Errorf("failed to draw graph: %v", err)
+	}
+
+	// A graph built without a site keeps the generic title
+	if title, _ := NewGraphVisualizer(100, 100).labels(); title != "Web Crawler Link Graph" {
+		t.Errorf("expected the generic title, got %q", title)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// GraphML document structure, see http://graphml.graphdrawing.org/specification.html
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as a GraphML document (for Gephi, yEd, etc.).
+// Nodes carry url, type (internal/external) and count attributes; edges carry a weight.
+func (gv *GraphVisualizer) WriteGraphML(w io.Writer) error {
+	doc := graphMLDocument{
+		Xmlns: graphMLNamespace,
+		Keys: []graphMLKey{
+			{ID: "url", For: "node", AttrName: "url", AttrType: "string"},
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "count", For: "node", AttrName: "count", AttrType: "int"},
+			{ID: "weight", For: "edge", AttrName: "weight", AttrType: "int"},
+		},
+		Graph: graphMLGraph{ID: "crawl", EdgeDefault: "directed"},
+	}
+
+	// Sort node URLs so output is stable between runs
+	urls := make([]string, 0, len(gv.nodes))
+	for nodeURL := range gv.nodes {
+		urls = append(urls, nodeURL)
+	}
+	sort.Strings(urls)
+
+	nodeIDs := make(map[string]string, len(urls))
+	for i, nodeURL := range urls {
+		node := gv.nodes[nodeURL]
+		id := fmt.Sprintf("n%d", i)
+		nodeIDs[nodeURL] = id
+
+		nodeType := "internal"
+		if node.IsExternal {
+			nodeType = "external"
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: id,
+			Data: []graphMLData{
+				{Key: "url", Value: node.URL},
+				{Key: "type", Value: nodeType},
+				{Key: "count", Value: strconv.Itoa(node.Count)},
+			},
+		})
+	}
+
+	for _, edge := range gv.edges {
+		source, sourceOK := nodeIDs[edge.From]
+		target, targetOK := nodeIDs[edge.To]
+		// Skip edges whose endpoints aren't in the graph, as DrawGraph does
+		if !sourceOK || !targetOK {
+			continue
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", len(doc.Graph.Edges)),
+			Source: source,
+			Target: target,
+			Data:   []graphMLData{{Key: "weight", Value: strconv.Itoa(edge.Weight)}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode GraphML: %v", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// GenerateGraphML writes the crawl results as a GraphML file
+func GenerateGraphML(pages map[string]int, externalLinks map[string]int, baseURL, filename string) error {
+	gv, err := buildGraph(pages, externalLinks, baseURL)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create GraphML file: %v", err)
+	}
+	if err := gv.WriteGraphML(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close GraphML file: %v", err)
+	}
+
+	fmt.Printf("GraphML graph saved to: %s\n", filename)
+	return nil
+}
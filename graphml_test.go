@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWriteGraphML(t *testing.T) {
+	pages := map[string]int{"example.com": 3, "example.com/about": 1}
+	externalLinks := map[string]int{"https://other.com": 2}
+
+	gv, err := buildGraph(pages, externalLinks, "https://example.com")
+	if err != nil {
+		t.Fatalf("unexpected error building graph: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gv.WriteGraphML(&buf); err != nil {
+		t.Fatalf("unexpected error writing GraphML: %v", err)
+	}
+
+	output := buf.String()
+	for _, element := range []string{"<graphml", "<graph ", "<node ", "<edge "} {
+		if !strings.Contains(output, element) {
+			t.Errorf("expected output to contain %s, got %s", element, output)
+		}
+	}
+
+	var doc graphMLDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v", err)
+	}
+	if doc.XMLName.Space != graphMLNamespace {
+		t.Errorf("expected namespace %s, got %q", graphMLNamespace, doc.XMLName.Space)
+	}
+	if len(doc.Graph.Nodes) != 3 {
+		t.Errorf("expected 3 nodes, got %d", len(doc.Graph.Nodes))
+	}
+	// The base page links to /about and to the external page
+	if len(doc.Graph.Edges) != 2 {
+		t.Errorf("expected 2 edges, got %d", len(doc.Graph.Edges))
+	}
+
+	external := 0
+	for _, node := range doc.Graph.Nodes {
+		for _, data := range node.Data {
+			if data.Key == "type" && data.Value == "external" {
+				external++
+			}
+		}
+	}
+	if external != 1 {
+		t.Errorf("expected 1 external node, got %d", external)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Share of failed pages from which a host counts as flaky for the next run
+	flakyHostFailureRatio = 0.2
+	// Successes in a row a flaky host needs before it gets another concurrent request slot
+	hostWarmupSuccesses = 5
+)
+
+// hostHealth is how a host fared over the pages of one crawl
+type hostHealth struct {
+	Pages     int       `json:"pages"`
+	Failures  int       `json:"failures"`
+	CrawledAt time.Time `json:"crawled_at"`
+}
+
+// flaky reports whether enough of the host's pages failed to start it cautiously next time
+func (h hostHealth) flaky() bool {
+	return h.Failures > 0 && float64(h.Failures) >= flakyHostFailureRatio*float64(h.Pages)
+}
+
+// recordHost notes the outcome of fetching a page of host this run
+func (l *crawlLedger) recordHost(host string, ok bool, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	host = strings.ToLower(host)
+	health := l.runHosts[host]
+	health.Pages++
+	if !ok {
+		health.Failures++
+	}
+	health.CrawledAt = now
+	l.runHosts[host] = health
+}
+
+// flakyHosts returns the hosts that were flaky the last time they were crawled, sorted
+func (l *crawlLedger) flakyHosts() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var hosts []string
+	for host, health := range l.hosts {
+		if health.flaky() {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// recordHostHealth notes whether a page of host was fetched, for the ledger and for warming up hosts
+// that started throttled. It does nothing unless --remember-host-health is on.
+func (cfg *config) recordHostHealth(host string, ok bool) {
+	if !cfg.rememberHostHealth {
+		return
+	}
+	if cfg.ledger != nil {
+		cfg.ledger.recordHost(host, ok, cfg.clock.Now())
+	}
+	if cfg.hostLimiter != nil {
+		cfg.hostLimiter.report(host, ok)
+	}
+}
+
+// throttleFlakyHosts starts the hosts that were flaky last run with a single request slot, adding a
+// limiter of limit slots per host if there is none, and returns those hosts. Without a ledger there is
+// no last run to go by.
+func (cfg *config) throttleFlakyHosts(limit int) []string {
+	if cfg.ledger == nil {
+		return nil
+	}
+	flaky := cfg.ledger.flakyHosts()
+	if len(flaky) == 0 {
+		return nil
+	}
+	if cfg.hostLimiter == nil {
+		cfg.hostLimiter = newHostLimiter(limit)
+	}
+	for _, host := range flaky {
+		cfg.hostLimiter.throttle(host)
+	}
+	return flaky
+}
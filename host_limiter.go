@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// hostLimiter bounds the number of simultaneous requests to any single host
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{} // per-host semaphores, created on first use
+	// Hosts started below the limit: slots still held back and successes since the last one was freed
+	throttled map[string]*hostThrottle
+}
+
+// hostThrottle tracks a host warming up from a single request slot to the full limit
+type hostThrottle struct {
+	reserved int
+	streak   int
+}
+
+// newHostLimiter creates a limiter allowing limit concurrent requests per host
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, slots: make(map[string]chan struct{}), throttled: make(map[string]*hostThrottle)}
+}
+
+// hostSlots returns the semaphore of host, creating it if needed. The caller must hold h.mu.
+func (h *hostLimiter) hostSlots(host string) chan struct{} {
+	slots, ok := h.slots[host]
+	if !ok {
+		slots = make(chan struct{}, h.limit)
+		h.slots[host] = slots
+	}
+	return slots
+}
+
+// acquire waits for a request slot on host and returns a function releasing it.
+// It fails with the context's error if ctx is done first.
+func (h *hostLimiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	h.mu.Lock()
+	slots := h.hostSlots(strings.ToLower(host))
+	h.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// throttle starts host with a single request slot. Reserved slots are filled with placeholders that
+// report frees one at a time as the host proves healthy.
+func (h *hostLimiter) throttle(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	host = strings.ToLower(host)
+	if _, ok := h.slots[host]; ok || h.limit <= 1 {
+		return
+	}
+	slots := h.hostSlots(host)
+	reserved := h.limit - 1
+	for i := 0; i < reserved; i++ {
+		slots <- struct{}{}
+	}
+	h.throttled[host] = &hostThrottle{reserved: reserved}
+}
+
+// report notes the outcome of a request to host. Every hostWarmupSuccesses successes in a row give a
+// throttled host back one slot; a failure starts the count over.
+func (h *hostLimiter) report(host string, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	host = strings.ToLower(host)
+	throttle := h.throttled[host]
+	if throttle == nil {
+		return
+	}
+	if !ok {
+		throttle.streak = 0
+		return
+	}
+	throttle.streak++
+	if throttle.streak < hostWarmupSuccesses {
+		return
+	}
+	throttle.streak = 0
+	throttle.reserved--
+	<-h.slots[host]
+	if throttle.reserved == 0 {
+		delete(h.throttled, host)
+	}
+}
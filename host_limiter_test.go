@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyPerHostBoundsSimultaneousRequests(t *testing.T) {
+	const perHost = 2
+	var inFlight, maxInFlight, pageRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		current := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			peak := atomic.LoadInt64(&maxInFlight)
+			if current <= peak || atomic.CompareAndSwapInt64(&maxInFlight, peak, current) {
+				break
+			}
+		}
+		atomic.AddInt64(&pageRequests, 1)
+		time.Sleep(50 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>")
+		if r.URL.Path == "/" {
+			for i := 0; i < 8; i++ {
+				fmt.Fprintf(w, `<a href="/page%d">page</a>`, i)
+			}
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 20)
+	cfg.concurrencyControl = make(chan struct{}, 8)
+	cfg.hostLimiter = newHostLimiter(perHost)
+	runTestCrawl(cfg)
+
+	if requests := atomic.LoadInt64(&pageRequests); requests != 9 {
+		t.Fatalf("expected 9 page requests, got %d", requests)
+	}
+	if peak := atomic.LoadInt64(&maxInFlight); peak > perHost {
+		t.Errorf("expected at most %d simultaneous requests to the host, got %d", perHost, peak)
+	}
+}
+
+func TestFlakyHostStartsWithReducedConcurrency(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	state := `{"pages": {}, "hosts": {
+		"flaky.test": {"pages": 10, "failures": 4, "crawled_at": "2024-05-01T12:00:00Z"},
+		"steady.test": {"pages": 10, "failures": 1, "crawled_at": "2024-05-01T12:00:00Z"}
+	}}`
+	if err := os.WriteFile(filename, []byte(state), 0644); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+	ledger, err := loadCrawlLedger(filename)
+	if err != nil {
+		t.Fatalf("failed to load state: %v", err)
+	}
+	cfg := &config{ledger: ledger, rememberHostHealth: true, clock: realClock{}}
+	if flaky := cfg.throttleFlakyHosts(3); !reflect.DeepEqual(flaky, []string{"flaky.test"}) {
+		t.Fatalf("expected only flaky.test to be throttled, got %v", flaky)
+	}
+
+	// tryAcquire takes a slot on host without waiting for one
+	tryAcquire := func(host string) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := cfg.hostLimiter.acquire(ctx, host)
+		return err == nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if !tryAcquire("steady.test") {
+			t.Fatalf("expected the healthy host to get all 3 slots, got %d", i)
+		}
+	}
+	if !tryAcquire("flaky.test") {
+		t.Fatal("expected the flaky host to get one slot")
+	}
+	if tryAcquire("flaky.test") {
+		t.Fatal("expected the flaky host to start with a single slot")
+	}
+
+	// A failure restarts the warm-up; enough successes in a row free another slot
+	for i := 0; i < hostWarmupSuccesses-1; i++ {
+		cfg.recordHostHealth("flaky.test", true)
+	}
+	cfg.recordHostHealth("flaky.test", false)
+	if tryAcquire("flaky.test") {
+		t.Fatal("expected a failure to reset the warm-up")
+	}
+	for i := 0; i < hostWarmupSuccesses; i++ {
+		cfg.recordHostHealth("flaky.test", true)
+	}
+	if !tryAcquire("flaky.test") {
+		t.Error("expected the flaky host to warm up to a second slot")
+	}
+	if health := ledger.runHosts["flaky.test"]; health.Pages != 2*hostWarmupSuccesses || health.Failures != 1 {
+		t.Errorf("expected this run's outcomes recorded for the next run, got %+v", health)
+	}
+}
+
+func TestThrottleFlakyHostsWithoutLedger(t *testing.T) {
+	// --seeds crawls run without a ledger, so there are no flaky hosts to throttle
+	cfg := &config{rememberHostHealth: true, clock: realClock{}}
+	if flaky := cfg.throttleFlakyHosts(3); flaky != nil {
+		t.Errorf("expected no hosts throttled without a ledger, got %v", flaky)
+	}
+	if cfg.hostLimiter != nil {
+		t.Error("expected no host limiter added without a ledger")
+	}
+}
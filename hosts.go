@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// How a host is compared with the seed's host, chosen with --host-match
+const (
+	hostMatchExact            = "exact"             // only the seed's host itself
+	hostMatchRegisteredDomain = "registered-domain" // any host of the seed's registered domain (eTLD+1)
+	hostMatchSuffix           = "suffix"            // the seed's host without www. and its subdomains
+)
+
+// isCrawledHost reports whether pages on host are crawled rather than recorded as external links:
+// the hosts matching the seed's under the --host-match policy and the hosts allowed with --allow-hosts
+func (cfg *config) isCrawledHost(host string) bool {
+	return matchesSeedHost(host, cfg.baseURL.Hostname(), cfg.hostMatch) || cfg.allowedHosts[strings.ToLower(host)]
+}
+
+// matchesSeedHost reports whether host belongs to the site of seedHost under the policy ("" is exact)
+func matchesSeedHost(host, seedHost, policy string) bool {
+	if host == seedHost {
+		return true
+	}
+	host, seedHost = strings.ToLower(host), strings.ToLower(seedHost)
+	switch policy {
+	case hostMatchRegisteredDomain:
+		return hostRegisteredDomain(host) == hostRegisteredDomain(seedHost)
+	case hostMatchSuffix:
+		seedHost = strings.TrimPrefix(seedHost, "www.")
+		return host == seedHost || strings.HasSuffix(host, "."+seedHost)
+	}
+	return false
+}
+
+// parseAllowedHosts parses a comma-separated list of extra hosts to crawl, such as "docs.example.com,blog.example.com"
+func parseAllowedHosts(raw string) (map[string]bool, error) {
+	hosts := make(map[string]bool)
+	for _, host := range strings.Split(raw, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || strings.ContainsAny(host, "/: ") {
+			return nil, fmt.Errorf("invalid host %q, expected a host name such as docs.example.com", host)
+		}
+		hosts[host] = true
+	}
+	return hosts, nil
+}
+
+// hostDepthLimits is how far each host is explored from its entry point, the shallowest page of the host
+// reached by the crawl
+type hostDepthLimits struct {
+	defaultLimit int            // limit of hosts without their own, or -1 for no limit
+	hosts        map[string]int // host -> limit
+}
+
+// parseHostDepthLimits parses a comma-separated list of host=N limits, where a bare N applies to every
+// other host, e.g. "1,example.com=5". A limit of 0 only crawls a host's entry page.
+func parseHostDepthLimits(raw string) (*hostDepthLimits, error) {
+	limits := &hostDepthLimits{defaultLimit: -1, hosts: make(map[string]int)}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		host, rawLimit, perHost := strings.Cut(entry, "=")
+		if !perHost {
+			host, rawLimit = "", entry
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(rawLimit))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid depth in %q, expected a non-negative number", entry)
+		}
+		host = strings.ToLower(strings.TrimSpace(host))
+		switch {
+		case !perHost:
+			limits.defaultLimit = limit
+		case host == "":
+			return nil, fmt.Errorf("missing host in %q", entry)
+		default:
+			limits.hosts[host] = limit
+		}
+	}
+	return limits, nil
+}
+
+// limit returns the depth limit of host, or false if it has none
+func (l *hostDepthLimits) limit(host string) (int, bool) {
+	if limit, ok := l.hosts[strings.ToLower(host)]; ok {
+		return limit, true
+	}
+	return l.defaultLimit, l.defaultLimit >= 0
+}
+
+// hostDepthReached notes a page of host found at crawl depth and reports whether it lies at or beyond
+// the host's depth limit, counted from the host's entry point, so its links must not be followed. past
+// reports a page beyond the limit: it was linked from a page judged against a deeper entry point, before
+// a shallower one turned up, so it isn't fetched either.
+func (cfg *config) hostDepthReached(host string, depth int) (reached, past bool) {
+	if cfg.hostDepthLimits == nil {
+		return false, false
+	}
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	host = strings.ToLower(host)
+	entry, seen := cfg.hostEntryDepths[host]
+	if !seen || depth < entry {
+		cfg.hostEntryDepths[host] = depth
+		entry = depth
+	}
+	limit, limited := cfg.hostDepthLimits.limit(host)
+	return limited && depth-entry >= limit, limited && depth-entry > limit
+}
+
+// hostTimeLimited reports whether host has been crawled for longer than maxHostDuration, counting the link
+// as skipped if so. The host's time starts with the first of its pages to get this far.
+func (cfg *config) hostTimeLimited(host string) bool {
+	if cfg.maxHostDuration <= 0 {
+		return false
+	}
+	now := cfg.clock.Now()
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	host = strings.ToLower(host)
+	started, seen := cfg.hostStartTimes[host]
+	if !seen {
+		cfg.hostStartTimes[host] = now
+		return false
+	}
+	if now.Sub(started) <= cfg.maxHostDuration {
+		return false
+	}
+	if _, limited := cfg.timeLimitedHosts[host]; !limited {
+		cfg.logf("Skipping the rest of %s: crawled for longer than %v\n", host, cfg.maxHostDuration)
+	}
+	cfg.timeLimitedHosts[host]++
+	return true
+}
+
+// printTimeLimitedHosts lists the hosts cut short by --max-host-duration with the links skipped on each
+func printTimeLimitedHosts(timeLimitedHosts map[string]int) {
+	hosts := make([]string, 0, len(timeLimitedHosts))
+	for host := range timeLimitedHosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	fmt.Println("Hosts stopped by the time limit:")
+	for _, host := range hosts {
+		fmt.Printf("  %s: %d links skipped\n", host, timeLimitedHosts[host])
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// multiHostSite serves pages of several hosts, keyed by host and path, each linking to the given URLs
+type multiHostSite map[string][]string
+
+func (s multiHostSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusNotFound, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: req}
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	links, ok := s[req.URL.Host+path]
+	if !ok {
+		return resp, nil
+	}
+	var body strings.Builder
+	body.WriteString("<html><body>")
+	for _, link := range links {
+		fmt.Fprintf(&body, `<a href="%s">link</a>`, link)
+	}
+	body.WriteString("</body></html>")
+	resp.StatusCode = http.StatusOK
+	resp.Header.Set("Content-Type", "text/html")
+	resp.Body = io.NopCloser(strings.NewReader(body.String()))
+	return resp, nil
+}
+
+func TestParseHostDepthLimits(t *testing.T) {
+	tests := []struct {
+		raw       string
+		expected  *hostDepthLimits
+		expectErr bool
+	}{
+		{raw: "2", expected: &hostDepthLimits{defaultLimit: 2, hosts: map[string]int{}}},
+		{raw: "1, Docs.example.com=3", expected: &hostDepthLimits{defaultLimit: 1, hosts: map[string]int{"docs.example.com": 3}}},
+		{raw: "example.com=0", expected: &hostDepthLimits{defaultLimit: -1, hosts: map[string]int{"example.com": 0}}},
+		{raw: "example.com=-1", expectErr: true},
+		{raw: "=2", expectErr: true},
+		{raw: "deep", expectErr: true},
+	}
+	for _, tc := range tests {
+		limits, err := parseHostDepthLimits(tc.raw)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %+v", tc.raw, limits)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(limits, tc.expected) {
+			t.Errorf("%q: expected %+v, got %+v (err %v)", tc.raw, tc.expected, limits, err)
+		}
+	}
+}
+
+func TestMaxDepthPerHost(t *testing.T) {
+	site := multiHostSite{
+		"a.test/":   {"/a1", "http://b.test/"},
+		"a.test/a1": {"/a2"},
+		"a.test/a2": {"/a3"},
+		"a.test/a3": {},
+		"b.test/":   {"/b1"},
+		"b.test/b1": {"/b2"},
+		"b.test/b2": {},
+	}
+	cfg := newTestConfig(t, "http://a.test/", 20)
+	useTransport(cfg, site)
+	cfg.allowedHosts = map[string]bool{"b.test": true}
+	// b.test is entered one hop from the seed, so its limit counts from there
+	cfg.hostDepthLimits = &hostDepthLimits{defaultLimit: 1, hosts: map[string]int{"a.test": 2}}
+	cfg.hostEntryDepths = make(map[string]int)
+	cfg.Run(time.Minute)
+
+	var pages []string
+	for page := range cfg.pages {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	expected := []string{"a.test", "a.test/a1", "a.test/a2", "b.test", "b.test/b1"}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("expected pages %v, got %v", expected, pages)
+	}
+	if len(cfg.externalLinks) != 0 {
+		t.Errorf("expected the allowed host to be crawled rather than external, got %v", cfg.externalLinks)
+	}
+	if expected := map[string]int{"a.test": 0, "b.test": 1}; !reflect.DeepEqual(cfg.hostEntryDepths, expected) {
+		t.Errorf("expected entry depths %v, got %v", expected, cfg.hostEntryDepths)
+	}
+}
+
+func TestMatchesSeedHost(t *testing.T) {
+	hosts := []string{"example.co.uk", "www.example.co.uk", "blog.example.co.uk", "a.blog.example.co.uk", "example.com", "notexample.co.uk"}
+	tests := []struct {
+		policy   string
+		seed     string
+		expected []bool
+	}{
+		{policy: hostMatchExact, seed: "example.co.uk", expected: []bool{true, false, false, false, false, false}},
+		{policy: hostMatchRegisteredDomain, seed: "www.example.co.uk", expected: []bool{true, true, true, true, false, false}},
+		{policy: hostMatchSuffix, seed: "www.example.co.uk", expected: []bool{true, true, true, true, false, false}},
+		{policy: hostMatchSuffix, seed: "blog.example.co.uk", expected: []bool{false, false, true, true, false, false}},
+	}
+	for _, tc := range tests {
+		for i, host := range hosts {
+			if actual := matchesSeedHost(host, tc.seed, tc.policy); actual != tc.expected[i] {
+				t.Errorf("%s with seed %s: expected %s to match %v, got %v", tc.policy, tc.seed, host, tc.expected[i], actual)
+			}
+		}
+	}
+}
+
+func TestHostMatchModes(t *testing.T) {
+	site := multiHostSite{
+		"example.com/":      {"http://www.example.com/", "http://blog.example.com/", "http://other.test/"},
+		"www.example.com/":  {},
+		"blog.example.com/": {},
+		"other.test/":       {},
+	}
+	tests := []struct {
+		policy   string
+		crawled  []string
+		external []string
+	}{
+		{policy: hostMatchExact, crawled: []string{"example.com"}, external: []string{"http://blog.example.com/", "http://other.test/", "http://www.example.com/"}},
+		{policy: hostMatchRegisteredDomain, crawled: []string{"blog.example.com", "example.com"}, external: []string{"http://other.test/"}},
+		{policy: hostMatchSuffix, crawled: []string{"blog.example.com", "example.com"}, external: []string{"http://other.test/"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			cfg := newTestConfig(t, "http://example.com/", 20)
+			useTransport(cfg, site)
+			cfg.hostMatch = tc.policy
+			cfg.Run(time.Minute)
+
+			// www.example.com normalizes to the same page as example.com
+			var pages, external []string
+			for page := range cfg.pages {
+				pages = append(pages, page)
+			}
+			for link := range cfg.externalLinks {
+				external = append(external, link)
+			}
+			sort.Strings(pages)
+			sort.Strings(external)
+			if !reflect.DeepEqual(pages, tc.crawled) {
+				t.Errorf("expected pages %v, got %v", tc.crawled, pages)
+			}
+			if !reflect.DeepEqual(external, tc.external) {
+				t.Errorf("expected external links %v, got %v", tc.external, external)
+			}
+		})
+	}
+}
+
+// slowHostSite delays every response of one host, as a pathologically slow server would
+type slowHostSite struct {
+	multiHostSite
+	slowHost string
+	delay    time.Duration
+}
+
+func (s slowHostSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Hostname() == s.slowHost {
+		time.Sleep(s.delay)
+	}
+	return s.multiHostSite.RoundTrip(req)
+}
+
+func TestHostDepthReachedReevaluatesShallowerEntry(t *testing.T) {
+	cfg := newTestConfig(t, "http://a.test/", 10)
+	cfg.hostDepthLimits = &hostDepthLimits{defaultLimit: 1, hosts: map[string]int{}}
+	cfg.hostEntryDepths = make(map[string]int)
+
+	// b.test is first reached deep in the crawl, so its pages are judged from there
+	if reached, past := cfg.hostDepthReached("b.test", 3); reached || past {
+		t.Errorf("expected the entry page to be followed, got reached=%v past=%v", reached, past)
+	}
+	if reached, past := cfg.hostDepthReached("b.test", 4); !reached || past {
+		t.Errorf("expected depth 4 at the limit, got reached=%v past=%v", reached, past)
+	}
+	// Then a shallower link to it turns up: pages linked under the old entry are now past the limit
+	if reached, past := cfg.hostDepthReached("b.test", 1); reached || past {
+		t.Errorf("expected the new entry page to be followed, got reached=%v past=%v", reached, past)
+	}
+	if reached, past := cfg.hostDepthReached("b.test", 3); !reached || !past {
+		t.Errorf("expected depth 3 past the limit from the new entry, got reached=%v past=%v", reached, past)
+	}
+}
+
+func TestMaxHostDurationSkipsSlowHost(t *testing.T) {
+	site := multiHostSite{
+		"fast.test/":   {"/f1", "http://slow.test/"},
+		"fast.test/f1": {"/f2"},
+		"fast.test/f2": {"/f3"},
+		"fast.test/f3": {},
+	}
+	// A long chain of slow pages that can't be finished within the limit, each linking back home
+	const slowPages = 10
+	for i := 0; i < slowPages; i++ {
+		page := "slow.test/"
+		if i > 0 {
+			page = fmt.Sprintf("slow.test/s%d", i)
+		}
+		site[page] = []string{fmt.Sprintf("/s%d", i+1), "/"}
+	}
+
+	cfg := newTestConfig(t, "http://fast.test/", 50)
+	useTransport(cfg, slowHostSite{multiHostSite: site, slowHost: "slow.test", delay: 400 * time.Millisecond})
+	cfg.allowedHosts = map[string]bool{"slow.test": true}
+	cfg.maxHostDuration = time.Second
+	cfg.hostStartTimes = make(map[string]time.Time)
+	cfg.timeLimitedHosts = make(map[string]int)
+	cfg.Run(time.Minute)
+
+	for _, page := range []string{"fast.test", "fast.test/f1", "fast.test/f2", "fast.test/f3"} {
+		if _, ok := cfg.pages[page]; !ok {
+			t.Errorf("expected the fast host to be crawled fully, missing %s in %v", page, cfg.pages)
+		}
+	}
+	slowCrawled := 0
+	for page := range cfg.pages {
+		if strings.HasPrefix(page, "slow.test") {
+			slowCrawled++
+		}
+	}
+	if slowCrawled == 0 || slowCrawled >= slowPages {
+		t.Errorf("expected the slow host to be cut short, got %d of its pages in %v", slowCrawled, cfg.pages)
+	}
+	// Links back to the already crawled home page are counted, not skipped: only the page left unfetched is
+	if cfg.timeLimitedHosts["slow.test"] != 1 || len(cfg.timeLimitedHosts) != 1 {
+		t.Errorf("expected only slow.test to be time-limited with one link skipped, got %v", cfg.timeLimitedHosts)
+	}
+	if cfg.pages["slow.test"] != slowCrawled {
+		t.Errorf("expected slow.test linked from fast.test and the %d fetched slow pages, got %d", slowCrawled-1, cfg.pages["slow.test"])
+	}
+
+	report, err := cfg.buildReport("http://fast.test/")
+	if err != nil {
+		t.Fatalf("failed to build report: %v", err)
+	}
+	if !reflect.DeepEqual(report.TimeLimitedHosts, map[string]int{"slow.test": 1}) {
+		t.Errorf("expected slow.test in the report's time-limited hosts, got %v", report.TimeLimitedHosts)
+	}
+}
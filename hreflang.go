@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// hreflangDefault is the hreflang value for the page shown to languages without their own translation
+const hreflangDefault = "x-default"
+
+// getAlternatesFromHTML returns the <link rel="alternate" hreflang="..."> translations of a page, keyed by
+// lowercased language code, with hrefs resolved against baseURL. The first tag wins for a repeated language.
+func getAlternatesFromHTML(html string, baseURL *url.URL) map[string]string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+	alternates := make(map[string]string)
+	doc.Find("link[hreflang][href]").Each(func(_ int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !strings.EqualFold(strings.TrimSpace(rel), "alternate") {
+			return
+		}
+		lang, _ := s.Attr("hreflang")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		href, _ := s.Attr("href")
+		parsed, err := url.Parse(strings.TrimSpace(href))
+		if lang == "" || href == "" || err != nil {
+			return
+		}
+		if _, seen := alternates[lang]; !seen {
+			alternates[lang] = baseURL.ResolveReference(parsed).String()
+		}
+	})
+	return alternates
+}
+
+// hreflangIssue is an alternate link that doesn't check out
+type hreflangIssue struct {
+	Page      string
+	Lang      string
+	Alternate string
+	Problem   string
+}
+
+// hreflangIssues checks the alternates recorded during the crawl. A translation that failed to load is
+// broken, and a crawled translation must list the page among its own alternates (the return link).
+// Translations that weren't crawled can't be checked and are skipped.
+func (cfg *config) hreflangIssues() []hreflangIssue {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	var issues []hreflangIssue
+	for page, alternates := range cfg.alternates {
+		pageURL := fullPageURL(page, cfg.baseURL, cfg.canonicalURLs)
+		for lang, alternate := range alternates {
+			if reason, broken := cfg.brokenLinks[alternate]; broken {
+				issues = append(issues, hreflangIssue{Page: pageURL, Lang: lang, Alternate: alternate, Problem: "broken: " + reason})
+				continue
+			}
+			target, err := cfg.normalizeURL(alternate)
+			if err != nil || target == page {
+				continue
+			}
+			if _, crawled := cfg.pages[target]; !crawled {
+				continue
+			}
+			returned := false
+			for _, back := range cfg.alternates[target] {
+				if normalized, err := cfg.normalizeURL(back); err == nil && normalized == page {
+					returned = true
+					break
+				}
+			}
+			if !returned {
+				issues = append(issues, hreflangIssue{Page: pageURL, Lang: lang, Alternate: alternate, Problem: "no return link"})
+			}
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Page != issues[j].Page {
+			return issues[i].Page < issues[j].Page
+		}
+		return issues[i].Lang < issues[j].Lang
+	})
+	return issues
+}
+
+// printHreflangReport lists the hreflang alternates that are broken or missing their return link
+func printHreflangReport(w io.Writer, issues []hreflangIssue, pagesWithAlternates int) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  HREFLANG ISSUES")
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "Pages with hreflang alternates: %d\n", pagesWithAlternates)
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s [%s] -> %s: %s\n", issue.Page, issue.Lang, issue.Alternate, issue.Problem)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetAlternatesFromHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+
+	tests := []struct {
+		name     string
+		html     string
+		expected map[string]string
+	}{
+		{
+			name: "several alternates resolved against the base",
+			html: `<head>
+				<link rel="alternate" hreflang="en" href="/en/post">
+				<link rel="alternate" hreflang="de" href="../de/post">
+				<link rel="alternate" hreflang="es" href="https://es.example.com/post">
+				<link rel="alternate" hreflang="x-default" href="post">
+			</head>`,
+			expected: map[string]string{
+				"en":        "https://example.com/en/post",
+				"de":        "https://example.com/de/post",
+				"es":        "https://es.example.com/post",
+				"x-default": "https://example.com/blog/post",
+			},
+		},
+		{
+			name: "languages lowercased and first tag wins",
+			html: `<link rel="Alternate" hreflang="EN-gb" href="/uk"><link rel="alternate" hreflang="en-GB" href="/other">`,
+			expected: map[string]string{
+				"en-gb": "https://example.com/uk",
+			},
+		},
+		{
+			name:     "other link relations and empty values ignored",
+			html:     `<link rel="canonical" hreflang="en" href="/en"><link rel="alternate" hreflang="" href="/x"><link rel="alternate" hreflang="fr" href=""><link rel="alternate" type="application/rss+xml" href="/feed">`,
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := getAlternatesFromHTML(tc.html, base)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHreflangIssues(t *testing.T) {
+	pages := map[string]string{
+		// The English and French pages point at each other
+		"/en": `<link rel="alternate" hreflang="fr" href="/fr"><link rel="alternate" hreflang="de" href="/de"><link rel="alternate" hreflang="x-default" href="/en"><a href="/fr">fr</a><a href="/de">de</a><a href="/es">es</a>`,
+		"/fr": `<link rel="alternate" hreflang="en" href="/en">`,
+		// The German page forgets to link back, and the Spanish one is missing
+		"/de": `<link rel="alternate" hreflang="fr" href="/fr">`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head></head><body>%s</body></html>", body)
+	}))
+	defer server.Close()
+
+	pages["/en"] += fmt.Sprintf(`<link rel="alternate" hreflang="es" href="%s/es">`, server.URL)
+
+	cfg := newTestConfig(t, server.URL+"/en", 10)
+	cfg.brokenLinks = make(map[string]string)
+	cfg.alternates = make(map[string]map[string]string)
+	runTestCrawl(cfg)
+
+	issues := cfg.hreflangIssues()
+	if len(issues) != 3 {
+		t.Fatalf("expected 3 issues, got %+v", issues)
+	}
+	expected := []struct{ page, lang, problem string }{
+		{"/de", "fr", "no return link"},
+		{"/en", "de", "no return link"},
+		{"/en", "es", "broken"},
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(issues[i].Page, e.page) || issues[i].Lang != e.lang || !strings.HasPrefix(issues[i].Problem, e.problem) {
+			t.Errorf("expected issue %d to be %s [%s] %s, got %+v", i, e.page, e.lang, e.problem, issues[i])
+		}
+	}
+}
@@ -34,3 +34,62 @@ func getFirstParagraphFromHTML(html string) string {
 	}
 	return ""
 }
+
+// hasRobotsMetaDirective reports whether a <meta name="robots"> tag, or one named after robotsToken, in the
+// HTML contains directive (e.g. "nofollow"). The "none" directive implies both noindex and nofollow.
+func hasRobotsMetaDirective(html, directive, robotsToken string) bool {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return false
+	}
+	found := false
+	doc.Find("meta[name]").Each(func(_ int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+		if !strings.EqualFold(name, "robots") && !strings.EqualFold(name, robotsToken) {
+			return
+		}
+		content, _ := s.Attr("content")
+		for _, value := range strings.Split(content, ",") {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value == directive || (value == "none" && (directive == "noindex" || directive == "nofollow")) {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+// getMetaContentFromHTML returns the content of the first <meta> tag whose name matches name case-insensitively
+func getMetaContentFromHTML(html, name string) (string, bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "", false
+	}
+	content, found := "", false
+	doc.Find("meta[name]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if metaName, _ := s.Attr("name"); strings.EqualFold(metaName, name) {
+			content, found = s.Attr("content")
+			return false
+		}
+		return true
+	})
+	return content, found
+}
+
+// getMainTextFromHTML returns the text of <main>, else <article>, else <body>, with whitespace collapsed.
+// Scripts, styles and the navigation, header and footer shared by templated pages are left out.
+func getMainTextFromHTML(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+	doc.Find("script, style, noscript, template, nav, header, footer").Remove()
+	content := doc.Find("main").First()
+	if content.Length() == 0 {
+		content = doc.Find("article").First()
+	}
+	if content.Length() == 0 {
+		content = doc.Find("body")
+	}
+	return strings.Join(strings.Fields(content.Text()), " ")
+}
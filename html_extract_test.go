@@ -60,3 +60,16 @@ func TestGetFirstParagraphFromHTMLNone(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, actual)
 	}
 }
+
+func TestHasRobotsMetaDirective(t *testing.T) {
+	inputBody := `<html><head><meta name="Robots" content="noindex, NoFollow"></head><body></body></html>`
+	if !hasRobotsMetaDirective(inputBody, "nofollow", "crawler") {
+		t.Errorf("expected nofollow directive to be found")
+	}
+	if hasRobotsMetaDirective("<html><head></head></html>", "nofollow", "crawler") {
+		t.Errorf("expected no directive without a robots meta tag")
+	}
+	if !hasRobotsMetaDirective(`<meta name="robots" content="none">`, "nofollow", "crawler") {
+		t.Errorf("expected none to imply nofollow")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// Bodies larger than this with fewer than minExpectedElements elements are flagged as suspicious
+	suspiciousBodySize  = 4096
+	minExpectedElements = 5
+)
+
+// checkHTMLStructure returns warnings about malformed markup that html.Parse would silently repair,
+// such as unclosed <a> tags, a missing <html> element, or very few elements in a large body.
+func checkHTMLStructure(htmlBody string) []string {
+	var warnings []string
+
+	tokenizer := html.NewTokenizer(strings.NewReader(htmlBody))
+	sawHTML := false
+	openAnchors := 0
+	unclosedAnchors := 0
+	elements := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break // io.EOF or a tokenizer error; either way we're done
+		}
+		if tokenType != html.StartTagToken && tokenType != html.EndTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+
+		name, _ := tokenizer.TagName()
+		tag := string(name)
+		switch tokenType {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			elements++
+			switch tag {
+			case "html":
+				sawHTML = true
+			case "a":
+				// A new <a> while one is still open means the previous one was never closed
+				if openAnchors > 0 {
+					unclosedAnchors++
+				} else {
+					openAnchors++
+				}
+			}
+		case html.EndTagToken:
+			if tag == "a" && openAnchors > 0 {
+				openAnchors--
+			}
+		}
+	}
+	unclosedAnchors += openAnchors
+
+	if unclosedAnchors > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d unclosed <a> tag(s)", unclosedAnchors))
+	}
+	if !sawHTML {
+		warnings = append(warnings, "missing <html> element")
+	}
+	if len(htmlBody) > suspiciousBodySize && elements < minExpectedElements {
+		warnings = append(warnings, fmt.Sprintf("only %d element(s) in a %d byte body", elements, len(htmlBody)))
+	}
+
+	return warnings
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckHTMLStructureBrokenHTML(t *testing.T) {
+	inputBody := `<body><a href="/one">one<a href="/two">two</body>`
+	warnings := checkHTMLStructure(inputBody)
+
+	joined := strings.Join(warnings, "; ")
+	if !strings.Contains(joined, "unclosed <a>") {
+		t.Errorf("expected an unclosed <a> warning, got %v", warnings)
+	}
+	if !strings.Contains(joined, "missing <html>") {
+		t.Errorf("expected a missing <html> warning, got %v", warnings)
+	}
+}
+
+func TestCheckHTMLStructureFewElements(t *testing.T) {
+	inputBody := "<html>" + strings.Repeat("text ", 2000) + "</html>"
+	warnings := checkHTMLStructure(inputBody)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "element(s)") {
+		t.Errorf("expected a single suspicious element count warning, got %v", warnings)
+	}
+}
+
+func TestCheckHTMLStructureWellFormed(t *testing.T) {
+	inputBody := `<html><body><a href="/one">one</a><a href="/two">two</a></body></html>`
+	if warnings := checkHTMLStructure(inputBody); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
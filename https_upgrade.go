@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// noteHTTPSUpgrade remembers that an http request to requested ended up on https on the same host,
+// so later http links to the host can skip the redirect
+func (cfg *config) noteHTTPSUpgrade(requested *url.URL, finalURL string) {
+	if cfg.httpsHosts == nil || requested.Scheme != "http" {
+		return
+	}
+	final, err := url.Parse(finalURL)
+	if err != nil || final.Scheme != "https" || final.Hostname() != requested.Hostname() {
+		return
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	if !cfg.httpsHosts[requested.Hostname()] {
+		cfg.httpsHosts[requested.Hostname()] = true
+		fmt.Printf("%s redirects to https, fetching its http links over https\n", requested.Hostname())
+	}
+}
+
+// upgradeToHTTPS rewrites an http link to https when its host is known to redirect there
+func (cfg *config) upgradeToHTTPS(rawURL string) string {
+	if cfg.httpsHosts == nil {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "http" {
+		return rawURL
+	}
+
+	cfg.mu.Lock()
+	upgrade := cfg.httpsHosts[u.Hostname()]
+	cfg.mu.Unlock()
+	if !upgrade {
+		return rawURL
+	}
+	u.Scheme = "https"
+	// An explicit default http port would be wrong for https
+	if u.Port() == "80" {
+		u.Host = strings.TrimSuffix(u.Host, ":80")
+	}
+	return u.String()
+}
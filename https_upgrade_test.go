@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// schemeSite serves a site over both schemes, redirecting every http request to https
+type schemeSite struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (s *schemeSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req.URL.String())
+	s.mu.Unlock()
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: req}
+	switch {
+	case req.URL.Scheme == "http":
+		target := *req.URL
+		target.Scheme = "https"
+		resp.StatusCode = http.StatusMovedPermanently
+		resp.Header.Set("Location", target.String())
+	case req.URL.Path == "/":
+		resp.Header.Set("Content-Type", "text/html")
+		resp.Body = io.NopCloser(strings.NewReader(`<html><body><a href="http://example.test/a">a</a><a href="http://example.test/b">b</a></body></html>`))
+	case req.URL.Path == "/a" || req.URL.Path == "/b":
+		resp.Header.Set("Content-Type", "text/html")
+		resp.Body = io.NopCloser(strings.NewReader(`<html><body>leaf</body></html>`))
+	default:
+		resp.StatusCode = http.StatusNotFound
+	}
+	return resp, nil
+}
+
+func TestUpgradeToHTTPSAfterObservedRedirect(t *testing.T) {
+	for _, upgrade := range []bool{false, true} {
+		site := &schemeSite{}
+		cfg := newTestConfig(t, "http://example.test/", 10)
+		useTransport(cfg, site)
+		if upgrade {
+			cfg.httpsHosts = make(map[string]bool)
+		}
+		cfg.Run(time.Minute)
+
+		if len(cfg.pages) != 3 {
+			t.Errorf("upgrade=%v: expected 3 pages, got %v", upgrade, cfg.pages)
+		}
+		for _, leaf := range []string{"http://example.test/a", "http://example.test/b"} {
+			fetchedOverHTTP := false
+			for _, request := range site.requests {
+				if request == leaf {
+					fetchedOverHTTP = true
+				}
+			}
+			if fetchedOverHTTP == upgrade {
+				t.Errorf("upgrade=%v: expected %s requested over http=%v, got requests %v", upgrade, leaf, !upgrade, site.requests)
+			}
+		}
+	}
+}
+
+func TestUpgradeToHTTPS(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.httpsHosts = map[string]bool{"example.com": true}
+
+	tests := map[string]string{
+		"http://example.com/page?q=1":  "https://example.com/page?q=1",
+		"http://example.com:80/page":   "https://example.com/page",
+		"http://example.com:8080/page": "https://example.com:8080/page",
+		"http://other.com/page":        "http://other.com/page",
+		"https://example.com/page":     "https://example.com/page",
+	}
+	for input, expected := range tests {
+		if actual := cfg.upgradeToHTTPS(input); actual != expected {
+			t.Errorf("upgradeToHTTPS(%q): expected %q, got %q", input, expected, actual)
+		}
+	}
+}
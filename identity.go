@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// defaultUserAgent is sent when neither --user-agent nor --contact is given
+const defaultUserAgent = "Mozilla/5.0 (compatible; Crawler/1.0)"
+
+// crawlerIdentity is how the crawler introduces itself to the sites it visits
+type crawlerIdentity struct {
+	userAgent   string
+	from        string // contact email for the From header, empty to omit it
+	robotsToken string // name robots.txt User-agent lines and robots meta tags address the crawler by
+}
+
+// defaultIdentity is sent with requests made outside a crawl, whose context carries no identity
+var defaultIdentity = newCrawlerIdentity("", "")
+
+// newCrawlerIdentity builds the identity for --user-agent and --contact. A contact is appended to the default
+// User-Agent as "+mailto:..." (or "+URL" for a web page) and an email contact is also sent as the From header.
+// An explicit userAgent is sent unchanged.
+func newCrawlerIdentity(userAgent, contact string) crawlerIdentity {
+	id := crawlerIdentity{userAgent: userAgent}
+	contact = strings.TrimSpace(contact)
+	email := strings.TrimPrefix(contact, "mailto:")
+	isEmail := contact != "" && !strings.Contains(contact, "://") && strings.Contains(email, "@")
+	if isEmail {
+		id.from = email
+	}
+
+	if id.userAgent == "" {
+		id.userAgent = defaultUserAgent
+		switch {
+		case isEmail:
+			id.userAgent = fmt.Sprintf("Mozilla/5.0 (compatible; Crawler/1.0; +mailto:%s)", email)
+		case contact != "":
+			id.userAgent = fmt.Sprintf("Mozilla/5.0 (compatible; Crawler/1.0; +%s)", contact)
+		}
+	}
+	id.robotsToken = robotsProductToken(id.userAgent)
+	return id
+}
+
+// robotsProductToken returns the product a User-Agent names the crawler by: the one in a "(compatible; Name/1.0)"
+// comment, else the first product other than Mozilla, e.g. "Crawler" for the default User-Agent and "MyBot" for
+// "MyBot/2.0 (+https://example.com/bot)". A User-Agent naming no product falls back to "crawler".
+func robotsProductToken(userAgent string) string {
+	isSeparator := func(r rune) bool { return r == '/' || r == ';' || r == ')' || r == ' ' }
+	if _, comment, ok := strings.Cut(userAgent, "(compatible;"); ok {
+		if fields := strings.FieldsFunc(comment, isSeparator); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	depth := 0
+	for _, field := range strings.Fields(userAgent) {
+		// Skip comments, which may span several fields
+		if depth > 0 || strings.HasPrefix(field, "(") {
+			depth += strings.Count(field, "(") - strings.Count(field, ")")
+			continue
+		}
+		name, _, _ := strings.Cut(field, "/")
+		if name != "" && !strings.EqualFold(name, "Mozilla") {
+			return name
+		}
+	}
+	return "crawler"
+}
+
+// identityKey is the context key carrying the identity of the crawl a request belongs to
+type identityKey struct{}
+
+// withIdentity returns a context whose requests introduce the crawler as id
+func withIdentity(ctx context.Context, id crawlerIdentity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// identityFor returns the identity to send requests made with ctx with
+func identityFor(ctx context.Context) crawlerIdentity {
+	if id, ok := ctx.Value(identityKey{}).(crawlerIdentity); ok {
+		return id
+	}
+	return defaultIdentity
+}
+
+// userAgentKey is the context key carrying a User-Agent that replaces the identity's for one request
+type userAgentKey struct{}
+
+// withUserAgent returns a context whose requests are sent with userAgent instead of the identity's
+func withUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, userAgent)
+}
+
+// userAgentFor returns the User-Agent to send for requests made with ctx
+func userAgentFor(ctx context.Context) string {
+	if userAgent, ok := ctx.Value(userAgentKey{}).(string); ok {
+		return userAgent
+	}
+	return identityFor(ctx).userAgent
+}
+
+// setIdentityHeaders sets the User-Agent (unless the request's context overrides it) and, when there's a
+// contact email, the From header on req
+func setIdentityHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", userAgentFor(req.Context()))
+	if from := identityFor(req.Context()).from; from != "" {
+		req.Header.Set("From", from)
+	}
+}
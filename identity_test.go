@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewCrawlerIdentity(t *testing.T) {
+	tests := []struct {
+		name          string
+		userAgent     string
+		contact       string
+		expectedAgent string
+		expectedFrom  string
+	}{
+		{
+			name:          "default",
+			expectedAgent: defaultUserAgent,
+		},
+		{
+			name:          "email contact",
+			contact:       "ops@example.com",
+			expectedAgent: "Mozilla/5.0 (compatible; Crawler/1.0; +mailto:ops@example.com)",
+			expectedFrom:  "ops@example.com",
+		},
+		{
+			name:          "mailto contact",
+			contact:       "mailto:ops@example.com",
+			expectedAgent: "Mozilla/5.0 (compatible; Crawler/1.0; +mailto:ops@example.com)",
+			expectedFrom:  "ops@example.com",
+		},
+		{
+			name:          "URL contact has no From header",
+			contact:       "https://example.com/bot",
+			expectedAgent: "Mozilla/5.0 (compatible; Crawler/1.0; +https://example.com/bot)",
+		},
+		{
+			name:          "explicit user agent overrides the contact suffix",
+			userAgent:     "MyBot/2.0",
+			contact:       "ops@example.com",
+			expectedAgent: "MyBot/2.0",
+			expectedFrom:  "ops@example.com",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			id := newCrawlerIdentity(tc.userAgent, tc.contact)
+			if id.userAgent != tc.expectedAgent {
+				t.Errorf("expected User-Agent %q, got %q", tc.expectedAgent, id.userAgent)
+			}
+			if id.from != tc.expectedFrom {
+				t.Errorf("expected From %q, got %q", tc.expectedFrom, id.from)
+			}
+		})
+	}
+}
+
+func TestRequestsCarryContact(t *testing.T) {
+	ctx := withIdentity(context.Background(), newCrawlerIdentity("", "ops@example.com"))
+
+	var userAgent, from string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		from = r.Header.Get("From")
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	defer server.Close()
+
+	if _, err := fetchPage(ctx, server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if userAgent != "Mozilla/5.0 (compatible; Crawler/1.0; +mailto:ops@example.com)" {
+		t.Errorf("expected the contact in the User-Agent, got %q", userAgent)
+	}
+	if from != "ops@example.com" {
+		t.Errorf("expected From header ops@example.com, got %q", from)
+	}
+}
+
+func TestUserAgentOverriddenByContext(t *testing.T) {
+	ctx := withIdentity(context.Background(), newCrawlerIdentity("", "ops@example.com"))
+
+	var userAgent, from string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		from = r.Header.Get("From")
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	defer server.Close()
+
+	if _, err := fetchPage(withUserAgent(ctx, "MobileBot/1.0"), server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if userAgent != "MobileBot/1.0" {
+		t.Errorf("expected the context's User-Agent, got %q", userAgent)
+	}
+	if from != "ops@example.com" {
+		t.Errorf("expected the From header to be kept, got %q", from)
+	}
+}
+
+func TestRobotsProductToken(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  string
+	}{
+		{defaultUserAgent, "Crawler"},
+		{"Mozilla/5.0 (compatible; Crawler/1.0; +mailto:ops@example.com)", "Crawler"},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "Googlebot"},
+		{"MyBot/2.0 (+https://example.com/bot)", "MyBot"},
+		{"Mozilla/5.0 (X11; Linux x86_64) SiteAudit/3.1", "SiteAudit"},
+		{"Mozilla/5.0", "crawler"},
+	}
+	for _, tc := range tests {
+		if actual := robotsProductToken(tc.userAgent); actual != tc.expected {
+			t.Errorf("robotsProductToken(%q) = %q, want %q", tc.userAgent, actual, tc.expected)
+		}
+	}
+}
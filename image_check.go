@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// brokenImage is an image that failed to load and the pages referencing it
+type brokenImage struct {
+	URL    string
+	Reason string
+	Pages  []string
+}
+
+// recordImages notes the http(s) images a page references, if images are being validated
+func (cfg *config) recordImages(pageURL string, htmlBody string, base *url.URL) {
+	if cfg.imageReferrers == nil {
+		return
+	}
+	images, err := getImagesFromHTML(htmlBody, base)
+	if err != nil || len(images) == 0 {
+		return
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for _, image := range images {
+		// Inline data: images and the like have nothing to fetch
+		if scheme := strings.ToLower(strings.SplitN(image, ":", 2)[0]); scheme != "http" && scheme != "https" {
+			continue
+		}
+		if indexOf(cfg.imageReferrers[image], pageURL) < 0 {
+			cfg.imageReferrers[image] = append(cfg.imageReferrers[image], pageURL)
+		}
+	}
+}
+
+// validateImages is the second phase of --validate-images: once the crawl is done, it checks every
+// distinct image once with HEAD (falling back to GET), pacing requests like page fetches
+func (cfg *config) validateImages(ctx context.Context, timeout time.Duration) {
+	cfg.mu.Lock()
+	images := make([]string, 0, len(cfg.imageReferrers))
+	for image := range cfg.imageReferrers {
+		if _, checked := cfg.imageChecks[image]; !checked {
+			images = append(images, image)
+		}
+	}
+	cfg.mu.Unlock()
+	sort.Strings(images)
+
+	if len(images) > 0 {
+		fmt.Printf("\nValidating %d images...\n", len(images))
+	}
+
+	cfg.checkLinks(ctx, images, func(ctx context.Context, image string) externalCheck {
+		// Images usually live on the crawled host, so respect its Crawl-delay and the request delay
+		if u, err := url.Parse(image); err == nil && !cfg.ignoreRobots {
+			if err := cfg.robots.waitCrawlDelay(ctx, cfg.clock, u); err != nil {
+				return externalCheck{Error: err.Error()}
+			}
+		}
+		cfg.clock.Sleep(requestDelay)
+		return checkExternalLink(ctx, image, timeout)
+	}, func(image string, check externalCheck) {
+		cfg.imageChecks[image] = check
+	})
+}
+
+// brokenImages returns the images that failed to load, sorted by URL, each with its sorted referring pages
+func (cfg *config) brokenImages() []brokenImage {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	var broken []brokenImage
+	for image, check := range cfg.imageChecks {
+		if check.alive() {
+			continue
+		}
+		reason := check.Error
+		if reason == "" {
+			reason = fmt.Sprintf("HTTP %d", check.StatusCode)
+		}
+		pages := append([]string(nil), cfg.imageReferrers[image]...)
+		sort.Strings(pages)
+		broken = append(broken, brokenImage{URL: image, Reason: reason, Pages: pages})
+	}
+	sort.Slice(broken, func(i, j int) bool { return broken[i].URL < broken[j].URL })
+	return broken
+}
+
+// printImageReport lists the broken images grouped by the page referencing them
+func printImageReport(w io.Writer, checked int, broken []brokenImage) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  IMAGE VALIDATION")
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "Checked %d images, %d broken\n", checked, len(broken))
+
+	byPage := make(map[string][]brokenImage)
+	for _, image := range broken {
+		for _, page := range image.Pages {
+			byPage[page] = append(byPage[page], image)
+		}
+	}
+	pages := make([]string, 0, len(byPage))
+	for page := range byPage {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	for _, page := range pages {
+		fmt.Fprintf(w, "%s:\n", page)
+		for _, image := range byPage[page] {
+			fmt.Fprintf(w, "  %s: %s\n", image.URL, image.Reason)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidateImagesReportsMissingImage(t *testing.T) {
+	var imageRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><img src="/logo.png"><img src="/missing.png"><img src="data:image/gif;base64,R0lGOD"><a href="/about">about</a></body></html>`)
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><img src="/missing.png"><img src="/logo.png"></body></html>`)
+		case "/logo.png":
+			atomic.AddInt64(&imageRequests, 1)
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			if strings.HasSuffix(r.URL.Path, ".png") {
+				atomic.AddInt64(&imageRequests, 1)
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.imageReferrers = make(map[string][]string)
+	cfg.imageChecks = make(map[string]externalCheck)
+	runTestCrawl(cfg)
+	cfg.validateImages(context.Background(), time.Second)
+
+	// Each image is checked once even though both pages use it, and the data: image isn't fetched
+	if atomic.LoadInt64(&imageRequests) != 2 || len(cfg.imageChecks) != 2 {
+		t.Errorf("expected 2 image checks, got %d requests and checks %v", imageRequests, cfg.imageChecks)
+	}
+	expected := []brokenImage{{
+		URL:    server.URL + "/missing.png",
+		Reason: "HTTP 404",
+		Pages:  []string{server.URL, server.URL + "/about"},
+	}}
+	if actual := cfg.brokenImages(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected broken images %+v, got %+v", expected, actual)
+	}
+}
+
+func TestPrintImageReportGroupsByPage(t *testing.T) {
+	broken := []brokenImage{
+		{URL: "https://example.com/a.png", Reason: "HTTP 404", Pages: []string{"https://example.com/", "https://example.com/blog"}},
+		{URL: "https://cdn.example.net/b.jpg", Reason: "HTTP 500", Pages: []string{"https://example.com/blog"}},
+	}
+	var out bytes.Buffer
+	printImageReport(&out, 5, broken)
+
+	expected := `Checked 5 images, 2 broken
+https://example.com/:
+  https://example.com/a.png: HTTP 404
+https://example.com/blog:
+  https://example.com/a.png: HTTP 404
+  https://cdn.example.net/b.jpg: HTTP 500
+`
+	if !strings.HasSuffix(out.String(), expected) {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bytes read from the start of an image to find its dimensions; JPEG headers may follow large metadata
+const imageHeaderBytes = 64 * 1024
+
+// imageInfo is an image catalogued by --crawl-images-as-pages
+type imageInfo struct {
+	StatusCode  int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"` // bytes, or -1 when the server didn't say
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// fetchImageInfo records an image's content type and size with HEAD, then reads the start of it to find
+// its width and height (PNG, JPEG and GIF). Servers not supporting HEAD are asked with GET only.
+func fetchImageInfo(ctx context.Context, rawURL string, timeout time.Duration) imageInfo {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	info := imageInfo{Size: -1}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return imageInfo{Size: -1, Error: fmt.Sprintf("failed to create request: %v", err)}
+	}
+	setIdentityHeaders(req)
+	resp, err := clientFor(req).Do(req)
+	if err != nil {
+		return imageInfo{Size: -1, Error: err.Error()}
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+		info.StatusCode = resp.StatusCode
+		info.ContentType = resp.Header.Get("Content-Type")
+		if resp.StatusCode >= http.StatusBadRequest {
+			return info
+		}
+		info.Size = resp.ContentLength
+	}
+
+	// Only the header is needed, so ask for the first bytes; servers ignoring Range send it all
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		info.Error = fmt.Sprintf("failed to create request: %v", err)
+		return info
+	}
+	setIdentityHeaders(req)
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", imageHeaderBytes-1))
+	resp, err = clientFor(req).Do(req)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	defer resp.Body.Close()
+	header, err := io.ReadAll(io.LimitReader(resp.Body, imageHeaderBytes))
+	if err != nil {
+		info.Error = fmt.Sprintf("failed to read image: %v", err)
+		return info
+	}
+
+	if info.StatusCode == 0 {
+		info.StatusCode = resp.StatusCode
+		info.ContentType = resp.Header.Get("Content-Type")
+		if resp.StatusCode >= http.StatusBadRequest {
+			return info
+		}
+	}
+	if info.Size < 0 {
+		info.Size = responseTotalSize(resp)
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(header)); err == nil {
+		info.Width, info.Height = config.Width, config.Height
+	}
+	return info
+}
+
+// responseTotalSize returns the full size of the resource behind a possibly partial response, or -1
+func responseTotalSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		// Content-Range: bytes 0-65535/123456
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+				return size
+			}
+		}
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// catalogImages is the second phase of --crawl-images-as-pages: once the crawl is done, it fetches the
+// details of every distinct image once, pacing requests like page fetches
+func (cfg *config) catalogImages(ctx context.Context, timeout time.Duration) {
+	cfg.mu.Lock()
+	images := make([]string, 0, len(cfg.imageReferrers))
+	for image := range cfg.imageReferrers {
+		if _, catalogued := cfg.imageInventory[image]; !catalogued {
+			images = append(images, image)
+		}
+	}
+	cfg.mu.Unlock()
+	sort.Strings(images)
+
+	if len(images) > 0 {
+		fmt.Printf("\nCataloguing %d images...\n", len(images))
+	}
+
+	cfg.checkLinks(ctx, images, func(ctx context.Context, image string) externalCheck {
+		if u, err := url.Parse(image); err == nil && !cfg.ignoreRobots {
+			if err := cfg.robots.waitCrawlDelay(ctx, cfg.clock, u); err != nil {
+				return externalCheck{Error: err.Error()}
+			}
+		}
+		cfg.clock.Sleep(requestDelay)
+		info := fetchImageInfo(ctx, image, timeout)
+		cfg.mu.Lock()
+		cfg.imageInventory[image] = info
+		cfg.mu.Unlock()
+		return externalCheck{StatusCode: info.StatusCode, Error: info.Error}
+	}, func(image string, check externalCheck) {
+		// An image that couldn't be checked at all still belongs in the inventory
+		if _, catalogued := cfg.imageInventory[image]; !catalogued {
+			cfg.imageInventory[image] = imageInfo{Size: -1, Error: check.Error}
+		}
+	})
+}
+
+// printImageInventory lists every catalogued image with its type, size and dimensions, sorted by URL
+func printImageInventory(w io.Writer, inventory map[string]imageInfo) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  IMAGE INVENTORY")
+	fmt.Fprintln(w, "=============================")
+	if len(inventory) == 0 {
+		fmt.Fprintln(w, "No images found")
+		return
+	}
+	images := make([]string, 0, len(inventory))
+	for image := range inventory {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	var totalSize int64
+	for _, image := range images {
+		info := inventory[image]
+		if info.Error != "" {
+			fmt.Fprintf(w, "%s: %s\n", image, info.Error)
+			continue
+		}
+		if info.StatusCode >= http.StatusBadRequest {
+			fmt.Fprintf(w, "%s: HTTP %d\n", image, info.StatusCode)
+			continue
+		}
+		details := []string{info.ContentType}
+		if info.Size >= 0 {
+			details = append(details, fmt.Sprintf("%d bytes", info.Size))
+			totalSize += info.Size
+		}
+		if info.Width > 0 && info.Height > 0 {
+			details = append(details, fmt.Sprintf("%dx%d", info.Width, info.Height))
+		}
+		fmt.Fprintf(w, "%s: %s\n", image, strings.Join(details, ", "))
+	}
+	fmt.Fprintf(w, "%d images, %d bytes in total\n", len(images), totalSize)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatalogImagesRecordsDimensionsAndSize(t *testing.T) {
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><img src="/logo.png"><img src="/missing.png"><a href="/about">about</a></body></html>`)
+		case "/about":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><img src="/logo.png"></body></html>`)
+		case "/logo.png":
+			methods = append(methods, r.Method)
+			// ServeContent answers HEAD and Range requests like a static file server
+			w.Header().Set("Content-Type", "image/png")
+			http.ServeContent(w, r, "logo.png", time.Time{}, bytes.NewReader(logo.Bytes()))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.imageReferrers = make(map[string][]string)
+	cfg.imageInventory = make(map[string]imageInfo)
+	runTestCrawl(cfg)
+	cfg.catalogImages(context.Background(), time.Second)
+
+	expected := map[string]imageInfo{
+		server.URL + "/logo.png":    {StatusCode: http.StatusOK, ContentType: "image/png", Size: int64(logo.Len()), Width: 3, Height: 2},
+		server.URL + "/missing.png": {StatusCode: http.StatusNotFound, ContentType: "text/plain; charset=utf-8", Size: -1},
+	}
+	if !reflect.DeepEqual(cfg.imageInventory, expected) {
+		t.Errorf("expected inventory %+v, got %+v", expected, cfg.imageInventory)
+	}
+	// The image is catalogued once although two pages use it, and never becomes a page
+	if !reflect.DeepEqual(methods, []string{http.MethodHead, http.MethodGet}) {
+		t.Errorf("expected one HEAD and one GET for the image, got %v", methods)
+	}
+	for page := range cfg.pages {
+		if strings.HasSuffix(page, ".png") {
+			t.Errorf("expected images to stay out of the pages, got %v", cfg.pages)
+		}
+	}
+
+	var out bytes.Buffer
+	printImageInventory(&out, cfg.imageInventory)
+	if line := fmt.Sprintf("%s/logo.png: image/png, %d bytes, 3x2\n", server.URL, logo.Len()); !strings.Contains(out.String(), line) {
+		t.Errorf("expected %q in the inventory, got:\n%s", line, out.String())
+	}
+}
+
+func TestFetchImageInfoWithoutHead(t *testing.T) {
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewGray(image.Rect(0, 0, 16, 9))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		http.ServeContent(w, r, "logo.png", time.Time{}, bytes.NewReader(logo.Bytes()))
+	}))
+	defer server.Close()
+
+	info := fetchImageInfo(context.Background(), server.URL+"/logo.png", time.Second)
+	expected := imageInfo{StatusCode: http.StatusPartialContent, ContentType: "image/png", Size: int64(logo.Len()), Width: 16, Height: 9}
+	if info != expected {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// defaultRenderTimeout bounds how long a headless browser may take to load and render one page
+const defaultRenderTimeout = 30 * time.Second
+
+// jsRenderer loads a page in a headless browser and returns its HTML after scripts have run
+type jsRenderer func(ctx context.Context, rawURL string) (string, error)
+
+// startHeadlessBrowser launches the headless Chrome of one crawl, stopped when ctx is done or stop is
+// called, and returns a renderer loading each page in a tab of it. It is set only in builds with the
+// chromedp tag.
+var startHeadlessBrowser func(ctx context.Context) (render jsRenderer, stop func(), err error)
+
+// fetchRenderedPage fetches a page and, when cfg has a renderer, replaces its body with the rendered HTML.
+// The raw fetch still runs first so that status codes, redirects and content types are checked as usual,
+// and a page whose rendering fails falls back to the raw HTML.
+func (cfg *config) fetchRenderedPage(ctx context.Context, rawURL string) (*pageResponse, error) {
+	page, err := fetchPage(ctx, rawURL)
+	if err != nil || cfg.renderer == nil {
+		return page, err
+	}
+
+	renderCtx, cancel := context.WithTimeout(ctx, defaultRenderTimeout)
+	defer cancel()
+	html, err := cfg.renderer(renderCtx, page.FinalURL)
+	if err != nil {
+		cfg.logf("Warning: rendering %s failed, using the raw HTML: %v\n", rawURL, err)
+		return page, nil
+	}
+	page.Body = html
+	return page, nil
+}
+
+// stopRendering shuts down the crawl's headless browser, if it has one
+func (cfg *config) stopRendering() {
+	if cfg.stopRenderer != nil {
+		cfg.stopRenderer()
+	}
+}
@@ -0,0 +1,69 @@
+//go:build chromedp
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+func init() {
+	startHeadlessBrowser = startChrome
+}
+
+// startChrome launches headless Chrome and waits for it to start, so a missing browser fails the crawl's
+// setup rather than every page
+func startChrome(ctx context.Context) (jsRenderer, func(), error) {
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, chromedp.DefaultExecAllocatorOptions[:]...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	stop := func() {
+		cancelBrowser()
+		cancelAlloc()
+	}
+	if err := chromedp.Run(browserCtx); err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("failed to start headless Chrome: %v", err)
+	}
+
+	render := func(ctx context.Context, rawURL string) (string, error) {
+		return renderInTab(browserCtx, ctx, rawURL)
+	}
+	return render, stop, nil
+}
+
+// renderInTab loads rawURL in a new tab of the browser of browserCtx, sending the User-Agent, From and
+// Accept-Language headers and the cookies of ctx's crawl, and returns the document's outer HTML. The tab
+// is closed once the page is rendered or ctx is done.
+func renderInTab(browserCtx, ctx context.Context, rawURL string) (string, error) {
+	tabCtx, cancelTab := chromedp.NewContext(browserCtx)
+	defer cancelTab()
+	defer context.AfterFunc(ctx, cancelTab)()
+
+	actions := []chromedp.Action{
+		network.Enable(),
+		emulation.SetUserAgentOverride(userAgentFor(ctx)).WithAcceptLanguage(acceptLanguageFor(ctx)),
+	}
+	if from := identityFor(ctx).from; from != "" {
+		actions = append(actions, network.SetExtraHTTPHeaders(network.Headers{"From": from}))
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		for _, cookie := range cookiesFor(ctx, u) {
+			actions = append(actions, network.SetCookie(cookie.Name, cookie.Value).WithURL(rawURL))
+		}
+	}
+
+	var html string
+	actions = append(actions,
+		chromedp.Navigate(rawURL),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	if err := chromedp.Run(tabCtx, actions...); err != nil {
+		return "", fmt.Errorf("headless Chrome failed: %w", err)
+	}
+	return html, nil
+}
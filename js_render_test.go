@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestFetchRenderedPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>raw</body></html>")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		render       jsRenderer
+		expectedBody string
+		expectErr    bool
+		expectRender bool
+	}{
+		{
+			name:         "no renderer uses the raw HTML",
+			path:         "/",
+			expectedBody: "<html><body>raw</body></html>",
+		},
+		{
+			name: "renderer output replaces the raw HTML",
+			path: "/",
+			render: func(ctx context.Context, rawURL string) (string, error) {
+				return "<html><body>rendered</body></html>", nil
+			},
+			expectedBody: "<html><body>rendered</body></html>",
+			expectRender: true,
+		},
+		{
+			name: "render failure falls back to the raw HTML",
+			path: "/",
+			render: func(ctx context.Context, rawURL string) (string, error) {
+				return "", errors.New("browser crashed")
+			},
+			expectedBody: "<html><body>raw</body></html>",
+			expectRender: true,
+		},
+		{
+			name: "broken page is never rendered",
+			path: "/missing",
+			render: func(ctx context.Context, rawURL string) (string, error) {
+				return "<html></html>", nil
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rendered := false
+			render := tc.render
+			if render != nil {
+				render = func(ctx context.Context, rawURL string) (string, error) {
+					rendered = true
+					return tc.render(ctx, rawURL)
+				}
+			}
+
+			cfg := newTestConfig(t, server.URL, 10)
+			cfg.renderer = render
+			page, err := cfg.fetchRenderedPage(context.Background(), server.URL+tc.path)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if page.Body != tc.expectedBody {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, page.Body)
+			}
+			if rendered != tc.expectRender {
+				t.Errorf("expected rendered=%v, got %v", tc.expectRender, rendered)
+			}
+		})
+	}
+}
+
+func TestHeadlessRendererRunsScripts(t *testing.T) {
+	if startHeadlessBrowser == nil {
+		t.Skip("built without the chromedp tag")
+	}
+	if _, err := exec.LookPath("google-chrome"); err != nil {
+		if _, err := exec.LookPath("chromium"); err != nil {
+			t.Skip("no headless Chrome available")
+		}
+	}
+
+	requests := make(chan *http.Request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		requests <- r
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><script>document.body.innerHTML += '<a href="/from-js">js</a>'</script></body></html>`)
+	}))
+	defer server.Close()
+
+	render, stop, err := startHeadlessBrowser(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer stop()
+
+	// Each page gets a tab of the same browser, sent with the crawl's headers and cookies
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.identity.from = "bot@example.com"
+	cfg.acceptLanguage = "fr-FR"
+	cfg.clients = newClientPool(&http.Client{}, nil)
+	if err := ensureCookieJar(cfg.clients.base); err != nil {
+		t.Fatal(err)
+	}
+	cfg.clients.base.Jar.SetCookies(cfg.baseURL, []*http.Cookie{{Name: "session", Value: "abc"}})
+	for i := 0; i < 2; i++ {
+		html, err := render(cfg.requestContext(context.Background()), server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(html, `href="/from-js"`) {
+			t.Errorf("expected the script-inserted link in %q", html)
+		}
+
+		r := <-requests
+		if r.UserAgent() != defaultIdentity.userAgent || r.Header.Get("From") != "bot@example.com" || r.Header.Get("Accept-Language") != "fr-FR" {
+			t.Errorf("expected the crawl's identity and Accept-Language, got %v", r.Header)
+		}
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "abc" {
+			t.Errorf("expected the crawl's session cookie, got %v", r.Cookies())
+		}
+	}
+}
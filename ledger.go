@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// ledgerEntry records when a page was last fetched and the links found on it
+type ledgerEntry struct {
+	CrawledAt time.Time `json:"crawled_at"`
+	Links     []string  `json:"links"`
+}
+
+// crawlLedger is the persistent record of past crawls, keyed by normalized URL. It lets --max-age
+// skip pages fetched recently while still following the links they had.
+type crawlLedger struct {
+	mu      sync.Mutex
+	entries map[string]ledgerEntry
+	// Health of each host in the last run that crawled it, and of the hosts crawled this run
+	hosts    map[string]hostHealth
+	runHosts map[string]hostHealth
+}
+
+// crawlState is the layout of the state file. Older state files hold only the pages map.
+type crawlState struct {
+	Pages map[string]ledgerEntry `json:"pages"`
+	Hosts map[string]hostHealth  `json:"hosts,omitempty"`
+}
+
+// newCrawlLedger returns an empty ledger
+func newCrawlLedger() *crawlLedger {
+	return &crawlLedger{
+		entries:  make(map[string]ledgerEntry),
+		hosts:    make(map[string]hostHealth),
+		runHosts: make(map[string]hostHealth),
+	}
+}
+
+// loadCrawlLedger reads the ledger from a state file; a missing file yields an empty ledger
+func loadCrawlLedger(filename string) (*crawlLedger, error) {
+	ledger := newCrawlLedger()
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %v", err)
+	}
+	var state crawlState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %v", filename, err)
+	}
+	if state.Pages == nil && state.Hosts == nil {
+		// A state file from before host health was kept: the pages map on its own
+		if err := json.Unmarshal(data, &state.Pages); err != nil {
+			return nil, fmt.Errorf("failed to parse state file %s: %v", filename, err)
+		}
+	}
+	if state.Pages != nil {
+		ledger.entries = state.Pages
+	}
+	if state.Hosts != nil {
+		ledger.hosts = state.Hosts
+	}
+	return ledger, nil
+}
+
+// save writes the ledger to a state file, including entries for pages and hosts not visited this run.
+// Hosts crawled this run replace their health from earlier runs.
+func (l *crawlLedger) save(filename string) error {
+	l.mu.Lock()
+	state := crawlState{Pages: l.entries, Hosts: make(map[string]hostHealth, len(l.hosts)+len(l.runHosts))}
+	for host, health := range l.hosts {
+		state.Hosts[host] = health
+	}
+	for host, health := range l.runHosts {
+		state.Hosts[host] = health
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	l.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %v", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %v", err)
+	}
+	return nil
+}
+
+// fresh returns the links recorded for a page if it was crawled less than maxAge before now
+func (l *crawlLedger) fresh(normalizedURL string, maxAge time.Duration, now time.Time) ([]string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[normalizedURL]
+	if !ok || now.Sub(entry.CrawledAt) >= maxAge {
+		return nil, false
+	}
+	return entry.Links, true
+}
+
+// record notes that a page was fetched at now with the given links
+func (l *crawlLedger) record(normalizedURL string, links []string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[normalizedURL] = ledgerEntry{CrawledAt: now, Links: links}
+}
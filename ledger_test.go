@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxAgeSkipsRecentlyCrawledPages(t *testing.T) {
+	site := map[string][]string{
+		"/":       {"/recent", "/old"},
+		"/recent": {},
+		"/old":    {},
+		"/cached": {},
+	}
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		links, ok := site[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>")
+		for _, link := range links {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	t.Cleanup(server.Close)
+
+	normalized := func(path string) string {
+		t.Helper()
+		u, err := normalizeURL(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to normalize URL: %v", err)
+		}
+		return u
+	}
+
+	// /recent was crawled an hour ago and linked to /cached; /old is past the max age
+	now := time.Now()
+	ledger := newCrawlLedger()
+	ledger.record(normalized("/recent"), []string{server.URL + "/cached"}, now.Add(-time.Hour))
+	ledger.record(normalized("/old"), nil, now.Add(-48*time.Hour))
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.ledger = ledger
+	cfg.maxAge = 24 * time.Hour
+	runTestCrawl(cfg)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["/recent"] != 0 {
+		t.Errorf("expected recently crawled /recent not to be fetched, got %d requests", hits["/recent"])
+	}
+	if hits["/old"] != 1 {
+		t.Errorf("expected stale /old to be refetched once, got %d requests", hits["/old"])
+	}
+	if _, ok := cfg.pages[normalized("/cached")]; !ok {
+		t.Errorf("expected links recorded for /recent to be followed, got pages %v", cfg.pages)
+	}
+	if *cfg.reusedPages != 1 {
+		t.Errorf("expected 1 reused page, got %d", *cfg.reusedPages)
+	}
+	if _, fresh := ledger.fresh(normalized("/old"), cfg.maxAge, time.Now()); !fresh {
+		t.Errorf("expected /old to be recorded as freshly crawled")
+	}
+}
+
+func TestCrawlLedgerSaveAndLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	// A missing state file starts an empty ledger
+	ledger, err := loadCrawlLedger(filename)
+	if err != nil {
+		t.Fatalf("unexpected error loading missing state file: %v", err)
+	}
+	if len(ledger.entries) != 0 {
+		t.Fatalf("expected empty ledger, got %v", ledger.entries)
+	}
+
+	crawledAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ledger.record("example.com/about", []string{"https://example.com/team"}, crawledAt)
+	if err := ledger.save(filename); err != nil {
+		t.Fatalf("failed to save ledger: %v", err)
+	}
+
+	loaded, err := loadCrawlLedger(filename)
+	if err != nil {
+		t.Fatalf("failed to load ledger: %v", err)
+	}
+	links, fresh := loaded.fresh("example.com/about", time.Hour, crawledAt.Add(30*time.Minute))
+	if !fresh || len(links) != 1 || links[0] != "https://example.com/team" {
+		t.Errorf("expected fresh entry with recorded links, got fresh=%v links=%v", fresh, links)
+	}
+	if _, fresh := loaded.fresh("example.com/about", time.Hour, crawledAt.Add(2*time.Hour)); fresh {
+		t.Errorf("expected entry older than max age not to be fresh")
+	}
+}
+
+func TestCrawlLedgerHostHealth(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	// State files from before host health was kept hold only the pages
+	legacy := `{"example.com/about": {"crawled_at": "2024-05-01T12:00:00Z", "links": ["https://example.com/team"]}}`
+	if err := os.WriteFile(filename, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+	ledger, err := loadCrawlLedger(filename)
+	if err != nil {
+		t.Fatalf("failed to load legacy state: %v", err)
+	}
+	if _, ok := ledger.entries["example.com/about"]; !ok {
+		t.Fatalf("expected legacy pages to be loaded, got %v", ledger.entries)
+	}
+
+	crawledAt := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	ledger.hosts["old.example.com"] = hostHealth{Pages: 4, Failures: 4, CrawledAt: crawledAt.Add(-24 * time.Hour)}
+	ledger.hosts["example.com"] = hostHealth{Pages: 2, Failures: 2, CrawledAt: crawledAt.Add(-24 * time.Hour)}
+	ledger.recordHost("Example.com", true, crawledAt)
+	ledger.recordHost("example.com", false, crawledAt)
+	ledger.recordHost("example.com", true, crawledAt)
+	if err := ledger.save(filename); err != nil {
+		t.Fatalf("failed to save ledger: %v", err)
+	}
+
+	loaded, err := loadCrawlLedger(filename)
+	if err != nil {
+		t.Fatalf("failed to load ledger: %v", err)
+	}
+	expected := map[string]hostHealth{
+		"example.com":     {Pages: 3, Failures: 1, CrawledAt: crawledAt},
+		"old.example.com": {Pages: 4, Failures: 4, CrawledAt: crawledAt.Add(-24 * time.Hour)},
+	}
+	if !reflect.DeepEqual(loaded.hosts, expected) {
+		t.Errorf("expected hosts %v, got %v", expected, loaded.hosts)
+	}
+	if _, ok := loaded.entries["example.com/about"]; !ok {
+		t.Errorf("expected pages to be kept, got %v", loaded.entries)
+	}
+	if flaky := loaded.flakyHosts(); !reflect.DeepEqual(flaky, []string{"example.com", "old.example.com"}) {
+		t.Errorf("expected both hosts to be flaky, got %v", flaky)
+	}
+}
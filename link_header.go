@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// headerLink is one link from an HTTP Link response header (RFC 8288)
+type headerLink struct {
+	URL  string   // target resolved against the response URL
+	Rels []string // lowercased relation types
+}
+
+// parseLinkHeader parses the values of Link response headers such as
+// `</page/2>; rel="next", <https://example.com/a>; rel=canonical`, resolving targets against base.
+// Links with a malformed target are skipped.
+func parseLinkHeader(values []string, base *url.URL) []headerLink {
+	var links []headerLink
+	for _, value := range values {
+		for rest := value; ; {
+			open := strings.Index(rest, "<")
+			if open == -1 {
+				break
+			}
+			end := strings.Index(rest[open:], ">")
+			if end == -1 {
+				break
+			}
+			target := strings.TrimSpace(rest[open+1 : open+end])
+			params, remaining := splitLinkParams(rest[open+end+1:])
+			rest = remaining
+
+			parsed, err := url.Parse(target)
+			if err != nil {
+				continue
+			}
+			link := headerLink{URL: base.ResolveReference(parsed).String()}
+			for _, param := range params {
+				name, value, _ := strings.Cut(param, "=")
+				if strings.EqualFold(strings.TrimSpace(name), "rel") {
+					link.Rels = strings.Fields(strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`)))
+				}
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// splitLinkParams splits the ";"-separated parameters following a link target up to the "," that
+// ends the link, ignoring separators inside quoted values. It returns the parameters and the rest of the header.
+func splitLinkParams(s string) (params []string, rest string) {
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ';', ',':
+			if inQuotes {
+				continue
+			}
+			if param := strings.TrimSpace(s[start:i]); param != "" {
+				params = append(params, param)
+			}
+			start = i + 1
+			if s[i] == ',' {
+				return params, s[i+1:]
+			}
+		}
+	}
+	if param := strings.TrimSpace(s[start:]); param != "" {
+		params = append(params, param)
+	}
+	return params, ""
+}
+
+// headerLinkTarget returns the target of the first link with relation rel, or "" if there is none
+func headerLinkTarget(links []headerLink, rel string) string {
+	for _, link := range links {
+		for _, linkRel := range link.Rels {
+			if linkRel == rel {
+				return link.URL
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	base, _ := url.Parse("https://example.com/articles/page/1")
+
+	tests := []struct {
+		name     string
+		values   []string
+		expected []headerLink
+	}{
+		{
+			name:   "relative target resolved against the response URL",
+			values: []string{`</articles/page/2>; rel="next"`},
+			expected: []headerLink{
+				{URL: "https://example.com/articles/page/2", Rels: []string{"next"}},
+			},
+		},
+		{
+			name:   "several links in one header and across headers",
+			values: []string{`<https://example.com/a>; rel=canonical, <2>; rel="next"; title="Page, two"`, `</style.css>; rel=preload; as=style`},
+			expected: []headerLink{
+				{URL: "https://example.com/a", Rels: []string{"canonical"}},
+				{URL: "https://example.com/articles/page/2", Rels: []string{"next"}},
+				{URL: "https://example.com/style.css", Rels: []string{"preload"}},
+			},
+		},
+		{
+			name:   "space-separated relations lowercased",
+			values: []string{`</start>; REL="Prev First"`},
+			expected: []headerLink{
+				{URL: "https://example.com/start", Rels: []string{"prev", "first"}},
+			},
+		},
+		{
+			name:     "malformed headers ignored",
+			values:   []string{`no target here`, `</unterminated; rel=next`},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := parseLinkHeader(tc.values, base)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlPageFollowsLinkHeaderNext(t *testing.T) {
+	var page2Requested bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			// The next page is only declared in the Link header, never linked from the HTML
+			w.Header().Set("Link", `</page/2>; rel="next"`)
+			fmt.Fprint(w, `<html><body>page 1</body></html>`)
+		case "/page/2":
+			page2Requested = true
+			fmt.Fprint(w, `<html><body>page 2</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	if !page2Requested {
+		t.Fatalf("expected the Link header's next page to be crawled, got pages %v", cfg.pages)
+	}
+	if len(cfg.nextLinks) != 1 {
+		t.Errorf("expected the next link to be tracked for pagination gaps, got %v", cfg.nextLinks)
+	}
+}
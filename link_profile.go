@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+)
+
+// Share of external outbound links above which a page is reported
+const highExternalLinkRatio = 0.5
+
+// linkProfile classifies a page's outbound links
+type linkProfile struct {
+	SelfLinks     int `json:"self_links"`     // links back to the page itself after normalization
+	InternalLinks int `json:"internal_links"` // links to other pages on the same host
+	ExternalLinks int `json:"external_links"`
+}
+
+// externalRatio is the share of outbound links pointing to other hosts, 0 for a page without links
+func (p linkProfile) externalRatio() float64 {
+	total := p.SelfLinks + p.InternalLinks + p.ExternalLinks
+	if total == 0 {
+		return 0
+	}
+	return float64(p.ExternalLinks) / float64(total)
+}
+
+// newLinkProfile classifies links found on the page at pageURL. Links that fail to parse are ignored.
+func newLinkProfile(pageURL string, links []string, normalize func(string) (string, error)) linkProfile {
+	var profile linkProfile
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return profile
+	}
+	self, err := normalize(pageURL)
+	if err != nil {
+		return profile
+	}
+
+	for _, link := range links {
+		parsed, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		if parsed.Hostname() != page.Hostname() {
+			profile.ExternalLinks++
+			continue
+		}
+		if normalized, err := normalize(link); err == nil && normalized == self {
+			profile.SelfLinks++
+		} else {
+			profile.InternalLinks++
+		}
+	}
+	return profile
+}
+
+// printLinkProfileReport lists pages linking to themselves and pages whose outbound links are mostly external
+func printLinkProfileReport(w io.Writer, profiles map[string]linkProfile) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  LINK PROFILE")
+	fmt.Fprintln(w, "=============================")
+
+	pages := make([]string, 0, len(profiles))
+	for page := range profiles {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	var selfLinking, mostlyExternal []string
+	for _, page := range pages {
+		if profiles[page].SelfLinks > 0 {
+			selfLinking = append(selfLinking, page)
+		}
+		if profiles[page].externalRatio() > highExternalLinkRatio {
+			mostlyExternal = append(mostlyExternal, page)
+		}
+	}
+
+	fmt.Fprintf(w, "Pages linking to themselves: %d\n", len(selfLinking))
+	for _, page := range selfLinking {
+		fmt.Fprintf(w, "  %s (%d self-links)\n", page, profiles[page].SelfLinks)
+	}
+	fmt.Fprintf(w, "Pages with over %.0f%% external links: %d\n", highExternalLinkRatio*100, len(mostlyExternal))
+	for _, page := range mostlyExternal {
+		profile := profiles[page]
+		fmt.Fprintf(w, "  %s (%d internal, %d external)\n", page, profile.SelfLinks+profile.InternalLinks, profile.ExternalLinks)
+	}
+}
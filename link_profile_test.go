@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewLinkProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageURL  string
+		links    []string
+		expected linkProfile
+	}{
+		{
+			name:    "self-links after normalization",
+			pageURL: "https://example.com/blog",
+			links: []string{
+				"https://example.com/blog",
+				"https://example.com/blog/",
+				"https://www.example.com/blog",
+				"https://example.com/blog?utm_source=nav",
+				"https://example.com/about",
+				"https://other.com/",
+			},
+			// Like the crawler, a www. host counts as another host
+			expected: linkProfile{SelfLinks: 3, InternalLinks: 1, ExternalLinks: 2},
+		},
+		{
+			name:     "no links",
+			pageURL:  "https://example.com/",
+			expected: linkProfile{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := newLinkProfile(tc.pageURL, tc.links, normalizeURL)
+			if actual != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlFlagsSelfLinkingPage(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/", "/about", "https://a.example.com/", "https://b.example.com/", "https://c.example.com/"},
+		"/about": {"/"},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.linkProfiles = make(map[string]linkProfile)
+	runTestCrawl(cfg)
+
+	seed := cfg.linkProfiles[server.URL]
+	if seed != (linkProfile{SelfLinks: 1, InternalLinks: 1, ExternalLinks: 3}) {
+		t.Errorf("unexpected profile for the seed page: %+v", seed)
+	}
+
+	var out strings.Builder
+	printLinkProfileReport(&out, cfg.linkProfiles)
+	report := out.String()
+	for _, expected := range []string{
+		"Pages linking to themselves: 1\n  " + server.URL + " (1 self-links)",
+		"Pages with over 50% external links: 1\n  " + server.URL + " (2 internal, 3 external)",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "/about (") {
+		t.Errorf("expected /about not to be flagged, got:\n%s", report)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// How often --live-report redraws the top pages
+	liveReportInterval = 2 * time.Second
+	// Pages shown by --live-report
+	liveReportTopN = 10
+)
+
+// liveReport redraws the most linked pages in place while a crawl runs
+type liveReport struct {
+	w     io.Writer
+	lines int // lines drawn by the previous frame, erased before the next one
+}
+
+// logf prints a line about the page being crawled, unless --live-report is redrawing over the terminal,
+// where the line would be erased by the next frame
+func (cfg *config) logf(format string, args ...any) {
+	if cfg.quietPages {
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// snapshotPages copies the crawled pages under the lock so they can be read while the crawl goes on
+func (cfg *config) snapshotPages() map[string]int {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	pages := make(map[string]int, len(cfg.pages))
+	for page, count := range cfg.pages {
+		pages[page] = count
+	}
+	return pages
+}
+
+// draw replaces the previous frame with the top pages of pages, most linked first
+func (r *liveReport) draw(pages map[string]int, requests int64) {
+	r.clear()
+	list := make([]Page, 0, len(pages))
+	for page, count := range pages {
+		list = append(list, Page{URL: page, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].URL < list[j].URL
+	})
+	if len(list) > liveReportTopN {
+		list = list[:liveReportTopN]
+	}
+
+	fmt.Fprintf(r.w, "--- live: %d pages, %d requests ---\n", len(pages), requests)
+	for _, page := range list {
+		fmt.Fprintf(r.w, "%5d  %s\n", page.Count, page.URL)
+	}
+	r.lines = len(list) + 1
+}
+
+// clear erases the previous frame by moving the cursor up over it and clearing to the end of the screen
+func (r *liveReport) clear() {
+	if r.lines > 0 {
+		fmt.Fprintf(r.w, "\x1b[%dA\x1b[J", r.lines)
+		r.lines = 0
+	}
+}
+
+// startLiveReport redraws the top pages to w every interval until the returned stop function is called.
+// stop erases the last frame and returns only once drawing has finished, so the final report is
+// printed on a clean screen.
+func (cfg *config) startLiveReport(w io.Writer, interval time.Duration) (stop func()) {
+	report := &liveReport{w: w}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				report.clear()
+				return
+			case <-cfg.clock.After(interval):
+				report.draw(cfg.snapshotPages(), atomic.LoadInt64(cfg.totalRequests))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
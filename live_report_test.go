@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the live report goroutine while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLiveReportDrawsWhileCrawlWrites(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 100)
+	var out syncBuffer
+	stop := cfg.startLiveReport(&out, time.Millisecond)
+
+	// Keep updating the shared map as crawling goroutines do; run with -race to catch unguarded reads
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				cfg.mu.Lock()
+				cfg.pages[fmt.Sprintf("example.com/%d/%d", worker, i%20)]++
+				cfg.mu.Unlock()
+				time.Sleep(10 * time.Microsecond)
+			}
+		}(worker)
+	}
+	wg.Wait()
+	time.Sleep(5 * time.Millisecond)
+	stop()
+	stop() // stopping twice is harmless
+
+	output := out.String()
+	if !strings.Contains(output, "--- live:") {
+		t.Fatalf("expected at least one live frame, got %q", output)
+	}
+	if !strings.HasSuffix(output, "\x1b[J") {
+		t.Errorf("expected the last frame to be erased on stop, got %q", output[len(output)-40:])
+	}
+	if len(cfg.pages) != 80 {
+		t.Errorf("expected the live report to leave the pages untouched, got %d pages", len(cfg.pages))
+	}
+}
+
+func TestLiveReportDrawShowsTopPages(t *testing.T) {
+	var out bytes.Buffer
+	report := &liveReport{w: &out}
+	pages := map[string]int{"example.com/a": 1, "example.com/b": 3}
+	for i := 0; i < 20; i++ {
+		pages[fmt.Sprintf("example.com/other/%02d", i)] = 1
+	}
+	report.draw(pages, 25)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != liveReportTopN+1 || report.lines != len(lines) {
+		t.Fatalf("expected a header and %d pages, got %d lines (recorded %d)", liveReportTopN, len(lines), report.lines)
+	}
+	if lines[0] != "--- live: 22 pages, 25 requests ---" || !strings.HasSuffix(lines[1], "example.com/b") {
+		t.Errorf("unexpected frame start: %q", lines[:2])
+	}
+
+	// The next frame starts by moving back over the previous one
+	out.Reset()
+	report.draw(pages, 26)
+	if !strings.HasPrefix(out.String(), fmt.Sprintf("\x1b[%dA\x1b[J", liveReportTopN+1)) {
+		t.Errorf("expected the previous frame to be erased, got %q", out.String()[:10])
+	}
+}
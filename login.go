@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// loginOptions describes a login form to submit before crawling
+type loginOptions struct {
+	url           string // form action the credentials are POSTed to
+	userField     string
+	passwordField string
+	user          string
+	password      string
+	csrfField     string // hidden input copied from the login page before posting (empty to skip)
+}
+
+// login submits the login form so the session cookie it sets is carried by client's cookie jar
+// into every later request. A cookie jar is attached to client if it doesn't have one.
+func login(ctx context.Context, client *http.Client, opts loginOptions) error {
+	loginURL, err := url.Parse(opts.url)
+	if err != nil {
+		return fmt.Errorf("invalid login URL: %v", err)
+	}
+
+	if err := ensureCookieJar(client); err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set(opts.userField, opts.user)
+	form.Set(opts.passwordField, opts.password)
+
+	// Load the login page first to pick up its CSRF token (and any pre-session cookie)
+	if opts.csrfField != "" {
+		token, err := fetchCSRFToken(ctx, client, opts.url, opts.csrfField)
+		if err != nil {
+			return err
+		}
+		form.Set(opts.csrfField, token)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", opts.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create login request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setIdentityHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login rejected: HTTP %s", resp.Status)
+	}
+
+	if len(client.Jar.Cookies(loginURL)) == 0 {
+		return fmt.Errorf("login to %s did not set a session cookie", opts.url)
+	}
+	return nil
+}
+
+// fetchCSRFToken loads the login page and returns the value of the named form input
+func fetchCSRFToken(ctx context.Context, client *http.Client, loginURL, field string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", loginURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create login page request: %v", err)
+	}
+	setIdentityHeaders(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to load login page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("failed to load login page: HTTP %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read login page: %v", err)
+	}
+
+	token, found := getInputValueFromHTML(string(body), field)
+	if !found {
+		return "", fmt.Errorf("login page has no %q field", field)
+	}
+	return token, nil
+}
+
+// getInputValueFromHTML returns the value of the first <input> with the given name
+func getInputValueFromHTML(html, name string) (string, bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "", false
+	}
+	var value string
+	found := false
+	doc.Find("input[name]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if inputName, _ := s.Attr("name"); inputName == name {
+			value, _ = s.Attr("value")
+			found = true
+			return false
+		}
+		return true
+	})
+	return value, found
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newLoginTestServer serves a login form protected by a CSRF token; every other page requires the
+// session cookie the form sets
+func newLoginTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	const csrfToken = "token-123"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Method == "GET" {
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprintf(w, `<html><body><form method="post"><input type="hidden" name="csrf_token" value="%s">`+
+					`<input name="username"><input name="password" type="password"></form></body></html>`, csrfToken)
+				return
+			}
+			if r.FormValue("csrf_token") != csrfToken || r.FormValue("username") != "alice" || r.FormValue("password") != "secret" {
+				http.Error(w, "bad credentials", http.StatusForbidden)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/robots.txt":
+			http.NotFound(w, r)
+		default:
+			if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "ok" {
+				http.Error(w, "login required", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			if r.URL.Path == "/" {
+				fmt.Fprint(w, `<html><body><a href="/private">private</a></body></html>`)
+			} else {
+				fmt.Fprint(w, `<html><body>secret stuff</body></html>`)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// useTestCookieJar removes any cookie jar login attaches to the shared client once the test ends
+func useTestCookieJar(t *testing.T) {
+	t.Helper()
+	previous := httpClient.Jar
+	t.Cleanup(func() { httpClient.Jar = previous })
+}
+
+func TestLoginEnablesCrawlOfProtectedPages(t *testing.T) {
+	server := newLoginTestServer(t)
+	useTestCookieJar(t)
+
+	opts := loginOptions{
+		url:           server.URL + "/login",
+		userField:     "username",
+		passwordField: "password",
+		user:          "alice",
+		password:      "secret",
+		csrfField:     "csrf_token",
+	}
+	if err := login(context.Background(), httpClient, opts); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	if len(cfg.brokenLinks) != 0 {
+		t.Errorf("expected no broken links after login, got %v", cfg.brokenLinks)
+	}
+	for _, path := range []string{"", "/private"} {
+		normalized, err := normalizeURL(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to normalize URL: %v", err)
+		}
+		if _, ok := cfg.pages[normalized]; !ok {
+			t.Errorf("expected %s to be crawled, got %v", normalized, cfg.pages)
+		}
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	server := newLoginTestServer(t)
+
+	tests := []struct {
+		name string
+		opts loginOptions
+	}{
+		{
+			name: "wrong password",
+			opts: loginOptions{url: server.URL + "/login", userField: "username", passwordField: "password", user: "alice", password: "wrong", csrfField: "csrf_token"},
+		},
+		{
+			name: "missing CSRF token",
+			opts: loginOptions{url: server.URL + "/login", userField: "username", passwordField: "password", user: "alice", password: "secret"},
+		},
+		{
+			name: "CSRF field not on login page",
+			opts: loginOptions{url: server.URL + "/login", userField: "username", passwordField: "password", user: "alice", password: "secret", csrfField: "authenticity_token"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{}
+			if err := login(context.Background(), client, tc.opts); err == nil {
+				t.Errorf("expected login to fail")
+			}
+		})
+	}
+}
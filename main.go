@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
@@ -17,16 +20,45 @@ import (
 
 // Page represents a page with its URL and count for sorting
 type Page struct {
-	URL   string
-	Count int
+	URL    string
+	Count  int
+	Depth  int // link depth from the base URL, when tracked
+	Status int // HTTP status of the last fetch, 0 when not tracked
 }
 
-// printReport sorts and prints the crawl results in a formatted report
-func printReport(pages map[string]int, externalLinks map[string]int, baseURL string) error {
-	fmt.Println()
-	fmt.Println("=============================")
-	fmt.Printf("  REPORT for %s\n", baseURL)
-	fmt.Println("=============================")
+// fullPageURL reconstructs a page's full URL from its normalized form using the base URL's scheme,
+// preferring the canonical form confirmed by a redirect when there is one
+func fullPageURL(normalizedURL string, parsedBaseURL *url.URL, canonicalURLs map[string]string) string {
+	if canonical, ok := canonicalURLs[normalizedURL]; ok {
+		return canonical
+	}
+
+	// Split normalized URL to get host, path and, when queries are kept, the query
+	normalizedURL, query, _ := strings.Cut(normalizedURL, "?")
+	parts := strings.SplitN(normalizedURL, "/", 2)
+	host := parts[0]
+	path := ""
+	if len(parts) > 1 {
+		path = "/" + parts[1]
+	}
+
+	// Create full URL using the original scheme and port from base URL
+	fullURL := &url.URL{
+		Scheme:   parsedBaseURL.Scheme,
+		Host:     host,
+		Path:     path,
+		RawQuery: query,
+	}
+	return fullURL.String()
+}
+
+// printReport sorts and prints the crawl results in a formatted report.
+// canonicalURLs optionally maps normalized URLs to the form confirmed by a redirect, which is shown instead.
+func printReport(w io.Writer, pages map[string]int, externalLinks map[string]int, canonicalURLs map[string]string, baseURL string, style reportStyle) error {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, style.paint(ansiBold, "============================="))
+	fmt.Fprintln(w, style.paint(ansiBold, fmt.Sprintf("  REPORT for %s", baseURL)))
+	fmt.Fprintln(w, style.paint(ansiBold, "============================="))
 
 	// Parse the baseURL to get the original scheme
 	parsedBaseURL, err := url.Parse(baseURL)
@@ -34,82 +66,182 @@ func printReport(pages map[string]int, externalLinks map[string]int, baseURL str
 		return fmt.Errorf("error parsing base URL: %v", err)
 	}
 
+	// Monitoring only needs the size of each section
+	if style.summaryOnly {
+		fmt.Fprintf(w, "Internal pages: %d\n", len(pages))
+		fmt.Fprintf(w, "External links: %d\n", len(externalLinks))
+		return nil
+	}
+
 	// Convert map to slice of structs for sorting
 	var pageList []Page
 	for normalizedURL, count := range pages {
-		// Reconstruct full URL from normalized URL using the parsed base URL
-		// Split normalized URL to get host and path
-		parts := strings.SplitN(normalizedURL, "/", 2)
-		host := parts[0]
-		path := ""
-		if len(parts) > 1 {
-			path = "/" + parts[1]
-		}
-
-		// Create full URL using the original scheme and port from base URL
-		fullURL := &url.URL{
-			Scheme: parsedBaseURL.Scheme,
-			Host:   host,
-			Path:   path,
-		}
-		pageList = append(pageList, Page{URL: fullURL.String(), Count: count})
+		pageList = append(pageList, Page{
+			URL:    fullPageURL(normalizedURL, parsedBaseURL, canonicalURLs),
+			Count:  count,
+			Depth:  style.sort.depths[normalizedURL],
+			Status: style.sort.statuses[normalizedURL],
+		})
 	}
 
-	// Sort by count (descending), then by URL (ascending) for ties
-	sort.Slice(pageList, func(i, j int) bool {
-		if pageList[i].Count != pageList[j].Count {
-			return pageList[i].Count > pageList[j].Count // Higher counts first
-		}
-		return pageList[i].URL < pageList[j].URL // Alphabetical for ties
-	})
+	// Sort by the selected key (count, descending, by default), then by URL (ascending) for ties
+	sort.Slice(pageList, style.sort.less(pageList))
 
-	// Print each internal page
+	// Print each internal page, up to the top N
+	pageList, morePages := style.truncate(pageList)
+	width := style.countWidth(pageList)
 	for _, page := range pageList {
-		fmt.Printf("Found %d internal links to %s\n", page.Count, page.URL)
+		count := style.paint(ansiBold+ansiCyan, fmt.Sprintf("%*d", width, page.Count))
+		fmt.Fprintf(w, "Found %s internal links to %s\n", count, page.URL)
+	}
+	if morePages > 0 {
+		fmt.Fprintf(w, "(… and %d more)\n", morePages)
 	}
 
 	// Print external links summary
-	fmt.Println()
-	fmt.Println("-----------------------------")
-	fmt.Println("  EXTERNAL LINKS REPORT")
-	fmt.Println("-----------------------------")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, style.paint(ansiDim, "-----------------------------"))
+	fmt.Fprintln(w, style.paint(ansiDim, "  EXTERNAL LINKS REPORT"))
+	fmt.Fprintln(w, style.paint(ansiDim, "-----------------------------"))
 	// Convert externalLinks map to slice for sorting
 	var externalList []Page
 	for url, count := range externalLinks {
 		externalList = append(externalList, Page{URL: url, Count: count})
 	}
-	sort.Slice(externalList, func(i, j int) bool {
-		if externalList[i].Count != externalList[j].Count {
-			return externalList[i].Count > externalList[j].Count
-		}
-		return externalList[i].URL < externalList[j].URL
-	})
+	// External links have no depth or status, so those keys keep the default order
+	externalSort := style.sort
+	if externalSort.pageKeyed() {
+		externalSort = reportSort{}
+	}
+	sort.Slice(externalList, externalSort.less(externalList))
+	externalList, moreExternal := style.truncate(externalList)
+	width = style.countWidth(externalList)
 	for _, ext := range externalList {
-		fmt.Printf("Found %d external links to %s\n", ext.Count, ext.URL)
+		fmt.Fprintln(w, style.paint(ansiDim, fmt.Sprintf("Found %*d external links to %s", width, ext.Count, ext.URL)))
+	}
+	if moreExternal > 0 {
+		fmt.Fprintln(w, style.paint(ansiDim, fmt.Sprintf("(… and %d more)", moreExternal)))
 	}
 
 	return nil
 }
 
 // printCrawlStatistics prints crawling statistics and performance metrics
-func printCrawlStatistics(cfg *config) {
-	totalReqs := atomic.LoadInt64(cfg.totalRequests)
-	failedReqs := atomic.LoadInt64(cfg.failedRequests)
-
+func printCrawlStatistics(cfg *config, summary Summary) {
 	fmt.Println()
 	fmt.Println("=============================")
 	fmt.Println("  CRAWLING STATISTICS")
 	fmt.Println("=============================")
-	fmt.Printf("Total HTTP requests: %d\n", totalReqs)
-	fmt.Printf("Failed HTTP requests: %d\n", failedReqs)
+	fmt.Printf("Pages attempted: %d\n", summary.TotalRequests)
+	fmt.Printf("Failed pages: %d\n", summary.FailedRequests)
+	fmt.Printf("HTTP attempts including retries: %d\n", summary.TotalAttempts)
+
+	if summary.TotalRequests > 0 {
+		fmt.Printf("Success rate: %.1f%%\n", summary.SuccessRate)
+		fmt.Printf("Average attempts per page: %.2f\n", summary.AttemptsPerPage)
+	}
+	fmt.Printf("Crawl duration: %v\n", summary.Duration.Round(time.Millisecond))
+	fmt.Printf("Request rate: %.2f requests/second\n", summary.RequestsPerSecond)
+
+	if cfg.visited != nil {
+		fmt.Printf("Unique pages discovered: %d (approximate, bloom filter)\n", cfg.visited.Len())
+	} else {
+		fmt.Printf("Unique pages discovered: %d\n", len(summary.Pages))
+	}
+	fmt.Printf("External links found: %d\n", len(summary.ExternalLinks))
+	if cfg.maxExternal > 0 {
+		cfg.mu.Lock()
+		fmt.Printf("External links truncated: %d (max %d distinct)\n", cfg.truncatedExternalLinks, cfg.maxExternal)
+		cfg.mu.Unlock()
+	}
+	fmt.Printf("Bytes downloaded: %d\n", summary.BytesDownloaded)
+	cfg.mu.Lock()
+	if len(cfg.skipReasons) > 0 {
+		printSkipReasons(cfg.skipReasons)
+	}
+	if len(cfg.failureKinds) > 0 {
+		printFailureKinds(cfg.failureKinds)
+	}
+	if len(cfg.timeLimitedHosts) > 0 {
+		printTimeLimitedHosts(cfg.timeLimitedHosts)
+	}
+	cfg.mu.Unlock()
+	if cfg.frontier != nil {
+		fmt.Printf("Peak queued links: %d (max %d)\n", atomic.LoadInt64(cfg.peakQueueSize), cap(cfg.frontier))
+		if cfg.queuePolicy == queuePolicyDrop {
+			fmt.Printf("Links dropped by full queue: %d\n", atomic.LoadInt64(cfg.droppedLinks))
+		}
+	}
+	if cfg.ledger != nil && cfg.maxAge > 0 {
+		fmt.Printf("Pages reused from the crawl ledger: %d\n", atomic.LoadInt64(cfg.reusedPages))
+	}
+	if cfg.tracer != nil {
+		printTraceStatistics(cfg.tracer)
+	}
+	if cfg.collectHTMLWarnings {
+		fmt.Printf("Malformed HTML warnings: %d\n", atomic.LoadInt64(cfg.htmlWarnings))
+	}
+
+	// Show how many pages were first found at each depth
+	cfg.mu.Lock()
+	if len(cfg.depthCounts) > 0 {
+		depths := make([]int, 0, len(cfg.depthCounts))
+		for depth := range cfg.depthCounts {
+			depths = append(depths, depth)
+		}
+		sort.Ints(depths)
+
+		fmt.Println("\nPages by depth:")
+		for _, depth := range depths {
+			fmt.Printf("  %d: %d\n", depth, cfg.depthCounts[depth])
+		}
+	}
+
+	if len(cfg.canonicalURLs) > 0 {
+		fmt.Printf("Trailing-slash redirects folded into one page: %d\n", len(cfg.canonicalURLs))
+	}
+
+	// Show links skipped because of their URL scheme
+	if len(cfg.skippedSchemes) > 0 {
+		schemes := make([]string, 0, len(cfg.skippedSchemes))
+		for scheme := range cfg.skippedSchemes {
+			schemes = append(schemes, scheme)
+		}
+		sort.Strings(schemes)
+
+		fmt.Println("\nLinks skipped by scheme:")
+		for _, scheme := range schemes {
+			fmt.Printf("  %s: %d\n", scheme, cfg.skippedSchemes[scheme])
+		}
+	}
+
+	// Show redirects to other hosts that were recorded instead of followed
+	if len(cfg.externalRedirects) > 0 {
+		targets := make([]string, 0, len(cfg.externalRedirects))
+		for target := range cfg.externalRedirects {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
 
-	if totalReqs > 0 {
-		successRate := float64(totalReqs-failedReqs) / float64(totalReqs) * 100
-		fmt.Printf("Success rate: %.1f%%\n", successRate)
+		fmt.Println("\nExternal redirects not followed:")
+		for _, target := range targets {
+			fmt.Printf("  %s -> %s\n", cfg.externalRedirects[target], target)
+		}
 	}
 
-	fmt.Printf("Unique pages discovered: %d\n", len(cfg.pages))
-	fmt.Printf("External links found: %d\n", len(cfg.externalLinks))
+	if len(cfg.tlsInfo) > 0 {
+		fmt.Println()
+		printTLSReport(os.Stdout, cfg.tlsInfo, cfg.certExpiryWindow, time.Now())
+	}
+	cfg.mu.Unlock()
+
+	// Show paginated series that break off at a page that couldn't be fetched
+	if gaps := cfg.paginationGaps(); len(gaps) > 0 {
+		fmt.Println("\nPagination gaps:")
+		for _, gap := range gaps {
+			fmt.Printf("  %s -> next %s: %s\n", gap.Page, gap.Next, gap.Reason)
+		}
+	}
 
 	// Show error summary per host
 	cfg.hostErrorsMu.RLock()
@@ -127,36 +259,492 @@ func printCrawlStatistics(cfg *config) {
 	cfg.hostErrorsMu.RUnlock()
 }
 
+// printUsage prints the command line usage, including every supported flag
+func printUsage() {
+	fmt.Println("Usage: crawler <URL> [max_concurrency] [max_pages] [batch_size] [flags]")
+	fmt.Println("  URL: The website URL to crawl")
+	fmt.Println("  max_concurrency: Maximum number of concurrent goroutines (default: 10)")
+	fmt.Println("  max_pages: Maximum number of pages to crawl (default: 10)")
+	fmt.Println("  batch_size: Number of URLs to process in each batch (default: 5)")
+	fmt.Println("Flags:")
+	fmt.Println("  --graph: Generate a graph visualization (saves as graph.png)")
+	fmt.Println("  --graph-format F: Graph output format: png, dot (graph.dot) or graphml (graph.graphml)")
+	fmt.Println("  --graph-layout L: PNG graph layout: circle (default) or force (force-directed)")
+	fmt.Println("  --graph-layout-grid: Approximate the force layout with a spatial grid for very large graphs")
+	fmt.Println("  --output F: Report format: text (default) or json (saves as report.json)")
+	fmt.Println("  --out-dir DIR: Write output files to DIR, named after the host (e.g. DIR/example.com-report.json)")
+	fmt.Println("  --seeds FILE: Crawl each URL in FILE (one per line) as its own site, writing a JSON report per seed")
+	fmt.Println("  --parallel-seeds N: Crawl up to N seeds from --seeds at once (default: 1)")
+	fmt.Println("  --name PREFIX: Prefix output file names with PREFIX instead of the host")
+	fmt.Println("  --report-file FILE / --graph-file FILE: Write the JSON report or graph to exactly FILE")
+	fmt.Println("  --baseline FILE: Compare against a previous JSON report and print only what changed")
+	fmt.Println("  --pretty: Colorize and align the report when writing to a terminal")
+	fmt.Println("  --no-color: Disable ANSI colors in the pretty report")
+	fmt.Println("  --sort-by KEY[:asc|desc]: Order the report by count (default), url, depth or status")
+	fmt.Println("  --live-report: Redraw the most linked pages in place every 2s while crawling (terminals only)")
+	fmt.Println("  --checkpoint-interval D: Save the JSON report so far every D (e.g. 30s) while crawling, overwriting it each time")
+	fmt.Println("  --top-n N: List only the N most linked internal pages and external links in the report")
+	fmt.Println("  --summary-only: Print the statistics and page/external link counts without listing each page and link")
+	fmt.Println("  --max-depth N: Do not follow links more than N hops from the URL (default: unlimited)")
+	fmt.Println("  --allow-hosts LIST: Also crawl these comma-separated hosts instead of treating their links as external")
+	fmt.Println("  --host-match MODE: Which hosts are the seed's: exact (default), registered-domain (e.g. www. and blog. of example.com) or suffix (subdomains of the seed host)")
+	fmt.Println("  --redirect-report MODE: List redirected pages under the final URL (target, the default), the linked URL (source) or both")
+	fmt.Println("  --max-host-duration D: Skip the rest of a host once it has been crawled for longer than D, e.g. 2m")
+	fmt.Println("  --max-depth-per-host SPEC: Explore each host at most N hops from where the crawl entered it, e.g. 1 or 1,docs.example.com=3")
+	fmt.Println("  --follow-external-redirects=false: Record redirects to other hosts as external links instead of following them")
+	fmt.Println("  --allow-insecure-redirects-only-to-base: Only follow redirects within the requested page's registered domain; record the others as external links")
+	fmt.Println("  --exclude-file FILE: Never crawl the URLs listed in FILE, one per line; entries ending in * are prefixes")
+	fmt.Println("  --sample-rate R: Only enqueue a fraction R (0-1] of links found below the seed page")
+	fmt.Println("  --sample-seed N: Seed for reproducible sampling (default: 1)")
+	fmt.Println("  --allowed-schemes LIST: Comma-separated URL schemes to follow (default: http,https)")
+	fmt.Println("  --max-external N: Stop recording new external URLs once N distinct ones are tracked (default: unlimited)")
+	fmt.Println("  --priority-header NAME: Crawl links found on pages first by the integer priority in this response header (e.g. X-Crawl-Priority)")
+	fmt.Println("  --priority-meta NAME: Likewise for <meta name=NAME content=N>; the header wins when both are present")
+	fmt.Println("  --default-priority N: Priority of pages without a hint (default: 0)")
+	fmt.Println("  --compact-memory: Store each kept URL once and skip the external redirect and pagination gap listings")
+	fmt.Println("  --soft-deadline D: Stop starting new pages after D, letting pages in flight finish")
+	fmt.Println("  --hard-deadline D: Stop the crawl outright after D (default: 10m)")
+	fmt.Println("  --max-queue N: Maximum number of discovered links waiting to be crawled (default: unbounded)")
+	fmt.Println("  --queue-policy P: When the queue is full, block discovery or drop links (block or drop, default: block)")
+	fmt.Println("  --warmup: Fetch robots.txt before crawling and exit early if the host is unreachable")
+	fmt.Println("  --warmup-sitemap: Also fetch /sitemap.xml during warmup and crawl the URLs it lists (implies --warmup)")
+	fmt.Println("  --state FILE: Keep a ledger of when each page was crawled in FILE (created if missing)")
+	fmt.Println("  --max-age D: Reuse pages crawled less than D ago (e.g. 24h) from the ledger instead of refetching; requires --state")
+	fmt.Println("  --remember-host-health: Keep each host's failure rate in the state file and start hosts that were flaky last run with one request at a time; requires --state")
+	fmt.Println("  --cert-expiry-window D: Warn about TLS certificates expiring within D (default: 720h)")
+	fmt.Println("  --tree: Print the crawled pages as an indented tree rooted at the URL, each under its shallowest parent")
+	fmt.Println("  --max-referrers N: Referrers listed per page in the JSON report's inbound links (default: 100, total is always exact)")
+	fmt.Println("  --host-profiles FILE: Send requests to the listed hosts through their own proxy, basic auth credentials or TLS settings")
+	fmt.Println("  --dns-cache-ttl D: Cache resolved host addresses for D (e.g. 5m), rotating between multiple A records")
+	fmt.Println("  --concurrency-per-host N: Maximum simultaneous requests to any one host (default: only max_concurrency applies)")
+	fmt.Println("  --method M: HTTP method for the seed request (default: GET, or POST with a body); links found are fetched with GET")
+	fmt.Println("  --body DATA: Request body for the seed request, sent as JSON if it parses as JSON and as a form otherwise")
+	fmt.Println("  --body-file FILE: Read the seed request body from FILE")
+	fmt.Println("  --max-filesize-per-type LIST: Size caps by content type, e.g. text/html=5MB (default and maximum: 10MB)")
+	fmt.Println("  --user-agent UA: Send UA as the User-Agent header instead of the default")
+	fmt.Println("  --contact EMAIL: Add a contact to the User-Agent and send it as the From header so site operators can reach you")
+	fmt.Println("  --events FILE: Stream every request, page, discovered link, retry and error to FILE as NDJSON")
+	fmt.Println("  --record-protocol: Report the HTTP protocol version (HTTP/1.1, HTTP/2.0) pages were served over, per host")
+	fmt.Println("  --capture-headers LIST: Report the values of these response headers per page (\"security\" adds the recommended security headers)")
+	fmt.Println("  --headers-out FILE: Write the captured headers and status of every page to FILE (CSV if it ends in .csv, JSON otherwise)")
+	fmt.Println("  --accept-language L: Request pages in language L (e.g. fr-FR) and report each page's Content-Language")
+	fmt.Println("  --only-new-hosts: Report every external domain linked from the site with its number of links")
+	fmt.Println("  --categorize-external: Report external links per category of domain (social, cdn, analytics, other)")
+	fmt.Println("  --external-categories FILE: Extra \"domain category\" lines for --categorize-external, taking precedence over the built-in list")
+	fmt.Println("  --respect-canonical: Crawl rel=canonical targets and report canonical chains and loops")
+	fmt.Println("  --canonical-report: Split pages into self-canonical, canonicalized elsewhere and without a canonical")
+	fmt.Println("  --link-profile: Report pages linking to themselves and pages with mostly external links")
+	fmt.Println("  --hreflang: Report hreflang alternate links that are broken or lack a return link")
+	fmt.Println("  --fail-fast: Stop at the first internal page returning a 4xx/5xx status and exit non-zero")
+	fmt.Println("  --follow-only-internal-then-validate-external: After crawling the site, check each external link once")
+	fmt.Println("  --fail-on-broken-external: Exit non-zero when a validated external link is dead")
+	fmt.Println("  --broken-external-statuses LIST: Comma-separated statuses failing --fail-on-broken-external, e.g. 404,410 (default every dead link)")
+	fmt.Println("  --validate-images: After crawling, check each image once and report broken images by page")
+	fmt.Println("  --crawl-images-as-pages: After crawling, catalogue each image once with its content type, size and dimensions")
+	fmt.Println("  --inventory-downloads: Count links to documents and media by file type, with their declared sizes, instead of crawling them")
+	fmt.Println("  --download-extensions LIST: Comma-separated file extensions counted by --inventory-downloads (default pdf, docx, xlsx, zip, mp4 and more)")
+	fmt.Println("  --external-concurrency N: Check at most N external links or images at once (default: max_concurrency)")
+	fmt.Println("  --external-timeout D: Time allowed for checking one external link or image (default: 10s)")
+	fmt.Println("  --top-anchors N: Report the N most frequent link texts across the site")
+	fmt.Println("  --anchor-case-fold: Count link texts case-insensitively for --top-anchors")
+	fmt.Println("  --anchor-text-filter RE: Only follow links whose text matches the regular expression RE (e.g. '^(Next|Read more)')")
+	fmt.Println("  --follow-per-page N: Only follow the first N internal links of each page, while still recording all of them")
+	fmt.Println("  --seo-checks LIST: Report pages failing on-page checks: empty-title, missing-h1, multiple-h1, empty-description or all")
+	fmt.Println("  --json-schema-validate: Report pages whose JSON-LD structured data lacks fields its @type requires")
+	fmt.Println("  --structured-data-rules FILE: JSON file of extra required fields per @type for --json-schema-validate")
+	fmt.Println("  --compare-mobile: Fetch each page again with a mobile User-Agent and report pages whose content differs")
+	fmt.Println("  --mobile-user-agent UA: User-Agent for the mobile fetches of --compare-mobile (default: an iPhone Safari one)")
+	fmt.Println("  --dedupe-near-duplicate-content: Report clusters of pages whose main text is nearly identical (SimHash)")
+	fmt.Println("  --near-duplicate-distance N: Maximum SimHash bits two near-duplicate pages may differ by (default: 3)")
+	fmt.Println("  --render-js: Render pages in headless Chrome before extracting links (needs a build with -tags chromedp)")
+	fmt.Println("  --crawl-fragments: Crawl single-page app #! routes as pages (fetched as ?_escaped_fragment_=route)")
+	fmt.Println("  --trace-requests: Log each request's DNS, connect, TLS and first-byte timings and average them in the statistics")
+	fmt.Println("  --redirect-chains: Report redirect loops and chains of 2 or more redirects, hop by hop")
+	fmt.Println("  --max-redirect-to-https: Once a host redirects http to https, fetch its other http links over https directly")
+	fmt.Println("  --scheme-fallback: Retry pages over http after a TLS error over https, unless linked explicitly as https")
+	fmt.Println("  --trailing-slash P: strip (default) or add a trailing slash when normalizing, or preserve it so /dir and /dir/ are distinct")
+	fmt.Println("  --case-insensitive-paths: Treat /About and /about as one page and report links that differ only by path case")
+	fmt.Println("  --keep-query: Treat URLs with different query strings as different pages (parameter order is ignored)")
+	fmt.Println("  --ignore-query-case: With --keep-query, treat ?ID=5 and ?id=5 as one page; values keep their case")
+	fmt.Println("  --fragments-as-pages: Record URLs differing only by #fragment as separate pages, fetching each URL once")
+	fmt.Println("  --visited-store S: Track visited pages in an exact map (default) or a compact bloom filter")
+	fmt.Println("  --bloom-fp-rate R: False-positive rate of the bloom filter visited store (default: 0.01)")
+	fmt.Println("  --record DIR: Save every response (headers and body) to DIR")
+	fmt.Println("  --replay DIR: Serve responses saved by --record from DIR instead of the network")
+	fmt.Println("  --cookies-file FILE: Preload per-host cookies (lines of \"host name=value ...\") before crawling")
+	fmt.Println("  --login URL: POST credentials to this login form before crawling and keep the session cookie")
+	fmt.Println("  --login-user U / --login-password P: Credentials for --login (password also read from CRAWLER_LOGIN_PASSWORD)")
+	fmt.Println("  --login-user-field F / --login-password-field F: Form field names (default: username, password)")
+	fmt.Println("  --login-csrf-field F: Copy this hidden field (e.g. a CSRF token) from the login page into the form")
+	fmt.Println("  --html-warnings: Print warnings for malformed HTML and count them in the statistics")
+	fmt.Println("  --max-crawl-delay D: Clamp robots.txt Crawl-delay values above D (default: 30s)")
+	fmt.Println("  --ignore-robots: Ignore robots.txt and robots meta directives (only for sites you are authorized to crawl)")
+	fmt.Println("Environment variable CRAWLER_MAX_CONCURRENCY can also be used")
+	fmt.Println("Send SIGUSR1 (kill -USR1 <pid>) to pause the crawl and again to resume it")
+}
+
+// newCrawlConfig builds the crawl config for baseURL from the command line options
+func newCrawlConfig(ctx context.Context, opts cliOptions, baseURL *url.URL, maxConcurrency, maxPages, batchSize int, ledger *crawlLedger) *config {
+	var totalRequests, failedRequests, bytesDownloaded, htmlWarnings, droppedLinks, peakQueueSize, reusedPages, totalAttempts int64
+	cfg := &config{
+		pages:              make(map[string]int),
+		externalLinks:      make(map[string]int),
+		baseURL:            baseURL,
+		maxPages:           maxPages,
+		maxDepth:           opts.maxDepth,
+		batchSize:          batchSize,
+		mu:                 &sync.Mutex{},
+		clock:              realClock{},
+		concurrencyControl: make(chan struct{}, maxConcurrency),
+		wg:                 &sync.WaitGroup{},
+		ctx:                ctx, // Use the cancellable context
+		clients:            newClientPool(newHTTPClient(), nil),
+		identity:           newCrawlerIdentity(opts.userAgent, opts.contact), // Sent with every request, robots.txt included
+		sizePolicy:         opts.sizePolicy,
+		acceptLanguage:     defaultAcceptLanguage,
+		paused:             new(atomic.Bool),
+		hostErrors:         make(map[string]*int64),
+		hostErrorsMu:       &sync.RWMutex{},
+		totalRequests:      &totalRequests,
+		failedRequests:     &failedRequests,
+		bytesDownloaded:    &bytesDownloaded,
+		depthCounts:        make(map[int]int),
+
+		followExternalRedirects: opts.followExternalRedirects,
+		redirectsOnlyToBase:     opts.redirectsOnlyToBase,
+		externalRedirects:       make(map[string]string),
+		robots:                  newRobotsCache(),
+		ignoreRobots:            opts.ignoreRobots,
+		collectHTMLWarnings:     opts.htmlWarnings,
+		htmlWarnings:            &htmlWarnings,
+		allowedSchemes:          opts.allowedSchemes,
+		skippedSchemes:          make(map[string]int),
+		skipReasons:             make(map[string]int),
+		failureKinds:            make(map[FetchErrorKind]int),
+		queuePolicy:             opts.queuePolicy,
+		droppedLinks:            &droppedLinks,
+		peakQueueSize:           &peakQueueSize,
+		canonicalURLs:           make(map[string]string),
+		redirectedPages:         make(map[string]string),
+		redirectLinks:           make(map[string]int),
+		contentLocations:        make(map[string]string),
+		redirectReport:          opts.redirectReport,
+		brokenLinks:             make(map[string]string),
+		ledger:                  ledger,
+		maxAge:                  opts.maxAge,
+		reusedPages:             &reusedPages,
+		crawlFragments:          opts.crawlFragments,
+		seoChecks:               opts.seoChecks,
+		seoIssues:               make(map[string][]string),
+		tlsInfo:                 make(map[string]hostTLSInfo),
+		certExpiryWindow:        opts.certExpiryWindow,
+		failFast:                opts.failFast,
+		maxExternal:             opts.maxExternal,
+		anchorTextFilter:        opts.anchorTextFilter,
+		followPerPage:           opts.followPerPage,
+		nextLinks:               make(map[string]string),
+		totalAttempts:           &totalAttempts,
+	}
+	// The JSON report, the site tree and --fail-fast need to know who links to each page
+	if opts.output == "json" || opts.tree || opts.failFast {
+		cfg.inboundLinks = make(map[string]*inboundLinks)
+		cfg.maxReferrers = opts.maxReferrers
+	}
+	if opts.output == "json" {
+		cfg.pageData = make(map[string]*PageData)
+	}
+	if opts.tree || opts.sortBy.key == "depth" {
+		cfg.pageDepths = make(map[string]int)
+	}
+	if opts.sortBy.key == "status" || opts.headersOut != "" {
+		cfg.pageStatuses = make(map[string]int)
+	}
+	if opts.captureHeaders != nil {
+		cfg.pageHeaders = make(map[string]map[string]string)
+		cfg.capturedHeaders = opts.captureHeaders
+	}
+	if opts.recordProtocol {
+		cfg.pageProtocols = make(map[string]string)
+	}
+	if opts.acceptLanguage != "" {
+		cfg.acceptLanguage = opts.acceptLanguage
+		cfg.contentLanguages = make(map[string]string)
+	}
+	if opts.respectCanonical {
+		cfg.declaredCanonicals = make(map[string]string)
+	}
+	if opts.nearDuplicates {
+		cfg.simHashes = make(map[string]uint64)
+		cfg.nearDuplicateDistance = opts.nearDuplicateDistance
+	}
+	if opts.compareMobile {
+		cfg.mobileDifferences = make(map[string]mobileDifference)
+		cfg.mobileUserAgent = opts.mobileUserAgent
+	}
+	cfg.allowedHosts = opts.allowedHosts
+	cfg.hostMatch = opts.hostMatch
+	if opts.hostDepthLimits != nil {
+		cfg.hostDepthLimits = opts.hostDepthLimits
+		cfg.hostEntryDepths = make(map[string]int)
+	}
+	if opts.validateStructuredData {
+		cfg.structuredDataRules = opts.structuredDataRules
+		if cfg.structuredDataRules == nil {
+			cfg.structuredDataRules = defaultStructuredDataRules
+		}
+		cfg.structuredDataIssues = make(map[string][]structuredDataIssue)
+	}
+	if opts.maxHostDuration > 0 {
+		cfg.maxHostDuration = opts.maxHostDuration
+		cfg.hostStartTimes = make(map[string]time.Time)
+		cfg.timeLimitedHosts = make(map[string]int)
+	}
+	if opts.canonicalReport {
+		cfg.pageCanonicals = make(map[string]string)
+	}
+	if opts.linkProfile {
+		cfg.linkProfiles = make(map[string]linkProfile)
+	}
+	if opts.traceRequests {
+		cfg.tracer = &requestTracer{}
+	}
+	if opts.redirectChains {
+		cfg.redirectIssues = make(map[string]redirectIssue)
+	}
+	if opts.upgradeToHTTPS {
+		cfg.httpsHosts = make(map[string]bool)
+	}
+	if opts.schemeFallback {
+		cfg.schemeFallbacks = make(map[string]string)
+		cfg.schemeDowngradable = make(map[string]bool)
+	}
+	if opts.caseInsensitivePaths {
+		cfg.pathVariants = make(map[string]map[string]bool)
+	}
+	if opts.caseInsensitivePaths || opts.trailingSlash != trailingSlashStrip || opts.keepQuery || opts.crawlFragments {
+		cfg.normalize = urlNormalizer{
+			trailingSlash:   opts.trailingSlash,
+			caseInsensitive: opts.caseInsensitivePaths,
+			keepQuery:       opts.keepQuery,
+			foldQueryKeys:   opts.ignoreQueryCase,
+			hashBangRoutes:  opts.crawlFragments,
+		}.normalize
+		cfg.trailingSlash = opts.trailingSlash
+	}
+	if opts.fragmentsAsPages {
+		cfg.sharedFetches = make(map[string]*sharedFetch)
+	}
+	if opts.hreflang {
+		cfg.alternates = make(map[string]map[string]string)
+	}
+	if opts.validateExternal {
+		cfg.externalChecks = make(map[string]externalCheck)
+	}
+	if opts.externalConcurrency > 0 {
+		cfg.validationSlots = make(chan struct{}, opts.externalConcurrency)
+	}
+	if opts.validateImages {
+		cfg.imageReferrers = make(map[string][]string)
+		cfg.imageChecks = make(map[string]externalCheck)
+	}
+	if opts.crawlImagesAsPages {
+		cfg.imageReferrers = make(map[string][]string)
+		cfg.imageInventory = make(map[string]imageInfo)
+	}
+	if opts.inventoryDownloads {
+		cfg.downloads = make(map[string]downloadInfo)
+		cfg.downloadExtensions = opts.downloadExtensions
+		if cfg.downloadExtensions == nil {
+			cfg.downloadExtensions = make(map[string]bool, len(defaultDownloadExtensions))
+			for _, extension := range defaultDownloadExtensions {
+				cfg.downloadExtensions[extension] = true
+			}
+		}
+	}
+	if opts.topAnchors > 0 {
+		cfg.anchorTexts = make(map[string]int)
+		cfg.anchorCaseFold = opts.anchorCaseFold
+	}
+	cfg.softDeadline = opts.softDeadline
+	if opts.priorityHeader != "" || opts.priorityMeta != "" {
+		cfg.priorities = newCrawlPriorities(opts.priorityHeader, opts.priorityMeta, opts.defaultPriority)
+	}
+	if opts.concurrencyPerHost > 0 {
+		cfg.hostLimiter = newHostLimiter(opts.concurrencyPerHost)
+	}
+	if opts.rememberHostHealth {
+		cfg.rememberHostHealth = true
+		if flaky := cfg.throttleFlakyHosts(maxConcurrency); len(flaky) > 0 {
+			fmt.Printf("Starting hosts flaky in the last run with one request at a time: %s\n", strings.Join(flaky, ", "))
+		}
+	}
+	if opts.visitedStore == "bloom" {
+		cfg.visited = newBloomVisitStore(maxPages, opts.bloomFPRate)
+	}
+	cfg.robots.maxCrawlDelay = opts.maxCrawlDelay
+	if opts.maxQueue > 0 {
+		cfg.frontier = make(chan struct{}, opts.maxQueue)
+	}
+	if len(opts.denylist) > 0 {
+		cfg.filters = append(cfg.filters, denylistFilter(opts.denylist, cfg.normalizeURL))
+	}
+	if cfg.downloads != nil {
+		cfg.filters = append(cfg.filters, downloadFilter(cfg.downloadExtensions, cfg.isCrawledHost))
+	}
+	if opts.sampleRate < 1 {
+		cfg.filters = append(cfg.filters, samplingFilter(newLinkSampler(opts.sampleRate, opts.sampleSeed)))
+	}
+	if opts.compactMemory {
+		cfg.compactMemory()
+	}
+	return cfg
+}
+
+// printReportSections prints the report sections of the optional checks and inventories cfg ran, after
+// the list of pages
+func printReportSections(cfg *config, opts cliOptions, domainCategories []domainCategory) {
+	if opts.tree {
+		fmt.Println()
+		fmt.Println("=============================")
+		fmt.Println("  SITE TREE")
+		fmt.Println("=============================")
+		seed, err := cfg.normalizeURL(cfg.baseURL.String())
+		if err != nil {
+			fmt.Printf("Error building site tree: %v\n", err)
+		} else {
+			printTree(os.Stdout, buildTree(cfg.inboundLinks, cfg.pageDepths, seed), func(normalizedURL string) string {
+				return fullPageURL(normalizedURL, cfg.baseURL, cfg.canonicalURLs)
+			})
+		}
+	}
+
+	if cfg.externalChecks != nil {
+		printExternalCheckReport(os.Stdout, cfg.externalChecks)
+	}
+
+	if cfg.imageChecks != nil {
+		printImageReport(os.Stdout, len(cfg.imageChecks), cfg.brokenImages())
+	}
+
+	if cfg.imageInventory != nil {
+		printImageInventory(os.Stdout, cfg.imageInventory)
+	}
+
+	if cfg.downloads != nil {
+		printDownloadInventory(os.Stdout, cfg.downloads)
+	}
+
+	if cfg.redirectIssues != nil {
+		printRedirectReport(os.Stdout, cfg.sortedRedirectIssues())
+	}
+
+	if cfg.schemeFallbacks != nil {
+		printSchemeFallbackReport(os.Stdout, cfg.schemeFallbacks)
+	}
+
+	if cfg.pageHeaders != nil {
+		printHeaderReport(os.Stdout, cfg.pageHeaders, cfg.capturedHeaders)
+	}
+
+	if cfg.pageProtocols != nil {
+		printProtocolReport(os.Stdout, cfg.pageProtocols)
+	}
+
+	if cfg.contentLanguages != nil {
+		printContentLanguageReport(os.Stdout, cfg.contentLanguages, cfg.acceptLanguage)
+	}
+
+	if opts.onlyNewHosts {
+		cfg.mu.Lock()
+		hosts := externalHosts(cfg.externalLinks)
+		cfg.mu.Unlock()
+		printExternalHostsReport(os.Stdout, hosts)
+	}
+
+	if domainCategories != nil {
+		cfg.mu.Lock()
+		categories := categorizeExternalLinks(cfg.externalLinks, domainCategories)
+		cfg.mu.Unlock()
+		printExternalCategoryReport(os.Stdout, categories)
+	}
+
+	if cfg.declaredCanonicals != nil {
+		printCanonicalReport(os.Stdout, cfg.canonicalIssues())
+	}
+
+	if cfg.pageCanonicals != nil {
+		printCanonicalBucketReport(os.Stdout, cfg.canonicalBuckets())
+	}
+
+	if cfg.pathVariants != nil {
+		printCaseCollisionReport(os.Stdout, cfg.caseCollisions())
+	}
+
+	if cfg.linkProfiles != nil {
+		printLinkProfileReport(os.Stdout, cfg.linkProfiles)
+	}
+
+	if cfg.alternates != nil {
+		printHreflangReport(os.Stdout, cfg.hreflangIssues(), len(cfg.alternates))
+	}
+
+	if cfg.anchorTexts != nil {
+		printTopAnchorTexts(os.Stdout, cfg.anchorTexts, opts.topAnchors)
+	}
+
+	if cfg.seoChecks != nil {
+		printSEOReport(os.Stdout, cfg.seoIssues, cfg.seoChecks)
+	}
+
+	if cfg.structuredDataRules != nil {
+		printStructuredDataReport(os.Stdout, cfg.structuredDataIssues)
+	}
+
+	if cfg.mobileDifferences != nil {
+		printMobileReport(os.Stdout, cfg.mobileCompared, cfg.sortedMobileDifferences())
+	}
+
+	if cfg.simHashes != nil {
+		printNearDuplicateReport(os.Stdout, nearDuplicateClusters(cfg.simHashes, cfg.nearDuplicateDistance), cfg.nearDuplicateDistance)
+	}
+}
+
 func main() {
 	// Get command line arguments (excluding program name)
 	args := os.Args[1:]
 
 	if len(args) < 1 {
-		fmt.Println("Usage: crawler <URL> [max_concurrency] [max_pages] [batch_size] [--graph]")
-		fmt.Println("  URL: The website URL to crawl")
-		fmt.Println("  max_concurrency: Maximum number of concurrent goroutines (default: 10)")
-		fmt.Println("  max_pages: Maximum number of pages to crawl (default: 10)")
-		fmt.Println("  batch_size: Number of URLs to process in each batch (default: 5)")
-		fmt.Println("  --graph: Generate a graph visualization (saves as graph.png)")
-		fmt.Println("  Environment variable CRAWLER_MAX_CONCURRENCY can also be used")
+		printUsage()
 		os.Exit(1)
 	}
 
-	// Check for graph flag first and remove it from args for cleaner processing
-	generateGraph := false
-	var filteredArgs []string
-	for _, arg := range args {
-		if arg == "--graph" {
-			generateGraph = true
-		} else {
-			filteredArgs = append(filteredArgs, arg)
+	// Parse --flags first and remove them from args for cleaner processing
+	opts, args, err := parseCLIFlags(args)
+	if err != nil {
+		fmt.Printf("Error parsing flags: %v\n", err)
+		os.Exit(1)
+	}
+	generateGraph := opts.generateGraph
+
+	// With --seeds the URLs come from a file, so the first seed stands in for the URL argument
+	var seeds []*url.URL
+	if opts.seedsFile != "" {
+		if seeds, err = loadSeeds(opts.seedsFile); err != nil {
+			fmt.Printf("Error loading seeds: %v\n", err)
+			os.Exit(1)
 		}
+		args = append([]string{seeds[0].String()}, args...)
+	}
+
+	if len(args) < 1 {
+		fmt.Println("no URL provided")
+		fmt.Println("Usage: crawler <URL> [max_concurrency] [max_pages] [batch_size] [flags]")
+		os.Exit(1)
 	}
-	args = filteredArgs
 
 	if len(args) > 4 {
 		fmt.Println("too many arguments provided")
-		fmt.Println("Usage: crawler <URL> [max_concurrency] [max_pages] [batch_size] [--graph]")
+		fmt.Println("Usage: crawler <URL> [max_concurrency] [max_pages] [batch_size] [flags]")
 		os.Exit(1)
 	}
 
@@ -226,12 +814,62 @@ func main() {
 		}
 	}
 
-	if generateGraph {
+	if seeds != nil {
+		fmt.Printf("starting independent crawls of %d seeds (%d at a time, max concurrency: %d, max pages: %d, batch size: %d)\n", len(seeds), opts.parallelSeeds, maxConcurrency, maxPages, batchSize)
+	} else if generateGraph {
 		fmt.Printf("starting crawl of: %s (max concurrency: %d, max pages: %d, batch size: %d) [Graph generation enabled]\n", baseURLString, maxConcurrency, maxPages, batchSize)
 	} else {
 		fmt.Printf("starting crawl of: %s (max concurrency: %d, max pages: %d, batch size: %d)\n", baseURLString, maxConcurrency, maxPages, batchSize)
 	}
 
+	if opts.ignoreRobots {
+		fmt.Println("WARNING: --ignore-robots is set. robots.txt and robots meta directives will NOT be respected.")
+		fmt.Println("WARNING: Only use this option on sites you own or are authorized to crawl.")
+	}
+
+	// Load the baseline report up front so a bad path fails before crawling
+	var baseline *Report
+	if opts.baseline != "" {
+		if baseline, err = loadJSONReport(opts.baseline); err != nil {
+			fmt.Printf("Error loading baseline: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the denylist up front so a bad path fails before crawling
+	if opts.excludeFile != "" {
+		if opts.denylist, err = loadDenylist(opts.excludeFile); err != nil {
+			fmt.Printf("Error loading denylist: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the structured data rules up front so a bad file fails before crawling
+	if opts.structuredDataRulesFile != "" {
+		if opts.structuredDataRules, err = loadStructuredDataRules(opts.structuredDataRulesFile); err != nil {
+			fmt.Printf("Error loading structured data rules: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the domain categories up front so a bad file fails before crawling
+	var domainCategories []domainCategory
+	if opts.categorizeExternal {
+		if domainCategories, err = loadDomainCategories(opts.externalCategoriesFile); err != nil {
+			fmt.Printf("Error loading domain categories: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the crawl ledger so recently crawled pages can be reused
+	var ledger *crawlLedger
+	if opts.stateFile != "" {
+		if ledger, err = loadCrawlLedger(opts.stateFile); err != nil {
+			fmt.Printf("Error loading state: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Parse the base URL
 	baseURL, err := url.Parse(baseURLString)
 	if err != nil {
@@ -254,65 +892,336 @@ func main() {
 		cancel() // Cancel the context to stop all crawling
 	}()
 
-	// Initialize the config struct
-	var totalRequests, failedRequests int64
-	cfg := &config{
-		pages:              make(map[string]int),
-		externalLinks:      make(map[string]int),
-		baseURL:            baseURL,
-		maxPages:           maxPages,
-		batchSize:          batchSize,
-		mu:                 &sync.Mutex{},
-		concurrencyControl: make(chan struct{}, maxConcurrency),
-		wg:                 &sync.WaitGroup{},
-		ctx:                ctx, // Use the cancellable context
-		hostErrors:         make(map[string]*int64),
-		hostErrorsMu:       &sync.RWMutex{},
-		totalRequests:      &totalRequests,
-		failedRequests:     &failedRequests,
+	if opts.renderJS && startHeadlessBrowser == nil {
+		fmt.Println("Error: --render-js requires a build with headless Chrome support (go build -tags chromedp)")
+		os.Exit(1)
+	}
+
+	// Open the event log and load what setting up each crawl needs up front, so a bad file fails before crawling
+	var events *eventLog
+	if opts.eventsFile != "" {
+		eventsOut, err := os.Create(opts.eventsFile)
+		if err != nil {
+			fmt.Printf("Error creating event log: %v\n", err)
+			os.Exit(1)
+		}
+		defer eventsOut.Close()
+		events = newEventLog(eventsOut)
+	}
+
+	// Fetch the seed page with a custom method and body, for sites whose entry point is an API
+	var seedReq *seedRequest
+	if opts.method != "" || opts.body != "" || opts.bodyFile != "" {
+		body := []byte(opts.body)
+		if opts.bodyFile != "" {
+			data, err := os.ReadFile(opts.bodyFile)
+			if err != nil {
+				fmt.Printf("Error reading request body: %v\n", err)
+				os.Exit(1)
+			}
+			body = data
+		}
+		if seedReq, err = newSeedRequest(opts.method, body); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	// Start crawling from the base URL
-	cfg.wg.Add(1)
-	go cfg.crawlPage(baseURLString)
+	// Hosts with a profile are sent requests through a client with that profile's proxy, credentials and TLS settings
+	var profiles map[string]clientProfile
+	if opts.hostProfilesFile != "" {
+		if profiles, err = loadHostProfiles(opts.hostProfilesFile); err != nil {
+			fmt.Printf("Error loading host profiles: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Preload the cookies hosts need to serve their real content, such as consent or region cookies
+	var cookies map[string][]*http.Cookie
+	if opts.cookiesFile != "" {
+		if cookies, err = loadHostCookies(opts.cookiesFile); err != nil {
+			fmt.Printf("Error loading cookies: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-	// Create a timeout context for very large crawls (maximum 10 minutes)
-	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, 10*time.Minute)
-	defer timeoutCancel()
+	if opts.login.url != "" && opts.login.password == "" {
+		opts.login.password = os.Getenv("CRAWLER_LOGIN_PASSWORD")
+	}
 
-	// Wait for all goroutines to complete or timeout
-	done := make(chan struct{})
-	go func() {
-		cfg.wg.Wait()
-		close(done)
-	}()
+	if opts.recordDir != "" {
+		fmt.Printf("Recording responses to %s\n", opts.recordDir)
+	} else if opts.replayDir != "" {
+		fmt.Printf("Replaying responses from %s\n", opts.replayDir)
+	}
 
-	select {
-	case <-done:
-		// Normal completion
-	case <-timeoutCtx.Done():
-		fmt.Printf("\nCrawl timed out after 10 minutes, stopping...\n")
-		cancel() // Cancel the main context
-		// Give goroutines a moment to clean up
-		time.Sleep(2 * time.Second)
+	// Pause and resume the crawl on SIGUSR1 to relieve a struggling site without losing progress
+	paused := new(atomic.Bool)
+	if len(pauseSignals) > 0 {
+		pauseChan := make(chan os.Signal, 1)
+		signal.Notify(pauseChan, pauseSignals...)
+		go func() {
+			for range pauseChan {
+				togglePause(paused)
+			}
+		}()
 	}
 
-	// Print crawling statistics
-	printCrawlStatistics(cfg)
+	// setUpCrawl readies a config's transport and session before it runs; with --seeds every seed's
+	// config gets the same setup
+	setUpCrawl := func(cfg *config) error {
+		cfg.events = events
+		cfg.seedRequest = seedReq
+		cfg.paused = paused
 
-	// Print the formatted report
-	if err := printReport(cfg.pages, cfg.externalLinks, baseURLString); err != nil {
-		fmt.Printf("Error generating report: %v\n", err)
+		// Cache DNS lookups so pages on the same hosts don't each resolve them again
+		if opts.dnsCacheTTL > 0 {
+			if transport, ok := cfg.clients.base.Transport.(*http.Transport); ok {
+				transport.DialContext = newDNSCache(net.DefaultResolver, opts.dnsCacheTTL).DialContext
+			}
+		}
+		cfg.clients.hosts = profiles
+
+		// Record responses for later, or replay a previous recording instead of using the network
+		if opts.recordDir != "" {
+			transport, err := newRecordingTransport(cfg.clients.base.Transport, opts.recordDir)
+			if err != nil {
+				return fmt.Errorf("error setting up recording: %v", err)
+			}
+			cfg.clients.base.Transport = transport
+		} else if opts.replayDir != "" {
+			transport, err := newReplayTransport(opts.replayDir)
+			if err != nil {
+				return fmt.Errorf("error setting up replay: %v", err)
+			}
+			cfg.clients.base.Transport = transport
+		}
+
+		// Attach the cookie jar before the first request: profile clients copy base when first used, so they
+		// share the preloaded cookies and the login session only if the jar is already there
+		if cookies != nil || opts.login.url != "" {
+			if err := ensureCookieJar(cfg.clients.base); err != nil {
+				return err
+			}
+		}
+		if cookies != nil {
+			if err := preloadCookies(cfg.clients.base, cookies); err != nil {
+				return fmt.Errorf("error loading cookies: %v", err)
+			}
+		}
+
+		// Warm up before crawling so connectivity problems surface immediately. This runs once the transport
+		// is set up, so --record captures the warmup requests and --replay serves them.
+		if opts.warmup || opts.warmupSitemap {
+			if err := cfg.warmup(opts.warmupSitemap); err != nil {
+				return fmt.Errorf("warmup failed: %v", err)
+			}
+		}
+
+		// Establish a session before crawling sites behind a login form, with the login host's client so
+		// its profile applies
+		if opts.login.url != "" {
+			loginURL, err := url.Parse(opts.login.url)
+			if err != nil {
+				return fmt.Errorf("login failed: invalid login URL: %v", err)
+			}
+			if err := login(cfg.requestContext(ctx), cfg.clients.client(loginURL), opts.login); err != nil {
+				return fmt.Errorf("login failed: %v", err)
+			}
+			fmt.Printf("Logged in via %s\n", opts.login.url)
+		}
+
+		// Render pages in tabs of one headless browser per crawl, started last so a failed setup leaves none
+		if opts.renderJS {
+			renderer, stop, err := startHeadlessBrowser(ctx)
+			if err != nil {
+				return err
+			}
+			cfg.renderer, cfg.stopRenderer = renderer, stop
+		}
+		return nil
+	}
+
+	// checkAfterCrawl checks each external link found once, now that the internal crawl is done, and the
+	// images and downloads, with the crawl's clients and identity
+	checkAfterCrawl := func(cfg *config, summary *Summary) {
+		requestCtx := cfg.requestContext(ctx)
+		if cfg.externalChecks != nil && summary.Failure == nil {
+			cfg.validateExternalLinks(requestCtx, opts.externalTimeout)
+			if opts.failOnBrokenExternal {
+				summary.BrokenExternal = brokenExternalLinks(cfg.externalChecks, opts.brokenExternalStatuses)
+			}
+		}
+		if cfg.imageChecks != nil && summary.Failure == nil {
+			cfg.validateImages(requestCtx, opts.externalTimeout)
+		}
+		if cfg.imageInventory != nil && summary.Failure == nil {
+			cfg.catalogImages(requestCtx, opts.externalTimeout)
+		}
+		if cfg.downloads != nil && summary.Failure == nil {
+			cfg.sizeDownloads(requestCtx, opts.externalTimeout)
+		}
+	}
+
+	saveLedger := func() {
+		if ledger != nil {
+			if err := ledger.save(opts.stateFile); err != nil {
+				fmt.Printf("Error saving state: %v\n", err)
+			}
+		}
+	}
+
+	// Crawl each seed from --seeds as its own site, writing one report per seed
+	if seeds != nil {
+		results := crawlSeedsIndependently(seeds, opts.parallelSeeds, opts.outDir, func(seed *url.URL) (*config, Summary, error) {
+			cfg := newCrawlConfig(ctx, opts, seed, maxConcurrency, maxPages, batchSize, ledger)
+			if err := setUpCrawl(cfg); err != nil {
+				return nil, Summary{}, err
+			}
+			summary := cfg.Run(opts.hardDeadline)
+			cfg.stopRendering()
+			checkAfterCrawl(cfg, &summary)
+			return cfg, summary, nil
+		})
+		saveLedger()
+
+		// The text sections of each seed follow one another, in seed order, once every crawl is done
+		for _, result := range results {
+			if result.config == nil {
+				continue
+			}
+			fmt.Printf("\n##### %s #####\n", result.Seed)
+			printCrawlStatistics(result.config, result.Summary)
+			printReportSections(result.config, opts, domainCategories)
+		}
+		printSeedResults(results)
+
+		// Fail the build in CI when any seed failed, as a single crawl would
+		if code := seedsExitCode(results); code != 0 {
+			os.Exit(code)
+		}
+		return
+	}
+
+	// Initialize the config struct
+	cfg := newCrawlConfig(ctx, opts, baseURL, maxConcurrency, maxPages, batchSize, ledger)
+	if err := setUpCrawl(cfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Redraw the top pages while crawling; overwriting lines only works on a terminal
+	stopLiveReport := func() {}
+	if opts.liveReport {
+		if isTerminal(os.Stdout) {
+			cfg.quietPages = true
+			stopLiveReport = cfg.startLiveReport(os.Stdout, liveReportInterval)
+		} else {
+			fmt.Println("Note: --live-report needs a terminal, so it is ignored")
+		}
+	}
+
+	// Output files go to --out-dir (named after the host) unless a path is given explicitly
+	paths := newArtifactPaths(opts.outDir, opts.name, baseURL)
+	reportFile := opts.reportFile
+	if reportFile == "" {
+		reportFile = paths.file("report.json")
+	}
+	graphFile := opts.graphFile
+	if graphFile == "" {
+		graphFile = paths.file("graph." + opts.graphFormat)
+	}
+	if err := paths.prepare(); err != nil {
+		fmt.Printf("Error preparing output: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Save the report so far on an interval, for dashboards polling a long crawl
+	stopCheckpoints := func() {}
+	if opts.checkpointInterval > 0 {
+		stopCheckpoints = cfg.startCheckpoints(reportFile, baseURLString, opts.checkpointInterval)
+		fmt.Printf("Writing checkpoints to %s every %v\n", reportFile, opts.checkpointInterval)
+	}
+
+	// Crawl until the hard deadline (10 minutes by default)
+	summary := cfg.Run(opts.hardDeadline)
+	cfg.stopRendering()
+	stopLiveReport()
+
+	checkAfterCrawl(cfg, &summary)
+	stopCheckpoints()
+
+	// Print crawling statistics
+	printCrawlStatistics(cfg, summary)
+
+	saveLedger()
+
+	if cfg.visited != nil {
+		fmt.Println("\nNote: --visited-store bloom does not keep page URLs, so pages are missing from the report and graph")
+	}
+
+	// Print the formatted report, unless it's replaced by JSON output or a diff against a baseline
+	if opts.output == "text" && opts.baseline == "" {
+		style := newReportStyle(os.Stdout, opts.pretty, opts.noColor)
+		style.topN = opts.topN
+		style.summaryOnly = opts.summaryOnly
+		style.sort = opts.sortBy
+		style.sort.depths, style.sort.statuses = cfg.pageDepths, cfg.pageStatuses
+		pages := redirectReportPages(cfg.pages, cfg.redirectedPages, cfg.redirectLinks, cfg.redirectReport)
+		if err := printReport(os.Stdout, pages, cfg.externalLinks, cfg.canonicalURLs, baseURLString, style); err != nil {
+			fmt.Printf("Error generating report: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		report, err := cfg.buildReport(baseURLString)
+		if err != nil {
+			fmt.Printf("Error generating report: %v\n", err)
+			os.Exit(1)
+		}
+		if opts.output == "json" {
+			if err := writeJSONReport(report, reportFile); err != nil {
+				fmt.Printf("Error writing JSON report: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("\nJSON report saved to: %s\n", reportFile)
+		}
+		if opts.baseline != "" {
+			printReportDiff(os.Stdout, diffReports(baseline, report), opts.baseline)
+		}
+	}
+
+	printReportSections(cfg, opts, domainCategories)
+	if cfg.pageHeaders != nil && opts.headersOut != "" {
+		if err := writeHeaderDump(opts.headersOut, cfg.headerRows(), cfg.capturedHeaders); err != nil {
+			fmt.Printf("Error writing headers: %v\n", err)
+		} else {
+			fmt.Printf("\nResponse headers saved to: %s\n", opts.headersOut)
+		}
+	}
+
 	// Generate graph visualization if requested
 	if generateGraph {
 		fmt.Println()
 		fmt.Println("Generating graph visualization...")
-		filename := "graph.png"
-		if err := GenerateGraphVisualization(cfg.pages, cfg.externalLinks, baseURLString, filename); err != nil {
+		var layout *forceLayoutOptions
+		if opts.graphLayout == "force" {
+			layout = &forceLayoutOptions{workers: maxConcurrency, useGrid: opts.graphGrid}
+		}
+		if err := writeGraph(cfg, baseURLString, opts.graphFormat, graphFile, layout); err != nil {
 			fmt.Printf("Error generating graph: %v\n", err)
 		}
 	}
+
+	// Fail the build in CI when --fail-fast found a broken page or --fail-on-broken-external a dead link
+	if code := summary.exitCode(); code != 0 {
+		if summary.Failure != nil {
+			fmt.Printf("\nCrawl stopped by --fail-fast at %s\n", summary.Failure)
+		} else {
+			fmt.Printf("\nFailing: %d broken external links\n", len(summary.BrokenExternal))
+			for _, link := range summary.BrokenExternal {
+				fmt.Printf("  %s\n", link)
+			}
+		}
+		os.Exit(code)
+	}
 }
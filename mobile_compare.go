@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// defaultMobileUserAgent is sent for the mobile fetch of --compare-mobile unless --mobile-user-agent is given
+const defaultMobileUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1 (compatible; Crawler/1.0)"
+
+// mobileDifference is a page whose main text differs between the desktop and mobile fetches
+type mobileDifference struct {
+	URL          string
+	DesktopWords int
+	MobileWords  int
+}
+
+// mainTextHash returns the SHA-256 of a page's main text, so markup-only changes don't count as different content
+func mainTextHash(text string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(text))
+}
+
+// compareMobile fetches pageURL again with the mobile User-Agent, if mobile comparison is on, and records
+// the page when its main text differs from the desktop fetch's desktopBody. The mobile fetch is paced and
+// rendered like the desktop one, so both sides of the comparison went through the same steps.
+func (cfg *config) compareMobile(ctx context.Context, pageURL, desktopBody string) {
+	if cfg.mobileDifferences == nil {
+		return
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+	if !cfg.ignoreRobots {
+		if err := cfg.robots.waitCrawlDelay(ctx, cfg.clock, u); err != nil {
+			return
+		}
+	}
+	if cfg.hostLimiter != nil {
+		release, err := cfg.hostLimiter.acquire(ctx, u.Hostname())
+		if err != nil {
+			return
+		}
+		defer release()
+	}
+
+	mobile, err := cfg.fetchRenderedPage(withUserAgent(ctx, cfg.mobileUserAgent), pageURL)
+	if err != nil {
+		cfg.logf("Error fetching mobile version of %s: %v\n", pageURL, err)
+		return
+	}
+
+	desktopText := getMainTextFromHTML(desktopBody)
+	mobileText := getMainTextFromHTML(mobile.Body)
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.mobileCompared++
+	if mainTextHash(desktopText) != mainTextHash(mobileText) {
+		cfg.mobileDifferences[pageURL] = mobileDifference{
+			URL:          pageURL,
+			DesktopWords: len(strings.Fields(desktopText)),
+			MobileWords:  len(strings.Fields(mobileText)),
+		}
+	}
+}
+
+// sortedMobileDifferences returns the pages serving different content to mobile, sorted by URL
+func (cfg *config) sortedMobileDifferences() []mobileDifference {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	differences := make([]mobileDifference, 0, len(cfg.mobileDifferences))
+	for _, difference := range cfg.mobileDifferences {
+		differences = append(differences, difference)
+	}
+	sort.Slice(differences, func(i, j int) bool { return differences[i].URL < differences[j].URL })
+	return differences
+}
+
+// printMobileReport lists the pages whose mobile content differs from their desktop content
+func printMobileReport(w io.Writer, compared int, differences []mobileDifference) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  MOBILE VS DESKTOP")
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "Compared %d pages, %d serve different content to mobile\n", compared, len(differences))
+	for _, difference := range differences {
+		fmt.Fprintf(w, "  %s (desktop %d words, mobile %d words)\n", difference.URL, difference.DesktopWords, difference.MobileWords)
+	}
+}
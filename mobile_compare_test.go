@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCompareMobileFlagsDifferingPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mobile := strings.Contains(r.Header.Get("User-Agent"), "iPhone")
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			// Same text either way, only the markup differs
+			if mobile {
+				fmt.Fprint(w, `<html><body><main><div>Welcome to the shop</div></main><a href="/products">products</a></body></html>`)
+			} else {
+				fmt.Fprint(w, `<html><body><main><p>Welcome to the shop</p></main><a href="/products">products</a></body></html>`)
+			}
+		case "/products":
+			if mobile {
+				fmt.Fprint(w, `<html><body><main><p>Download our app to browse products</p></main></body></html>`)
+			} else {
+				fmt.Fprint(w, `<html><body><main><p>Desks, chairs and lamps in stock</p></main></body></html>`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.mobileDifferences = make(map[string]mobileDifference)
+	cfg.mobileUserAgent = defaultMobileUserAgent
+	runTestCrawl(cfg)
+
+	differences := cfg.sortedMobileDifferences()
+	expected := []mobileDifference{{URL: server.URL + "/products", DesktopWords: 6, MobileWords: 6}}
+	if len(differences) != 1 || differences[0] != expected[0] {
+		t.Errorf("expected %+v, got %+v", expected, differences)
+	}
+	if cfg.mobileCompared != 2 {
+		t.Errorf("expected 2 pages compared, got %d", cfg.mobileCompared)
+	}
+
+	var out strings.Builder
+	printMobileReport(&out, cfg.mobileCompared, differences)
+	if !strings.Contains(out.String(), "Compared 2 pages, 1 serve different content to mobile") || !strings.Contains(out.String(), "/products (desktop 6 words, mobile 6 words)") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestCompareMobileRendersBothFetches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		// The raw pages differ, but only in the shells that scripts replace
+		if strings.Contains(r.Header.Get("User-Agent"), "iPhone") {
+			fmt.Fprint(w, `<html><body><main><p>Loading mobile app</p></main></body></html>`)
+		} else {
+			fmt.Fprint(w, `<html><body><main><p>Loading</p></main></body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var renderedAs []string
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.mobileDifferences = make(map[string]mobileDifference)
+	cfg.mobileUserAgent = defaultMobileUserAgent
+	cfg.renderer = func(ctx context.Context, rawURL string) (string, error) {
+		mu.Lock()
+		renderedAs = append(renderedAs, userAgentFor(ctx))
+		mu.Unlock()
+		return `<html><body><main><p>Desks and chairs</p></main></body></html>`, nil
+	}
+	runTestCrawl(cfg)
+
+	if differences := cfg.sortedMobileDifferences(); len(differences) != 0 {
+		t.Errorf("expected the rendered pages to match, got %+v", differences)
+	}
+	if len(renderedAs) != 2 || renderedAs[1] != defaultMobileUserAgent {
+		t.Errorf("expected the desktop and then the mobile fetch to be rendered, got User-Agents %q", renderedAs)
+	}
+}
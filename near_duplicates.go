@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Default Hamming distance up to which two pages' SimHashes count as near-duplicates
+const defaultNearDuplicateDistance = 3
+
+// simHash returns the 64-bit SimHash of text: each lowercased word votes for the bits of its FNV-1a hash,
+// so texts sharing most words get hashes differing in few bits. Word order is ignored, which keeps short
+// templated texts differing by a word or two within a few bits.
+func simHash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var votes [64]int
+	vote := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				votes[bit]++
+			} else {
+				votes[bit]--
+			}
+		}
+	}
+	for _, word := range words {
+		vote(word)
+	}
+
+	var hash uint64
+	for bit, count := range votes {
+		if count > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// recordSimHash notes the SimHash of a page's main text, if near-duplicates are being detected.
+// Pages without text are left out, as they would all look alike.
+func (cfg *config) recordSimHash(pageURL, htmlBody string) {
+	if cfg.simHashes == nil {
+		return
+	}
+	text := getMainTextFromHTML(htmlBody)
+	if text == "" {
+		return
+	}
+	hash := simHash(text)
+	cfg.mu.Lock()
+	cfg.simHashes[pageURL] = hash
+	cfg.mu.Unlock()
+}
+
+// nearDuplicateClusters groups pages whose SimHashes are within maxDistance bits of each other, directly
+// or through other pages of the group. Each cluster is sorted and clusters are ordered by first page.
+func nearDuplicateClusters(hashes map[string]uint64, maxDistance int) [][]string {
+	pages := make([]string, 0, len(hashes))
+	for page := range hashes {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	// Union-find over the pages, by index
+	parent := make([]int, len(pages))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range pages {
+		for j := i + 1; j < len(pages); j++ {
+			if bits.OnesCount64(hashes[pages[i]]^hashes[pages[j]]) <= maxDistance {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]string)
+	for i, page := range pages {
+		root := find(i)
+		members[root] = append(members[root], page)
+	}
+	var clusters [][]string
+	for _, cluster := range members {
+		if len(cluster) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i][0] < clusters[j][0] })
+	return clusters
+}
+
+// printNearDuplicateReport lists the clusters of pages with near-duplicate content
+func printNearDuplicateReport(w io.Writer, clusters [][]string, maxDistance int) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  NEAR-DUPLICATE CONTENT")
+	fmt.Fprintln(w, "=============================")
+	if len(clusters) == 0 {
+		fmt.Fprintf(w, "No pages within %d bits of each other\n", maxDistance)
+		return
+	}
+	for i, cluster := range clusters {
+		fmt.Fprintf(w, "Cluster %d (%d pages):\n", i+1, len(cluster))
+		for _, page := range cluster {
+			fmt.Fprintf(w, "  %s\n", page)
+		}
+	}
+}
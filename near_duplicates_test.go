@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const productText = "This sturdy oak desk has two drawers, a cable tray and a scratch resistant finish. " +
+	"It ships flat packed with all tools included and assembles in under an hour. " +
+	"Dimensions are one hundred and twenty by sixty centimetres, with a height of seventy five centimetres."
+
+func TestSimHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        string
+		maxDistance int
+		minDistance int
+	}{
+		{
+			name:        "identical text ignoring case and punctuation",
+			a:           productText,
+			b:           strings.ToUpper(strings.ReplaceAll(productText, ",", "")),
+			maxDistance: 0,
+		},
+		{
+			name:        "one word changed",
+			a:           productText,
+			b:           strings.Replace(productText, "oak", "walnut", 1),
+			maxDistance: defaultNearDuplicateDistance,
+		},
+		{
+			name:        "unrelated text",
+			a:           productText,
+			b:           "Our returns policy gives you thirty days to send back any item in its original packaging for a full refund.",
+			maxDistance: 64,
+			minDistance: defaultNearDuplicateDistance + 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			distance := bits.OnesCount64(simHash(tc.a) ^ simHash(tc.b))
+			if distance > tc.maxDistance || distance < tc.minDistance {
+				t.Errorf("expected a distance between %d and %d, got %d", tc.minDistance, tc.maxDistance, distance)
+			}
+		})
+	}
+}
+
+func TestNearDuplicateClusters(t *testing.T) {
+	hashes := map[string]uint64{
+		"/a": 0b0000,
+		"/b": 0b0011, // 2 bits from /a
+		"/c": 0b1111, // 2 bits from /b, 4 from /a
+		"/d": 0xff00,
+		"/e": 0xff01,
+		"/f": 0xf0f0f0,
+	}
+	expected := [][]string{{"/a", "/b", "/c"}, {"/d", "/e"}}
+	if clusters := nearDuplicateClusters(hashes, 2); !reflect.DeepEqual(clusters, expected) {
+		t.Errorf("expected %v, got %v", expected, clusters)
+	}
+	if clusters := nearDuplicateClusters(hashes, 0); len(clusters) != 0 {
+		t.Errorf("expected no clusters at distance 0, got %v", clusters)
+	}
+}
+
+func TestNearDuplicatePagesGrouped(t *testing.T) {
+	bodies := map[string]string{
+		"/":         `<a href="/desk-oak">oak</a><a href="/desk-walnut">walnut</a><a href="/returns">returns</a>`,
+		"/desk-oak": productText,
+		// Same template, one word different
+		"/desk-walnut": strings.Replace(productText, "oak", "walnut", 1),
+		"/returns":     "Our returns policy gives you thirty days to send back any item in its original packaging for a full refund.",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><nav><a href="/">Home</a> Shop</nav><main><p>%s</p></main><footer>Contact us</footer></body></html>`, body)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.simHashes = make(map[string]uint64)
+	runTestCrawl(cfg)
+
+	clusters := nearDuplicateClusters(cfg.simHashes, defaultNearDuplicateDistance)
+	expected := [][]string{{server.URL + "/desk-oak", server.URL + "/desk-walnut"}}
+	if !reflect.DeepEqual(clusters, expected) {
+		t.Errorf("expected clusters %v, got %v", expected, clusters)
+	}
+
+	var out strings.Builder
+	printNearDuplicateReport(&out, clusters, defaultNearDuplicateDistance)
+	if !strings.Contains(out.String(), "Cluster 1 (2 pages):") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
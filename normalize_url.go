@@ -2,11 +2,36 @@ package main
 
 import (
 	"net/url"
+	"sort"
 	"strings"
 )
 
+// Policies for the trailing slash on a normalized path, chosen with --trailing-slash
+const (
+	trailingSlashStrip    = "strip"    // /dir and /dir/ are the same page, reported as /dir
+	trailingSlashAdd      = "add"      // /dir and /dir/ are the same page, reported as /dir/
+	trailingSlashPreserve = "preserve" // /dir and /dir/ are distinct pages
+)
+
+// urlNormalizer normalizes URLs under a trailing-slash policy ("" strips), optionally ignoring the path's case
+type urlNormalizer struct {
+	trailingSlash   string
+	caseInsensitive bool
+	// Keep the query as part of the page, optionally treating parameter names that differ only by case as one
+	keepQuery     bool
+	foldQueryKeys bool
+	// Keep a hash-bang route rewritten by escapeHashBangURL as a distinct page (--crawl-fragments)
+	hashBangRoutes bool
+}
+
 // normalizeURL takes a URL string and returns its normalized form.
 func normalizeURL(rawURL string) (string, error) {
+	return urlNormalizer{}.normalize(rawURL)
+}
+
+// normalize returns the normalized form of rawURL: its host without www. and its path, with the
+// trailing slash handled according to the policy. The root path is always dropped.
+func (n urlNormalizer) normalize(rawURL string) (string, error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		return "", err
@@ -14,13 +39,98 @@ func normalizeURL(rawURL string) (string, error) {
 
 	host := strings.TrimPrefix(u.Hostname(), "www.")
 
-	path := strings.TrimSuffix(u.Path, "/")
+	path := u.Path
+	if n.caseInsensitive {
+		path = strings.ToLower(path)
+	}
+	switch {
+	case path == "/":
+		path = ""
+	case n.trailingSlash == trailingSlashPreserve:
+	case n.trailingSlash == trailingSlashAdd:
+		// Only directory-like paths gain a slash, so /style.css stays as it is
+		if path != "" && !strings.HasSuffix(path, "/") && !strings.Contains(path[strings.LastIndex(path, "/")+1:], ".") {
+			path += "/"
+		}
+	default:
+		path = strings.TrimSuffix(path, "/")
+	}
 
 	// Rebuild normalized URL: host + path
 	normalized := host
 	if path != "" {
 		normalized += path
 	}
+	if n.keepQuery {
+		if query := n.normalizeQuery(u.Query()); query != "" {
+			normalized += "?" + query
+		}
+	}
+
+	// A hash-bang route rewritten by escapeHashBangURL is a distinct page of a single-page app
+	if route, ok := u.Query()[escapedFragmentParam]; ok && n.hashBangRoutes {
+		normalized += "#!" + route[0]
+	}
 
 	return normalized, nil
 }
+
+// normalizeQuery encodes query with its parameters sorted by name, so their order doesn't matter. With
+// foldQueryKeys, names are lowercased (?ID=5 and ?id=5 are the same page) but values keep their case,
+// since they are often case-sensitive. With hashBangRoutes, the route is left out, as normalize adds it
+// separately.
+func (n urlNormalizer) normalizeQuery(query url.Values) string {
+	if n.hashBangRoutes {
+		query.Del(escapedFragmentParam)
+	}
+	if !n.foldQueryKeys {
+		return query.Encode()
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	folded := make(url.Values, len(query))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		folded[key] = append(folded[key], query[name]...)
+	}
+	return folded.Encode()
+}
+
+// escapedFragmentParam is the query parameter carrying a hash-bang route in the legacy AJAX crawling scheme
+const escapedFragmentParam = "_escaped_fragment_"
+
+// escapeHashBangURL rewrites a hash-bang URL (https://example.com/#!/products) to its crawlable
+// _escaped_fragment_ form (https://example.com/?_escaped_fragment_=/products). Other URLs, including
+// plain #section anchors, are returned unchanged.
+func escapeHashBangURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.HasPrefix(u.Fragment, "!") {
+		return rawURL
+	}
+	query := u.Query()
+	query.Set(escapedFragmentParam, strings.TrimPrefix(u.Fragment, "!"))
+	u.RawQuery = query.Encode()
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// isTrailingSlashVariant reports whether two URLs differ only by a trailing slash on the path,
+// e.g. https://example.com/page and https://example.com/page/
+func isTrailingSlashVariant(rawA, rawB string) bool {
+	a, err := url.Parse(rawA)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(rawB)
+	if err != nil {
+		return false
+	}
+	if a.Scheme != b.Scheme || a.Host != b.Host || a.RawQuery != b.RawQuery || a.Path == b.Path {
+		return false
+	}
+	return strings.TrimSuffix(a.Path, "/") == strings.TrimSuffix(b.Path, "/")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -45,6 +46,11 @@ func TestNormalizeURL(t *testing.T) {
 			inputURL: "https://example.com/path#section",
 			expected: "example.com/path",
 		},
+		{
+			name:     "escaped hash-bang route is an ordinary query",
+			inputURL: "https://example.com/?_escaped_fragment_=/products",
+			expected: "example.com",
+		},
 	}
 
 	for i, tc := range tests {
@@ -60,3 +66,190 @@ func TestNormalizeURL(t *testing.T) {
 		})
 	}
 }
+
+func TestIsTrailingSlashVariant(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"https://example.com/page", "https://example.com/page/", true},
+		{"https://example.com/page/", "https://example.com/page", true},
+		{"https://example.com/page", "https://example.com/page", false},
+		{"https://example.com/page", "https://example.com/other/", false},
+		{"http://example.com/page", "https://example.com/page/", false},
+		{"https://example.com/page?x=1", "https://example.com/page/?x=2", false},
+	}
+
+	for _, tc := range tests {
+		if actual := isTrailingSlashVariant(tc.a, tc.b); actual != tc.expected {
+			t.Errorf("isTrailingSlashVariant(%q, %q): expected %v, got %v", tc.a, tc.b, tc.expected, actual)
+		}
+	}
+}
+
+func TestEscapeHashBangURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://example.com/#!/products", "https://example.com/?_escaped_fragment_=%2Fproducts"},
+		{"https://example.com/app?lang=en#!/products/1", "https://example.com/app?_escaped_fragment_=%2Fproducts%2F1&lang=en"},
+		{"https://example.com/page#section", "https://example.com/page#section"},
+		{"https://example.com/page", "https://example.com/page"},
+	}
+
+	for _, tc := range tests {
+		if actual := escapeHashBangURL(tc.input); actual != tc.expected {
+			t.Errorf("escapeHashBangURL(%q): expected %q, got %q", tc.input, tc.expected, actual)
+		}
+	}
+}
+
+func TestNormalizeURLTrailingSlashPolicies(t *testing.T) {
+	inputs := []string{
+		"https://example.com/",
+		"https://example.com/dir",
+		"https://example.com/dir/",
+		"https://www.example.com/a/b/",
+		"https://example.com/style.css",
+	}
+	expected := map[string][]string{
+		trailingSlashStrip:    {"example.com", "example.com/dir", "example.com/dir", "example.com/a/b", "example.com/style.css"},
+		trailingSlashAdd:      {"example.com", "example.com/dir/", "example.com/dir/", "example.com/a/b/", "example.com/style.css"},
+		trailingSlashPreserve: {"example.com", "example.com/dir", "example.com/dir/", "example.com/a/b/", "example.com/style.css"},
+	}
+
+	for policy, want := range expected {
+		t.Run(policy, func(t *testing.T) {
+			normalizer := urlNormalizer{trailingSlash: policy}
+			for i, input := range inputs {
+				actual, err := normalizer.normalize(input)
+				if err != nil {
+					t.Fatalf("normalize(%q) error: %v", input, err)
+				}
+				if actual != want[i] {
+					t.Errorf("normalize(%q): expected %q, got %q", input, want[i], actual)
+				}
+			}
+		})
+	}
+}
+
+func TestCrawlTrailingSlashPreserveKeepsPagesDistinct(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":     {"/dir", "/dir/"},
+		"/dir":  {},
+		"/dir/": {},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.trailingSlash = trailingSlashPreserve
+	cfg.normalize = urlNormalizer{trailingSlash: trailingSlashPreserve}.normalize
+	runTestCrawl(cfg)
+
+	for _, path := range []string{"/dir", "/dir/"} {
+		key, _ := cfg.normalizeURL(server.URL + path)
+		if cfg.pages[key] != 1 {
+			t.Errorf("expected %s to be crawled as its own page, pages: %v", path, cfg.pages)
+		}
+	}
+}
+
+func TestNormalizeURLKeepQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer urlNormalizer
+		input      string
+		expected   string
+	}{
+		{
+			name:       "query dropped by default",
+			normalizer: urlNormalizer{},
+			input:      "https://example.com/item?id=5",
+			expected:   "example.com/item",
+		},
+		{
+			name:       "parameters sorted by name",
+			normalizer: urlNormalizer{keepQuery: true},
+			input:      "https://example.com/item?b=2&a=1",
+			expected:   "example.com/item?a=1&b=2",
+		},
+		{
+			name:       "name case kept without folding",
+			normalizer: urlNormalizer{keepQuery: true},
+			input:      "https://example.com/item?ID=5",
+			expected:   "example.com/item?ID=5",
+		},
+		{
+			name:       "mixed-case names folded",
+			normalizer: urlNormalizer{keepQuery: true, foldQueryKeys: true},
+			input:      "https://example.com/item?Sort=Name&ID=5",
+			expected:   "example.com/item?id=5&sort=Name",
+		},
+		{
+			name:       "values of folded names merged in name order",
+			normalizer: urlNormalizer{keepQuery: true, foldQueryKeys: true},
+			input:      "https://example.com/item?tag=b&TAG=A",
+			expected:   "example.com/item?tag=A&tag=b",
+		},
+		{
+			name:       "empty query",
+			normalizer: urlNormalizer{keepQuery: true},
+			input:      "https://example.com/item?",
+			expected:   "example.com/item",
+		},
+		{
+			name:       "hash-bang route kept apart from the query",
+			normalizer: urlNormalizer{keepQuery: true, hashBangRoutes: true},
+			input:      "https://example.com/?lang=en&_escaped_fragment_=/products",
+			expected:   "example.com?lang=en#!/products",
+		},
+		{
+			name:       "hash-bang route without a query",
+			normalizer: urlNormalizer{hashBangRoutes: true},
+			input:      "https://example.com/?_escaped_fragment_=/products",
+			expected:   "example.com#!/products",
+		},
+		{
+			name:       "escaped fragment kept as a query without hash-bang routes",
+			normalizer: urlNormalizer{keepQuery: true},
+			input:      "https://example.com/?lang=en&_escaped_fragment_=/products",
+			expected:   "example.com?_escaped_fragment_=%2Fproducts&lang=en",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := tc.normalizer.normalize(tc.input)
+			if err != nil {
+				t.Fatalf("normalize(%q) error: %v", tc.input, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("normalize(%q): expected %q, got %q", tc.input, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlIgnoreQueryCaseCollapsesPages(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":     {"/item?ID=5", "/item?id=5", "/item?id=6", "/item?q=Go", "/item?q=go"},
+		"/item": {},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.normalize = urlNormalizer{keepQuery: true, foldQueryKeys: true}.normalize
+	runTestCrawl(cfg)
+
+	expected := map[string]int{"id=5": 2, "id=6": 1, "q=Go": 1, "q=go": 1}
+	for query, count := range expected {
+		key, _ := cfg.normalizeURL(server.URL + "/item?" + query)
+		if cfg.pages[key] != count {
+			t.Errorf("expected %d links to ?%s, pages: %v", count, query, cfg.pages)
+		}
+	}
+	if len(cfg.pages) != 5 {
+		t.Errorf("expected the root and 4 distinct item pages, got %v", cfg.pages)
+	}
+	if full := fullPageURL("example.com/item?id=5", cfg.baseURL, nil); !strings.HasSuffix(full, "/item?id=5") {
+		t.Errorf("expected the report URL to keep the query, got %q", full)
+	}
+}
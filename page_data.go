@@ -0,0 +1,35 @@
+package main
+
+import "net/url"
+
+// PageData is the content extracted from one crawled page, listed per page in the JSON report
+type PageData struct {
+	URL            string   `json:"url"`
+	H1             string   `json:"h1,omitempty"`
+	FirstParagraph string   `json:"first_paragraph,omitempty"`
+	OutgoingLinks  []string `json:"outgoing_links,omitempty"`
+	ImageURLs      []string `json:"image_urls,omitempty"`
+	// Outbound links split into self-links, internal and external links
+	Links linkProfile `json:"links"`
+	// Targets of rel="next" and rel="prev" in a paginated series, "" when absent
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+	// hreflang language code (or "x-default") -> URL of the page's translation
+	Alternates map[string]string `json:"alternates,omitempty"`
+}
+
+// extractPageData gathers the PageData for the HTML of the page at pageURL, except its outbound links, which
+// crawlPage adds once it has extracted them. Relative URLs resolve against pageURL.
+func extractPageData(html string, pageURL *url.URL) *PageData {
+	images, _ := getImagesFromHTML(html, pageURL)
+	next, prev := getPaginationFromHTML(html, pageURL)
+	return &PageData{
+		URL:            pageURL.String(),
+		H1:             getH1FromHTML(html),
+		FirstParagraph: getFirstParagraphFromHTML(html),
+		ImageURLs:      images,
+		Next:           next,
+		Prev:           prev,
+		Alternates:     getAlternatesFromHTML(html, pageURL),
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestExtractPageData(t *testing.T) {
+	html := `<html><head>
+		<link rel="alternate" hreflang="en" href="/en/">
+		<link rel="alternate" hreflang="fr-CA" href="https://example.ca/fr/">
+		<link rel="alternate" hreflang="x-default" href="/">
+		<link rel="next" href="/en/?page=2">
+	</head><body>
+		<h1>Welcome</h1>
+		<p>First paragraph.</p>
+		<a href="/about">About</a>
+		<img src="/logo.png">
+	</body></html>`
+
+	pageURL, _ := url.Parse("https://example.com/en/")
+	actual := extractPageData(html, pageURL)
+
+	expected := &PageData{
+		URL:            "https://example.com/en/",
+		H1:             "Welcome",
+		FirstParagraph: "First paragraph.",
+		ImageURLs:      []string{"https://example.com/logo.png"},
+		Next:           "https://example.com/en/?page=2",
+		Alternates: map[string]string{
+			"en":        "https://example.com/en/",
+			"fr-ca":     "https://example.ca/fr/",
+			"x-default": "https://example.com/",
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestCrawlPageReportsPageData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head>
+				<link rel="alternate" hreflang="fr" href="/fr/">
+				<link rel="next" href="/page/2">
+			</head><body><h1>Home</h1><a href="/">Home</a><a href="https://other.test/">Other</a></body></html>`)
+		case "/fr/", "/page/2":
+			fmt.Fprint(w, `<html><body><h1>Other page</h1></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.pageData = make(map[string]*PageData)
+	cfg.alternates = make(map[string]map[string]string)
+	cfg.linkProfiles = make(map[string]linkProfile)
+	cfg.nextLinks = make(map[string]string)
+	runTestCrawl(cfg)
+
+	report, err := cfg.buildReport(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	home, ok := report.PageData[server.URL]
+	if !ok {
+		t.Fatalf("expected the home page's data in the report, got %v", report.PageData)
+	}
+	expected := PageData{
+		URL:           server.URL,
+		H1:            "Home",
+		OutgoingLinks: []string{server.URL + "/page/2", server.URL + "/", "https://other.test/"},
+		Links:         linkProfile{SelfLinks: 1, InternalLinks: 1, ExternalLinks: 1},
+		Next:          server.URL + "/page/2",
+		Alternates:    map[string]string{"fr": server.URL + "/fr/"},
+	}
+	if !reflect.DeepEqual(home, expected) {
+		t.Errorf("expected %+v, got %+v", expected, home)
+	}
+
+	// The hreflang, pagination and link profile reports use the same extraction
+	normalizedHome, _ := cfg.normalizeURL(server.URL)
+	if !reflect.DeepEqual(cfg.alternates[normalizedHome], expected.Alternates) {
+		t.Errorf("expected the alternates recorded for the hreflang report, got %v", cfg.alternates)
+	}
+	if cfg.nextLinks[expected.Next] != server.URL {
+		t.Errorf("expected the rel=next link recorded for the pagination report, got %v", cfg.nextLinks)
+	}
+	if cfg.linkProfiles[server.URL] != expected.Links {
+		t.Errorf("expected the link profile recorded, got %v", cfg.linkProfiles)
+	}
+	if len(report.PageData) != 2 {
+		t.Errorf("expected data for the 2 crawled pages (hreflang alternates aren't followed), got %v", report.PageData)
+	}
+}
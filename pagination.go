@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// getPaginationFromHTML returns the targets of the page's <link rel="next"> and <link rel="prev"> tags,
+// resolved against baseURL, or "" for a missing link. <a rel="next"> links count too.
+func getPaginationFromHTML(html string, baseURL *url.URL) (next, prev string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "", ""
+	}
+	doc.Find("link[rel][href], a[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		href, _ := s.Attr("href")
+		parsed, err := url.Parse(strings.TrimSpace(href))
+		if err != nil || strings.TrimSpace(href) == "" {
+			return
+		}
+		// rel holds a space-separated list of relations
+		for _, value := range strings.Fields(strings.ToLower(rel)) {
+			switch {
+			case value == "next" && next == "":
+				next = baseURL.ResolveReference(parsed).String()
+			case (value == "prev" || value == "previous") && prev == "":
+				prev = baseURL.ResolveReference(parsed).String()
+			}
+		}
+	})
+	return next, prev
+}
+
+// prioritizeNext moves next to the front of urls so a paginated series is followed first, adding it if the
+// page has no <a> link to it
+func prioritizeNext(urls []string, next string) []string {
+	prioritized := []string{next}
+	for _, u := range urls {
+		if u != next {
+			prioritized = append(prioritized, u)
+		}
+	}
+	return prioritized
+}
+
+// followableNext returns the rel=next target to follow ahead of a page's other links at depth, or "" when
+// it fails the checks they go through: a scheme in allowedSchemes and the filters. A target the filters
+// drop is counted as skipped if the page also has an <a> link to it, when that link is enqueued.
+func (cfg *config) followableNext(next string, depth int) string {
+	parsed, err := url.Parse(next)
+	if err != nil || !cfg.allowedSchemes[strings.ToLower(parsed.Scheme)] {
+		return ""
+	}
+	if cfg.crawlFragments {
+		next = escapeHashBangURL(next)
+	}
+	if cfg.filterReason(next, depth) != "" {
+		return ""
+	}
+	return next
+}
+
+// paginationGap is a rel=next link whose target could not be fetched
+type paginationGap struct {
+	Page   string
+	Next   string
+	Reason string
+}
+
+// paginationGaps returns the pages whose rel=next target is broken, sorted by page
+func (cfg *config) paginationGaps() []paginationGap {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	var gaps []paginationGap
+	for next, page := range cfg.nextLinks {
+		if reason, broken := cfg.brokenLinks[next]; broken {
+			gaps = append(gaps, paginationGap{Page: page, Next: next, Reason: reason})
+		}
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].Page < gaps[j].Page })
+	return gaps
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGetPaginationFromHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/page/2")
+
+	tests := []struct {
+		name         string
+		html         string
+		expectedNext string
+		expectedPrev string
+	}{
+		{
+			name:         "link tags",
+			html:         `<head><link rel="prev" href="/blog/page/1"><link rel="next" href="3"></head>`,
+			expectedNext: "https://example.com/blog/page/3",
+			expectedPrev: "https://example.com/blog/page/1",
+		},
+		{
+			name:         "anchor with several relations and previous spelling",
+			html:         `<a rel="nofollow Next" href="/blog/page/3">Older</a><a rel="previous" href="/blog/page/1">Newer</a>`,
+			expectedNext: "https://example.com/blog/page/3",
+			expectedPrev: "https://example.com/blog/page/1",
+		},
+		{
+			name:         "first link wins",
+			html:         `<link rel="next" href="/a"><link rel="next" href="/b">`,
+			expectedNext: "https://example.com/a",
+		},
+		{
+			name: "none",
+			html: `<link rel="stylesheet" href="/style.css"><a href="/blog/page/3">3</a>`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			next, prev := getPaginationFromHTML(tc.html, base)
+			if next != tc.expectedNext || prev != tc.expectedPrev {
+				t.Errorf("expected next %q and prev %q, got %q and %q", tc.expectedNext, tc.expectedPrev, next, prev)
+			}
+		})
+	}
+}
+
+func TestPrioritizeNext(t *testing.T) {
+	actual := prioritizeNext([]string{"/a", "/page/2", "/b"}, "/page/2")
+	expected := []string{"/page/2", "/a", "/b"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCrawlFollowsRelNextChain(t *testing.T) {
+	// The pages link to each other only through <link rel="next">, and page 3 points at a missing page 4
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		if _, err := fmt.Sscanf(r.URL.Path, "/page/%d", &n); err != nil || n > 3 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><link rel="next" href="/page/%d"></head><body>page %d</body></html>`, n+1, n)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL+"/page/1", 10)
+	runTestCrawl(cfg)
+
+	for n := 1; n <= 3; n++ {
+		page, _ := cfg.normalizeURL(fmt.Sprintf("%s/page/%d", server.URL, n))
+		if _, ok := cfg.pages[page]; !ok {
+			t.Errorf("expected %s to be crawled, got %v", page, cfg.pages)
+		}
+	}
+
+	gaps := cfg.paginationGaps()
+	if len(gaps) != 1 || gaps[0].Page != server.URL+"/page/3" || gaps[0].Next != server.URL+"/page/4" {
+		t.Errorf("expected a gap after page 3, got %+v", gaps)
+	}
+}
+
+func TestCrawlChecksRelNextLikeOtherLinks(t *testing.T) {
+	var archiveRequests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			// The next page is excluded by the filters
+			fmt.Fprint(w, `<html><body><a rel="next" href="/archive/2">older</a><a href="/about">about</a></body></html>`)
+		case "/archive/2":
+			atomic.AddInt64(&archiveRequests, 1)
+			fmt.Fprint(w, `<html><body>archive</body></html>`)
+		default:
+			// and this one is on a scheme that isn't crawled
+			w.Header().Set("Link", `<ftp://files.example.com/page/2>; rel="next"`)
+			fmt.Fprint(w, `<html><body>about</body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.filters = []FilterFunc{func(u *url.URL, depth int) (bool, string) {
+		return !strings.HasPrefix(u.Path, "/archive"), "exclude"
+	}}
+	cfg.nextLinks = make(map[string]string)
+	runTestCrawl(cfg)
+
+	if requests := atomic.LoadInt64(&archiveRequests); requests != 0 {
+		t.Errorf("expected the filtered next page not to be fetched, got %d requests", requests)
+	}
+	if cfg.skipReasons["exclude"] != 1 {
+		t.Errorf("expected the filtered next page counted once as skipped, got %v", cfg.skipReasons)
+	}
+	if len(cfg.nextLinks) != 0 || len(cfg.externalLinks) != 0 {
+		t.Errorf("expected neither next page followed, got next links %v and external links %v", cfg.nextLinks, cfg.externalLinks)
+	}
+	if len(cfg.pages) != 2 {
+		t.Errorf("expected / and /about crawled, got %v", cfg.pages)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// How often paused workers check whether the crawl has resumed
+const pausePollInterval = 100 * time.Millisecond
+
+// togglePause pauses the running crawls sharing state or resumes them, and reports the new state. While
+// paused, pages already being fetched finish but no new page starts; queued pages stay queued.
+func togglePause(state *atomic.Bool) (paused bool) {
+	for {
+		old := state.Load()
+		if state.CompareAndSwap(old, !old) {
+			paused = !old
+			break
+		}
+	}
+	if paused {
+		fmt.Println("\nCrawl paused, send the signal again to resume")
+	} else {
+		fmt.Println("\nCrawl resumed")
+	}
+	return paused
+}
+
+// waitWhilePaused blocks while the crawl is paused. It returns false if the crawl was cancelled meanwhile.
+func (cfg *config) waitWhilePaused() bool {
+	for cfg.paused.Load() {
+		select {
+		case <-cfg.ctx.Done():
+			return false
+		case <-cfg.clock.After(pausePollInterval):
+		}
+	}
+	return true
+}
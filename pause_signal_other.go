@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// pauseSignals toggle pausing the crawl; there is no SIGUSR1 on this platform
+var pauseSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// pauseSignals toggle pausing the crawl
+var pauseSignals = []os.Signal{syscall.SIGUSR1}
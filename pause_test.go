@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTogglePause(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 10)
+	if !togglePause(cfg.paused) || !cfg.paused.Load() {
+		t.Error("expected the first toggle to pause the crawl")
+	}
+	if togglePause(cfg.paused) || cfg.paused.Load() {
+		t.Error("expected the second toggle to resume the crawl")
+	}
+}
+
+func TestPausedCrawlStopsAdvancing(t *testing.T) {
+	site := newTestServer(t, map[string][]string{
+		"/":  {"/a", "/b", "/c"},
+		"/a": {},
+		"/b": {},
+		"/c": {},
+	})
+	var cfg *config
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			atomic.AddInt64(&requests, 1)
+		}
+		// Pause while serving the seed page, so its links are queued but must not start
+		if r.URL.Path == "/" {
+			togglePause(cfg.paused)
+		}
+		site.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	cfg = newTestConfig(t, server.URL, 10)
+	done := make(chan struct{})
+	go func() {
+		runTestCrawl(cfg)
+		close(done)
+	}()
+
+	time.Sleep(5 * pausePollInterval)
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Fatalf("expected only the seed page to be fetched while paused, got %d requests", n)
+	}
+	select {
+	case <-done:
+		t.Fatal("expected the paused crawl not to finish")
+	default:
+	}
+
+	togglePause(cfg.paused)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the crawl to finish after resuming")
+	}
+	if len(cfg.pages) != 4 {
+		t.Errorf("expected all 4 pages crawled after resuming, got %v", cfg.pages)
+	}
+}
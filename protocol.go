@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// hostProtocols is how many pages of one host were served over each HTTP protocol version
+type hostProtocols struct {
+	Host   string
+	Counts map[string]int // protocol, e.g. "HTTP/2.0" -> pages
+}
+
+// supportsHTTP2 reports whether any page of the host came over HTTP/2 or later
+func (h hostProtocols) supportsHTTP2() bool {
+	for proto := range h.Counts {
+		if !isHTTP1(proto) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHTTP1 reports whether proto is HTTP/1.0 or HTTP/1.1
+func isHTTP1(proto string) bool {
+	return strings.HasPrefix(proto, "HTTP/1.")
+}
+
+// protocolsByHost groups the protocol of each page by the page's host, sorted by host
+func protocolsByHost(pageProtocols map[string]string) []hostProtocols {
+	byHost := make(map[string]map[string]int)
+	for page, proto := range pageProtocols {
+		u, err := url.Parse(page)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if byHost[host] == nil {
+			byHost[host] = make(map[string]int)
+		}
+		byHost[host][proto]++
+	}
+
+	hosts := make([]hostProtocols, 0, len(byHost))
+	for host, counts := range byHost {
+		hosts = append(hosts, hostProtocols{Host: host, Counts: counts})
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+// unexpectedHTTP1Pages returns the pages served over HTTP/1.x by a host that served other pages over
+// HTTP/2, sorted: a sign of a misconfigured server or CDN path
+func unexpectedHTTP1Pages(pageProtocols map[string]string) []string {
+	http2Hosts := make(map[string]bool)
+	for _, host := range protocolsByHost(pageProtocols) {
+		if host.supportsHTTP2() {
+			http2Hosts[host.Host] = true
+		}
+	}
+
+	var pages []string
+	for page, proto := range pageProtocols {
+		if u, err := url.Parse(page); err == nil && isHTTP1(proto) && http2Hosts[strings.ToLower(u.Hostname())] {
+			pages = append(pages, page)
+		}
+	}
+	sort.Strings(pages)
+	return pages
+}
+
+// printProtocolReport prints how many pages came over each protocol version per host, then the pages
+// served over HTTP/1.x by hosts supporting HTTP/2
+func printProtocolReport(w io.Writer, pageProtocols map[string]string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  HTTP PROTOCOLS")
+	fmt.Fprintln(w, "=============================")
+	for _, host := range protocolsByHost(pageProtocols) {
+		protos := make([]string, 0, len(host.Counts))
+		for proto := range host.Counts {
+			protos = append(protos, proto)
+		}
+		sort.Strings(protos)
+		counts := make([]string, len(protos))
+		for i, proto := range protos {
+			counts[i] = fmt.Sprintf("%s: %d", proto, host.Counts[proto])
+		}
+		fmt.Fprintf(w, "%s: %s\n", host.Host, strings.Join(counts, ", "))
+	}
+
+	if pages := unexpectedHTTP1Pages(pageProtocols); len(pages) > 0 {
+		fmt.Fprintf(w, "\nPages served over HTTP/1.x by hosts supporting HTTP/2: %d\n", len(pages))
+		for _, page := range pages {
+			fmt.Fprintf(w, "  %s (%s)\n", page, pageProtocols[page])
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordProtocolPerPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		http2    bool
+		expected string
+	}{
+		{name: "HTTP/2 server", http2: true, expected: "HTTP/2.0"},
+		{name: "server forced to HTTP/1.1", http2: false, expected: "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				if r.URL.Path == "/" {
+					fmt.Fprint(w, `<html><body><a href="/about">about</a></body></html>`)
+					return
+				}
+				fmt.Fprint(w, `<html><body>about</body></html>`)
+			}))
+			server.EnableHTTP2 = tt.http2
+			server.StartTLS()
+			defer server.Close()
+
+			cfg := newTestConfig(t, server.URL, 10)
+			// The test server's client trusts its certificate and speaks HTTP/2 when the server does
+			useTransport(cfg, server.Client().Transport)
+			cfg.pageProtocols = make(map[string]string)
+			cfg.Run(time.Minute)
+
+			if len(cfg.pageProtocols) != 2 {
+				t.Fatalf("expected the protocol of both pages, got %v", cfg.pageProtocols)
+			}
+			for page, proto := range cfg.pageProtocols {
+				if proto != tt.expected {
+					t.Errorf("expected %s served over %s, got %s", page, tt.expected, proto)
+				}
+			}
+			if pages := unexpectedHTTP1Pages(cfg.pageProtocols); len(pages) != 0 {
+				t.Errorf("expected no unexpected HTTP/1.x pages, got %v", pages)
+			}
+		})
+	}
+}
+
+func TestUnexpectedHTTP1Pages(t *testing.T) {
+	pageProtocols := map[string]string{
+		"https://example.com/":          "HTTP/2.0",
+		"https://example.com/about":     "HTTP/2.0",
+		"https://example.com/legacy":    "HTTP/1.1",
+		"https://old.example.com/":      "HTTP/1.1",
+		"https://old.example.com/about": "HTTP/1.0",
+	}
+	expected := []string{"https://example.com/legacy"}
+	if got := unexpectedHTTP1Pages(pageProtocols); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	expectedHosts := []hostProtocols{
+		{Host: "example.com", Counts: map[string]int{"HTTP/2.0": 2, "HTTP/1.1": 1}},
+		{Host: "old.example.com", Counts: map[string]int{"HTTP/1.1": 1, "HTTP/1.0": 1}},
+	}
+	if got := protocolsByHost(pageProtocols); !reflect.DeepEqual(got, expectedHosts) {
+		t.Errorf("expected %+v, got %+v", expectedHosts, got)
+	}
+
+	var buf bytes.Buffer
+	printProtocolReport(&buf, pageProtocols)
+	for _, line := range []string{
+		"example.com: HTTP/1.1: 1, HTTP/2.0: 2\n",
+		"old.example.com: HTTP/1.0: 1, HTTP/1.1: 1\n",
+		"  https://example.com/legacy (HTTP/1.1)\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("expected %q in the report, got:\n%s", line, buf.String())
+		}
+	}
+}
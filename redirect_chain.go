@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// longRedirectChainHops is the number of redirects from which a chain is reported as unnecessarily long.
+// A single hop (http -> https, or adding www) is normal; two or more mean links could point further along.
+const longRedirectChainHops = 2
+
+// redirectLoopError reports a redirect back to a URL already visited twice while following the same request
+type redirectLoopError struct {
+	Chain []string // every URL visited, ending with the repeated one
+}
+
+func (e *redirectLoopError) Error() string {
+	return "redirect loop: " + strings.Join(e.Chain, " -> ")
+}
+
+// redirectChain returns the URLs resp was redirected through, from the requested URL to the final one
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil; {
+		chain = append([]string{req.URL.String()}, chain...)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	return chain
+}
+
+// redirectHopChange describes what a redirect from one URL to the next changes: the scheme, the host
+// (including adding or removing www) and the rest of the URL
+func redirectHopChange(rawFrom, rawTo string) string {
+	from, err := url.Parse(rawFrom)
+	if err != nil {
+		return ""
+	}
+	to, err := url.Parse(rawTo)
+	if err != nil {
+		return ""
+	}
+
+	var changes []string
+	if from.Scheme != to.Scheme {
+		changes = append(changes, from.Scheme+" -> "+to.Scheme)
+	}
+	switch {
+	case from.Host == to.Host:
+	case "www."+from.Host == to.Host:
+		changes = append(changes, "adds www")
+	case from.Host == "www."+to.Host:
+		changes = append(changes, "removes www")
+	default:
+		changes = append(changes, "host "+from.Host+" -> "+to.Host)
+	}
+	if from.RequestURI() != to.RequestURI() {
+		changes = append(changes, "path")
+	}
+	return strings.Join(changes, ", ")
+}
+
+// redirectIssue is a redirect chain that loops or takes longRedirectChainHops or more hops
+type redirectIssue struct {
+	Chain []string
+	Loop  bool
+}
+
+// recordRedirectIssue notes a redirect chain worth reporting, if redirect chains are being tracked
+func (cfg *config) recordRedirectIssue(chain []string, loop bool) {
+	if cfg.redirectIssues == nil || (!loop && len(chain)-1 < longRedirectChainHops) {
+		return
+	}
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.redirectIssues[chain[0]] = redirectIssue{Chain: chain, Loop: loop}
+}
+
+// sortedRedirectIssues returns the recorded issues, loops first, then by starting URL
+func (cfg *config) sortedRedirectIssues() []redirectIssue {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	issues := make([]redirectIssue, 0, len(cfg.redirectIssues))
+	for _, issue := range cfg.redirectIssues {
+		issues = append(issues, issue)
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Loop != issues[j].Loop {
+			return issues[i].Loop
+		}
+		return issues[i].Chain[0] < issues[j].Chain[0]
+	})
+	return issues
+}
+
+// printRedirectReport prints each redirect loop as an error and each long chain as a warning, hop by hop
+func printRedirectReport(w io.Writer, issues []redirectIssue) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  REDIRECT CHAINS")
+	fmt.Fprintln(w, "=============================")
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No redirect loops or long redirect chains")
+		return
+	}
+	for _, issue := range issues {
+		if issue.Loop {
+			fmt.Fprintf(w, "ERROR: redirect loop starting at %s\n", issue.Chain[0])
+		} else {
+			fmt.Fprintf(w, "WARNING: %d redirects from %s\n", len(issue.Chain)-1, issue.Chain[0])
+		}
+		for i := 1; i < len(issue.Chain); i++ {
+			fmt.Fprintf(w, "  -> %s (%s)\n", issue.Chain[i], redirectHopChange(issue.Chain[i-1], issue.Chain[i]))
+		}
+	}
+}
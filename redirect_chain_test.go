@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// redirectSite answers from a fixed table of redirects, serving a page for every other URL
+type redirectSite map[string]string
+
+func (s redirectSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: req}
+	switch target, redirected := s[req.URL.String()]; {
+	case redirected:
+		resp.StatusCode = http.StatusMovedPermanently
+		resp.Header.Set("Location", target)
+	case req.URL.Path == "/robots.txt":
+		resp.StatusCode = http.StatusNotFound
+	default:
+		resp.Header.Set("Content-Type", "text/html")
+		resp.Body = io.NopCloser(strings.NewReader(`<html><body><a href="http://example.test/old">old</a><a href="http://example.test/moved">moved</a></body></html>`))
+	}
+	return resp, nil
+}
+
+func TestRedirectLoopReported(t *testing.T) {
+	site := redirectSite{
+		"http://example.test/":      "https://example.test/",
+		"https://example.test/":     "https://www.example.test/",
+		"https://www.example.test/": "http://www.example.test/",
+		"http://www.example.test/":  "http://example.test/",
+	}
+	cfg := newTestConfig(t, "http://example.test/", 10)
+	useTransport(cfg, site)
+	cfg.redirectIssues = make(map[string]redirectIssue)
+	cfg.Run(time.Minute)
+
+	// The loop is followed around once more before it's given up, in case it was a one-off bounce
+	loop := []string{"http://example.test/", "https://example.test/", "https://www.example.test/", "http://www.example.test/"}
+	expected := []redirectIssue{{
+		Chain: append(append(append([]string{}, loop...), loop...), "http://example.test/"),
+		Loop:  true,
+	}}
+	if issues := cfg.sortedRedirectIssues(); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected %+v, got %+v", expected, issues)
+	}
+	if _, broken := cfg.brokenLinks["http://example.test/"]; !broken {
+		t.Errorf("expected the looping page to be reported as broken, got %v", cfg.brokenLinks)
+	}
+}
+
+func TestLongRedirectChainReported(t *testing.T) {
+	site := redirectSite{
+		"http://example.test/old":   "http://example.test/new",
+		"http://example.test/new":   "https://example.test/new",
+		"http://example.test/moved": "http://example.test/",
+	}
+	cfg := newTestConfig(t, "http://example.test/", 10)
+	useTransport(cfg, site)
+	cfg.redirectIssues = make(map[string]redirectIssue)
+	cfg.Run(time.Minute)
+
+	// A single redirect is not worth reporting, two are
+	expected := []redirectIssue{{
+		Chain: []string{"http://example.test/old", "http://example.test/new", "https://example.test/new"},
+	}}
+	if issues := cfg.sortedRedirectIssues(); !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected %+v, got %+v", expected, issues)
+	}
+}
+
+func TestCheckRedirectStopsLoops(t *testing.T) {
+	newRequest := func(rawURL string) *http.Request {
+		req, _ := http.NewRequest("GET", rawURL, nil)
+		return req
+	}
+	via := []*http.Request{newRequest("http://example.com/a"), newRequest("http://example.com/b")}
+
+	// Coming back once is allowed, e.g. after a detour to set a cookie
+	if err := checkRedirect(newRequest("http://example.com/a"), via); err != nil {
+		t.Errorf("expected a single return to be followed, got %v", err)
+	}
+	if err := checkRedirect(newRequest("http://example.com/c"), via); err != nil {
+		t.Errorf("expected a new URL to be followed, got %v", err)
+	}
+
+	via = append(via, newRequest("http://example.com/a"), newRequest("http://example.com/b"))
+	err := checkRedirect(newRequest("http://example.com/a"), via)
+	var loopErr *redirectLoopError
+	if !errors.As(err, &loopErr) {
+		t.Fatalf("expected a redirect loop error, got %v", err)
+	}
+	expected := []string{"http://example.com/a", "http://example.com/b", "http://example.com/a", "http://example.com/b", "http://example.com/a"}
+	if !reflect.DeepEqual(loopErr.Chain, expected) {
+		t.Errorf("expected chain %v, got %v", expected, loopErr.Chain)
+	}
+}
+
+func TestRedirectHopChange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		expected string
+	}{
+		{"http://example.com/", "https://example.com/", "http -> https"},
+		{"https://example.com/", "https://www.example.com/", "adds www"},
+		{"https://www.example.com/", "http://example.com/", "https -> http, removes www"},
+		{"https://example.com/old", "https://example.com/new", "path"},
+		{"https://example.com/", "https://other.com/", "host example.com -> other.com"},
+	}
+	for _, tc := range tests {
+		if actual := redirectHopChange(tc.from, tc.to); actual != tc.expected {
+			t.Errorf("redirectHopChange(%q, %q): expected %q, got %q", tc.from, tc.to, tc.expected, actual)
+		}
+	}
+}
+
+// loggedRedirectSite is a redirectSite that remembers the URLs requested from it
+type loggedRedirectSite struct {
+	redirectSite
+	mu        sync.Mutex
+	requested []string
+}
+
+func (s *loggedRedirectSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requested = append(s.requested, req.URL.String())
+	s.mu.Unlock()
+	return s.redirectSite.RoundTrip(req)
+}
+
+func TestRedirectsOnlyToBaseDomain(t *testing.T) {
+	site := &loggedRedirectSite{redirectSite: redirectSite{
+		"http://example.test/old":   "http://blog.example.test/new",
+		"http://example.test/moved": "http://evil.test/landing",
+	}}
+	cfg := newTestConfig(t, "http://example.test/", 10)
+	useTransport(cfg, site)
+	cfg.redirectsOnlyToBase = true
+	cfg.externalRedirects = make(map[string]string)
+	cfg.Run(time.Minute)
+
+	site.mu.Lock()
+	defer site.mu.Unlock()
+	if indexOf(site.requested, "http://blog.example.test/new") < 0 {
+		t.Errorf("expected the same-domain redirect to be followed, requested %v", site.requested)
+	}
+	if indexOf(site.requested, "http://evil.test/landing") >= 0 {
+		t.Errorf("expected the cross-domain redirect not to be fetched, requested %v", site.requested)
+	}
+	if cfg.externalLinks["http://evil.test/landing"] != 1 {
+		t.Errorf("expected the cross-domain target recorded as external, got %v", cfg.externalLinks)
+	}
+	if source := cfg.externalRedirects["http://evil.test/landing"]; source != "http://example.test/moved" {
+		t.Errorf("expected the redirect source to be recorded, got %v", cfg.externalRedirects)
+	}
+	if _, broken := cfg.brokenLinks["http://example.test/moved"]; broken {
+		t.Errorf("expected the stopped redirect not to count as broken, got %v", cfg.brokenLinks)
+	}
+}
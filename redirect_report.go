@@ -0,0 +1,38 @@
+package main
+
+// How redirected pages appear in the report, chosen with --redirect-report
+const (
+	redirectReportTarget = "target" // only the final URL, with the links to every URL redirecting to it
+	redirectReportSource = "source" // the URLs as linked: each redirecting URL with its own links
+	redirectReportBoth   = "both"   // each redirecting URL with its own links, and the final URL with all of them
+)
+
+// redirectReportPages returns the pages to report under mode. The crawl records a redirected page under
+// its final URL, with redirectLinks holding the links that reached it through each redirecting URL.
+// Under source, a final URL only stays when links point to it directly.
+func redirectReportPages(pages map[string]int, redirectedPages map[string]string, redirectLinks map[string]int, mode string) map[string]int {
+	if mode != redirectReportSource && mode != redirectReportBoth {
+		return pages
+	}
+	reported := make(map[string]int, len(pages)+len(redirectedPages))
+	for page, count := range pages {
+		reported[page] = count
+	}
+	for source, target := range redirectedPages {
+		if _, crawled := pages[target]; !crawled {
+			continue
+		}
+		reported[source] = redirectLinks[source]
+		if mode == redirectReportSource {
+			reported[target] -= redirectLinks[source]
+		}
+	}
+	if mode == redirectReportSource {
+		for _, target := range redirectedPages {
+			if count, ok := reported[target]; ok && count <= 0 {
+				delete(reported, target)
+			}
+		}
+	}
+	return reported
+}
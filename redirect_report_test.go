@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRedirectReportModes(t *testing.T) {
+	// /old redirects to /new, which is also linked directly from /about
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links := map[string]string{
+			"/":      `<a href="/old">old</a><a href="/about">about</a>`,
+			"/about": `<a href="/old">old</a><a href="/new">new</a>`,
+			"/new":   ``,
+		}
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		body, ok := links[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", body)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	page := func(path string) string {
+		normalized, _ := normalizeURL(server.URL + path)
+		return fullPageURL(normalized, cfg.baseURL, nil)
+	}
+	tests := []struct {
+		mode     string
+		expected map[string]int
+	}{
+		{mode: redirectReportTarget, expected: map[string]int{page("/"): 1, page("/about"): 1, page("/new"): 3}},
+		{mode: redirectReportSource, expected: map[string]int{page("/"): 1, page("/about"): 1, page("/old"): 2, page("/new"): 1}},
+		{mode: redirectReportBoth, expected: map[string]int{page("/"): 1, page("/about"): 1, page("/old"): 2, page("/new"): 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg.redirectReport = tt.mode
+			report, err := cfg.buildReport(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(report.Pages, tt.expected) {
+				t.Errorf("expected pages %v, got %v", tt.expected, report.Pages)
+			}
+			if report.Redirects[page("/old")] != page("/new") {
+				t.Errorf("expected the redirect to be recorded in every mode, got %v", report.Redirects)
+			}
+		})
+	}
+}
+
+func TestRedirectReportPagesDropsTargetOnlyReachedByRedirect(t *testing.T) {
+	pages := map[string]int{"example.com": 1, "example.com/new": 2}
+	redirectedPages := map[string]string{"example.com/old": "example.com/new"}
+	redirectLinks := map[string]int{"example.com/old": 2}
+
+	expected := map[string]int{"example.com": 1, "example.com/old": 2}
+	if got := redirectReportPages(pages, redirectedPages, redirectLinks, redirectReportSource); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	// The crawl's own pages are left alone
+	if !reflect.DeepEqual(pages, map[string]int{"example.com": 1, "example.com/new": 2}) {
+		t.Errorf("expected the pages to be unchanged, got %v", pages)
+	}
+}
+
+func TestFoldPageVisitCountsLinksArrivingDuringFold(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.pages["example.com/old"] = 1
+	cfg.depthCounts[1] = 1
+
+	// Links to the redirecting page keep arriving while it's folded into its target
+	const links = 50
+	var wg sync.WaitGroup
+	for i := 0; i < links; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if isFirst, _ := cfg.addPageVisit("example.com/old", 2); isFirst {
+				t.Error("expected no link to make the page a first visit again")
+			}
+		}()
+	}
+	cfg.foldPageVisit("example.com/old", "example.com/new", 1, true)
+	wg.Wait()
+
+	if cfg.pages["example.com/new"] != links+1 || cfg.redirectLinks["example.com/old"] != links+1 {
+		t.Errorf("expected all %d links counted toward the target and the redirect, got pages %v and redirect links %v", links+1, cfg.pages, cfg.redirectLinks)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// Report is the machine-readable crawl result written by --output json
+type Report struct {
+	BaseURL       string            `json:"base_url"`
+	Pages         map[string]int    `json:"pages"`          // full page URL -> internal links found to it
+	ExternalLinks map[string]int    `json:"external_links"` // external URL -> links found to it
+	BrokenLinks   map[string]string `json:"broken_links"`   // page URL -> error from the last fetch attempt
+	// Page URL -> pages linking to it, when link recording is enabled
+	InboundLinks map[string]*inboundLinks `json:"inbound_links,omitempty"`
+	// Page URL -> content extracted from it: headings, outbound links, images, pagination and hreflang alternates
+	PageData map[string]PageData `json:"page_data,omitempty"`
+	// Page URL -> Content-Language header ("" when missing), when --accept-language is given
+	ContentLanguages map[string]string `json:"content_languages,omitempty"`
+	// Page URL -> HTTP protocol version it was served over, when --record-protocol is given
+	Protocols map[string]string `json:"protocols,omitempty"`
+	// Page URL -> captured response headers, when --capture-headers is given
+	Headers map[string]map[string]string `json:"headers,omitempty"`
+	// External URL -> result of checking it, when external links are validated
+	ExternalChecks map[string]externalCheck `json:"external_checks,omitempty"`
+	// Image URL -> content type, size and dimensions, when --crawl-images-as-pages is given
+	Images map[string]imageInfo `json:"images,omitempty"`
+	// Download URL -> file type and declared size, when --inventory-downloads is given
+	Downloads map[string]downloadInfo `json:"downloads,omitempty"`
+	// Page URL -> structured data issues, for pages with any when --json-schema-validate is given
+	StructuredDataIssues map[string][]structuredDataIssue `json:"structured_data_issues,omitempty"`
+	// Host -> links skipped once the host reached --max-host-duration, for hosts that did
+	TimeLimitedHosts map[string]int `json:"time_limited_hosts,omitempty"`
+	// Requested page URL -> page URL it redirected to, for pages recorded under their final URL
+	Redirects map[string]string `json:"redirects,omitempty"`
+	// Crawl progress when the report was written, for reports saved by --checkpoint-interval
+	Checkpoint *checkpointStats `json:"checkpoint,omitempty"`
+}
+
+// buildReport snapshots the crawl results into a Report
+func (cfg *config) buildReport(baseURL string) (*Report, error) {
+	parsedBaseURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base URL: %v", err)
+	}
+
+	report := &Report{
+		BaseURL:       baseURL,
+		Pages:         make(map[string]int),
+		ExternalLinks: make(map[string]int),
+		BrokenLinks:   make(map[string]string),
+	}
+
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	for normalizedURL, count := range redirectReportPages(cfg.pages, cfg.redirectedPages, cfg.redirectLinks, cfg.redirectReport) {
+		report.Pages[fullPageURL(normalizedURL, parsedBaseURL, cfg.canonicalURLs)] = count
+	}
+	for link, count := range cfg.externalLinks {
+		report.ExternalLinks[link] = count
+	}
+	for link, reason := range cfg.brokenLinks {
+		report.BrokenLinks[link] = reason
+	}
+	if cfg.inboundLinks != nil {
+		report.InboundLinks = make(map[string]*inboundLinks, len(cfg.inboundLinks))
+		for target, inbound := range cfg.inboundLinks {
+			referrers := make([]string, len(inbound.Referrers))
+			for i, referrer := range inbound.Referrers {
+				referrers[i] = fullPageURL(referrer, parsedBaseURL, cfg.canonicalURLs)
+			}
+			report.InboundLinks[fullPageURL(target, parsedBaseURL, cfg.canonicalURLs)] = &inboundLinks{Referrers: referrers, Total: inbound.Total}
+		}
+	}
+	if cfg.pageData != nil {
+		report.PageData = make(map[string]PageData, len(cfg.pageData))
+		for page, data := range cfg.pageData {
+			report.PageData[page] = *data
+		}
+	}
+	if cfg.contentLanguages != nil {
+		report.ContentLanguages = make(map[string]string, len(cfg.contentLanguages))
+		for page, language := range cfg.contentLanguages {
+			report.ContentLanguages[page] = language
+		}
+	}
+	if cfg.pageProtocols != nil {
+		report.Protocols = make(map[string]string, len(cfg.pageProtocols))
+		for page, proto := range cfg.pageProtocols {
+			report.Protocols[page] = proto
+		}
+	}
+	if cfg.pageHeaders != nil {
+		report.Headers = make(map[string]map[string]string, len(cfg.pageHeaders))
+		for page, headers := range cfg.pageHeaders {
+			report.Headers[page] = headers
+		}
+	}
+	if cfg.imageInventory != nil {
+		report.Images = make(map[string]imageInfo, len(cfg.imageInventory))
+		for image, info := range cfg.imageInventory {
+			report.Images[image] = info
+		}
+	}
+	if cfg.downloads != nil {
+		report.Downloads = make(map[string]downloadInfo, len(cfg.downloads))
+		for link, info := range cfg.downloads {
+			report.Downloads[link] = info
+		}
+	}
+	if len(cfg.structuredDataIssues) > 0 {
+		report.StructuredDataIssues = make(map[string][]structuredDataIssue, len(cfg.structuredDataIssues))
+		for page, issues := range cfg.structuredDataIssues {
+			report.StructuredDataIssues[page] = issues
+		}
+	}
+	if len(cfg.timeLimitedHosts) > 0 {
+		report.TimeLimitedHosts = make(map[string]int, len(cfg.timeLimitedHosts))
+		for host, skipped := range cfg.timeLimitedHosts {
+			report.TimeLimitedHosts[host] = skipped
+		}
+	}
+	if len(cfg.redirectedPages) > 0 {
+		report.Redirects = make(map[string]string, len(cfg.redirectedPages))
+		for source, target := range cfg.redirectedPages {
+			report.Redirects[fullPageURL(source, parsedBaseURL, cfg.canonicalURLs)] = fullPageURL(target, parsedBaseURL, cfg.canonicalURLs)
+		}
+	}
+	if cfg.externalChecks != nil {
+		report.ExternalChecks = make(map[string]externalCheck, len(cfg.externalChecks))
+		for link, check := range cfg.externalChecks {
+			report.ExternalChecks[link] = check
+		}
+	}
+	return report, nil
+}
+
+// writeJSONReport writes report to filename as indented JSON
+func writeJSONReport(report *Report, filename string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return nil
+}
+
+// loadJSONReport reads a report previously written by writeJSONReport
+func loadJSONReport(filename string) (*Report, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %v", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %v", filename, err)
+	}
+	return &report, nil
+}
+
+// PageCountChange records a page whose inbound link count differs between two reports
+type PageCountChange struct {
+	URL      string
+	OldCount int
+	NewCount int
+}
+
+// ReportDiff lists what changed between a baseline report and a new one
+type ReportDiff struct {
+	NewPages     []string
+	RemovedPages []string
+	ChangedPages []PageCountChange
+	NewlyBroken  []string
+}
+
+// Empty reports whether the two reports had no differences
+func (d *ReportDiff) Empty() bool {
+	return len(d.NewPages) == 0 && len(d.RemovedPages) == 0 && len(d.ChangedPages) == 0 && len(d.NewlyBroken) == 0
+}
+
+// diffReports compares a baseline report with a new one. All lists are sorted by URL.
+func diffReports(old, new *Report) *ReportDiff {
+	diff := &ReportDiff{}
+
+	for page, newCount := range new.Pages {
+		oldCount, existed := old.Pages[page]
+		switch {
+		case !existed:
+			diff.NewPages = append(diff.NewPages, page)
+		case oldCount != newCount:
+			diff.ChangedPages = append(diff.ChangedPages, PageCountChange{URL: page, OldCount: oldCount, NewCount: newCount})
+		}
+	}
+	for page := range old.Pages {
+		if _, exists := new.Pages[page]; !exists {
+			diff.RemovedPages = append(diff.RemovedPages, page)
+		}
+	}
+	for link := range new.BrokenLinks {
+		if _, wasBroken := old.BrokenLinks[link]; !wasBroken {
+			diff.NewlyBroken = append(diff.NewlyBroken, link)
+		}
+	}
+
+	sort.Strings(diff.NewPages)
+	sort.Strings(diff.RemovedPages)
+	sort.Strings(diff.NewlyBroken)
+	sort.Slice(diff.ChangedPages, func(i, j int) bool {
+		return diff.ChangedPages[i].URL < diff.ChangedPages[j].URL
+	})
+	return diff
+}
+
+// printReportDiff prints the changes since the baseline report
+func printReportDiff(w io.Writer, diff *ReportDiff, baselineFile string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintf(w, "  CHANGES since %s\n", baselineFile)
+	fmt.Fprintln(w, "=============================")
+
+	if diff.Empty() {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	for _, page := range diff.NewPages {
+		fmt.Fprintf(w, "New page: %s\n", page)
+	}
+	for _, page := range diff.RemovedPages {
+		fmt.Fprintf(w, "Removed page: %s\n", page)
+	}
+	for _, change := range diff.ChangedPages {
+		fmt.Fprintf(w, "Changed: %s (%d -> %d internal links)\n", change.URL, change.OldCount, change.NewCount)
+	}
+	for _, link := range diff.NewlyBroken {
+		fmt.Fprintf(w, "Newly broken: %s\n", link)
+	}
+}
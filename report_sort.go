@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reportSort selects the order of the entries in printReport. The zero value sorts by count, highest first.
+type reportSort struct {
+	key   string // "count" (or ""), "url", "depth" or "status"
+	order string // "asc", "desc", or "" for the key's natural order
+	// Depth and HTTP status of each page by normalized URL, for the depth and status keys
+	depths   map[string]int
+	statuses map[string]int
+}
+
+// reportSortKeys are the keys accepted by --sort-by
+var reportSortKeys = map[string]bool{"count": true, "url": true, "depth": true, "status": true}
+
+// parseReportSort parses a --sort-by value such as "url" or "depth:desc"
+func parseReportSort(raw string) (reportSort, error) {
+	key, order, _ := strings.Cut(strings.ToLower(strings.TrimSpace(raw)), ":")
+	if !reportSortKeys[key] {
+		return reportSort{}, fmt.Errorf("unknown sort key %q (want count, url, depth or status)", key)
+	}
+	if order != "" && order != "asc" && order != "desc" {
+		return reportSort{}, fmt.Errorf("unknown sort order %q (want asc or desc)", order)
+	}
+	return reportSort{key: key, order: order}, nil
+}
+
+// descending reports whether entries are sorted highest first: counts by default, everything else on request
+func (s reportSort) descending() bool {
+	if s.order == "" {
+		return s.key == "" || s.key == "count"
+	}
+	return s.order == "desc"
+}
+
+// pageKeyed reports whether the sort key only applies to crawled pages, not external links
+func (s reportSort) pageKeyed() bool {
+	return s.key == "depth" || s.key == "status"
+}
+
+// less returns a sort.Slice comparator ordering list by the selected key, with ties broken by URL
+func (s reportSort) less(list []Page) func(i, j int) bool {
+	return func(i, j int) bool {
+		a, b := list[i], list[j]
+		var keyA, keyB int
+		switch s.key {
+		case "url":
+			if a.URL != b.URL {
+				return (a.URL < b.URL) != s.descending()
+			}
+			return false
+		case "depth":
+			keyA, keyB = a.Depth, b.Depth
+		case "status":
+			keyA, keyB = a.Status, b.Status
+		default:
+			keyA, keyB = a.Count, b.Count
+		}
+		if keyA != keyB {
+			return (keyA < keyB) != s.descending()
+		}
+		return a.URL < b.URL
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReportSort(t *testing.T) {
+	tests := []struct {
+		raw       string
+		expected  reportSort
+		expectErr bool
+	}{
+		{raw: "count", expected: reportSort{key: "count"}},
+		{raw: "URL", expected: reportSort{key: "url"}},
+		{raw: "depth:desc", expected: reportSort{key: "depth", order: "desc"}},
+		{raw: "status:asc", expected: reportSort{key: "status", order: "asc"}},
+		{raw: "size", expectErr: true},
+		{raw: "url:up", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.raw, func(t *testing.T) {
+			actual, err := parseReportSort(tc.raw)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPrintReportSortBy(t *testing.T) {
+	pages := map[string]int{
+		"example.com":         1,
+		"example.com/about":   5,
+		"example.com/blog":    5,
+		"example.com/contact": 2,
+	}
+	depths := map[string]int{"example.com": 0, "example.com/about": 1, "example.com/blog": 2, "example.com/contact": 1}
+	statuses := map[string]int{"example.com": 200, "example.com/about": 200, "example.com/blog": 404, "example.com/contact": 301}
+	externalLinks := map[string]int{"https://a.com": 1, "https://b.com": 3}
+
+	tests := []struct {
+		sortBy           string
+		expectedPages    []string
+		expectedExternal []string
+	}{
+		{
+			sortBy:           "count",
+			expectedPages:    []string{"/about", "/blog", "/contact", ""},
+			expectedExternal: []string{"https://b.com", "https://a.com"},
+		},
+		{
+			sortBy:           "count:asc",
+			expectedPages:    []string{"", "/contact", "/about", "/blog"},
+			expectedExternal: []string{"https://a.com", "https://b.com"},
+		},
+		{
+			sortBy:           "url",
+			expectedPages:    []string{"", "/about", "/blog", "/contact"},
+			expectedExternal: []string{"https://a.com", "https://b.com"},
+		},
+		{
+			sortBy:           "url:desc",
+			expectedPages:    []string{"/contact", "/blog", "/about", ""},
+			expectedExternal: []string{"https://b.com", "https://a.com"},
+		},
+		{
+			sortBy:           "depth",
+			expectedPages:    []string{"", "/about", "/contact", "/blog"},
+			expectedExternal: []string{"https://b.com", "https://a.com"},
+		},
+		{
+			sortBy:           "depth:desc",
+			expectedPages:    []string{"/blog", "/about", "/contact", ""},
+			expectedExternal: []string{"https://b.com", "https://a.com"},
+		},
+		{
+			sortBy:           "status",
+			expectedPages:    []string{"", "/about", "/contact", "/blog"},
+			expectedExternal: []string{"https://b.com", "https://a.com"},
+		},
+		{
+			sortBy:           "status:desc",
+			expectedPages:    []string{"/blog", "/contact", "", "/about"},
+			expectedExternal: []string{"https://b.com", "https://a.com"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.sortBy, func(t *testing.T) {
+			sortBy, err := parseReportSort(tc.sortBy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sortBy.depths, sortBy.statuses = depths, statuses
+
+			var buf bytes.Buffer
+			if err := printReport(&buf, pages, externalLinks, nil, "https://example.com", reportStyle{sort: sortBy}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var actualPages, actualExternal []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if _, page, ok := strings.Cut(line, " internal links to https://example.com"); ok {
+					actualPages = append(actualPages, page)
+				}
+				if _, link, ok := strings.Cut(line, " external links to "); ok {
+					actualExternal = append(actualExternal, link)
+				}
+			}
+			if !reflect.DeepEqual(actualPages, tc.expectedPages) {
+				t.Errorf("expected pages in order %q, got %q", tc.expectedPages, actualPages)
+			}
+			if !reflect.DeepEqual(actualExternal, tc.expectedExternal) {
+				t.Errorf("expected external links in order %q, got %q", tc.expectedExternal, actualExternal)
+			}
+		})
+	}
+}
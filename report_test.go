@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffReports(t *testing.T) {
+	old := &Report{
+		Pages: map[string]int{
+			"https://example.com":         5,
+			"https://example.com/about":   2,
+			"https://example.com/old":     1,
+			"https://example.com/contact": 3,
+		},
+		BrokenLinks: map[string]string{
+			"https://example.com/already-broken": "HTTP error 404",
+		},
+	}
+	new := &Report{
+		Pages: map[string]int{
+			"https://example.com":         5,
+			"https://example.com/about":   4,
+			"https://example.com/contact": 1,
+			"https://example.com/new":     1,
+		},
+		BrokenLinks: map[string]string{
+			"https://example.com/already-broken": "HTTP error 404",
+			"https://example.com/gone":           "HTTP error 410",
+		},
+	}
+
+	diff := diffReports(old, new)
+
+	expected := &ReportDiff{
+		NewPages:     []string{"https://example.com/new"},
+		RemovedPages: []string{"https://example.com/old"},
+		ChangedPages: []PageCountChange{
+			{URL: "https://example.com/about", OldCount: 2, NewCount: 4},
+			{URL: "https://example.com/contact", OldCount: 3, NewCount: 1},
+		},
+		NewlyBroken: []string{"https://example.com/gone"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected diff %+v, got %+v", expected, diff)
+	}
+	if diffReports(new, new).Empty() != true {
+		t.Errorf("expected no differences between identical reports")
+	}
+}
+
+func TestJSONReportRoundTrip(t *testing.T) {
+	report := &Report{
+		BaseURL:       "https://example.com",
+		Pages:         map[string]int{"https://example.com": 2},
+		ExternalLinks: map[string]int{"https://other.com": 1},
+		BrokenLinks:   map[string]string{},
+	}
+	filename := filepath.Join(t.TempDir(), "report.json")
+	if err := writeJSONReport(report, filename); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	loaded, err := loadJSONReport(filename)
+	if err != nil {
+		t.Fatalf("unexpected load error: %v", err)
+	}
+	if !reflect.DeepEqual(report, loaded) {
+		t.Errorf("expected %+v after round trip, got %+v", report, loaded)
+	}
+}
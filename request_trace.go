@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// requestTracerKey is the context key carrying the tracer that times the phases of page requests
+type requestTracerKey struct{}
+
+// withRequestTracer returns a context whose page requests are traced by tracer
+func withRequestTracer(ctx context.Context, tracer *requestTracer) context.Context {
+	return context.WithValue(ctx, requestTracerKey{}, tracer)
+}
+
+// phaseStats is the total time spent in one request phase and how many requests went through it
+type phaseStats struct {
+	Total time.Duration
+	Count int
+}
+
+// Average returns the mean time spent in the phase, or 0 if no request went through it
+func (p phaseStats) Average() time.Duration {
+	if p.Count == 0 {
+		return 0
+	}
+	return p.Total / time.Duration(p.Count)
+}
+
+// add counts d towards the phase when the request went through it
+func (p *phaseStats) add(d time.Duration, happened bool) {
+	if happened {
+		p.Total += d
+		p.Count++
+	}
+}
+
+// requestTracer aggregates DNS, connect, TLS and time-to-first-byte timings across traced requests.
+// Reused connections skip DNS, connect and TLS, so each phase is averaged over the requests that had it.
+type requestTracer struct {
+	mu        sync.Mutex
+	Requests  int
+	DNS       phaseStats
+	Connect   phaseStats
+	TLS       phaseStats
+	FirstByte phaseStats
+}
+
+// requestTrace times the phases of a single request, across any redirects it follows
+type requestTrace struct {
+	id    string
+	start time.Time
+
+	mu                                   sync.Mutex
+	dnsStart, connectStart, tlsStart     time.Time
+	dns, connect, tls, firstByte         time.Duration
+	sawDNS, sawConnect, sawTLS, sawFirst bool
+}
+
+// newRequestID returns a random identifier for correlating a request's debug lines
+func newRequestID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// begin starts tracing a request, returning the trace and a context carrying its httptrace hooks
+func (t *requestTracer) begin(ctx context.Context) (*requestTrace, context.Context) {
+	rt := &requestTrace{id: newRequestID(), start: time.Now()}
+	// locked runs f with the trace's lock held, as callbacks may fire on other goroutines
+	locked := func(f func()) {
+		rt.mu.Lock()
+		defer rt.mu.Unlock()
+		f()
+	}
+	hooks := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { locked(func() { rt.dnsStart = time.Now() }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			locked(func() { rt.dns += time.Since(rt.dnsStart); rt.sawDNS = true })
+		},
+		ConnectStart: func(string, string) { locked(func() { rt.connectStart = time.Now() }) },
+		ConnectDone: func(string, string, error) {
+			locked(func() { rt.connect += time.Since(rt.connectStart); rt.sawConnect = true })
+		},
+		TLSHandshakeStart: func() { locked(func() { rt.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			locked(func() { rt.tls += time.Since(rt.tlsStart); rt.sawTLS = true })
+		},
+		GotFirstResponseByte: func() {
+			locked(func() { rt.firstByte = time.Since(rt.start); rt.sawFirst = true })
+		},
+	}
+	return rt, httptrace.WithClientTrace(ctx, hooks)
+}
+
+// finish adds a finished request's phase timings to the totals and logs them as a debug line
+func (t *requestTracer) finish(rt *requestTrace, rawURL string) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	fmt.Printf("DEBUG: request %s %s: dns=%v connect=%v tls=%v ttfb=%v\n", rt.id, rawURL,
+		rt.dns.Round(time.Microsecond), rt.connect.Round(time.Microsecond), rt.tls.Round(time.Microsecond), rt.firstByte.Round(time.Microsecond))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Requests++
+	t.DNS.add(rt.dns, rt.sawDNS)
+	t.Connect.add(rt.connect, rt.sawConnect)
+	t.TLS.add(rt.tls, rt.sawTLS)
+	t.FirstByte.add(rt.firstByte, rt.sawFirst)
+}
+
+// printTraceStatistics prints the average time spent in each request phase
+func printTraceStatistics(t *requestTracer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Printf("Traced requests: %d\n", t.Requests)
+	for _, phase := range []struct {
+		name  string
+		stats phaseStats
+	}{
+		{"DNS lookup", t.DNS},
+		{"connect", t.Connect},
+		{"TLS handshake", t.TLS},
+		{"time to first byte", t.FirstByte},
+	} {
+		fmt.Printf("  Average %s: %v (%d requests)\n", phase.name, phase.stats.Average().Round(time.Microsecond), phase.stats.Count)
+	}
+}
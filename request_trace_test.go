@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestTracerRecordsPhases(t *testing.T) {
+	var requestID string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get("X-Request-ID")
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>traced</body></html>")
+	}))
+	defer server.Close()
+
+	// Trust the test server's certificate, with a fresh transport so the connection isn't reused
+	original := httpClient
+	httpClient = &http.Client{Timeout: defaultRequestTimeout, Transport: server.Client().Transport, CheckRedirect: checkRedirect}
+	t.Cleanup(func() { httpClient = original })
+
+	tracer := &requestTracer{}
+	ctx := withRequestTracer(context.Background(), tracer)
+	if _, err := performHTTPRequest(ctx, server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tracer.Requests != 1 {
+		t.Fatalf("expected 1 traced request, got %d", tracer.Requests)
+	}
+	for name, phase := range map[string]phaseStats{"connect": tracer.Connect, "TLS": tracer.TLS, "first byte": tracer.FirstByte} {
+		if phase.Count != 1 || phase.Total <= 0 {
+			t.Errorf("expected one timed %s phase, got %+v", name, phase)
+		}
+	}
+	// The server is reached by IP address, so there is no DNS lookup to time
+	if tracer.DNS.Count != 0 {
+		t.Errorf("expected no DNS phase, got %+v", tracer.DNS)
+	}
+	// The request ID only tags the log line; crawled sites never see it
+	if requestID != "" {
+		t.Errorf("expected no X-Request-ID header, got %q", requestID)
+	}
+
+	// A second request reuses the connection, so only the first byte is timed again
+	if _, err := performHTTPRequest(ctx, server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tracer.Requests != 2 || tracer.Connect.Count != 1 || tracer.FirstByte.Count != 2 {
+		t.Errorf("expected the reused connection to skip connect, got %+v", tracer)
+	}
+}
+
+func TestPhaseStatsAverage(t *testing.T) {
+	var stats phaseStats
+	if stats.Average() != 0 {
+		t.Errorf("expected 0 for no requests, got %v", stats.Average())
+	}
+	stats.add(10*time.Millisecond, true)
+	stats.add(time.Hour, false)
+	stats.add(20*time.Millisecond, true)
+	if stats.Average() != 15*time.Millisecond {
+		t.Errorf("expected 15ms, got %v", stats.Average())
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// recommendedSecurityHeaders are the headers reported as missing when they are captured but a page lacks them
+var recommendedSecurityHeaders = []string{
+	"Strict-Transport-Security",
+	"Content-Security-Policy",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+}
+
+// parseHeaderList parses a comma-separated list of header names such as "Server,Content-Security-Policy".
+// "security" stands for the recommended security headers. Names are returned in canonical form.
+func parseHeaderList(raw string) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		name = http.CanonicalHeaderKey(name)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			return nil, fmt.Errorf("empty header name in %q", raw)
+		case strings.EqualFold(part, "security"):
+			for _, name := range recommendedSecurityHeaders {
+				add(name)
+			}
+		case strings.ContainsAny(part, " :"):
+			return nil, fmt.Errorf("invalid header name %q", part)
+		default:
+			add(part)
+		}
+	}
+	return names, nil
+}
+
+// captureHeaders returns the values of the named headers present in header
+func captureHeaders(header http.Header, names []string) map[string]string {
+	captured := make(map[string]string)
+	for _, name := range names {
+		if values := header.Values(name); len(values) > 0 {
+			captured[name] = strings.Join(values, ", ")
+		}
+	}
+	return captured
+}
+
+// missingHeader is a recommended security header and the pages served without it
+type missingHeader struct {
+	Header string
+	Pages  []string
+}
+
+// missingSecurityHeaders returns, for each captured recommended security header, the pages lacking it.
+// Strict-Transport-Security only applies to pages served over https.
+func missingSecurityHeaders(pageHeaders map[string]map[string]string, captured []string) []missingHeader {
+	var findings []missingHeader
+	for _, header := range recommendedSecurityHeaders {
+		if indexOf(captured, header) < 0 {
+			continue
+		}
+		finding := missingHeader{Header: header}
+		for page, headers := range pageHeaders {
+			if header == "Strict-Transport-Security" && !strings.HasPrefix(page, "https://") {
+				continue
+			}
+			if _, ok := headers[header]; !ok {
+				finding.Pages = append(finding.Pages, page)
+			}
+		}
+		if len(finding.Pages) > 0 {
+			sort.Strings(finding.Pages)
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+// printHeaderReport prints how many pages sent each value of the captured headers, then the pages missing
+// recommended security headers
+func printHeaderReport(w io.Writer, pageHeaders map[string]map[string]string, captured []string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  RESPONSE HEADERS")
+	fmt.Fprintln(w, "=============================")
+
+	for _, header := range captured {
+		counts := make(map[string]int)
+		for _, headers := range pageHeaders {
+			if value, ok := headers[header]; ok {
+				counts[value]++
+			}
+		}
+		values := make([]string, 0, len(counts))
+		for value := range counts {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		fmt.Fprintf(w, "%s:\n", header)
+		if len(values) == 0 {
+			fmt.Fprintln(w, "  (not sent by any page)")
+		}
+		for _, value := range values {
+			fmt.Fprintf(w, "  %s (%d pages)\n", value, counts[value])
+		}
+	}
+
+	for _, finding := range missingSecurityHeaders(pageHeaders, captured) {
+		fmt.Fprintf(w, "Missing %s: %d pages\n", finding.Header, len(finding.Pages))
+		for _, page := range finding.Pages {
+			fmt.Fprintf(w, "  %s\n", page)
+		}
+	}
+}
+
+// headerRow is one crawled page in the --headers-out dump
+type headerRow struct {
+	URL     string            `json:"url"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+}
+
+// headerRows returns the captured headers of every crawled page with its status, sorted by URL
+func (cfg *config) headerRows() []headerRow {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+
+	rows := make([]headerRow, 0, len(cfg.pageHeaders))
+	for page, headers := range cfg.pageHeaders {
+		row := headerRow{URL: page, Headers: headers}
+		if normalized, err := cfg.normalizeURL(page); err == nil {
+			row.Status = cfg.pageStatuses[normalized]
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].URL < rows[j].URL })
+	return rows
+}
+
+// writeHeaderDump writes rows to filename as CSV when it ends in .csv, with one column per captured
+// header, and as JSON otherwise
+func writeHeaderDump(filename string, rows []headerRow, captured []string) error {
+	var data []byte
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		var out strings.Builder
+		w := csv.NewWriter(&out)
+		w.Write(append([]string{"url", "status"}, captured...))
+		for _, row := range rows {
+			record := []string{row.URL, fmt.Sprint(row.Status)}
+			for _, name := range captured {
+				record = append(record, row.Headers[name])
+			}
+			w.Write(record)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to encode headers: %v", err)
+		}
+		data = []byte(out.String())
+	} else {
+		encoded, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode headers: %v", err)
+		}
+		data = append(encoded, '\n')
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write headers: %v", err)
+	}
+	return nil
+}
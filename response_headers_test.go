@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseHeaderList(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "names canonicalized and deduplicated",
+			raw:      "server, x-frame-options,Server",
+			expected: []string{"Server", "X-Frame-Options"},
+		},
+		{
+			name:     "security expands to the recommended headers",
+			raw:      "Server,security",
+			expected: []string{"Server", "Strict-Transport-Security", "Content-Security-Policy", "X-Frame-Options", "X-Content-Type-Options"},
+		},
+		{
+			name:      "empty name",
+			raw:       "Server,,X-Frame-Options",
+			expectErr: true,
+		},
+		{
+			name:      "name with a value",
+			raw:       "Server: nginx",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := parseHeaderList(tc.raw)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlPageCapturesHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "nginx")
+		w.Header().Set("X-Frame-Options", "DENY")
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Security-Policy", "default-src 'self'")
+			fmt.Fprint(w, `<html><body><a href="/about">about</a></body></html>`)
+		case "/about":
+			fmt.Fprint(w, `<html><body>about</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.capturedHeaders = []string{"Server", "Content-Security-Policy", "X-Frame-Options"}
+	cfg.pageHeaders = make(map[string]map[string]string)
+	runTestCrawl(cfg)
+
+	expected := map[string]string{"Server": "nginx", "Content-Security-Policy": "default-src 'self'", "X-Frame-Options": "DENY"}
+	if headers := cfg.pageHeaders[server.URL]; !reflect.DeepEqual(headers, expected) {
+		t.Errorf("expected headers %v for the home page, got %v", expected, cfg.pageHeaders)
+	}
+
+	findings := missingSecurityHeaders(cfg.pageHeaders, cfg.capturedHeaders)
+	expectedFindings := []missingHeader{{Header: "Content-Security-Policy", Pages: []string{server.URL + "/about"}}}
+	if !reflect.DeepEqual(findings, expectedFindings) {
+		t.Errorf("expected findings %+v, got %+v", expectedFindings, findings)
+	}
+}
+
+func TestMissingSecurityHeadersHSTSOnlyOverHTTPS(t *testing.T) {
+	pageHeaders := map[string]map[string]string{
+		"http://example.com/":  {},
+		"https://example.com/": {},
+	}
+	findings := missingSecurityHeaders(pageHeaders, []string{"Strict-Transport-Security"})
+	expected := []missingHeader{{Header: "Strict-Transport-Security", Pages: []string{"https://example.com/"}}}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("expected %+v, got %+v", expected, findings)
+	}
+}
+
+func TestWriteHeaderDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Server", "nginx")
+		w.Header().Set("X-Frame-Options", "DENY")
+		fmt.Fprint(w, `<html><body>home</body></html>`)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.capturedHeaders = []string{"Server", "X-Frame-Options", "Content-Security-Policy"}
+	cfg.pageHeaders = make(map[string]map[string]string)
+	cfg.pageStatuses = make(map[string]int)
+	runTestCrawl(cfg)
+
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "headers.json")
+	if err := writeHeaderDump(jsonFile, cfg.headerRows(), cfg.capturedHeaders); err != nil {
+		t.Fatalf("failed to write JSON dump: %v", err)
+	}
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("failed to read JSON dump: %v", err)
+	}
+	var rows []headerRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("failed to parse JSON dump: %v", err)
+	}
+	expected := []headerRow{{URL: server.URL, Status: http.StatusOK, Headers: map[string]string{"Server": "nginx", "X-Frame-Options": "DENY"}}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected rows %+v, got %+v", expected, rows)
+	}
+
+	csvFile := filepath.Join(dir, "headers.csv")
+	if err := writeHeaderDump(csvFile, cfg.headerRows(), cfg.capturedHeaders); err != nil {
+		t.Fatalf("failed to write CSV dump: %v", err)
+	}
+	data, err = os.ReadFile(csvFile)
+	if err != nil {
+		t.Fatalf("failed to read CSV dump: %v", err)
+	}
+	expectedCSV := "url,status,Server,X-Frame-Options,Content-Security-Policy\n" + server.URL + ",200,nginx,DENY,\n"
+	if string(data) != expectedCSV {
+		t.Errorf("expected CSV:\n%s\ngot:\n%s", expectedCSV, data)
+	}
+}
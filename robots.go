@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Maximum robots.txt size to read (Google's limit is 500KiB)
+	maxRobotsSize = 500 * 1024
+	// Timeout for fetching robots.txt
+	robotsFetchTimeout = 10 * time.Second
+	// Default cap on a robots.txt Crawl-delay
+	defaultMaxCrawlDelay = 30 * time.Second
+)
+
+// robotsRule is a single Allow or Disallow path pattern
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules holds the rules from robots.txt that apply to this crawler
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration // minimum time between requests to the host, 0 if not declared
+}
+
+// allowed reports whether path may be crawled. The longest matching pattern wins,
+// and Allow wins a tie; a path matching no rule is allowed.
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	bestLen := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !robotsPatternMatches(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > bestLen || (len(rule.pattern) == bestLen && rule.allow) {
+			bestLen = len(rule.pattern)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsPatternMatches matches a robots.txt path pattern, supporting the "*" wildcard and "$" end anchor
+func robotsPatternMatches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		// An anchored pattern's last segment must match the end of the path
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	if anchored {
+		return pos == len(path)
+	}
+	return true
+}
+
+// parseRobotsTxt extracts the rules for userAgent, falling back to the "*" group
+func parseRobotsTxt(body, userAgent string) *robotsRules {
+	userAgent = strings.ToLower(userAgent)
+
+	var specific, wildcard []robotsRule
+	var specificDelay, wildcardDelay time.Duration
+	var hasSpecific bool
+	var groupAgents []string
+	inRules := false // true once the current group has started listing rules
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A User-agent line after rules starts a new group
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			// An empty Disallow allows everything and adds no rule
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: key == "allow"}
+			for _, agent := range groupAgents {
+				switch {
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				case strings.Contains(userAgent, agent):
+					specific = append(specific, rule)
+					hasSpecific = true
+				}
+			}
+		case "crawl-delay":
+			inRules = true
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				continue
+			}
+			delay := time.Duration(seconds * float64(time.Second))
+			for _, agent := range groupAgents {
+				switch {
+				case agent == "*":
+					wildcardDelay = delay
+				case strings.Contains(userAgent, agent):
+					specificDelay = delay
+					hasSpecific = true
+				}
+			}
+		}
+	}
+
+	if hasSpecific {
+		return &robotsRules{rules: specific, crawlDelay: specificDelay}
+	}
+	return &robotsRules{rules: wildcard, crawlDelay: wildcardDelay}
+}
+
+// robotsEntry caches the rules for one host, fetched at most once
+type robotsEntry struct {
+	once  sync.Once
+	rules *robotsRules
+	err   error // set when robots.txt could not be fetched
+	// Earliest time the next request may start under Crawl-delay
+	delayMu     sync.Mutex
+	nextRequest time.Time
+}
+
+// robotsCache fetches and caches robots.txt rules per scheme and host
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+	// Declared Crawl-delay values above this are clamped to it
+	maxCrawlDelay time.Duration
+}
+
+// newRobotsCache creates an empty robots.txt cache
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: make(map[string]*robotsEntry), maxCrawlDelay: defaultMaxCrawlDelay}
+}
+
+// allowed reports whether robots.txt on u's host permits crawling u
+func (c *robotsCache) allowed(ctx context.Context, u *url.URL) bool {
+	entry := c.load(ctx, u)
+	if entry.err != nil {
+		return true // Missing or unreachable robots.txt means no restrictions
+	}
+	return entry.rules.allowed(u.EscapedPath())
+}
+
+// load fetches robots.txt for u's host the first time it is needed and returns the cached entry
+func (c *robotsCache) load(ctx context.Context, u *url.URL) *robotsEntry {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &robotsEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules, entry.err = fetchRobotsTxt(ctx, key+"/robots.txt")
+		if entry.err != nil {
+			fmt.Printf("Could not load robots.txt for %s: %v\n", u.Host, entry.err)
+			return
+		}
+		if entry.rules.crawlDelay > c.maxCrawlDelay {
+			fmt.Printf("Warning: robots.txt for %s asks for a Crawl-delay of %v, using the maximum of %v\n", u.Host, entry.rules.crawlDelay, c.maxCrawlDelay)
+			entry.rules.crawlDelay = c.maxCrawlDelay
+		}
+	})
+	return entry
+}
+
+// waitCrawlDelay blocks until the host's Crawl-delay has passed since the previous request to it
+// on clock and reserves the next slot. It returns the context's error if ctx is done first.
+func (c *robotsCache) waitCrawlDelay(ctx context.Context, clock Clock, u *url.URL) error {
+	entry := c.load(ctx, u)
+	if entry.err != nil || entry.rules.crawlDelay <= 0 {
+		return nil
+	}
+
+	entry.delayMu.Lock()
+	now := clock.Now()
+	start := now
+	if entry.nextRequest.After(start) {
+		start = entry.nextRequest
+	}
+	entry.nextRequest = start.Add(entry.rules.crawlDelay)
+	entry.delayMu.Unlock()
+
+	select {
+	case <-clock.After(start.Sub(now)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fetchRobotsTxt downloads and parses a robots.txt file. A 4xx response means no rules apply.
+func fetchRobotsTxt(ctx context.Context, robotsURL string) (*robotsRules, error) {
+	ctx, cancel := context.WithTimeout(ctx, robotsFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setIdentityHeaders(req)
+
+	resp, err := clientFor(req).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return &robotsRules{}, nil
+	}
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("HTTP error %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return parseRobotsTxt(string(body), identityFor(ctx).robotsToken), nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseRobotsTxt(t *testing.T) {
+	body := `
+# Example robots.txt
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: OtherBot
+Disallow: /
+`
+	rules := parseRobotsTxt(body, "crawler")
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/", true},
+		{"/about", true},
+		{"/private", false},
+		{"/private/secret", false},
+		{"/private/public/page", true},
+		{"/files/report.pdf", false},
+		{"/files/report.pdf.html", true},
+	}
+	for _, tc := range tests {
+		if actual := rules.allowed(tc.path); actual != tc.expected {
+			t.Errorf("path %s: expected allowed=%v, got %v", tc.path, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseRobotsTxtSpecificAgent(t *testing.T) {
+	body := `
+User-agent: *
+Disallow: /
+
+User-agent: Crawler
+Disallow: /admin
+`
+	rules := parseRobotsTxt(body, "crawler")
+	if !rules.allowed("/about") {
+		t.Errorf("expected the Crawler group to override the * group")
+	}
+	if rules.allowed("/admin") {
+		t.Errorf("expected /admin to be disallowed for Crawler")
+	}
+}
+
+// newRobotsTestServer serves a robots.txt disallowing /private and a home page linking to it
+func newRobotsTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/private">private</a></body></html>`)
+		case "/private":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body>private</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCrawlPageRespectsRobotsTxt(t *testing.T) {
+	server := newRobotsTestServer(t)
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	if len(cfg.pages) != 1 {
+		t.Errorf("expected only the home page to be crawled, got %v", cfg.pages)
+	}
+}
+
+func TestCrawlPageIgnoreRobots(t *testing.T) {
+	server := newRobotsTestServer(t)
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.ignoreRobots = true
+	runTestCrawl(cfg)
+
+	if len(cfg.pages) != 2 {
+		t.Errorf("expected the disallowed page to be crawled with the override, got %v", cfg.pages)
+	}
+}
+
+func TestParseRobotsTxtCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected time.Duration
+	}{
+		{"wildcard group", "User-agent: *\nCrawl-delay: 2\n", 2 * time.Second},
+		{"fractional seconds", "User-agent: *\nCrawl-delay: 0.5\n", 500 * time.Millisecond},
+		{"specific group wins", "User-agent: *\nCrawl-delay: 10\n\nUser-agent: crawler\nCrawl-delay: 1\n", time.Second},
+		{"other agent only", "User-agent: OtherBot\nCrawl-delay: 10\n", 0},
+		{"invalid value", "User-agent: *\nCrawl-delay: soon\n", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := parseRobotsTxt(tc.body, "crawler").crawlDelay; actual != tc.expected {
+				t.Errorf("expected crawl delay %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRobotsCacheClampsCrawlDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 3600\n")
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL + "/page")
+
+	const maxDelay = 100 * time.Millisecond
+	cache := newRobotsCache()
+	cache.maxCrawlDelay = maxDelay
+	if delay := cache.load(context.Background(), u).rules.crawlDelay; delay != maxDelay {
+		t.Fatalf("expected the declared 3600s delay to be clamped to %v, got %v", maxDelay, delay)
+	}
+
+	// The first request goes straight through and the next two are spaced by the clamped delay
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := cache.waitCrawlDelay(context.Background(), realClock{}, u); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*maxDelay || elapsed > 10*maxDelay {
+		t.Errorf("expected 3 requests to take about %v, took %v", 2*maxDelay, elapsed)
+	}
+}
+
+func TestCrawlRobotsGroupOfUserAgent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: MyBot\nDisallow: /private\n\nUser-agent: *\nDisallow:\n")
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/private">private</a></body></html>`)
+		case "/private":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body>private</body></html>")
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.identity = newCrawlerIdentity("MyBot/2.0 (+https://example.com/bot)", "")
+	cfg.Run(time.Minute)
+
+	if len(cfg.pages) != 1 {
+		t.Errorf("expected the group for the User-Agent's product to apply, got %v", cfg.pages)
+	}
+}
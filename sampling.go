@@ -0,0 +1,30 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+)
+
+// linkSampler decides which discovered links are enqueued when sampling a large site.
+// Each decision comes from an RNG seeded with the sampler seed and the link itself, so the
+// chosen subset is reproducible regardless of the order goroutines discover links in.
+type linkSampler struct {
+	rate float64
+	seed uint64
+}
+
+// newLinkSampler creates a sampler keeping roughly rate (0 < rate <= 1) of links
+func newLinkSampler(rate float64, seed uint64) *linkSampler {
+	return &linkSampler{rate: rate, seed: seed}
+}
+
+// keep reports whether rawURL should be enqueued
+func (s *linkSampler) keep(rawURL string) bool {
+	if s.rate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(rawURL))
+	rng := rand.New(rand.NewPCG(s.seed, h.Sum64()))
+	return rng.Float64() < s.rate
+}
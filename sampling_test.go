@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func sampleLinks(s *linkSampler, urls []string) []string {
+	var kept []string
+	for _, u := range urls {
+		if s.keep(u) {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+func TestLinkSamplerStableSubset(t *testing.T) {
+	var urls []string
+	for i := 0; i < 200; i++ {
+		urls = append(urls, fmt.Sprintf("https://example.com/page/%d", i))
+	}
+
+	first := sampleLinks(newLinkSampler(0.1, 42), urls)
+	second := sampleLinks(newLinkSampler(0.1, 42), urls)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("expected the same subset for the same seed, got %v and %v", first, second)
+	}
+
+	// Discovery order must not change the decision for a given link
+	reversed := make([]string, len(urls))
+	for i, u := range urls {
+		reversed[len(urls)-1-i] = u
+	}
+	kept := make(map[string]bool)
+	for _, u := range sampleLinks(newLinkSampler(0.1, 42), reversed) {
+		kept[u] = true
+	}
+	for _, u := range first {
+		if !kept[u] {
+			t.Errorf("expected %s to be kept regardless of order", u)
+		}
+	}
+
+	if len(first) < 5 || len(first) > 40 {
+		t.Errorf("expected roughly 10%% of 200 links to be kept, got %d", len(first))
+	}
+
+	other := sampleLinks(newLinkSampler(0.1, 7), urls)
+	if reflect.DeepEqual(first, other) {
+		t.Errorf("expected a different seed to choose a different subset")
+	}
+}
+
+func TestLinkSamplerFullRateKeepsEverything(t *testing.T) {
+	s := newLinkSampler(1, 42)
+	if !s.keep("https://example.com/anything") {
+		t.Errorf("expected rate 1 to keep every link")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// noteLinkSchemes records, for --scheme-fallback, which https links on a page may be retried over http:
+// those written protocol-relative (//host/path), relative to the page, or explicitly as http and upgraded.
+// A link written explicitly as https anywhere is never downgraded. Links resolve against linkBase, like
+// the page's other links.
+func (cfg *config) noteLinkSchemes(htmlBody string, linkBase *url.URL) {
+	if cfg.schemeFallbacks == nil {
+		return
+	}
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	scanAnchors(htmlBody, func(href string) bool {
+		parsed, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return true
+		}
+		resolved := linkBase.ResolveReference(parsed)
+		if !strings.EqualFold(resolved.Scheme, "https") && !strings.EqualFold(resolved.Scheme, "http") {
+			return true
+		}
+		key, err := cfg.normalizeURL(resolved.String())
+		if err != nil {
+			return true
+		}
+		explicitHTTPS := strings.EqualFold(parsed.Scheme, "https")
+		if eligible, seen := cfg.schemeDowngradable[key]; !seen || (eligible && explicitHTTPS) {
+			cfg.schemeDowngradable[key] = !explicitHTTPS
+		}
+		return true
+	})
+}
+
+// schemeFallbackURL returns the http URL to retry an https page over after err, or "" when the failure
+// wasn't a TLS error or the page's links don't allow a downgrade
+func (cfg *config) schemeFallbackURL(pageURL *url.URL, normalizedURL string, err error) string {
+	if cfg.schemeFallbacks == nil || pageURL.Scheme != "https" || !isTLSError(err) {
+		return ""
+	}
+	cfg.mu.Lock()
+	eligible := cfg.schemeDowngradable[normalizedURL]
+	cfg.mu.Unlock()
+	if !eligible {
+		return ""
+	}
+	fallback := *pageURL
+	fallback.Scheme = "http"
+	// An explicit default https port would be wrong for http
+	if fallback.Port() == "443" {
+		fallback.Host = strings.TrimSuffix(fallback.Host, ":443")
+	}
+	return fallback.String()
+}
+
+// recordSchemeFallback notes that pageURL was fetched over http after its https fetch failed
+func (cfg *config) recordSchemeFallback(pageURL, reason string) {
+	cfg.mu.Lock()
+	cfg.schemeFallbacks[pageURL] = reason
+	cfg.mu.Unlock()
+}
+
+// printSchemeFallbackReport lists the pages fetched over http because of a TLS error over https
+func printSchemeFallbackReport(w io.Writer, fallbacks map[string]string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  SCHEME FALLBACKS")
+	fmt.Fprintln(w, "=============================")
+	if len(fallbacks) == 0 {
+		fmt.Fprintln(w, "No pages needed an http fallback")
+		return
+	}
+	pages := make([]string, 0, len(fallbacks))
+	for page := range fallbacks {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+	fmt.Fprintf(w, "%d pages fetched over http after a TLS error:\n", len(pages))
+	for _, page := range pages {
+		fmt.Fprintf(w, "  %s: %s\n", page, fallbacks[page])
+	}
+}
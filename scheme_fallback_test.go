@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// brokenTLSSite serves its home page over https, but every other page only over http: their https
+// requests fail certificate verification
+type brokenTLSSite struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (s *brokenTLSSite) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req.URL.String())
+	s.mu.Unlock()
+
+	if req.URL.Scheme == "https" && req.URL.Path != "/" && req.URL.Path != "" && req.URL.Path != "/robots.txt" {
+		return nil, &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}
+	}
+	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: req}
+	resp.Header.Set("Content-Type", "text/html")
+	switch req.URL.Path {
+	case "/", "":
+		resp.Body = io.NopCloser(strings.NewReader(`<html><body><a href="//example.test/relaxed">relaxed</a><a href="https://example.test/strict">strict</a></body></html>`))
+	case "/relaxed", "/strict":
+		resp.Body = io.NopCloser(strings.NewReader(`<html><body>leaf</body></html>`))
+	default:
+		resp.StatusCode = http.StatusNotFound
+	}
+	return resp, nil
+}
+
+func TestSchemeFallbackAfterTLSError(t *testing.T) {
+	site := &brokenTLSSite{}
+	cfg := newTestConfig(t, "https://example.test/", 10)
+	useTransport(cfg, site)
+	cfg.schemeFallbacks = make(map[string]string)
+	cfg.schemeDowngradable = make(map[string]bool)
+	summary := cfg.Run(time.Minute)
+
+	if _, ok := cfg.schemeFallbacks["https://example.test/relaxed"]; !ok || len(cfg.schemeFallbacks) != 1 {
+		t.Errorf("expected only the protocol-relative page to fall back to http, got %v", cfg.schemeFallbacks)
+	}
+	for _, request := range site.requests {
+		if request == "http://example.test/strict" {
+			t.Errorf("expected the explicit https link never to be downgraded, got requests %v", site.requests)
+		}
+	}
+	if summary.FailedRequests != 1 {
+		t.Errorf("expected only the explicit https page to fail, got %d failures", summary.FailedRequests)
+	}
+	if _, ok := cfg.pages["example.test/relaxed"]; !ok {
+		t.Errorf("expected the fallback page to be crawled, got %v", cfg.pages)
+	}
+
+	var out strings.Builder
+	printSchemeFallbackReport(&out, cfg.schemeFallbacks)
+	if !strings.Contains(out.String(), "1 pages fetched over http after a TLS error:") || !strings.Contains(out.String(), "https://example.test/relaxed: ") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestSchemeFallbackDisabledByDefault(t *testing.T) {
+	site := &brokenTLSSite{}
+	cfg := newTestConfig(t, "https://example.test/", 10)
+	useTransport(cfg, site)
+	summary := cfg.Run(time.Minute)
+
+	if summary.FailedRequests != 2 {
+		t.Errorf("expected both broken pages to fail without the fallback, got %d failures", summary.FailedRequests)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// seedRequest replaces the GET for the seed page, for sites whose entry point is a POST or GraphQL endpoint
+type seedRequest struct {
+	method      string
+	body        []byte
+	contentType string
+}
+
+// newSeedRequest validates a --method/--body combination. A body without a method is POSTed, and its
+// Content-Type is JSON when the body parses as JSON and a form otherwise.
+func newSeedRequest(method string, body []byte) (*seedRequest, error) {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		method = http.MethodGet
+		if len(body) > 0 {
+			method = http.MethodPost
+		}
+	}
+
+	switch method {
+	case http.MethodHead:
+		return nil, fmt.Errorf("method HEAD returns no page to crawl")
+	case http.MethodGet, http.MethodDelete, http.MethodOptions:
+		if len(body) > 0 {
+			return nil, fmt.Errorf("method %s doesn't take a request body", method)
+		}
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return nil, fmt.Errorf("unsupported method %q (expected GET, POST, PUT, PATCH, DELETE or OPTIONS)", method)
+	}
+
+	seed := &seedRequest{method: method, body: body}
+	if len(body) > 0 {
+		seed.contentType = "application/x-www-form-urlencoded"
+		if json.Valid(body) {
+			seed.contentType = "application/json"
+		}
+	}
+	return seed, nil
+}
+
+// seedRequestKey is the context key carrying the seedRequest for the seed page's fetch
+type seedRequestKey struct{}
+
+// withSeedRequest returns a context whose page requests use seed's method and body instead of GET
+func withSeedRequest(ctx context.Context, seed *seedRequest) context.Context {
+	return context.WithValue(ctx, seedRequestKey{}, seed)
+}
+
+// seedRequestFrom returns the seedRequest carried by ctx, or nil for a plain GET
+func seedRequestFrom(ctx context.Context) *seedRequest {
+	seed, _ := ctx.Value(seedRequestKey{}).(*seedRequest)
+	return seed
+}
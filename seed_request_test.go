@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestNewSeedRequest(t *testing.T) {
+	tests := []struct {
+		name                string
+		method              string
+		body                string
+		expectedMethod      string
+		expectedContentType string
+		wantErr             bool
+	}{
+		{name: "defaults to GET", expectedMethod: "GET"},
+		{name: "body implies POST", body: "q=1", expectedMethod: "POST", expectedContentType: "application/x-www-form-urlencoded"},
+		{name: "JSON body", method: "post", body: `{"query": "{ posts { url } }"}`, expectedMethod: "POST", expectedContentType: "application/json"},
+		{name: "PUT without body", method: "PUT", expectedMethod: "PUT"},
+		{name: "GET with body", method: "GET", body: "q=1", wantErr: true},
+		{name: "HEAD", method: "HEAD", wantErr: true},
+		{name: "unknown method", method: "FETCH", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			seed, err := newSeedRequest(tc.method, []byte(tc.body))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", seed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if seed.method != tc.expectedMethod || seed.contentType != tc.expectedContentType {
+				t.Errorf("expected %s with Content-Type %q, got %s with %q", tc.expectedMethod, tc.expectedContentType, seed.method, seed.contentType)
+			}
+		})
+	}
+}
+
+func TestCrawlPostsSeedBody(t *testing.T) {
+	const query = `{"query": "{ pages }"}`
+	var mu sync.Mutex
+	methods := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		methods[r.URL.Path] = r.Method
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/graphql":
+			if r.Method != http.MethodPost || string(body) != query || r.Header.Get("Content-Type") != "application/json" {
+				http.Error(w, "expected the JSON query", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `<html><body><a href="/posts/1">one</a><a href="/posts/2">two</a></body></html>`)
+		case "/posts/1", "/posts/2":
+			fmt.Fprint(w, `<html><body>post</body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	seed, err := newSeedRequest("", []byte(query))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := newTestConfig(t, server.URL+"/graphql", 10)
+	cfg.seedRequest = seed
+	runTestCrawl(cfg)
+
+	expected := map[string]string{"/graphql": "POST", "/posts/1": "GET", "/posts/2": "GET"}
+	for path, method := range expected {
+		if methods[path] != method {
+			t.Errorf("expected %s to be requested with %s, got %q", path, method, methods[path])
+		}
+	}
+	if len(cfg.brokenLinks) != 0 {
+		t.Errorf("expected no broken links, got %v", cfg.brokenLinks)
+	}
+}
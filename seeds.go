@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// loadSeeds reads seed URLs from filename, one per line. Blank lines and lines starting with # are ignored.
+func loadSeeds(filename string) ([]*url.URL, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seeds: %v", err)
+	}
+	defer file.Close()
+
+	var seeds []*url.URL
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" || strings.HasPrefix(raw, "#") {
+			continue
+		}
+		seed, err := url.Parse(raw)
+		if err != nil || seed.Host == "" {
+			return nil, fmt.Errorf("invalid seed URL on line %d: %q", line, raw)
+		}
+		seeds = append(seeds, seed)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read seeds: %v", err)
+	}
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("no seed URLs in %s", filename)
+	}
+	return seeds, nil
+}
+
+// seedReportNames names each seed's report after its host, adding the port when one is given and a
+// counter when several seeds share a host, so that no two seeds write to the same file
+func seedReportNames(seeds []*url.URL) []string {
+	names := make([]string, len(seeds))
+	used := make(map[string]int)
+	for i, seed := range seeds {
+		name := seed.Hostname()
+		if port := seed.Port(); port != "" {
+			name += "-" + port
+		}
+		used[name]++
+		if used[name] > 1 {
+			name += "-" + strconv.Itoa(used[name])
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// seedResult is the outcome of crawling one seed independently
+type seedResult struct {
+	Seed       string
+	Summary    Summary
+	ReportFile string
+	Err        error
+	config     *config // the seed's crawl, for its text report sections (nil if it couldn't start)
+}
+
+// crawlSeedsIndependently crawls each seed's site with crawl, which sets up and runs a config of its own,
+// running up to limit crawls at once, and writes a separate JSON report for each seed into outDir.
+// Results are in seed order.
+func crawlSeedsIndependently(seeds []*url.URL, limit int, outDir string, crawl func(seed *url.URL) (*config, Summary, error)) []seedResult {
+	if limit < 1 {
+		limit = 1
+	}
+	names := seedReportNames(seeds)
+	results := make([]seedResult, len(seeds))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i, seed := range seeds {
+		wg.Add(1)
+		go func(i int, seed *url.URL) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result := seedResult{Seed: seed.String()}
+			cfg, summary, err := crawl(seed)
+			if err != nil {
+				result.Err = err
+				results[i] = result
+				return
+			}
+			result.Summary = summary
+			result.config = cfg
+
+			paths := newArtifactPaths(outDir, names[i], seed)
+			result.ReportFile = paths.file("report.json")
+			report, err := cfg.buildReport(seed.String())
+			if err == nil {
+				err = paths.prepare()
+			}
+			if err == nil {
+				err = writeJSONReport(report, result.ReportFile)
+			}
+			if err != nil {
+				result.Err = fmt.Errorf("error writing report: %v", err)
+			}
+			results[i] = result
+		}(i, seed)
+	}
+	wg.Wait()
+	return results
+}
+
+// printSeedResults prints one line per independently crawled seed
+func printSeedResults(results []seedResult) {
+	fmt.Println()
+	fmt.Println("=============================")
+	fmt.Println("  SEED REPORTS")
+	fmt.Println("=============================")
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("%s: %v\n", result.Seed, result.Err)
+			continue
+		}
+		fmt.Printf("%s: %d pages, %d requests (%d failed) -> %s\n", result.Seed, len(result.Summary.Pages), result.Summary.TotalRequests, result.Summary.FailedRequests, result.ReportFile)
+		if result.Summary.Failure != nil {
+			fmt.Printf("  stopped by --fail-fast at %s\n", result.Summary.Failure)
+		}
+		for _, link := range result.Summary.BrokenExternal {
+			fmt.Printf("  broken external link: %s\n", link)
+		}
+	}
+}
+
+// seedsExitCode returns the exit code of a --seeds run: the highest exit code of the seeds' crawls, and at
+// least 1 when a seed couldn't be set up or its report couldn't be written
+func seedsExitCode(results []seedResult) int {
+	code := 0
+	for _, result := range results {
+		seedCode := result.Summary.exitCode()
+		if result.Err != nil && seedCode == 0 {
+			seedCode = 1
+		}
+		if seedCode > code {
+			code = seedCode
+		}
+	}
+	return code
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadSeeds(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "seeds.txt")
+	content := "# sites to audit\nhttps://example.com\n\n  https://example.org/docs  \n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write seeds: %v", err)
+	}
+	seeds, err := loadSeeds(file)
+	if err != nil {
+		t.Fatalf("loadSeeds() error: %v", err)
+	}
+	var got []string
+	for _, seed := range seeds {
+		got = append(got, seed.String())
+	}
+	want := []string{"https://example.com", "https://example.org/docs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadSeeds() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(file, []byte("not a url\n"), 0644); err != nil {
+		t.Fatalf("failed to write seeds: %v", err)
+	}
+	if _, err := loadSeeds(file); err == nil {
+		t.Error("expected an error for an invalid seed URL")
+	}
+}
+
+func TestSeedReportNames(t *testing.T) {
+	var seeds []*url.URL
+	for _, raw := range []string{"https://example.com", "http://127.0.0.1:8080", "https://example.com/blog"} {
+		seed, _ := url.Parse(raw)
+		seeds = append(seeds, seed)
+	}
+	want := []string{"example.com", "127.0.0.1-8080", "example.com-2"}
+	if got := seedReportNames(seeds); !reflect.DeepEqual(got, want) {
+		t.Errorf("seedReportNames() = %v, want %v", got, want)
+	}
+}
+
+func TestCrawlSeedsIndependently(t *testing.T) {
+	first := newTestServer(t, map[string][]string{
+		"/":  {"/a"},
+		"/a": {},
+	})
+	second := newTestServer(t, map[string][]string{
+		"/":  {"/b", "/c"},
+		"/b": {},
+		"/c": {},
+	})
+	var seeds []*url.URL
+	for _, raw := range []string{first.URL, second.URL} {
+		seed, _ := url.Parse(raw)
+		seeds = append(seeds, seed)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "reports")
+	results := crawlSeedsIndependently(seeds, 2, outDir, func(seed *url.URL) (*config, Summary, error) {
+		cfg := newTestConfig(t, seed.String(), 10)
+		return cfg, cfg.Run(time.Minute), nil
+	})
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	wantPages := []int{2, 3}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("seed %s: %v", result.Seed, result.Err)
+		}
+		report, err := loadJSONReport(result.ReportFile)
+		if err != nil {
+			t.Fatalf("seed %s: %v", result.Seed, err)
+		}
+		if report.BaseURL != seeds[i].String() {
+			t.Errorf("report %s has base URL %s, want %s", result.ReportFile, report.BaseURL, seeds[i])
+		}
+		if len(report.Pages) != wantPages[i] {
+			t.Errorf("report for %s has %d pages, want %d: %v", result.Seed, len(report.Pages), wantPages[i], report.Pages)
+		}
+	}
+	if results[0].ReportFile == results[1].ReportFile {
+		t.Errorf("both seeds wrote to %s", results[0].ReportFile)
+	}
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatalf("failed to read output directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 report files, got %d", len(entries))
+	}
+}
+
+func TestCrawlSeedsIndependentlySetupFailure(t *testing.T) {
+	server := newTestServer(t, map[string][]string{"/": {}})
+	var seeds []*url.URL
+	for _, raw := range []string{server.URL, "http://unreachable.test"} {
+		seed, _ := url.Parse(raw)
+		seeds = append(seeds, seed)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "reports")
+	results := crawlSeedsIndependently(seeds, 1, outDir, func(seed *url.URL) (*config, Summary, error) {
+		if seed.Host == "unreachable.test" {
+			return nil, Summary{}, fmt.Errorf("warmup failed: seed host %s is unreachable", seed.Host)
+		}
+		cfg := newTestConfig(t, seed.String(), 10)
+		return cfg, cfg.Run(time.Minute), nil
+	})
+
+	if results[0].Err != nil || results[0].config == nil {
+		t.Errorf("expected the first seed to be crawled, got %+v", results[0])
+	}
+	if results[1].Err == nil || results[1].config != nil || results[1].ReportFile != "" {
+		t.Errorf("expected the seed that failed to set up to report its error and no report, got %+v", results[1])
+	}
+}
+
+func TestSeedsExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []seedResult
+		expected int
+	}{
+		{"all clean", []seedResult{{Seed: "a"}, {Seed: "b"}}, 0},
+		{"setup error", []seedResult{{Seed: "a"}, {Seed: "b", Err: fmt.Errorf("warmup failed")}}, 1},
+		{"broken external link", []seedResult{{Seed: "a", Summary: Summary{BrokenExternal: []string{"https://gone.example/"}}}, {Seed: "b"}}, 1},
+		{"fail fast", []seedResult{{Seed: "a"}, {Seed: "b", Summary: Summary{Failure: &crawlFailure{}}}}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := seedsExitCode(tc.results); code != tc.expected {
+				t.Errorf("expected exit code %d, got %d", tc.expected, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// On-page SEO checks selectable with --seo-checks
+const (
+	seoCheckEmptyTitle       = "empty-title"
+	seoCheckMissingH1        = "missing-h1"
+	seoCheckMultipleH1       = "multiple-h1"
+	seoCheckEmptyDescription = "empty-description"
+)
+
+// allSEOChecks lists every SEO check in report order
+var allSEOChecks = []string{seoCheckEmptyTitle, seoCheckMissingH1, seoCheckMultipleH1, seoCheckEmptyDescription}
+
+// parseSEOChecks parses a comma-separated list of SEO checks; "all" enables every check
+func parseSEOChecks(raw string) (map[string]bool, error) {
+	checks := make(map[string]bool)
+	for _, check := range strings.Split(raw, ",") {
+		check = strings.ToLower(strings.TrimSpace(check))
+		switch {
+		case check == "":
+		case check == "all":
+			for _, c := range allSEOChecks {
+				checks[c] = true
+			}
+		case check == seoCheckEmptyTitle || check == seoCheckMissingH1 || check == seoCheckMultipleH1 || check == seoCheckEmptyDescription:
+			checks[check] = true
+		default:
+			return nil, fmt.Errorf("unknown SEO check %q (expected %s or all)", check, strings.Join(allSEOChecks, ", "))
+		}
+	}
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("no SEO checks given")
+	}
+	return checks, nil
+}
+
+// checkSEO returns the enabled checks that the page fails, in report order
+func checkSEO(htmlBody string, checks map[string]bool) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlBody))
+	if err != nil {
+		return nil
+	}
+
+	h1Count := doc.Find("h1").Length()
+	description := ""
+	doc.Find("meta[name]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if name, _ := s.Attr("name"); strings.EqualFold(name, "description") {
+			description, _ = s.Attr("content")
+			return false
+		}
+		return true
+	})
+
+	failed := map[string]bool{
+		seoCheckEmptyTitle:       strings.TrimSpace(doc.Find("title").First().Text()) == "",
+		seoCheckMissingH1:        h1Count == 0,
+		seoCheckMultipleH1:       h1Count > 1,
+		seoCheckEmptyDescription: strings.TrimSpace(description) == "",
+	}
+
+	var issues []string
+	for _, check := range allSEOChecks {
+		if checks[check] && failed[check] {
+			issues = append(issues, check)
+		}
+	}
+	return issues
+}
+
+// printSEOReport lists the pages failing each SEO check
+func printSEOReport(w io.Writer, issues map[string][]string, checks map[string]bool) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  SEO ISSUES")
+	fmt.Fprintln(w, "=============================")
+
+	for _, check := range allSEOChecks {
+		if !checks[check] {
+			continue
+		}
+		pages := append([]string(nil), issues[check]...)
+		sort.Strings(pages)
+		fmt.Fprintf(w, "%s: %d pages\n", check, len(pages))
+		for _, page := range pages {
+			fmt.Fprintf(w, "  %s\n", page)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckSEO(t *testing.T) {
+	allChecks, err := parseSEOChecks("all")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		html     string
+		checks   map[string]bool
+		expected []string
+	}{
+		{
+			name:     "complete page",
+			html:     `<html><head><title>Home</title><meta name="description" content="Welcome"></head><body><h1>Home</h1></body></html>`,
+			checks:   allChecks,
+			expected: nil,
+		},
+		{
+			name:     "empty title",
+			html:     `<html><head><title>  </title><meta name="description" content="Welcome"></head><body><h1>Home</h1></body></html>`,
+			checks:   allChecks,
+			expected: []string{seoCheckEmptyTitle},
+		},
+		{
+			name:     "missing h1",
+			html:     `<html><head><title>Home</title><meta name="description" content="Welcome"></head><body><h2>Home</h2></body></html>`,
+			checks:   allChecks,
+			expected: []string{seoCheckMissingH1},
+		},
+		{
+			name:     "multiple h1",
+			html:     `<html><head><title>Home</title><meta name="description" content="Welcome"></head><body><h1>One</h1><h1>Two</h1></body></html>`,
+			checks:   allChecks,
+			expected: []string{seoCheckMultipleH1},
+		},
+		{
+			name:     "empty description",
+			html:     `<html><head><title>Home</title><meta name="Description" content=""></head><body><h1>Home</h1></body></html>`,
+			checks:   allChecks,
+			expected: []string{seoCheckEmptyDescription},
+		},
+		{
+			name:     "only enabled checks are reported",
+			html:     `<html><body><p>nothing</p></body></html>`,
+			checks:   map[string]bool{seoCheckMissingH1: true},
+			expected: []string{seoCheckMissingH1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := checkSEO(tc.html, tc.checks); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseSEOChecks(t *testing.T) {
+	checks, err := parseSEOChecks("missing-h1, Empty-Title")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(checks, map[string]bool{seoCheckMissingH1: true, seoCheckEmptyTitle: true}) {
+		t.Errorf("unexpected checks %v", checks)
+	}
+	if _, err := parseSEOChecks("missing-h2"); err == nil {
+		t.Errorf("expected an error for an unknown check")
+	}
+}
+
+func TestCrawlPageReportsSEOIssues(t *testing.T) {
+	pages := map[string]string{
+		"/":               `<title>Home</title><meta name="description" content="Home"><h1>Home</h1><a href="/untitled">a</a><a href="/no-h1">b</a><a href="/two-h1">c</a><a href="/no-description">d</a>`,
+		"/untitled":       `<title></title><meta name="description" content="x"><h1>Untitled</h1>`,
+		"/no-h1":          `<title>No H1</title><meta name="description" content="x">`,
+		"/two-h1":         `<title>Two</title><meta name="description" content="x"><h1>One</h1><h1>Two</h1>`,
+		"/no-description": `<title>No description</title><h1>No description</h1>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head></head><body>%s</body></html>", body)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.seoChecks, _ = parseSEOChecks("all")
+	runTestCrawl(cfg)
+
+	expected := map[string][]string{
+		seoCheckEmptyTitle:       {server.URL + "/untitled"},
+		seoCheckMissingH1:        {server.URL + "/no-h1"},
+		seoCheckMultipleH1:       {server.URL + "/two-h1"},
+		seoCheckEmptyDescription: {server.URL + "/no-description"},
+	}
+	if !reflect.DeepEqual(cfg.seoIssues, expected) {
+		t.Errorf("expected issues %v, got %v", expected, cfg.seoIssues)
+	}
+
+	var buf bytes.Buffer
+	printSEOReport(&buf, cfg.seoIssues, cfg.seoChecks)
+	if !strings.Contains(buf.String(), "missing-h1: 1 pages\n  "+server.URL+"/no-h1\n") {
+		t.Errorf("expected missing-h1 bucket in report, got %q", buf.String())
+	}
+}
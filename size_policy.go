@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizePolicy caps response sizes by content type prefix (such as "text/html" or "image/"). The longest
+// matching prefix applies; other types fall back to maxResponseSize.
+type sizePolicy map[string]int64
+
+// sizePolicyKey is the context key carrying the size policy of the crawl a page is fetched for
+type sizePolicyKey struct{}
+
+// withSizePolicy returns a context whose fetched pages are capped by policy
+func withSizePolicy(ctx context.Context, policy sizePolicy) context.Context {
+	return context.WithValue(ctx, sizePolicyKey{}, policy)
+}
+
+// sizePolicyFor returns the size policy of pages fetched with ctx (nil applies maxResponseSize to everything)
+func sizePolicyFor(ctx context.Context) sizePolicy {
+	policy, _ := ctx.Value(sizePolicyKey{}).(sizePolicy)
+	return policy
+}
+
+// byteSizeUnits are the suffixes accepted by parseByteSize, longest first so "MB" isn't read as "B"
+var byteSizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// parseByteSize parses a size such as 512KB, 5MB or 1048576 (bytes)
+func parseByteSize(raw string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(raw))
+	factor := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			factor = unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (expected a positive size such as 5MB)", raw)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// parseSizePolicy parses a comma-separated list of type=size pairs, e.g. "text/html=5MB,image/=20MB"
+func parseSizePolicy(raw string) (sizePolicy, error) {
+	policy := make(sizePolicy)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, size, ok := strings.Cut(entry, "=")
+		prefix = strings.ToLower(strings.TrimSpace(prefix))
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("invalid entry %q (expected type=size such as text/html=5MB)", entry)
+		}
+		limit, err := parseByteSize(size)
+		if err != nil {
+			return nil, err
+		}
+		policy[prefix] = limit
+	}
+	if len(policy) == 0 {
+		return nil, fmt.Errorf("no size limits given")
+	}
+	return policy, nil
+}
+
+// limit returns the maximum size for a response of contentType. Limits never exceed maxResponseSize,
+// which bounds what the crawler will hold in memory.
+func (p sizePolicy) limit(contentType string) int64 {
+	mediaType := strings.ToLower(strings.TrimSpace(contentType))
+	limit, matched := int64(maxResponseSize), ""
+	for prefix, size := range p {
+		if strings.HasPrefix(mediaType, prefix) && len(prefix) > len(matched) {
+			limit, matched = size, prefix
+		}
+	}
+	if limit > maxResponseSize {
+		limit = maxResponseSize
+	}
+	return limit
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected int64
+		wantErr  bool
+	}{
+		{raw: "1048576", expected: 1048576},
+		{raw: "512KB", expected: 512 * 1024},
+		{raw: "5MB", expected: 5 * 1024 * 1024},
+		{raw: "1.5mb", expected: 1536 * 1024},
+		{raw: "1GB", expected: 1024 * 1024 * 1024},
+		{raw: "10B", expected: 10},
+		{raw: "0MB", wantErr: true},
+		{raw: "big", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.raw, func(t *testing.T) {
+			actual, err := parseByteSize(tc.raw)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %d", actual)
+				}
+				return
+			}
+			if err != nil || actual != tc.expected {
+				t.Errorf("expected %d, got %d (err %v)", tc.expected, actual, err)
+			}
+		})
+	}
+}
+
+func TestSizePolicyLimit(t *testing.T) {
+	policy, err := parseSizePolicy("text/=2MB, text/html=5MB ,image/=20MB")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		contentType string
+		expected    int64
+	}{
+		{contentType: "text/html; charset=utf-8", expected: 5 * 1024 * 1024},
+		{contentType: "TEXT/HTML", expected: 5 * 1024 * 1024},
+		{contentType: "text/plain", expected: 2 * 1024 * 1024},
+		{contentType: "image/png", expected: maxResponseSize},
+		{contentType: "application/pdf", expected: maxResponseSize},
+	}
+	for _, tc := range tests {
+		if actual := policy.limit(tc.contentType); actual != tc.expected {
+			t.Errorf("expected limit %d for %s, got %d", tc.expected, tc.contentType, actual)
+		}
+	}
+
+	if _, err := parseSizePolicy("text/html"); err == nil {
+		t.Error("expected an error for an entry without a size")
+	}
+}
+
+func TestFetchRejectsHTMLOverTypeCap(t *testing.T) {
+	ctx := withSizePolicy(context.Background(), sizePolicy{"text/html": 5 * 1024 * 1024})
+
+	page := "<html><body>" + strings.Repeat("a", 8*1024*1024) + "</body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/chunked" {
+			// Without a Content-Length the cap is enforced while reading the body
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/", "/chunked"} {
+		_, err := fetchPage(ctx, server.URL+path)
+		if err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("expected the 8MB page at %s to be rejected, got %v", path, err)
+		}
+	}
+
+	if _, err := fetchPage(context.Background(), server.URL); err != nil {
+		t.Errorf("expected the 8MB page to be accepted under the default cap, got %v", err)
+	}
+}
+
+func TestCrawlAppliesItsSizePolicy(t *testing.T) {
+	server := newTestServer(t, map[string][]string{"/": {"/a"}, "/a": {}})
+
+	capped := newTestConfig(t, server.URL, 10)
+	capped.sizePolicy = sizePolicy{"text/html": 16}
+	uncapped := newTestConfig(t, server.URL, 10)
+	capped.Run(time.Minute)
+	uncapped.Run(time.Minute)
+
+	if capped.failureKinds[FetchTooLarge] != 1 {
+		t.Errorf("expected the seed page to exceed the crawl's cap, got failures %v", capped.failureKinds)
+	}
+	if len(uncapped.pages) != 2 {
+		t.Errorf("expected another crawl to keep the default cap, got pages %v", uncapped.pages)
+	}
+}
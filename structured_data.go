@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultStructuredDataRules are the fields each schema.org @type needs for rich results, checked by
+// --json-schema-validate
+var defaultStructuredDataRules = map[string][]string{
+	"Article":        {"headline", "datePublished"},
+	"NewsArticle":    {"headline", "datePublished"},
+	"BlogPosting":    {"headline", "datePublished"},
+	"Product":        {"name"},
+	"Event":          {"name", "startDate", "location"},
+	"Organization":   {"name"},
+	"Person":         {"name"},
+	"Recipe":         {"name", "image"},
+	"BreadcrumbList": {"itemListElement"},
+	"FAQPage":        {"mainEntity"},
+	"JobPosting":     {"title", "datePosted", "description"},
+}
+
+// structuredDataIssue is a JSON-LD item missing required fields, or a JSON-LD script that isn't valid JSON
+type structuredDataIssue struct {
+	Type    string   `json:"type,omitempty"`
+	Missing []string `json:"missing,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// String describes the issue for the console
+func (i structuredDataIssue) String() string {
+	if i.Error != "" {
+		return i.Error
+	}
+	return fmt.Sprintf("%s missing %s", i.Type, strings.Join(i.Missing, ", "))
+}
+
+// loadStructuredDataRules reads extra rules from a JSON file mapping @type to its required fields, e.g.
+// {"Course": ["name", "provider"]}, on top of the built-in ones. A type listed in the file replaces its
+// built-in rule.
+func loadStructuredDataRules(filename string) (map[string][]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read structured data rules: %v", err)
+	}
+	var extra map[string][]string
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return nil, fmt.Errorf("failed to parse structured data rules: %v", err)
+	}
+	rules := make(map[string][]string, len(defaultStructuredDataRules)+len(extra))
+	for schemaType, fields := range defaultStructuredDataRules {
+		rules[schemaType] = fields
+	}
+	for schemaType, fields := range extra {
+		rules[schemaType] = fields
+	}
+	return rules, nil
+}
+
+// getJSONLDFromHTML returns the items of the page's application/ld+json scripts, with the items of a
+// top-level array or @graph listed one by one, and an error for each script that isn't valid JSON
+func getJSONLDFromHTML(html string) (items []map[string]any, errs []string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, nil
+	}
+	doc.Find("script[type]").Each(func(_ int, s *goquery.Selection) {
+		scriptType, _ := s.Attr("type")
+		if !strings.EqualFold(strings.TrimSpace(scriptType), "application/ld+json") {
+			return
+		}
+		var data any
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid JSON-LD: %v", err))
+			return
+		}
+		items = append(items, jsonLDItems(data)...)
+	})
+	return items, errs
+}
+
+// jsonLDItems flattens a JSON-LD value into its objects, descending into arrays and @graph
+func jsonLDItems(data any) []map[string]any {
+	switch value := data.(type) {
+	case []any:
+		var items []map[string]any
+		for _, element := range value {
+			items = append(items, jsonLDItems(element)...)
+		}
+		return items
+	case map[string]any:
+		if graph, ok := value["@graph"]; ok {
+			return jsonLDItems(graph)
+		}
+		return []map[string]any{value}
+	}
+	return nil
+}
+
+// jsonLDTypes returns an item's @type, which may be a single type or a list of them
+func jsonLDTypes(item map[string]any) []string {
+	switch value := item["@type"].(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var types []string
+		for _, element := range value {
+			if schemaType, ok := element.(string); ok {
+				types = append(types, schemaType)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// hasJSONLDField reports whether an item has field with a value: null, "" and [] count as missing
+func hasJSONLDField(item map[string]any, field string) bool {
+	switch value := item[field].(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(value) != ""
+	case []any:
+		return len(value) > 0
+	}
+	return true
+}
+
+// checkStructuredData returns the issues of a page's JSON-LD: the items missing fields their @type requires
+// under rules, then the scripts that aren't valid JSON. Types without a rule aren't checked.
+func checkStructuredData(html string, rules map[string][]string) []structuredDataIssue {
+	items, errs := getJSONLDFromHTML(html)
+	var issues []structuredDataIssue
+	for _, item := range items {
+		for _, schemaType := range jsonLDTypes(item) {
+			// Types may be written as full IRIs, e.g. https://schema.org/Article
+			schemaType = schemaType[strings.LastIndex(schemaType, "/")+1:]
+			var missing []string
+			for _, field := range rules[schemaType] {
+				if !hasJSONLDField(item, field) {
+					missing = append(missing, field)
+				}
+			}
+			if len(missing) > 0 {
+				issues = append(issues, structuredDataIssue{Type: schemaType, Missing: missing})
+			}
+		}
+	}
+	for _, err := range errs {
+		issues = append(issues, structuredDataIssue{Error: err})
+	}
+	return issues
+}
+
+// printStructuredDataReport lists the pages with incomplete or invalid structured data, sorted by URL
+func printStructuredDataReport(w io.Writer, issues map[string][]structuredDataIssue) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "=============================")
+	fmt.Fprintln(w, "  STRUCTURED DATA")
+	fmt.Fprintln(w, "=============================")
+	pages := make([]string, 0, len(issues))
+	for page := range issues {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	fmt.Fprintf(w, "%d pages with incomplete structured data\n", len(pages))
+	for _, page := range pages {
+		fmt.Fprintf(w, "%s\n", page)
+		for _, issue := range issues[page] {
+			fmt.Fprintf(w, "  %s\n", issue)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckStructuredData(t *testing.T) {
+	jsonLD := func(data string) string {
+		return `<html><head><script type="application/ld+json">` + data + `</script></head><body></body></html>`
+	}
+	tests := []struct {
+		name     string
+		html     string
+		expected []structuredDataIssue
+	}{
+		{
+			name: "complete article",
+			html: jsonLD(`{"@context": "https://schema.org", "@type": "Article", "headline": "Hi", "datePublished": "2024-01-01"}`),
+		},
+		{
+			name:     "article missing headline",
+			html:     jsonLD(`{"@type": "Article", "datePublished": "2024-01-01"}`),
+			expected: []structuredDataIssue{{Type: "Article", Missing: []string{"headline"}}},
+		},
+		{
+			name:     "empty values count as missing",
+			html:     jsonLD(`{"@type": "Event", "name": " ", "startDate": null, "location": []}`),
+			expected: []structuredDataIssue{{Type: "Event", Missing: []string{"name", "startDate", "location"}}},
+		},
+		{
+			name:     "graph items and type lists",
+			html:     jsonLD(`{"@graph": [{"@type": ["Product", "Thing"]}, {"@type": "https://schema.org/Organization", "name": "Acme"}]}`),
+			expected: []structuredDataIssue{{Type: "Product", Missing: []string{"name"}}},
+		},
+		{
+			name:     "top-level array",
+			html:     jsonLD(`[{"@type": "Person"}, {"@type": "Unknown"}]`),
+			expected: []structuredDataIssue{{Type: "Person", Missing: []string{"name"}}},
+		},
+		{
+			name:     "invalid JSON",
+			html:     jsonLD(`{"@type": "Article",}`),
+			expected: []structuredDataIssue{{Error: "invalid JSON-LD: invalid character '}' looking for beginning of object key string"}},
+		},
+		{
+			name: "other scripts are ignored",
+			html: `<html><head><script type="application/json">{"@type": "Article"}</script></head></html>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkStructuredData(tt.html, defaultStructuredDataRules); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadStructuredDataRules(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(filename, []byte(`{"Course": ["name", "provider"], "Article": ["headline"]}`), 0644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	rules, err := loadStructuredDataRules(filename)
+	if err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+	if !reflect.DeepEqual(rules["Course"], []string{"name", "provider"}) || !reflect.DeepEqual(rules["Article"], []string{"headline"}) {
+		t.Errorf("expected the file's rules, got %v", rules)
+	}
+	if !reflect.DeepEqual(rules["Product"], defaultStructuredDataRules["Product"]) {
+		t.Errorf("expected the built-in rules to be kept, got %v", rules)
+	}
+	if _, err := loadStructuredDataRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestCrawlPageReportsIncompleteStructuredData(t *testing.T) {
+	pages := map[string]string{
+		"/":      `<script type="application/ld+json">{"@type": "WebSite", "name": "Home"}</script><a href="/post">post</a><a href="/draft">draft</a>`,
+		"/post":  `<script type="application/ld+json">{"@type": "Article", "headline": "Post", "datePublished": "2024-01-01"}</script>`,
+		"/draft": `<script type="application/ld+json">{"@type": "Article", "datePublished": "2024-01-01"}</script>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head></head><body>%s</body></html>", body)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.structuredDataRules = defaultStructuredDataRules
+	cfg.structuredDataIssues = make(map[string][]structuredDataIssue)
+	runTestCrawl(cfg)
+
+	expected := map[string][]structuredDataIssue{
+		server.URL + "/draft": {{Type: "Article", Missing: []string{"headline"}}},
+	}
+	if !reflect.DeepEqual(cfg.structuredDataIssues, expected) {
+		t.Errorf("expected issues %+v, got %+v", expected, cfg.structuredDataIssues)
+	}
+
+	var buf bytes.Buffer
+	printStructuredDataReport(&buf, cfg.structuredDataIssues)
+	if !strings.Contains(buf.String(), server.URL+"/draft\n  Article missing headline\n") {
+		t.Errorf("expected the draft in the report, got %q", buf.String())
+	}
+}
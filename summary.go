@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Summary describes the outcome of a crawl for programmatic callers
+type Summary struct {
+	TotalRequests     int64 // pages attempted
+	TotalAttempts     int64 // HTTP attempts for those pages, retries included
+	AttemptsPerPage   float64
+	FailedRequests    int64
+	SuccessRate       float64 // Percentage of successful requests, 0 when no requests were made
+	Pages             map[string]int
+	ExternalLinks     map[string]int
+	HostErrors        map[string]int64
+	Duration          time.Duration // Wall-clock crawl time, excluding report generation
+	BytesDownloaded   int64
+	RequestsPerSecond float64 // Achieved request rate over Duration
+	// Broken page that stopped a --fail-fast crawl, nil if the crawl wasn't stopped
+	Failure *crawlFailure
+	// Dead external links failing --fail-on-broken-external, set once external links are validated
+	BrokenExternal []string
+}
+
+// exitCode is the process exit status for the crawl: non-zero when --fail-fast stopped it or
+// --fail-on-broken-external found dead links
+func (s Summary) exitCode() int {
+	if s.Failure != nil || len(s.BrokenExternal) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Run crawls from the base URL until every page is done or maxDuration elapses and returns a Summary.
+// With a soft deadline, no new page starts after it but pages in flight may finish until maxDuration.
+// Cancelling cfg.ctx stops the crawl early.
+func (cfg *config) Run(maxDuration time.Duration) Summary {
+	start := cfg.clock.Now()
+
+	// Wrap the context so a timeout can stop every crawling goroutine, and so requests use this crawl's
+	// clients and identity
+	ctx, cancel := context.WithCancel(cfg.requestContext(cfg.ctx))
+	defer cancel()
+	cfg.ctx = ctx
+	cfg.cancel = cancel
+
+	// Hand out concurrency slots by priority while the crawl runs
+	if cfg.priorities != nil {
+		go cfg.priorities.dispatch(ctx, cfg.concurrencyControl)
+	}
+
+	// Start crawling from the base URL
+	cfg.wg.Add(1)
+	go cfg.crawlPage(cfg.baseURL.String(), 0)
+
+	// Queue the pages the warmup sitemap listed as if the seed page linked to them
+	if len(cfg.sitemapSeeds) > 0 {
+		cfg.wg.Add(1)
+		go func() {
+			defer cfg.wg.Done()
+			cfg.enqueueLinks(cfg.sitemapSeeds, 0, cfg.defaultPriority())
+		}()
+	}
+
+	// Wait for all goroutines to complete or timeout
+	done := make(chan struct{})
+	go func() {
+		cfg.wg.Wait()
+		close(done)
+	}()
+
+	// At the soft deadline, stop starting pages but let those being fetched finish until maxDuration
+	var softDeadline <-chan time.Time
+	if cfg.softDeadline > 0 && cfg.softDeadline < maxDuration {
+		softDeadline = cfg.clock.After(cfg.softDeadline)
+	}
+	hardDeadline := cfg.clock.After(maxDuration)
+
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			// Normal completion
+			finished = true
+		case <-ctx.Done():
+			// Cancelled by the caller (e.g. on a shutdown signal)
+			waitBriefly(done)
+			finished = true
+		case <-softDeadline:
+			fmt.Printf("\nSoft deadline of %v reached, finishing pages in flight...\n", cfg.softDeadline)
+			cfg.draining.Store(true)
+			softDeadline = nil
+		case <-hardDeadline:
+			fmt.Printf("\nCrawl timed out after %v, stopping...\n", maxDuration)
+			cancel()
+			waitBriefly(done)
+			finished = true
+		}
+	}
+
+	return cfg.summary(cfg.clock.Now().Sub(start))
+}
+
+// waitBriefly gives crawling goroutines a moment to clean up after cancellation
+func waitBriefly(done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+	}
+}
+
+// summary snapshots the crawl counters and maps into a Summary
+func (cfg *config) summary(duration time.Duration) Summary {
+	s := Summary{
+		TotalRequests:   atomic.LoadInt64(cfg.totalRequests),
+		TotalAttempts:   atomic.LoadInt64(cfg.totalAttempts),
+		FailedRequests:  atomic.LoadInt64(cfg.failedRequests),
+		Pages:           make(map[string]int),
+		ExternalLinks:   make(map[string]int),
+		HostErrors:      make(map[string]int64),
+		Duration:        duration,
+		BytesDownloaded: atomic.LoadInt64(cfg.bytesDownloaded),
+	}
+	if s.TotalRequests > 0 {
+		s.SuccessRate = float64(s.TotalRequests-s.FailedRequests) / float64(s.TotalRequests) * 100
+		s.AttemptsPerPage = float64(s.TotalAttempts) / float64(s.TotalRequests)
+	}
+	if duration > 0 {
+		s.RequestsPerSecond = float64(s.TotalRequests) / duration.Seconds()
+	}
+
+	cfg.mu.Lock()
+	for page, count := range cfg.pages {
+		s.Pages[page] = count
+	}
+	for link, count := range cfg.externalLinks {
+		s.ExternalLinks[link] = count
+	}
+	s.Failure = cfg.failure
+	cfg.mu.Unlock()
+
+	cfg.hostErrorsMu.RLock()
+	for host, errorCount := range cfg.hostErrors {
+		if errorCount != nil {
+			s.HostErrors[host] = atomic.LoadInt64(errorCount)
+		}
+	}
+	cfg.hostErrorsMu.RUnlock()
+
+	return s
+}
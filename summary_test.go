@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunReturnsSummary(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/about", "https://other.com/page"},
+		"/about": {"/"},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	summary := cfg.Run(time.Minute)
+
+	if summary.TotalRequests != 2 {
+		t.Errorf("expected 2 requests, got %d", summary.TotalRequests)
+	}
+	if summary.FailedRequests != 0 {
+		t.Errorf("expected 0 failed requests, got %d", summary.FailedRequests)
+	}
+	if summary.SuccessRate != 100 {
+		t.Errorf("expected 100%% success rate, got %.1f", summary.SuccessRate)
+	}
+	if len(summary.Pages) != 2 {
+		t.Errorf("expected 2 pages, got %v", summary.Pages)
+	}
+	if summary.ExternalLinks["https://other.com/page"] != 1 {
+		t.Errorf("expected the external link to be counted once, got %v", summary.ExternalLinks)
+	}
+	if len(summary.HostErrors) != 0 {
+		t.Errorf("expected no host errors, got %v", summary.HostErrors)
+	}
+	if summary.BytesDownloaded <= 0 {
+		t.Errorf("expected downloaded bytes to be counted, got %d", summary.BytesDownloaded)
+	}
+	if summary.Duration <= 0 {
+		t.Errorf("expected a positive duration, got %v", summary.Duration)
+	}
+	// Each request waits at least requestDelay, so with 2 requests the rate is bounded
+	if summary.RequestsPerSecond <= 0 || summary.RequestsPerSecond > float64(summary.TotalRequests)/requestDelay.Seconds() {
+		t.Errorf("expected a rate between 0 and %.0f requests/second, got %.2f", float64(summary.TotalRequests)/requestDelay.Seconds(), summary.RequestsPerSecond)
+	}
+	if expected := float64(summary.TotalRequests) / summary.Duration.Seconds(); summary.RequestsPerSecond != expected {
+		t.Errorf("expected rate %.2f to match requests over duration, got %.2f", expected, summary.RequestsPerSecond)
+	}
+
+	// The summary is a snapshot, not a view of the live maps
+	cfg.pages["extra"] = 1
+	if _, ok := summary.Pages["extra"]; ok {
+		t.Errorf("expected summary pages to be a copy")
+	}
+}
+
+func TestSummaryCountsRetriedAttempts(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		// Fail twice with a retryable status, then succeed
+		if atomic.AddInt64(&requests, 1) <= 2 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>ok</body></html>")
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	summary := cfg.Run(time.Minute)
+
+	if summary.TotalRequests != 1 || summary.FailedRequests != 0 {
+		t.Errorf("expected 1 successful page, got %d attempted and %d failed", summary.TotalRequests, summary.FailedRequests)
+	}
+	if summary.TotalAttempts != 3 {
+		t.Errorf("expected 3 HTTP attempts, got %d", summary.TotalAttempts)
+	}
+	if summary.AttemptsPerPage != 3 {
+		t.Errorf("expected 3 attempts per page, got %.2f", summary.AttemptsPerPage)
+	}
+}
+
+// spanRecorder is an otel-like RoundTripper wrapper recording one span per request
+type spanRecorder struct {
+	next  http.RoundTripper
+	mu    sync.Mutex
+	spans []recordedSpan
+}
+
+type recordedSpan struct {
+	Method string
+	Path   string
+	Status int
+}
+
+func (r *spanRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	span := recordedSpan{Method: req.Method, Path: req.URL.Path}
+	if err == nil {
+		span.Status = resp.StatusCode
+	}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return resp, err
+}
+
+func TestRunUsesConfigClient(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/about"},
+		"/about": {},
+	})
+
+	original := httpClient.Transport
+	recorder := &spanRecorder{next: http.DefaultTransport}
+	cfg := newTestConfig(t, server.URL, 10)
+	useTransport(cfg, recorder)
+	cfg.Run(time.Minute)
+
+	statuses := make(map[string]int)
+	for _, span := range recorder.spans {
+		path := span.Path
+		if path == "" {
+			path = "/"
+		}
+		statuses[path] = span.Status
+	}
+	expected := map[string]int{"/robots.txt": http.StatusNotFound, "/": http.StatusOK, "/about": http.StatusOK}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected spans %v, got %+v", expected, recorder.spans)
+	}
+	if httpClient.Transport != original {
+		t.Error("expected the shared client to be left alone")
+	}
+}
+
+func TestParallelRunsUseTheirOwnClients(t *testing.T) {
+	first := newTestServer(t, map[string][]string{"/": {"/a"}, "/a": {}})
+	second := newTestServer(t, map[string][]string{"/": {"/b"}, "/b": {}})
+
+	recorders := []*spanRecorder{{next: http.DefaultTransport}, {next: http.DefaultTransport}}
+	var wg sync.WaitGroup
+	for i, server := range []string{first.URL, second.URL} {
+		cfg := newTestConfig(t, server, 10)
+		useTransport(cfg, recorders[i])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.Run(time.Minute)
+		}()
+	}
+	wg.Wait()
+
+	for i, expected := range []string{"/a", "/b"} {
+		paths := make(map[string]bool)
+		for _, span := range recorders[i].spans {
+			paths[span.Path] = true
+		}
+		if len(recorders[i].spans) != 3 || !paths[expected] {
+			t.Errorf("expected crawl %d's 3 requests, including %s, on its own transport, got %+v", i, expected, recorders[i].spans)
+		}
+	}
+}
+
+func TestRunSoftDeadlineFinishesPagesInFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			// Still being fetched when the soft deadline passes
+			time.Sleep(300 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><a href="/after">after</a></body></html>`)
+		case "/after":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body>late</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.softDeadline = 100 * time.Millisecond
+	summary := cfg.Run(time.Minute)
+
+	if summary.TotalRequests != 1 || summary.FailedRequests != 0 {
+		t.Errorf("expected the page in flight to complete, got %d attempted and %d failed", summary.TotalRequests, summary.FailedRequests)
+	}
+	seed, _ := cfg.normalizeURL(server.URL)
+	after, _ := cfg.normalizeURL(server.URL + "/after")
+	if _, ok := summary.Pages[seed]; !ok {
+		t.Errorf("expected the page in flight in the report, got %v", summary.Pages)
+	}
+	if _, ok := summary.Pages[after]; ok {
+		t.Errorf("expected no page to start after the soft deadline, got %v", summary.Pages)
+	}
+	if summary.Duration >= time.Minute {
+		t.Errorf("expected the crawl to end once the page in flight finished, took %v", summary.Duration)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Default window for warning about certificates that expire soon
+const defaultCertExpiryWindow = 30 * 24 * time.Hour
+
+// hostTLSInfo is the TLS connection metadata observed for one host
+type hostTLSInfo struct {
+	Version     string
+	CipherSuite string
+	NotAfter    time.Time // expiry of the leaf certificate
+}
+
+// newHostTLSInfo extracts the negotiated version, cipher suite and leaf certificate expiry
+func newHostTLSInfo(state *tls.ConnectionState) hostTLSInfo {
+	info := hostTLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) > 0 {
+		info.NotAfter = state.PeerCertificates[0].NotAfter
+	}
+	return info
+}
+
+// printTLSReport lists the TLS metadata of each host and warns about certificates expiring within window of now
+func printTLSReport(w io.Writer, hosts map[string]hostTLSInfo, window time.Duration, now time.Time) {
+	if len(hosts) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "TLS by host:")
+	for _, host := range names {
+		info := hosts[host]
+		fmt.Fprintf(w, "  %s: %s, %s, certificate expires %s\n", host, info.Version, info.CipherSuite, info.NotAfter.Format("2006-01-02"))
+	}
+	for _, host := range names {
+		if remaining := hosts[host].NotAfter.Sub(now); remaining < window {
+			if remaining < 0 {
+				fmt.Fprintf(w, "WARNING: certificate for %s expired on %s\n", host, hosts[host].NotAfter.Format("2006-01-02"))
+			} else {
+				fmt.Fprintf(w, "WARNING: certificate for %s expires in %d days\n", host, int(remaining.Hours()/24))
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrawlCollectsTLSInfo(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>secure</body></html>")
+	}))
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS13}
+	server.StartTLS()
+	defer server.Close()
+	useTestTransport(t, server.Client().Transport)
+
+	cfg := newTestConfig(t, server.URL, 10)
+	runTestCrawl(cfg)
+
+	info, ok := cfg.tlsInfo[cfg.baseURL.Hostname()]
+	if !ok {
+		t.Fatalf("expected TLS info for %s, got %v", cfg.baseURL.Hostname(), cfg.tlsInfo)
+	}
+	if info.Version != "TLS 1.3" {
+		t.Errorf("expected TLS 1.3, got %q", info.Version)
+	}
+	if info.CipherSuite == "" {
+		t.Errorf("expected a cipher suite name")
+	}
+	if !info.NotAfter.Equal(server.Certificate().NotAfter) {
+		t.Errorf("expected certificate expiry %v, got %v", server.Certificate().NotAfter, info.NotAfter)
+	}
+}
+
+func TestPrintTLSReportWarnsAboutExpiringCertificates(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	hosts := map[string]hostTLSInfo{
+		"soon.example.com":    {Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256", NotAfter: now.Add(10 * 24 * time.Hour)},
+		"later.example.com":   {Version: "TLS 1.2", CipherSuite: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", NotAfter: now.Add(365 * 24 * time.Hour)},
+		"expired.example.com": {Version: "TLS 1.2", CipherSuite: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", NotAfter: now.Add(-24 * time.Hour)},
+	}
+
+	var buf bytes.Buffer
+	printTLSReport(&buf, hosts, defaultCertExpiryWindow, now)
+	output := buf.String()
+
+	if !strings.Contains(output, "later.example.com: TLS 1.2, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, certificate expires 2025-06-01") {
+		t.Errorf("expected host metadata line, got %q", output)
+	}
+	if !strings.Contains(output, "WARNING: certificate for soon.example.com expires in 10 days") {
+		t.Errorf("expected expiry warning for soon.example.com, got %q", output)
+	}
+	if !strings.Contains(output, "WARNING: certificate for expired.example.com expired on 2024-05-31") {
+		t.Errorf("expected expired warning, got %q", output)
+	}
+	if strings.Contains(output, "WARNING: certificate for later.example.com") {
+		t.Errorf("expected no warning for later.example.com, got %q", output)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TreeNode is a page in the site tree, under the parent giving it the shallowest depth
+type TreeNode struct {
+	URL      string
+	Children []*TreeNode
+}
+
+// buildTree arranges the crawled pages (the keys of depths) as a tree rooted at seed. Each page hangs
+// under the referrer in edges with the smallest depth, which is always shallower than the page itself,
+// so the tree follows shortest crawl paths; the shallowest referrer is considered even when the listed
+// referrers were capped. Pages whose referrers weren't recorded hang under the seed.
+func buildTree(edges map[string]*inboundLinks, depths map[string]int, seed string) *TreeNode {
+	nodes := make(map[string]*TreeNode, len(depths))
+	for page := range depths {
+		nodes[page] = &TreeNode{URL: page}
+	}
+	root := nodes[seed]
+	if root == nil {
+		root = &TreeNode{URL: seed}
+		nodes[seed] = root
+	}
+
+	for page, depth := range depths {
+		if page == seed {
+			continue
+		}
+		parent := ""
+		if inbound := edges[page]; inbound != nil {
+			referrers := inbound.Referrers
+			if inbound.shallowest != "" {
+				referrers = append(referrers[:len(referrers):len(referrers)], inbound.shallowest)
+			}
+			for _, referrer := range referrers {
+				referrerDepth, crawled := depths[referrer]
+				if !crawled || referrerDepth >= depth {
+					continue
+				}
+				// Prefer the shallowest referrer, breaking ties by URL for a stable tree
+				if parent == "" || referrerDepth < depths[parent] || (referrerDepth == depths[parent] && referrer < parent) {
+					parent = referrer
+				}
+			}
+		}
+		if parent == "" {
+			parent = seed
+		}
+		nodes[parent].Children = append(nodes[parent].Children, nodes[page])
+	}
+
+	for _, node := range nodes {
+		sort.Slice(node.Children, func(i, j int) bool {
+			return node.Children[i].URL < node.Children[j].URL
+		})
+	}
+	return root
+}
+
+// printTree writes the tree with two spaces of indentation per level. label formats each node's URL.
+func printTree(w io.Writer, root *TreeNode, label func(string) string) {
+	var walk func(node *TreeNode, level int)
+	walk = func(node *TreeNode, level int) {
+		fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", level), label(node.URL))
+		for _, child := range node.Children {
+			walk(child, level+1)
+		}
+	}
+	walk(root, 0)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuildTree(t *testing.T) {
+	// /team is linked from /about (depth 1) and /about/people (depth 2); the shallower parent wins
+	depths := map[string]int{
+		"example.com":              0,
+		"example.com/about":        1,
+		"example.com/blog":         1,
+		"example.com/about/people": 2,
+		"example.com/team":         2,
+		"example.com/orphan":       3,
+	}
+	edges := map[string]*inboundLinks{
+		"example.com/about":        {Referrers: []string{"example.com", "example.com/blog"}, Total: 2},
+		"example.com/blog":         {Referrers: []string{"example.com"}, Total: 1},
+		"example.com/about/people": {Referrers: []string{"example.com/about"}, Total: 1},
+		"example.com/team":         {Referrers: []string{"example.com/about/people", "example.com/about"}, Total: 2},
+		"example.com":              {Referrers: []string{"example.com/blog"}, Total: 1},
+	}
+
+	root := buildTree(edges, depths, "example.com")
+
+	var buf bytes.Buffer
+	printTree(&buf, root, func(u string) string { return u })
+	expected := `example.com
+  example.com/about
+    example.com/about/people
+    example.com/team
+  example.com/blog
+  example.com/orphan
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected tree:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestBuildTreeUsesShallowestReferrerPastCap(t *testing.T) {
+	// /hub's listed referrers were capped at /deep before /docs' shallower link to it was recorded
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	cfg.maxReferrers = 1
+	cfg.recordLinks("example.com/deep", 2, []string{"https://example.com/hub"})
+	cfg.recordLinks("example.com/docs", 1, []string{"https://example.com/hub"})
+
+	depths := map[string]int{"example.com": 0, "example.com/docs": 1, "example.com/deep": 2, "example.com/hub": 2}
+	var buf bytes.Buffer
+	printTree(&buf, buildTree(cfg.inboundLinks, depths, "example.com"), func(u string) string { return u })
+	expected := `example.com
+  example.com/deep
+  example.com/docs
+    example.com/hub
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected tree:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestPageDepthsKeepShallowest(t *testing.T) {
+	cfg := newTestConfig(t, "https://example.com", 10)
+	cfg.pageDepths = make(map[string]int)
+
+	// A deep link can arrive before a shallow one
+	cfg.addPageVisit("example.com/about", 3)
+	cfg.addPageVisit("example.com/about", 1)
+	cfg.addPageVisit("example.com/about", 2)
+	if depth := cfg.pageDepths["example.com/about"]; depth != 1 {
+		t.Errorf("expected the shallowest depth 1, got %d", depth)
+	}
+
+	// Folding keeps the shallower of the two pages' depths
+	cfg.addPageVisit("example.com/team", 2)
+	cfg.foldPageVisit("example.com/about", "example.com/team", 3, false)
+	if depth, ok := cfg.pageDepths["example.com/team"]; !ok || depth != 1 {
+		t.Errorf("expected the folded page at depth 1, got %v", cfg.pageDepths)
+	}
+}
+
+func TestCrawlTree(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":           {"/docs", "/docs/guide"},
+		"/docs":       {"/docs/guide", "/docs/api"},
+		"/docs/guide": {"/docs/api"},
+		"/docs/api":   {"/"},
+	})
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.inboundLinks = make(map[string]*inboundLinks)
+	cfg.maxReferrers = defaultMaxReferrers
+	cfg.pageDepths = make(map[string]int)
+	runTestCrawl(cfg)
+
+	seed, _ := normalizeURL(server.URL)
+	var buf bytes.Buffer
+	printTree(&buf, buildTree(cfg.inboundLinks, cfg.pageDepths, seed), func(u string) string {
+		return u[len(seed):]
+	})
+	expected := `
+  /docs
+    /docs/api
+  /docs/guide
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected tree:\n%q\nexpected:\n%q", buf.String(), expected)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Default false-positive rate for the bloom filter visit store
+const defaultBloomFalsePositiveRate = 0.01
+
+// VisitStore is a compact alternative to the pages map for remembering which normalized URLs were visited.
+// It keeps no per-page link counts, so pages tracked this way don't appear in the report.
+type VisitStore interface {
+	Add(normalizedURL string)
+	Contains(normalizedURL string) bool
+	Len() int // number of URLs added
+}
+
+// bloomVisitStore is a VisitStore backed by a bloom filter. It uses a few bits per URL instead of the
+// full string, at the cost of occasionally reporting a URL as visited when it wasn't (a false positive),
+// which skips that page. It never forgets a URL that was added.
+type bloomVisitStore struct {
+	mu     sync.Mutex
+	bits   []uint64
+	m      uint64 // number of bits
+	k      uint64 // number of hash functions
+	length int
+}
+
+// newBloomVisitStore sizes a bloom filter for capacity URLs at the given false-positive rate
+func newBloomVisitStore(capacity int, falsePositiveRate float64) *bloomVisitStore {
+	if capacity < 1 {
+		capacity = 1
+	}
+	// Optimal sizing: m = -n ln(p) / ln(2)^2 bits and k = m/n ln(2) hash functions
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomVisitStore{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// positions derives the k bit positions for a URL by double hashing two 64-bit FNV hashes
+func (b *bloomVisitStore) positions(normalizedURL string) []uint64 {
+	h1 := fnv.New64a()
+	h1.Write([]byte(normalizedURL))
+	h2 := fnv.New64()
+	h2.Write([]byte(normalizedURL))
+	a, c := h1.Sum64(), h2.Sum64()|1 // an odd step visits distinct positions
+
+	positions := make([]uint64, b.k)
+	for i := uint64(0); i < b.k; i++ {
+		positions[i] = (a + i*c) % b.m
+	}
+	return positions
+}
+
+func (b *bloomVisitStore) Add(normalizedURL string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, pos := range b.positions(normalizedURL) {
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+	b.length++
+}
+
+func (b *bloomVisitStore) Contains(normalizedURL string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, pos := range b.positions(normalizedURL) {
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloomVisitStore) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.length
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomVisitStore(t *testing.T) {
+	const n = 10000
+	const falsePositiveRate = 0.01
+	store := newBloomVisitStore(n, falsePositiveRate)
+
+	for i := 0; i < n; i++ {
+		store.Add(fmt.Sprintf("example.com/page/%d", i))
+	}
+	if store.Len() != n {
+		t.Errorf("expected Len %d, got %d", n, store.Len())
+	}
+
+	// Inserted URLs are always reported as visited
+	for i := 0; i < n; i++ {
+		if url := fmt.Sprintf("example.com/page/%d", i); !store.Contains(url) {
+			t.Fatalf("expected %s to be reported as visited", url)
+		}
+	}
+
+	// Absent URLs are only rarely reported as visited
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if store.Contains(fmt.Sprintf("example.com/other/%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 2*falsePositiveRate {
+		t.Errorf("false-positive rate %.4f exceeds twice the configured %.2f", rate, falsePositiveRate)
+	}
+}
+
+func TestCrawlPageWithBloomVisitStore(t *testing.T) {
+	server := newTestServer(t, map[string][]string{
+		"/":      {"/about", "/blog"},
+		"/about": {"/", "/blog"},
+		"/blog":  {"/about"},
+	})
+
+	cfg := newTestConfig(t, server.URL, 10)
+	cfg.visited = newBloomVisitStore(10, 0.001)
+	runTestCrawl(cfg)
+
+	if cfg.visited.Len() != 3 {
+		t.Errorf("expected 3 visited pages, got %d", cfg.visited.Len())
+	}
+	if *cfg.totalRequests != 3 {
+		t.Errorf("expected each page to be fetched once, got %d requests", *cfg.totalRequests)
+	}
+	if len(cfg.pages) != 0 {
+		t.Errorf("expected the pages map to stay empty, got %v", cfg.pages)
+	}
+}
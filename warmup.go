@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// Maximum sitemap size to read during warmup
+	maxSitemapSize = 10 * 1024 * 1024
+	// Timeout for fetching the sitemap during warmup
+	sitemapFetchTimeout = 15 * time.Second
+)
+
+// sitemapDocument matches both <urlset> sitemaps and <sitemapindex> files
+type sitemapDocument struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// warmup fetches robots.txt (and optionally /sitemap.xml) for the seed host before the crawl starts,
+// so the first page isn't stalled on it and an unreachable seed host fails fast with a clear error.
+// The page URLs the sitemap lists are kept in sitemapSeeds and crawled alongside the seed.
+func (cfg *config) warmup(includeSitemap bool) error {
+	fmt.Printf("Warming up: fetching robots.txt for %s\n", cfg.baseURL.Host)
+
+	ctx := cfg.requestContext(cfg.ctx)
+	entry := cfg.robots.load(ctx, cfg.baseURL)
+	// Transport failures (DNS, refused connections, timeouts) mean the seed host can't be crawled at all
+	var urlErr *url.Error
+	if errors.As(entry.err, &urlErr) {
+		return fmt.Errorf("seed host %s is unreachable: %w", cfg.baseURL.Host, urlErr.Err)
+	}
+
+	if includeSitemap {
+		sitemapURL := cfg.baseURL.Scheme + "://" + cfg.baseURL.Host + "/sitemap.xml"
+		urls, sitemaps, err := fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			fmt.Printf("Could not load sitemap %s: %v\n", sitemapURL, err)
+		} else {
+			fmt.Printf("Sitemap %s lists %d URLs and %d nested sitemaps\n", sitemapURL, len(urls), len(sitemaps))
+			cfg.sitemapSeeds = urls
+		}
+	}
+
+	return nil
+}
+
+// fetchSitemap downloads a sitemap and returns the page URLs and nested sitemap URLs it lists
+func fetchSitemap(ctx context.Context, sitemapURL string) (urls []string, sitemaps []string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, sitemapFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setIdentityHeaders(req)
+
+	resp, err := clientFor(req).Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("HTTP error %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+	for _, u := range doc.URLs {
+		urls = append(urls, u.Loc)
+	}
+	for _, s := range doc.Sitemaps {
+		sitemaps = append(sitemaps, s.Loc)
+	}
+	return urls, sitemaps, nil
+}
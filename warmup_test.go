@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWarmupUnreachableSeedFails(t *testing.T) {
+	// Start and immediately close a server so its address refuses connections
+	server := httptest.NewServer(http.NotFoundHandler())
+	seedURL := server.URL
+	server.Close()
+
+	cfg := newTestConfig(t, seedURL, 10)
+	err := cfg.warmup(false)
+	if err == nil {
+		t.Fatal("expected warmup to fail for an unreachable seed host")
+	}
+	if !strings.Contains(err.Error(), "is unreachable") || !strings.Contains(err.Error(), cfg.baseURL.Host) {
+		t.Errorf("expected a descriptive unreachable error naming the host, got %q", err)
+	}
+}
+
+func TestWarmupCachesRobotsAndReadsSitemap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/sitemap.xml":
+			fmt.Fprint(w, `<?xml version="1.0"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/a</loc></url>
+	<url><loc>https://example.com/b</loc></url>
+</urlset>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	if err := cfg.warmup(true); err != nil {
+		t.Fatalf("unexpected warmup error: %v", err)
+	}
+	if len(cfg.robots.entries) != 1 {
+		t.Errorf("expected robots.txt for the seed host to be cached, got %d entries", len(cfg.robots.entries))
+	}
+
+	urls, _, err := fetchSitemap(cfg.ctx, server.URL+"/sitemap.xml")
+	if err != nil {
+		t.Fatalf("unexpected sitemap error: %v", err)
+	}
+	if !reflect.DeepEqual(urls, []string{"https://example.com/a", "https://example.com/b"}) {
+		t.Errorf("unexpected sitemap URLs: %v", urls)
+	}
+}
+
+func TestWarmupSitemapURLsAreCrawled(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>%s/orphan</loc></url>
+</urlset>`, server.URL)
+		case "/", "/orphan":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html><body>no links</body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, server.URL, 10)
+	if err := cfg.warmup(true); err != nil {
+		t.Fatalf("unexpected warmup error: %v", err)
+	}
+	cfg.Run(time.Minute)
+
+	orphan, _ := normalizeURL(server.URL + "/orphan")
+	if cfg.pages[orphan] != 1 {
+		t.Errorf("expected the page only the sitemap lists to be crawled, got pages %v", cfg.pages)
+	}
+}